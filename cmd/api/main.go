@@ -4,15 +4,21 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 
 	"github.com/aridsondez/AWS-SQS-LITE/internal/api"
 	"github.com/aridsondez/AWS-SQS-LITE/internal/config"
+	"github.com/aridsondez/AWS-SQS-LITE/internal/queue"
+	"github.com/aridsondez/AWS-SQS-LITE/internal/queue/maintenance"
+	"github.com/aridsondez/AWS-SQS-LITE/internal/queue/store"
 	pgstore "github.com/aridsondez/AWS-SQS-LITE/internal/queue/store/postgres"
 	"github.com/aridsondez/AWS-SQS-LITE/internal/queue/sweeper"
 )
@@ -39,13 +45,60 @@ func main() {
 		log.Fatalf("pgx ping: %v", err)
 	}
 
-	store := pgstore.New(pool)
+	pg := pgstore.New(pool)
 
-	swp := sweeper.New(store, cfg.SweeperInterval)
-	go swp.Start(ctx)
+	if cfg.ClockSkewWarn > 0 {
+		skew, err := pg.ClockSkew(connectCtx)
+		if err != nil {
+			log.Printf("clock skew check failed: %v", err)
+		} else if skew > cfg.ClockSkewWarn || skew < -cfg.ClockSkewWarn {
+			log.Printf("WARNING: database clock differs from this host by %s; lease_until and other timestamps use the database clock", skew.Round(time.Millisecond))
+		}
+	}
+
+	var st store.Store = pg
+	if cfg.SerializationRetries > 0 {
+		st = store.NewRetryStore(st, cfg.SerializationRetries)
+	}
+	if cfg.SlowQueryThreshold > 0 {
+		// outside the retries, so a call that was slow because it retried is logged
+		st = store.NewSlowQueryStore(st, cfg.SlowQueryThreshold)
+	}
+
+	swp := sweeper.New(st, cfg.SweeperInterval, queue.SweepOptions{
+		DLQRetention:     cfg.DLQRetention,
+		ArchiveRetention: cfg.ArchiveRetention,
+		MaxLifetime:      cfg.MaxMessageLifetime,
+		MaxDeliveries:    cfg.MaxDeliveries,
+		BatchSize:        cfg.SweeperBatch,
+		Backoff: queue.Backoff{
+			Strategy: queue.RetryStrategy(cfg.RetryStrategy),
+			Base:     cfg.RetryBackoff,
+			Max:      cfg.RetryBackoffMax,
+		},
+	})
+	// Not on ctx: the sweeper outlives the servers' drain and is stopped
+	// after it (see shutdown).
+	go swp.Start(context.Background())
+
+	if cfg.MaintenanceInterval > 0 {
+		// Postgres-specific, so it gets the store itself rather than st
+		m := maintenance.New(pg, cfg.MaintenanceInterval, maintenance.Options{
+			DeadRatio: float64(cfg.MaintenanceDeadPct) / 100,
+			Vacuum:    cfg.MaintenanceVacuum,
+		})
+		go m.Start(ctx)
+	}
+
+	if cfg.PushInterval > 0 {
+		go api.NewPushDeliverer(st, cfg).Start(ctx)
+	}
 
 	addr := fmt.Sprintf(":%d", cfg.Port)
-	httpSrv := api.NewServer(addr, store)
+	var level slog.Level
+	_ = level.UnmarshalText([]byte(cfg.LogLevel)) // validated by LoadConfig
+	accessLog := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: level}))
+	httpSrv := api.NewServer(addr, st, cfg, api.WithAccessLogger(accessLog))
 
 	log.Printf("HTTP server listening on %s", addr)
 	go func() {
@@ -54,7 +107,39 @@ func main() {
 		}
 	}()
 
+	var grpcSrv *api.GRPCServer
+	if cfg.GRPCPort > 0 {
+		lis, err := net.Listen("tcp", fmt.Sprintf(":%d", cfg.GRPCPort))
+		if err != nil {
+			log.Fatalf("grpc listen: %v", err)
+		}
+		grpcSrv = api.NewGRPCServer(st, cfg)
+		log.Printf("gRPC server listening on :%d", cfg.GRPCPort)
+		go func() {
+			if err := grpcSrv.Serve(lis); err != nil {
+				log.Fatalf("grpc server error: %v", err)
+			}
+		}()
+	}
+
 	<-ctx.Done()
 	log.Println("shutting down...")
-	_ = httpSrv.Shutdown(context.Background())
+	shutdown(httpSrv, grpcSrv, swp)
+}
+
+// shutdown stops the servers, then the sweeper. Until the servers have
+// drained, requests can still lease messages, and only a sweep requeues a
+// lease that lapses; with the sweeper stopped first, leases taken during the
+// drain could sit expired until another replica sweeps. The other way round
+// costs nothing, as requests never wait on a sweep. Stop lets a sweep already
+// running finish rather than cancelling it halfway through a batch.
+func shutdown(httpSrv *http.Server, grpcSrv *api.GRPCServer, swp *sweeper.Sweeper) {
+	if err := httpSrv.Shutdown(context.Background()); err != nil {
+		log.Printf("http shutdown: %v", err)
+	}
+	if grpcSrv != nil {
+		grpcSrv.GracefulStop()
+	}
+	swp.Stop()
+	log.Println("sweeper stopped")
 }
@@ -133,7 +133,7 @@ func scenario1_BasicFlow() {
 
 	// 3. Ack
 	fmt.Printf("%s→ Acknowledging message...%s\n", colorYellow, colorReset)
-	ackMessage(int64(messages[0].ID))
+	ackMessage(messages[0])
 	fmt.Printf("%s  ✓ Message acknowledged and deleted%s\n", colorGreen, colorReset)
 
 	// 4. Verify empty
@@ -183,7 +183,7 @@ func scenario2_SweeperRequeue() {
 	if len(messages) > 0 {
 		fmt.Printf("%s  ✓ Message requeued! Delivery count: %d%s\n",
 			colorGreen, messages[0].DeliveryCount, colorReset)
-		ackMessage(int64(messages[0].ID))
+		ackMessage(messages[0])
 		fmt.Printf("%s  ✓ Cleaned up message%s\n", colorGreen, colorReset)
 	}
 
@@ -244,7 +244,7 @@ func scenario3_DLQRouting() {
 		fmt.Printf("    ID: %d, Body: %v\n", int64(dlqMessages[0].ID), dlqMessages[0].Body)
 
 		// Clean up
-		ackMessage(int64(dlqMessages[0].ID))
+		ackMessage(dlqMessages[0])
 		fmt.Printf("%s  ✓ Cleaned up DLQ message%s\n", colorGreen, colorReset)
 	}
 
@@ -346,10 +346,10 @@ func receiveMessages(queue string, max int, visibilityMS int) []ReceivedMessage
 	return messages
 }
 
-func ackMessage(id int64) {
-	payload := []byte("{}")
+func ackMessage(msg ReceivedMessage) {
+	payload, _ := json.Marshal(map[string]string{"receipt": msg.Receipt})
 	resp, err := http.Post(
-		fmt.Sprintf("%s/v1/messages/%d:ack", baseURL, id),
+		fmt.Sprintf("%s/v1/messages/%d:ack", baseURL, msg.ID),
 		"application/json",
 		bytes.NewReader(payload),
 	)
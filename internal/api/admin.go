@@ -0,0 +1,31 @@
+package api
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// requireAdmin rejects requests that don't carry "Authorization: Bearer <token>".
+func requireAdmin(token string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !checkAdmin(w, r, token) {
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// checkAdmin reports whether r carries the admin token, writing a 401 if
+// not. An empty token authorizes nothing.
+func checkAdmin(w http.ResponseWriter, r *http.Request, token string) bool {
+	got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+		httpError(w, http.StatusUnauthorized, "admin token required")
+		return false
+	}
+	return true
+}
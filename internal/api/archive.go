@@ -0,0 +1,192 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/aridsondez/AWS-SQS-LITE/internal/metrics"
+	"github.com/aridsondez/AWS-SQS-LITE/internal/queue"
+)
+
+const (
+	defaultReplayMax = 100
+	maxReplayMax     = 1000
+)
+
+// replayRequest picks archived messages to enqueue again. At least one of
+// Queue and IDs must be set, so a request can't replay the whole archive.
+type replayRequest struct {
+	Queue  string            `json:"queue,omitempty"`  // archived from this queue
+	From   *time.Time        `json:"from,omitempty"`   // acked at or after this
+	To     *time.Time        `json:"to,omitempty"`     // acked before this
+	IDs    []json.RawMessage `json:"ids,omitempty"`    // the original messages' IDs, as the API showed them
+	Target string            `json:"target,omitempty"` // queue to replay into; default the one each was acked on
+	Max    int               `json:"max,omitempty"`    // copies to enqueue; default defaultReplayMax
+	DryRun bool              `json:"dry_run,omitempty"`
+}
+
+type replayResponse struct {
+	DryRun   bool              `json:"dry_run,omitempty"`
+	Replayed []replayedMessage `json:"replayed"`
+	More     bool              `json:"more,omitempty"` // more than max matched; narrow the filter to get the rest
+}
+
+// replayedMessage is one archived message and, unless the replay was a dry
+// run, the copy enqueued from it.
+type replayedMessage struct {
+	ArchivedID msgID     `json:"archived_id"`
+	AckedAt    time.Time `json:"acked_at"`
+	Queue      string    `json:"queue"` // the one the copy went, or would go, to
+	ID         *msgID    `json:"id,omitempty"`
+}
+
+// handleReplay enqueues fresh copies of archived messages: same body, body
+// ref and trace ID, a new ID and delivery count, and the retry settings of
+// the queue they go to. Copies don't join their original's group, whose
+// order they would otherwise have to wait their turn in. They go in one
+// transaction and are available at once; the archive keeps its copies.
+func (s *Server) handleReplay(w http.ResponseWriter, r *http.Request) {
+	var req replayRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httpError(w, http.StatusBadRequest, "invalid json: %v", err)
+		return
+	}
+	if req.Queue == "" && len(req.IDs) == 0 {
+		httpError(w, http.StatusBadRequest, "`queue` or `ids` is required")
+		return
+	}
+	if req.Max == 0 {
+		req.Max = defaultReplayMax
+	}
+	if req.Max < 1 || req.Max > maxReplayMax {
+		httpError(w, http.StatusBadRequest, "`max` must be between 1 and %d", maxReplayMax)
+		return
+	}
+	if len(req.IDs) > maxReplayMax {
+		httpError(w, http.StatusBadRequest, "`ids` must have at most %d items", maxReplayMax)
+		return
+	}
+	f := queue.ArchiveFilter{Queue: req.Queue}
+	if req.From != nil {
+		f.From = *req.From
+	}
+	if req.To != nil {
+		f.To = *req.To
+	}
+	if !f.From.IsZero() && !f.To.IsZero() && !f.From.Before(f.To) {
+		httpError(w, http.StatusBadRequest, "`from` must be before `to`")
+		return
+	}
+	for i, raw := range req.IDs {
+		if err := s.addArchiveID(&f, raw); err != nil {
+			httpError(w, http.StatusBadRequest, "`ids[%d]`: %v", i, err)
+			return
+		}
+	}
+
+	ctx := r.Context()
+	// one more than asked for says whether there are more
+	found, err := s.store.ArchivedMessages(ctx, f, req.Max+1)
+	if err != nil {
+		httpError(w, http.StatusInternalServerError, "read archive failed: %v", err)
+		return
+	}
+	resp := &replayResponse{DryRun: req.DryRun, Replayed: []replayedMessage{}}
+	if len(found) > req.Max {
+		found, resp.More = found[:req.Max], true
+	}
+
+	entries := make([]queue.EnqueueEntry, len(found))
+	configs := map[string]queue.QueueConfig{}
+	for i, a := range found {
+		target := req.Target
+		if target == "" {
+			target = a.Queue
+		}
+		qcfg, ok := configs[target]
+		if !ok {
+			if !req.DryRun {
+				if err := s.admitQueue(ctx, target); err != nil {
+					httpError(w, admitStatus(err), "%v", err)
+					return
+				}
+			}
+			if qcfg, err = s.store.GetQueueConfig(ctx, target); err != nil {
+				httpError(w, http.StatusInternalServerError, "get config failed: %v", err)
+				return
+			}
+			configs[target] = qcfg
+		}
+		entries[i].Message = s.replayCopy(a, target, qcfg)
+		resp.Replayed = append(resp.Replayed, replayedMessage{
+			ArchivedID: s.showID(a.Message),
+			AckedAt:    a.AckedAt,
+			Queue:      target,
+		})
+	}
+	if req.DryRun || len(entries) == 0 {
+		writeJSON(w, http.StatusOK, resp)
+		return
+	}
+
+	ids, err := s.store.EnqueueBatch(ctx, entries)
+	if err != nil {
+		httpError(w, http.StatusInternalServerError, "replay failed: %v", err)
+		return
+	}
+	shown, err := s.showIDs(ctx, ids, nil)
+	if err != nil {
+		httpError(w, http.StatusInternalServerError, "%v", err)
+		return
+	}
+	for i := range resp.Replayed {
+		resp.Replayed[i].ID = &shown[i]
+		metrics.MessagesEnqueued.WithLabelValues(resp.Replayed[i].Queue).Inc()
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// addArchiveID adds an original message's ID, a number or a ULID as the
+// server shows IDs, to f.
+func (s *Server) addArchiveID(f *queue.ArchiveFilter, raw json.RawMessage) error {
+	if !s.ulidIDs {
+		id, err := strconv.ParseInt(string(raw), 10, 64)
+		if err != nil {
+			return fmt.Errorf("%s is not a message ID", raw)
+		}
+		f.IDs = append(f.IDs, id)
+		return nil
+	}
+	var str string
+	if err := json.Unmarshal(raw, &str); err != nil {
+		return fmt.Errorf("%s is not a ULID", raw)
+	}
+	ulid, ok := parseULID(str)
+	if !ok {
+		return fmt.Errorf("%q is not a ULID", str)
+	}
+	f.ULIDs = append(f.ULIDs, ulid)
+	return nil
+}
+
+// replayCopy is the message a replay of a enqueues on target. The body is
+// already as the store keeps it, so it doesn't go through transformIn again.
+func (s *Server) replayCopy(a queue.ArchivedMessage, target string, qcfg queue.QueueConfig) queue.Message {
+	m := queue.Message{
+		Queue:      target,
+		Body:       a.Body,
+		BodyRef:    a.BodyRef,
+		TraceID:    a.TraceID,
+		MaxRetries: qcfg.MaxRetries,
+	}
+	if m.MaxRetries <= 0 {
+		m.MaxRetries = s.defaultMaxRetries
+	}
+	if qcfg.DLQ != nil && *qcfg.DLQ != target {
+		m.DLQ = qcfg.DLQ
+	}
+	return m
+}
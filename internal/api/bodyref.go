@@ -0,0 +1,163 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aridsondez/AWS-SQS-LITE/internal/queue"
+)
+
+// maxBodyRefLen bounds the `body_ref` of a claim-check enqueue.
+const maxBodyRefLen = 2048
+
+// bodyRefParallel bounds the fetches one receive runs at a time.
+const bodyRefParallel = 8
+
+var errBodyRefNotAllowed = errors.New("`body_ref` is not under BODY_REF_ALLOWED_PREFIXES")
+
+// refAllowed reports whether ref starts with one of prefixes. Refs with
+// credentials or dot segments are refused outright, since either can make a
+// ref that matches a prefix point somewhere else.
+func refAllowed(prefixes []string, ref string) bool {
+	u, err := url.Parse(ref)
+	if err != nil || u.User != nil || u.Host == "" {
+		return false
+	}
+	for _, seg := range strings.Split(u.Path, "/") {
+		if seg == "." || seg == ".." {
+			return false
+		}
+	}
+	for _, p := range prefixes {
+		if strings.HasPrefix(ref, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// BodyResolver fetches the body a claim-check message refers to, for the
+// server to inline at receive time. The result must be JSON.
+type BodyResolver interface {
+	Resolve(ctx context.Context, qname, ref string) ([]byte, error)
+}
+
+// HTTPBodyResolver resolves references that are URLs by GETting them.
+type HTTPBodyResolver struct {
+	Client   *http.Client
+	MaxBytes int64    // larger bodies fail to resolve
+	Allowed  []string // URL prefixes it will fetch, redirects included
+}
+
+// NewHTTPBodyResolver returns a resolver that fetches only URLs under
+// allowed, each within timeout and returning at most maxBytes.
+func NewHTTPBodyResolver(timeout time.Duration, maxBytes int, allowed []string) *HTTPBodyResolver {
+	h := &HTTPBodyResolver{MaxBytes: int64(maxBytes), Allowed: allowed}
+	h.Client = &http.Client{
+		Timeout: timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 10 {
+				return errors.New("stopped after 10 redirects")
+			}
+			if !refAllowed(h.Allowed, req.URL.String()) {
+				return fmt.Errorf("redirect to %s is not under an allowed prefix", req.URL.Redacted())
+			}
+			return nil
+		},
+	}
+	return h
+}
+
+func (h *HTTPBodyResolver) Resolve(ctx context.Context, _ string, ref string) ([]byte, error) {
+	if !refAllowed(h.Allowed, ref) {
+		return nil, fmt.Errorf("GET %s: not under an allowed prefix", ref)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ref, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := h.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", ref, resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, h.MaxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > h.MaxBytes {
+		return nil, fmt.Errorf("GET %s: body exceeds %d bytes", ref, h.MaxBytes)
+	}
+	return body, nil
+}
+
+// WithBodyResolver has receives inline claim-check bodies with r, in place
+// of the HTTP resolver BODY_REF_TIMEOUT configures.
+func WithBodyResolver(r BodyResolver) Option {
+	return func(s *Server) { s.resolver = r }
+}
+
+var errResolvedBody = errors.New("resolved body is not JSON")
+
+// resolveBodies inlines the bodies of the claim-check messages in out and
+// returns the messages that can be delivered. One whose body can't be
+// fetched is nacked with the reason instead, so it is retried, and
+// eventually dead-lettered, like a message its consumer failed. Without a
+// resolver the references are left for consumers to follow. Fetches run
+// bodyRefParallel at a time, so a full batch costs a few fetch timeouts at
+// most rather than one per message.
+func (s *Server) resolveBodies(ctx context.Context, out []queue.Message) []queue.Message {
+	if s.resolver == nil {
+		return out
+	}
+	bodies := make([][]byte, len(out))
+	errs := make([]error, len(out))
+	slots := make(chan struct{}, bodyRefParallel)
+	var wg sync.WaitGroup
+	for i, m := range out {
+		if m.BodyRef == nil {
+			continue
+		}
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(i int, m queue.Message) {
+			defer func() { <-slots; wg.Done() }()
+			bodies[i], errs[i] = s.resolver.Resolve(ctx, m.Queue, *m.BodyRef)
+			if errs[i] == nil && !json.Valid(bodies[i]) {
+				errs[i] = errResolvedBody
+			}
+		}(i, m)
+	}
+	wg.Wait()
+
+	kept := out[:0]
+	for i, m := range out {
+		if m.BodyRef == nil {
+			kept = append(kept, m)
+			continue
+		}
+		if err := errs[i]; err != nil {
+			reason := fmt.Sprintf("body fetch failed: %v", err)
+			if _, nerr := s.store.Nack(ctx, m.Receipt(), reason); nerr != nil {
+				log.Printf("nack message %d after %s: %v", m.ID, reason, nerr)
+			}
+			continue
+		}
+		m.Body = bodies[i]
+		kept = append(kept, m)
+	}
+	return kept
+}
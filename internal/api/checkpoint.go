@@ -0,0 +1,150 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/aridsondez/AWS-SQS-LITE/internal/queue"
+)
+
+// maxConsumerGroupLen bounds a consumer group name.
+const maxConsumerGroupLen = 128
+
+type readRequest struct {
+	Max int `json:"max"`
+}
+
+type readResponse struct {
+	Queue      string            `json:"queue"`
+	Group      string            `json:"group"`
+	Checkpoint int64             `json:"checkpoint"` // the messages are the ones after this ID
+	Messages   []receivedMessage `json:"messages"`
+}
+
+type checkpointRequest struct {
+	LastID *int64 `json:"last_id"`
+}
+
+type checkpointResponse struct {
+	Queue     string     `json:"queue"`
+	Group     string     `json:"group"`
+	LastID    int64      `json:"last_id"`
+	UpdatedAt *timestamp `json:"updated_at,omitempty"`
+}
+
+// consumerGroup is the {group} path param, or "" after answering 400.
+func consumerGroup(w http.ResponseWriter, r *http.Request) string {
+	group := chi.URLParam(r, "group")
+	if group == "" || len(group) > maxConsumerGroupLen {
+		httpError(w, http.StatusBadRequest, "consumer group must be 1 to %d bytes", maxConsumerGroupLen)
+		return ""
+	}
+	return group
+}
+
+// handleRead returns the messages after the group's checkpoint, log style:
+// nothing is leased or deleted, so any number of groups can read the same
+// messages, and a group reads them again until it commits a checkpoint past
+// them with PUT .../checkpoints/{group}.
+func (s *Server) handleRead(w http.ResponseWriter, r *http.Request) {
+	if s.ulidIDs {
+		httpError(w, http.StatusNotImplemented, "%v", errSerialOnly)
+		return
+	}
+	qname := chi.URLParam(r, "queue")
+	group := consumerGroup(w, r)
+	if group == "" {
+		return
+	}
+	var req readRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		httpError(w, http.StatusBadRequest, "invalid json: %v", err)
+		return
+	}
+	max := s.receiveLimit(req.Max)
+
+	ctx := r.Context()
+	cp, err := s.store.GetCheckpoint(ctx, qname, group)
+	if err != nil {
+		httpError(w, http.StatusInternalServerError, "get checkpoint failed: %v", err)
+		return
+	}
+	msgs, err := s.store.ReadAfter(ctx, qname, cp.LastID, max)
+	if err != nil {
+		httpError(w, http.StatusInternalServerError, "read failed: %v", err)
+		return
+	}
+	if err := s.transformOut(ctx, msgs); err != nil {
+		httpError(w, http.StatusInternalServerError, "%v", err)
+		return
+	}
+
+	resp := &readResponse{Queue: qname, Group: group, Checkpoint: cp.LastID, Messages: make([]receivedMessage, 0, len(msgs))}
+	for _, m := range msgs {
+		rm := s.toReceivedMessage(m)
+		rm.Receipt = "" // read, not leased
+		resp.Messages = append(resp.Messages, rm)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) handleGetCheckpoint(w http.ResponseWriter, r *http.Request) {
+	if s.ulidIDs {
+		httpError(w, http.StatusNotImplemented, "%v", errSerialOnly)
+		return
+	}
+	qname := chi.URLParam(r, "queue")
+	group := consumerGroup(w, r)
+	if group == "" {
+		return
+	}
+	cp, err := s.store.GetCheckpoint(r.Context(), qname, group)
+	if err != nil {
+		httpError(w, http.StatusInternalServerError, "get checkpoint failed: %v", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, toCheckpointResponse(cp))
+}
+
+// handlePutCheckpoint commits the group's progress. last_id may go back as
+// well as forward: moving it back replays the messages after it that the
+// queue still holds.
+func (s *Server) handlePutCheckpoint(w http.ResponseWriter, r *http.Request) {
+	if s.ulidIDs {
+		httpError(w, http.StatusNotImplemented, "%v", errSerialOnly)
+		return
+	}
+	qname := chi.URLParam(r, "queue")
+	group := consumerGroup(w, r)
+	if group == "" {
+		return
+	}
+	var req checkpointRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httpError(w, http.StatusBadRequest, "invalid json: %v", err)
+		return
+	}
+	if req.LastID == nil || *req.LastID < 0 {
+		httpError(w, http.StatusBadRequest, "`last_id` is required and must not be negative")
+		return
+	}
+
+	cp, err := s.store.SetCheckpoint(r.Context(), qname, group, *req.LastID)
+	if err != nil {
+		httpError(w, http.StatusInternalServerError, "set checkpoint failed: %v", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, toCheckpointResponse(cp))
+}
+
+func toCheckpointResponse(cp queue.Checkpoint) *checkpointResponse {
+	resp := &checkpointResponse{Queue: cp.Queue, Group: cp.Group, LastID: cp.LastID}
+	if !cp.UpdatedAt.IsZero() {
+		ts := timestamp(cp.UpdatedAt)
+		resp.UpdatedAt = &ts
+	}
+	return resp
+}
@@ -0,0 +1,74 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/aridsondez/AWS-SQS-LITE/internal/queue"
+)
+
+type claimRequest struct {
+	VisibilityMS int64 `json:"visibility_ms"` // optional; else the server's default
+}
+
+// handleClaim leases one message by ID instead of the head of its queue, so
+// a reprocessing tool can take a known message and work it like a consumer
+// would: it comes back with a receipt to ack, nack or extend.
+func (s *Server) handleClaim(w http.ResponseWriter, r *http.Request) {
+	id, err := s.pathID(r)
+	if err != nil {
+		pathIDError(w, err)
+		return
+	}
+	var req claimRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		httpError(w, http.StatusBadRequest, "invalid json: %v", err)
+		return
+	}
+	if req.VisibilityMS < 0 {
+		httpError(w, http.StatusBadRequest, "`visibility_ms` must not be negative")
+		return
+	}
+	vis := time.Duration(req.VisibilityMS) * time.Millisecond
+	if vis == 0 {
+		vis = s.visibility
+	}
+
+	ctx := r.Context()
+	if _, ok := s.limitInFlight(ctx, w, 1); !ok {
+		return
+	}
+	m, err := s.store.ClaimByID(ctx, id.serial, vis)
+	switch {
+	case errors.Is(err, queue.ErrMessageNotFound):
+		httpError(w, http.StatusNotFound, "%v", err)
+		return
+	case errors.Is(err, queue.ErrMessageLeased), errors.Is(err, queue.ErrMessageDelayed),
+		errors.Is(err, queue.ErrQueuePaused), errors.Is(err, queue.ErrMaxInFlight), errors.Is(err, queue.ErrGroupOrder):
+		httpError(w, http.StatusConflict, "%v", err)
+		return
+	case err != nil:
+		httpError(w, http.StatusInternalServerError, "claim failed: %v", err)
+		return
+	}
+	s.noteLeased(1)
+	out := []queue.Message{m}
+	if s.abandoned(ctx, out) {
+		return
+	}
+	if out = s.resolveBodies(ctx, out); len(out) == 0 {
+		httpError(w, http.StatusBadGateway, "claim-check body could not be fetched; the message was nacked")
+		return
+	}
+	if err := s.transformOut(ctx, out); err != nil {
+		httpError(w, http.StatusInternalServerError, "%v", err)
+		return
+	}
+
+	observeReceived(out[0])
+	s.countByWorker(r, out)
+	writeJSON(w, http.StatusOK, s.toReceivedMessage(out[0]))
+}
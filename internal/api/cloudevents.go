@@ -0,0 +1,120 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	cloudEventsVersion = "1.0"
+
+	// type of the events a raw body is wrapped in
+	wrappedEventType = "sqslite.message"
+)
+
+// cloudEvent is the envelope a raw body is wrapped in on a cloudevents
+// queue: the event in structured JSON mode, with the body as its data.
+type cloudEvent struct {
+	SpecVersion     string          `json:"specversion"`
+	ID              string          `json:"id"`
+	Source          string          `json:"source"`
+	Type            string          `json:"type"`
+	Time            string          `json:"time"`
+	DataContentType string          `json:"datacontenttype"`
+	Data            json.RawMessage `json:"data"`
+}
+
+// toCloudEvent returns body as a CloudEvent for queue qname. A body with a
+// specversion is taken to be an event already: it is checked against the
+// spec's required attributes and kept byte for byte. Any other body becomes
+// the data of a new event sourced from the queue.
+func toCloudEvent(qname string, body json.RawMessage) (json.RawMessage, error) {
+	var attrs map[string]json.RawMessage
+	if json.Unmarshal(body, &attrs) == nil && attrs["specversion"] != nil {
+		if err := validateCloudEvent(attrs); err != nil {
+			return nil, fmt.Errorf("`body` is not a valid CloudEvent: %w", err)
+		}
+		return body, nil
+	}
+
+	var id [16]byte
+	rand.Read(id[:])
+	return json.Marshal(&cloudEvent{
+		SpecVersion:     cloudEventsVersion,
+		ID:              hex.EncodeToString(id[:]),
+		Source:          "/v1/queues/" + qname,
+		Type:            wrappedEventType,
+		Time:            time.Now().UTC().Format(time.RFC3339Nano),
+		DataContentType: "application/json",
+		Data:            body,
+	})
+}
+
+// reviseCloudEvent is toCloudEvent for an edit of a message whose stored
+// body is stored: a plain body replaces only the data of the event already
+// there, so the event keeps its id, time and other attributes. A body that
+// is an event itself, or a stored body that isn't one, is as toCloudEvent.
+func reviseCloudEvent(qname string, stored, body json.RawMessage) (json.RawMessage, error) {
+	var attrs, old map[string]json.RawMessage
+	if json.Unmarshal(body, &attrs) == nil && attrs["specversion"] != nil {
+		return toCloudEvent(qname, body)
+	}
+	if json.Unmarshal(stored, &old) != nil || old["specversion"] == nil {
+		return toCloudEvent(qname, body)
+	}
+	delete(old, "data_base64")
+	old["data"] = body
+	old["datacontenttype"] = json.RawMessage(`"application/json"`)
+	return json.Marshal(old)
+}
+
+// validateCloudEvent checks the attributes CloudEvents 1.0 requires of every
+// event, and the optional ones it gives a format.
+func validateCloudEvent(attrs map[string]json.RawMessage) error {
+	str := func(name string) (string, bool, error) {
+		raw, ok := attrs[name]
+		if !ok {
+			return "", false, nil
+		}
+		var v string
+		if err := json.Unmarshal(raw, &v); err != nil {
+			return "", true, fmt.Errorf("`%s` must be a string", name)
+		}
+		return v, true, nil
+	}
+
+	version, _, err := str("specversion")
+	if err != nil {
+		return err
+	}
+	if version != cloudEventsVersion {
+		return fmt.Errorf("`specversion` %q is not supported, only %q", version, cloudEventsVersion)
+	}
+	for _, name := range []string{"id", "source", "type"} {
+		v, _, err := str(name)
+		if err != nil {
+			return err
+		}
+		if v == "" {
+			return fmt.Errorf("`%s` is required", name)
+		}
+	}
+	if t, ok, err := str("time"); err != nil {
+		return err
+	} else if ok {
+		if _, err := time.Parse(time.RFC3339Nano, t); err != nil {
+			return errors.New("`time` must be an RFC 3339 timestamp")
+		}
+	}
+	if _, _, err := str("datacontenttype"); err != nil {
+		return err
+	}
+	if attrs["data_base64"] != nil && attrs["data"] != nil {
+		return errors.New("use either `data` or `data_base64`, not both")
+	}
+	return nil
+}
@@ -0,0 +1,178 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/aridsondez/AWS-SQS-LITE/internal/queue"
+)
+
+const (
+	defaultDelayedLimit = 10
+	maxDelayedLimit     = 100
+)
+
+type delayedResponse struct {
+	Queue    string            `json:"queue"`
+	Messages []receivedMessage `json:"messages"`
+}
+
+type updateMessageRequest struct {
+	Body json.RawMessage `json:"body"`
+}
+
+type cancelResponse struct {
+	ID        msgID  `json:"id"`
+	Queue     string `json:"queue"`
+	Cancelled bool   `json:"cancelled"`
+}
+
+// handleDelayed lists a queue's scheduled messages, soonest first, so they
+// can be checked or cancelled before they go out. Nothing is leased.
+func (s *Server) handleDelayed(w http.ResponseWriter, r *http.Request) {
+	qname := chi.URLParam(r, "queue")
+	if qname == "" {
+		httpError(w, http.StatusBadRequest, "missing queue path param")
+		return
+	}
+	limit := defaultDelayedLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxDelayedLimit {
+			httpError(w, http.StatusBadRequest, "`limit` must be between 1 and %d", maxDelayedLimit)
+			return
+		}
+		limit = n
+	}
+
+	ctx := r.Context()
+	msgs, err := s.store.DelayedMessages(ctx, qname, limit)
+	if err != nil {
+		httpError(w, http.StatusInternalServerError, "list delayed failed: %v", err)
+		return
+	}
+	if err := s.transformOut(ctx, msgs); err != nil {
+		httpError(w, http.StatusInternalServerError, "%v", err)
+		return
+	}
+
+	resp := &delayedResponse{Queue: qname, Messages: make([]receivedMessage, 0, len(msgs))}
+	for _, m := range msgs {
+		rm := s.toReceivedMessage(m)
+		rm.Receipt = "" // not leased
+		resp.Messages = append(resp.Messages, rm)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// handleCancelMessage deletes a message before it is delivered, e.g. a
+// scheduled notification that is no longer wanted. Once a consumer has had
+// it, cancelling is refused unless ?force=true is sent with the admin token.
+func (s *Server) handleCancelMessage(w http.ResponseWriter, r *http.Request) {
+	id, err := s.pathID(r)
+	if err != nil {
+		pathIDError(w, err)
+		return
+	}
+	force := r.URL.Query().Get("force") == "true"
+	if force && !checkAdmin(w, r, s.adminToken) {
+		return
+	}
+
+	qname, err := s.store.CancelMessage(r.Context(), id.serial, force)
+	switch {
+	case errors.Is(err, queue.ErrMessageNotFound):
+		httpError(w, http.StatusNotFound, "%v", err)
+		return
+	case errors.Is(err, queue.ErrMessageDelivered):
+		httpError(w, http.StatusConflict, "%v; pass force=true with the admin token to delete it anyway", err)
+		return
+	case err != nil:
+		httpError(w, http.StatusInternalServerError, "cancel failed: %v", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, &cancelResponse{ID: id, Queue: qname, Cancelled: true})
+}
+
+// handleUpdateMessage replaces the body of a message that hasn't gone out
+// yet, e.g. to correct a scheduled notification. The new body is checked and
+// transformed as it would be on enqueue to the message's queue. Like a
+// cancel, it is refused once a consumer has had the message.
+func (s *Server) handleUpdateMessage(w http.ResponseWriter, r *http.Request) {
+	id, err := s.pathID(r)
+	if err != nil {
+		pathIDError(w, err)
+		return
+	}
+	var req updateMessageRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httpError(w, http.StatusBadRequest, "invalid json: %v", err)
+		return
+	}
+
+	ctx := r.Context()
+	m, err := s.store.GetMessage(ctx, id.serial)
+	if errors.Is(err, queue.ErrMessageNotFound) {
+		httpError(w, http.StatusNotFound, "%v", err)
+		return
+	}
+	if err != nil {
+		httpError(w, http.StatusInternalServerError, "get message failed: %v", err)
+		return
+	}
+	qcfg, err := s.store.GetQueueConfig(ctx, m.Queue)
+	if err != nil {
+		httpError(w, http.StatusInternalServerError, "get config failed: %v", err)
+		return
+	}
+	// An edit keeps the message's event, so newMessage mustn't wrap the
+	// body in a new one; the event stored is revised below instead.
+	stored := []queue.Message{m}
+	cloudEvents := qcfg.CloudEvents
+	if cloudEvents {
+		if err := s.transformOut(ctx, stored); err != nil {
+			httpError(w, http.StatusInternalServerError, "%v", err)
+			return
+		}
+		qcfg.CloudEvents = false
+	}
+	msg, _, err := s.newMessage(m.Queue, qcfg, enqueueRequest{Body: req.Body})
+	if err == nil && cloudEvents {
+		if msg.Body, err = reviseCloudEvent(m.Queue, stored[0].Body, msg.Body); err == nil && len(msg.Body) > s.maxMessageBytes {
+			err = fmt.Errorf("%w: `body` is %d bytes, max is %d", errMessageTooLarge, len(msg.Body), s.maxMessageBytes)
+		}
+	}
+	if err != nil {
+		code := http.StatusBadRequest
+		if errors.Is(err, errMessageTooLarge) {
+			code = http.StatusRequestEntityTooLarge
+		}
+		httpError(w, code, "%v", err)
+		return
+	}
+	if err := s.transformIn(ctx, &msg); err != nil {
+		httpError(w, http.StatusBadRequest, "%v", err)
+		return
+	}
+
+	updated, err := s.store.UpdateBody(ctx, id.serial, msg.Body)
+	switch {
+	case errors.Is(err, queue.ErrMessageNotFound):
+		httpError(w, http.StatusNotFound, "%v", err)
+		return
+	case errors.Is(err, queue.ErrMessageDelivered):
+		httpError(w, http.StatusConflict, "%v; only undelivered messages can be edited", err)
+		return
+	case err != nil:
+		httpError(w, http.StatusInternalServerError, "update failed: %v", err)
+		return
+	}
+	rm := s.toReceivedMessage(updated)
+	rm.Receipt = "" // not leased
+	writeJSON(w, http.StatusOK, &rm)
+}
@@ -0,0 +1,56 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// depthCacheTTL is how long a counted queue depth is reused before the
+// queue is counted again.
+const depthCacheTTL = time.Second
+
+// depthCache remembers each queue's available count for depthCacheTTL, so
+// receives can report the backlog without a count per receive. Receives in
+// between subtract what they claimed; enqueues in between aren't seen.
+type depthCache struct {
+	mu      sync.Mutex
+	entries map[string]depthEntry
+}
+
+type depthEntry struct {
+	depth     int64
+	countedAt time.Time
+}
+
+// setDepthHeader sets X-Approx-Queue-Depth to the messages left available in
+// qname after a receive that claimed claimed of them. A failed count leaves
+// the header off rather than failing a receive that already holds leases.
+func (s *Server) setDepthHeader(ctx context.Context, w http.ResponseWriter, qname string, claimed int) {
+	c := &s.depths
+	c.mu.Lock()
+	e, ok := c.entries[qname]
+	if ok && time.Since(e.countedAt) < depthCacheTTL {
+		e.depth = max(e.depth-int64(claimed), 0)
+		c.entries[qname] = e
+		c.mu.Unlock()
+		w.Header().Set("X-Approx-Queue-Depth", strconv.FormatInt(e.depth, 10))
+		return
+	}
+	c.mu.Unlock()
+
+	// counted after the claim, so it already excludes what was claimed
+	st, err := s.store.Stats(ctx, qname)
+	if err != nil {
+		return
+	}
+	c.mu.Lock()
+	if c.entries == nil {
+		c.entries = make(map[string]depthEntry)
+	}
+	c.entries[qname] = depthEntry{depth: st.Available, countedAt: time.Now()}
+	c.mu.Unlock()
+	w.Header().Set("X-Approx-Queue-Depth", strconv.FormatInt(st.Available, 10))
+}
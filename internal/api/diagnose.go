@@ -0,0 +1,94 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Reasons a diagnosis gives for messages a receive can't have right now.
+const (
+	reasonEmpty        = "empty"         // the queue holds no messages at all
+	reasonPaused       = "paused"        // the queue is paused; nothing is delivered
+	reasonMaxInFlight  = "max_in_flight" // the queue's in-flight budget is used up
+	reasonLeased       = "leased"        // messages are leased to consumers
+	reasonLeaseExpired = "lease_expired" // leases ran out; waiting for the sweeper to requeue them
+	reasonDelayed      = "delayed"       // enqueued with a delay that hasn't run out
+	reasonBackedOff    = "backed_off"    // waiting out a redelivery delay after failing
+)
+
+type diagnoseResponse struct {
+	Queue         string     `json:"queue"`
+	Claimable     int64      `json:"claimable"` // what a receive could get right now
+	Available     int64      `json:"available"` // visible and not leased, paused or not
+	Leased        int64      `json:"leased"`
+	LeaseExpired  int64      `json:"lease_expired"`
+	Delayed       int64      `json:"delayed"`
+	BackedOff     int64      `json:"backed_off"`
+	Paused        bool       `json:"paused"`
+	MaxInFlight   int        `json:"max_in_flight,omitempty"`
+	NextVisibleAt *timestamp `json:"next_visible_at,omitempty"` // when the next delayed or backed off message shows
+	Reasons       []string   `json:"reasons"`                   // why messages are held back, most blocking first
+}
+
+// handleDiagnose explains an empty or short receive: it counts the queue's
+// messages by why they can or can't be claimed, and lists the reasons that
+// apply. Like Stats the counts are a snapshot; they can be stale by the
+// time a receive runs.
+func (s *Server) handleDiagnose(w http.ResponseWriter, r *http.Request) {
+	qname := chi.URLParam(r, "queue")
+	ctx := r.Context()
+	qcfg, err := s.store.GetQueueConfig(ctx, qname)
+	if err != nil {
+		httpError(w, http.StatusInternalServerError, "get config failed: %v", err)
+		return
+	}
+	d, err := s.store.Diagnose(ctx, qname)
+	if err != nil {
+		httpError(w, http.StatusInternalServerError, "diagnose failed: %v", err)
+		return
+	}
+
+	resp := &diagnoseResponse{
+		Queue:         qname,
+		Claimable:     d.Available,
+		Available:     d.Available,
+		Leased:        d.Leased,
+		LeaseExpired:  d.LeaseExpired,
+		Delayed:       d.Delayed,
+		BackedOff:     d.BackedOff,
+		Paused:        qcfg.Paused,
+		MaxInFlight:   qcfg.MaxInFlight,
+		NextVisibleAt: optionalTimestamp(d.NextVisible),
+		Reasons:       []string{},
+	}
+	if qcfg.Paused {
+		resp.Claimable = 0
+		resp.Reasons = append(resp.Reasons, reasonPaused)
+	}
+	if qcfg.MaxInFlight > 0 {
+		// the budget counts every lease, expired or not, until it is swept
+		left := max(int64(qcfg.MaxInFlight)-d.Leased-d.LeaseExpired, 0)
+		if left < d.Available {
+			resp.Claimable = min(resp.Claimable, left)
+			resp.Reasons = append(resp.Reasons, reasonMaxInFlight)
+		}
+	}
+	for _, c := range []struct {
+		reason string
+		n      int64
+	}{
+		{reasonLeased, d.Leased},
+		{reasonLeaseExpired, d.LeaseExpired},
+		{reasonDelayed, d.Delayed},
+		{reasonBackedOff, d.BackedOff},
+	} {
+		if c.n > 0 {
+			resp.Reasons = append(resp.Reasons, c.reason)
+		}
+	}
+	if d.Available+d.Leased+d.LeaseExpired+d.Delayed+d.BackedOff == 0 {
+		resp.Reasons = append(resp.Reasons, reasonEmpty)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
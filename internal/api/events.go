@@ -0,0 +1,83 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/aridsondez/AWS-SQS-LITE/internal/events"
+)
+
+// sseHeartbeat keeps idle connections from being closed by proxies.
+const sseHeartbeat = 15 * time.Second
+
+// handleEvents streams queue activity as Server-Sent Events. It only observes;
+// nothing is leased or modified on behalf of the subscriber.
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	qname := chi.URLParam(r, "queue")
+	if qname == "" {
+		httpError(w, http.StatusBadRequest, "missing queue path param")
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		httpError(w, http.StatusInternalServerError, "streaming unsupported")
+		return
+	}
+
+	ch, unsubscribe := events.Subscribe(qname)
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	if _, err := fmt.Fprint(w, ": connected\n\n"); err != nil {
+		return
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(sseHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			// client went away
+			return
+
+		case <-s.shutdown:
+			return
+
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+
+		case ev, ok := <-ch:
+			if !ok {
+				return
+			}
+			var id *int64
+			if !s.ulidIDs {
+				id = &ev.ID // events carry no ULID, and the serial would give it away
+			}
+			data, err := json.Marshal(struct {
+				events.Event
+				ID *int64    `json:"id,omitempty"`
+				At timestamp `json:"at"`
+			}{ev, id, timestamp(ev.At)})
+			if err != nil {
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ev.Type, data); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/aridsondez/AWS-SQS-LITE/internal/queue"
+)
+
+const (
+	// maxErrorBytes bounds the reason kept from a nack; longer ones are cut.
+	maxErrorBytes = 4 << 10
+
+	defaultFailuresLimit = 10
+	maxFailuresLimit     = 100
+
+	defaultRedriveMax = 100
+	maxRedriveMax     = 1000
+)
+
+type nackRequest struct {
+	Receipt string `json:"receipt"`
+	Error   string `json:"error,omitempty"` // why processing failed; kept as last_error
+
+	// Terminal says retrying won't help: at its retry limit the message is
+	// dead-lettered in this request rather than at the next sweep.
+	Terminal bool `json:"terminal,omitempty"`
+}
+
+type nackResponse struct {
+	OK  bool    `json:"ok"`
+	DLQ *string `json:"dlq,omitempty"` // set if a terminal nack dead-lettered the message
+}
+
+type redriveRequest struct {
+	Max    int    `json:"max,omitempty"`    // dead letters to move; default defaultRedriveMax
+	Target string `json:"target,omitempty"` // queue to move them to; default the one each failed in
+}
+
+type redriveResponse struct {
+	Queue    string `json:"queue"`
+	Redriven int    `json:"redriven"`
+}
+
+type failuresResponse struct {
+	Queue    string          `json:"queue"`
+	Failures []failureRecord `json:"failures"`
+}
+
+// failureRecord is a dead letter with what is known about how it failed.
+type failureRecord struct {
+	ID             msgID           `json:"id"`
+	Body           json.RawMessage `json:"body"`
+	SourceQueue    *string         `json:"source_queue"`
+	LastError      *string         `json:"last_error"` // nil if it never got a nack with a reason
+	FailedAt       *timestamp      `json:"failed_at"`
+	Deliveries     *int            `json:"deliveries"` // delivery count in the source queue
+	MaxRetries     int             `json:"max_retries"`
+	EnqueuedAt     timestamp       `json:"enqueued_at"`
+	DeadLetteredAt *timestamp      `json:"dead_lettered_at"`
+	TraceID        *string         `json:"trace_id,omitempty"`
+	GroupID        *string         `json:"group_id,omitempty"`
+}
+
+// handleNack gives up a lease before it expires, recording why, so the
+// message is retried (or dead-lettered) at the next sweep rather than after
+// the visibility timeout.
+func (s *Server) handleNack(w http.ResponseWriter, r *http.Request) {
+	id, err := s.pathID(r)
+	if err != nil {
+		pathIDError(w, err)
+		return
+	}
+	var req nackRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		httpError(w, http.StatusBadRequest, "invalid json: %v", err)
+		return
+	}
+	rc, ok := s.checkReceipt(r.Context(), w, req.Receipt, id)
+	if !ok {
+		return
+	}
+
+	reason := truncateError(req.Error)
+	if req.Terminal {
+		dlq, moved, err := s.store.NackToDLQ(r.Context(), rc, reason)
+		if err != nil {
+			httpError(w, http.StatusInternalServerError, "nack failed: %v", err)
+			return
+		}
+		if moved {
+			writeJSON(w, http.StatusOK, &nackResponse{OK: true, DLQ: &dlq})
+			return
+		}
+		// Retries left or no DLQ: fall through to an ordinary nack, which
+		// also reports a stale receipt or an expired lease.
+	}
+
+	ok, err = s.store.Nack(r.Context(), rc, reason)
+	if errors.Is(err, queue.ErrStaleReceipt) {
+		httpError(w, http.StatusForbidden, "%v", err)
+		return
+	}
+	if err != nil {
+		httpError(w, http.StatusInternalServerError, "nack failed: %v", err)
+		return
+	}
+	if !ok {
+		httpError(w, http.StatusNotFound, "message not found or lease expired")
+		return
+	}
+	writeJSON(w, http.StatusOK, &nackResponse{OK: true})
+}
+
+// truncateError cuts a nack reason to maxErrorBytes, on a UTF-8 boundary.
+func truncateError(reason string) string {
+	if len(reason) <= maxErrorBytes {
+		return reason
+	}
+	return strings.ToValidUTF8(reason[:maxErrorBytes], "")
+}
+
+// handleFailures peeks at a DLQ's dead letters with their failure context,
+// for triage. Nothing is leased.
+func (s *Server) handleFailures(w http.ResponseWriter, r *http.Request) {
+	qname := chi.URLParam(r, "queue")
+	if qname == "" {
+		httpError(w, http.StatusBadRequest, "missing queue path param")
+		return
+	}
+	limit := defaultFailuresLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxFailuresLimit {
+			httpError(w, http.StatusBadRequest, "`limit` must be between 1 and %d", maxFailuresLimit)
+			return
+		}
+		limit = n
+	}
+
+	ctx := r.Context()
+	msgs, err := s.store.DeadLetters(ctx, qname, limit)
+	if err != nil {
+		httpError(w, http.StatusInternalServerError, "list failures failed: %v", err)
+		return
+	}
+	if err := s.transformOut(ctx, msgs); err != nil {
+		httpError(w, http.StatusInternalServerError, "%v", err)
+		return
+	}
+
+	resp := &failuresResponse{Queue: qname, Failures: make([]failureRecord, 0, len(msgs))}
+	for _, m := range msgs {
+		resp.Failures = append(resp.Failures, failureRecord{
+			ID:             s.showID(m),
+			Body:           json.RawMessage(m.Body),
+			SourceQueue:    m.DLQSource,
+			LastError:      m.LastError,
+			FailedAt:       optionalTimestamp(m.FailedAt),
+			Deliveries:     m.DLQDeliveries,
+			MaxRetries:     m.MaxRetries,
+			EnqueuedAt:     timestamp(m.EnqueuedAt),
+			DeadLetteredAt: optionalTimestamp(m.DLQdAt),
+			TraceID:        m.TraceID,
+			GroupID:        m.GroupID,
+		})
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// handleRedrive sends a DLQ's dead letters back to the queues they failed in,
+// or to the request's target, oldest first, with fresh retries.
+func (s *Server) handleRedrive(w http.ResponseWriter, r *http.Request) {
+	qname := chi.URLParam(r, "queue")
+	if qname == "" {
+		httpError(w, http.StatusBadRequest, "missing queue path param")
+		return
+	}
+	var req redriveRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		httpError(w, http.StatusBadRequest, "invalid json: %v", err)
+		return
+	}
+	if req.Max == 0 {
+		req.Max = defaultRedriveMax
+	}
+	if req.Max < 1 || req.Max > maxRedriveMax {
+		httpError(w, http.StatusBadRequest, "`max` must be between 1 and %d", maxRedriveMax)
+		return
+	}
+	if req.Target == qname {
+		httpError(w, http.StatusBadRequest, "`target` must differ from the DLQ")
+		return
+	}
+
+	if req.Target != "" {
+		if err := s.admitQueue(r.Context(), req.Target); err != nil {
+			httpError(w, admitStatus(err), "%v", err)
+			return
+		}
+	}
+
+	n, err := s.store.Redrive(r.Context(), qname, req.Target, req.Max)
+	if err != nil {
+		httpError(w, http.StatusInternalServerError, "redrive failed: %v", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, &redriveResponse{Queue: qname, Redriven: n})
+}
@@ -0,0 +1,45 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type sealGroupResponse struct {
+	Queue   string `json:"queue"`
+	GroupID string `json:"group_id"`
+	Sealed  bool   `json:"sealed"`
+	LastID  *msgID `json:"last_id"` // null if the group had no messages left
+}
+
+// handleSealGroup marks a group complete, for groups that carry a bounded
+// sequence: no more messages can be enqueued into it, and its last message
+// is received with group_sealed set so the consumer can finish the group.
+func (s *Server) handleSealGroup(w http.ResponseWriter, r *http.Request) {
+	qname := chi.URLParam(r, "queue")
+	group := chi.URLParam(r, "group")
+	if qname == "" || group == "" {
+		httpError(w, http.StatusBadRequest, "missing queue or group path param")
+		return
+	}
+	if len(group) > maxGroupIDLen {
+		httpError(w, http.StatusBadRequest, "group must be 1 to %d bytes", maxGroupIDLen)
+		return
+	}
+	last, err := s.store.SealGroup(r.Context(), qname, group)
+	if err != nil {
+		httpError(w, http.StatusInternalServerError, "seal group failed: %v", err)
+		return
+	}
+	resp := &sealGroupResponse{Queue: qname, GroupID: group, Sealed: true}
+	if last != nil {
+		id, err := s.showIDOf(r.Context(), *last)
+		if err != nil {
+			httpError(w, http.StatusInternalServerError, "%v", err)
+			return
+		}
+		resp.LastID = &id
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
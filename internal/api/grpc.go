@@ -0,0 +1,353 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/aridsondez/AWS-SQS-LITE/internal/config"
+	"github.com/aridsondez/AWS-SQS-LITE/internal/metrics"
+	"github.com/aridsondez/AWS-SQS-LITE/internal/queue"
+	"github.com/aridsondez/AWS-SQS-LITE/internal/queue/store"
+	"github.com/aridsondez/AWS-SQS-LITE/pkg/sqslitepb"
+)
+
+// GRPCServer serves sqslitepb.QueueService. Stop it with GracefulStop, which
+// ends open receive streams first; the embedded grpc.Server's would wait on
+// them forever.
+type GRPCServer struct {
+	*grpc.Server
+	srv  *Server
+	once sync.Once
+}
+
+// NewGRPCServer returns a gRPC server for the queue API on s, with the same
+// limits and defaults as the HTTP server built from cfg.
+func NewGRPCServer(s store.Store, cfg *config.Config, opts ...Option) *GRPCServer {
+	srv := newServer("", s, cfg, opts...)
+	gs := grpc.NewServer(grpc.MaxRecvMsgSize(cfg.MaxMessageBytes + maxEnvelopeBytes))
+	sqslitepb.RegisterQueueServiceServer(gs, &queueService{Server: srv})
+	return &GRPCServer{Server: gs, srv: srv}
+}
+
+// GracefulStop ends receive streams, then waits for in-flight calls.
+func (g *GRPCServer) GracefulStop() {
+	g.once.Do(func() { close(g.srv.shutdown) })
+	g.Server.GracefulStop()
+}
+
+type queueService struct {
+	sqslitepb.UnimplementedQueueServiceServer
+	*Server
+}
+
+func (q *queueService) Enqueue(ctx context.Context, req *sqslitepb.EnqueueRequest) (*sqslitepb.EnqueueResponse, error) {
+	if req.Queue == "" {
+		return nil, status.Error(codes.InvalidArgument, "queue is required")
+	}
+	qcfg, err := q.store.GetQueueConfig(ctx, req.Queue)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "get config failed: %v", err)
+	}
+	// proto3 can't tell 0 from unset, so 0 takes the queue's default delay
+	var delayMS *int64
+	if req.DelayMs != 0 {
+		delayMS = &req.DelayMs
+	}
+	msg, delay, err := q.newMessage(req.Queue, qcfg, enqueueRequest{
+		Body:       req.Body,
+		DelayMS:    delayMS,
+		MaxRetries: int(req.MaxRetries),
+		DLQ:        req.Dlq,
+		TraceID:    req.TraceId,
+	})
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err := q.transformIn(ctx, &msg); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err := q.admitQueue(ctx, req.Queue); err != nil {
+		switch {
+		case errors.Is(err, errQueueNotCreated):
+			return nil, status.Error(codes.NotFound, err.Error())
+		case errors.Is(err, queue.ErrTooManyQueues):
+			return nil, status.Error(codes.ResourceExhausted, err.Error())
+		}
+		return nil, status.Errorf(codes.Internal, "register queue failed: %v", err)
+	}
+	start := time.Now()
+	id, err := q.store.Enqueue(ctx, msg, delay)
+	metrics.EnqueueDuration.WithLabelValues(req.Queue).Observe(time.Since(start).Seconds())
+	if errors.Is(err, queue.ErrInvalidBody) {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "enqueue failed: %v", err)
+	}
+	metrics.MessagesEnqueued.WithLabelValues(req.Queue).Inc()
+	return &sqslitepb.EnqueueResponse{Id: id}, nil
+}
+
+func (q *queueService) Receive(ctx context.Context, req *sqslitepb.ReceiveRequest) (*sqslitepb.ReceiveResponse, error) {
+	if req.Queue == "" {
+		return nil, status.Error(codes.InvalidArgument, "queue is required")
+	}
+	limit := q.batchLimit(req.Max)
+	wait := time.Duration(req.WaitMs) * time.Millisecond
+	if wait < 0 || wait > maxReceiveWait {
+		return nil, status.Errorf(codes.InvalidArgument, "wait_ms must be between 0 and %d", maxReceiveWait.Milliseconds())
+	}
+	if req.MinMessages < 0 || int(req.MinMessages) > limit {
+		return nil, status.Errorf(codes.InvalidArgument, "min_messages must be between 0 and max (%d)", limit)
+	}
+	vis, err := q.receiveVisibility(ctx, req.Queue, req.VisibilityMs)
+	if err != nil {
+		return nil, err
+	}
+
+	if wait > 0 {
+		if err := q.waitForMessages(ctx, req.Queue, max(int(req.MinMessages), 1), wait); err != nil {
+			return nil, status.FromContextError(err).Err()
+		}
+	}
+	left, err := q.inFlightLeft(ctx)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "count in flight failed: %v", err)
+	}
+	if left == 0 {
+		return nil, status.Error(codes.ResourceExhausted, errTotalInFlight.Error())
+	}
+	if left > 0 {
+		limit = min(limit, left)
+	}
+	start := time.Now()
+	out, err := q.store.Claim(ctx, queue.ClaimOptions{Queue: req.Queue, Limit: limit, Visibility: vis})
+	metrics.ReceiveDuration.WithLabelValues(req.Queue).Observe(time.Since(start).Seconds())
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "claim failed: %v", err)
+	}
+	q.noteLeased(len(out))
+	if q.abandoned(ctx, out) {
+		return nil, status.FromContextError(ctx.Err()).Err()
+	}
+	if q.draining(ctx, out) {
+		out = nil
+	}
+	out = q.resolveBodies(ctx, out)
+	if err := q.transformOut(ctx, out); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	resp := &sqslitepb.ReceiveResponse{Messages: make([]*sqslitepb.Message, 0, len(out))}
+	for _, m := range out {
+		resp.Messages = append(resp.Messages, toProtoMessage(m))
+		observeReceived(m)
+	}
+	return resp, nil
+}
+
+func (q *queueService) StreamReceive(req *sqslitepb.StreamReceiveRequest, stream grpc.ServerStreamingServer[sqslitepb.Message]) error {
+	if req.Queue == "" {
+		return status.Error(codes.InvalidArgument, "queue is required")
+	}
+	ctx := stream.Context()
+	limit := q.batchLimit(req.Batch)
+	vis, err := q.receiveVisibility(ctx, req.Queue, req.VisibilityMs)
+	if err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-q.shutdown:
+			return nil
+		default:
+		}
+
+		left, err := q.inFlightLeft(ctx)
+		if err != nil {
+			return status.Errorf(codes.Internal, "count in flight failed: %v", err)
+		}
+		if left == 0 {
+			// hold the stream open until leases free up under the cap
+			select {
+			case <-ctx.Done():
+				return status.FromContextError(ctx.Err()).Err()
+			case <-q.shutdown:
+				return nil
+			case <-time.After(inFlightCacheTTL):
+			}
+			continue
+		}
+		n := limit
+		if left > 0 {
+			n = min(limit, left)
+		}
+		out, err := q.store.Claim(ctx, queue.ClaimOptions{Queue: req.Queue, Limit: n, Visibility: vis})
+		if err != nil {
+			if ctx.Err() != nil {
+				return status.FromContextError(ctx.Err()).Err()
+			}
+			return status.Errorf(codes.Internal, "claim failed: %v", err)
+		}
+		q.noteLeased(len(out))
+		if q.draining(ctx, out) {
+			return nil
+		}
+		out = q.resolveBodies(ctx, out)
+		if err := q.transformOut(ctx, out); err != nil {
+			return status.Error(codes.Internal, err.Error())
+		}
+		// Send blocks once the client's flow-control window is full, so we
+		// don't claim the next batch until this one is taken.
+		for i, m := range out {
+			if err := stream.Send(toProtoMessage(m)); err != nil {
+				q.release(ctx, out[i:])
+				return err
+			}
+			observeReceived(m)
+		}
+		if len(out) == 0 {
+			if err := q.waitForMessages(ctx, req.Queue, 1, maxReceiveWait); err != nil {
+				return status.FromContextError(err).Err()
+			}
+		}
+	}
+}
+
+func (q *queueService) Ack(ctx context.Context, req *sqslitepb.AckRequest) (*sqslitepb.AckResponse, error) {
+	rc, err := parseReceipt(req.Receipt)
+	if err != nil {
+		return nil, err
+	}
+	ok, err := q.store.Ack(ctx, rc)
+	if errors.Is(err, queue.ErrStaleReceipt) {
+		return nil, status.Error(codes.PermissionDenied, err.Error())
+	}
+	if errors.Is(err, queue.ErrGroupOrder) {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "ack failed: %v", err)
+	}
+	if !ok {
+		return nil, status.Error(codes.NotFound, "message not found")
+	}
+	metrics.MessagesAcked.Inc()
+	return &sqslitepb.AckResponse{}, nil
+}
+
+func (q *queueService) Nack(ctx context.Context, req *sqslitepb.NackRequest) (*sqslitepb.NackResponse, error) {
+	// Ending the lease now leaves an ordinary expired lease for the sweeper.
+	rc, err := parseReceipt(req.Receipt)
+	if err != nil {
+		return nil, err
+	}
+	reason := truncateError(req.Error)
+	if req.Terminal {
+		dlq, moved, err := q.store.NackToDLQ(ctx, rc, reason)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "nack failed: %v", err)
+		}
+		if moved {
+			return &sqslitepb.NackResponse{Dlq: &dlq}, nil
+		}
+	}
+	ok, err := q.store.Nack(ctx, rc, reason)
+	if err != nil && !errors.Is(err, queue.ErrStaleReceipt) {
+		return nil, status.Errorf(codes.Internal, "nack failed: %v", err)
+	}
+	if !ok {
+		return nil, status.Error(codes.FailedPrecondition, "lease expired or receipt is stale")
+	}
+	return &sqslitepb.NackResponse{}, nil
+}
+
+func (q *queueService) Extend(ctx context.Context, req *sqslitepb.ExtendRequest) (*sqslitepb.ExtendResponse, error) {
+	if req.VisibilityMs <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "visibility_ms must be positive")
+	}
+	receipt, err := q.extend(ctx, req.Receipt, time.Duration(req.VisibilityMs)*time.Millisecond)
+	if err != nil {
+		return nil, err
+	}
+	return &sqslitepb.ExtendResponse{Receipt: receipt}, nil
+}
+
+// extend renews the lease and returns the receipt for the renewed lease.
+func (q *queueService) extend(ctx context.Context, raw string, visibility time.Duration) (string, error) {
+	rc, err := parseReceipt(raw)
+	if err != nil {
+		return "", err
+	}
+	expires := time.Now().Add(visibility) // no later than the lease the store sets
+	ids, err := q.store.ExtendBatch(ctx, []queue.Receipt{rc}, visibility)
+	if err != nil {
+		return "", status.Errorf(codes.Internal, "extend failed: %v", err)
+	}
+	if len(ids) == 0 {
+		return "", status.Error(codes.FailedPrecondition, "lease expired or receipt is stale")
+	}
+	rc.Expires = expires
+	return rc.String(), nil
+}
+
+// batchLimit applies the REST receive's rule: sizes are capped at
+// receiveMax, and a missing one means 1.
+func (q *queueService) batchLimit(n int32) int {
+	return q.receiveLimit(int(n))
+}
+
+func (q *queueService) receiveVisibility(ctx context.Context, qname string, ms int64) (time.Duration, error) {
+	if ms > 0 {
+		return time.Duration(ms) * time.Millisecond, nil
+	}
+	qcfg, err := q.store.GetQueueConfig(ctx, qname)
+	if err != nil {
+		return 0, status.Errorf(codes.Internal, "get config failed: %v", err)
+	}
+	return q.visibilityFor(qcfg), nil
+}
+
+func parseReceipt(raw string) (queue.Receipt, error) {
+	if raw == "" {
+		return queue.Receipt{}, status.Error(codes.InvalidArgument, "receipt required")
+	}
+	rc, err := queue.ParseReceipt(raw)
+	if err != nil {
+		return queue.Receipt{}, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if rc.Expired(time.Now()) {
+		return queue.Receipt{}, status.Error(codes.PermissionDenied, queue.ErrReceiptExpired.Error())
+	}
+	return rc, nil
+}
+
+func toProtoMessage(m queue.Message) *sqslitepb.Message {
+	pm := &sqslitepb.Message{
+		Id:            m.ID,
+		Queue:         m.Queue,
+		Body:          m.Body,
+		Receipt:       m.Receipt().String(),
+		DeliveryCount: int32(m.DeliveryCount),
+		MaxRetries:    int32(m.MaxRetries),
+		Dlq:           m.DLQ,
+		TraceId:       m.TraceID,
+
+		ApproximateReceiveCount: int32(m.ReceiveCount),
+	}
+	if m.LeaseUntil != nil {
+		pm.LeaseUntil = timestamppb.New(*m.LeaseUntil)
+	}
+	if m.DLQdAt != nil {
+		pm.DeadLetteredAt = timestamppb.New(*m.DLQdAt)
+	}
+	return pm
+}
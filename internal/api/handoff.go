@@ -0,0 +1,119 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/aridsondez/AWS-SQS-LITE/internal/metrics"
+	"github.com/aridsondez/AWS-SQS-LITE/internal/queue"
+)
+
+type ackAndEnqueueRequest struct {
+	Receipt string         `json:"receipt"`
+	Queue   string         `json:"queue"`   // where the next message goes
+	Message enqueueRequest `json:"message"` // as for an enqueue to queue
+}
+
+type ackAndEnqueueResponse struct {
+	OK bool  `json:"ok"`
+	ID msgID `json:"id"` // the next message
+}
+
+// handleAckAndEnqueue finishes one workflow step and starts the next: it acks
+// the message and enqueues its successor in one transaction, so a crash or
+// failure in between can neither lose the next step nor run this one twice.
+// The successor is checked as an enqueue to its queue would be; it has no
+// Idempotency-Key or dedup_id, since the ack already makes a retry fail.
+func (s *Server) handleAckAndEnqueue(w http.ResponseWriter, r *http.Request) {
+	id, err := s.pathID(r)
+	if err != nil {
+		pathIDError(w, err)
+		return
+	}
+	var req ackAndEnqueueRequest
+	r.Body = http.MaxBytesReader(w, r.Body, int64(s.maxMessageBytes)+maxEnvelopeBytes)
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			httpError(w, http.StatusRequestEntityTooLarge, "request exceeds %d bytes", tooLarge.Limit)
+			return
+		}
+		httpError(w, http.StatusBadRequest, "invalid json: %v", err)
+		return
+	}
+	rc, ok := s.checkReceipt(r.Context(), w, req.Receipt, id)
+	if !ok {
+		return
+	}
+	if req.Queue == "" {
+		httpError(w, http.StatusBadRequest, "`queue` is required")
+		return
+	}
+	if req.Message.DedupID != nil {
+		httpError(w, http.StatusBadRequest, "`dedup_id` is not supported with ack-and-enqueue")
+		return
+	}
+	if tp := traceParentIn(r); tp != "" && req.Message.TraceID == nil {
+		req.Message.TraceID = &tp
+	}
+
+	ctx := r.Context()
+	qcfg, err := s.store.GetQueueConfig(ctx, req.Queue)
+	if err != nil {
+		httpError(w, http.StatusInternalServerError, "get config failed: %v", err)
+		return
+	}
+	msg, delay, err := s.newMessage(req.Queue, qcfg, req.Message)
+	if err != nil {
+		code := http.StatusBadRequest
+		if errors.Is(err, errMessageTooLarge) {
+			code = http.StatusRequestEntityTooLarge
+		}
+		httpError(w, code, "`message`: %v", err)
+		return
+	}
+	if err := s.transformIn(ctx, &msg); err != nil {
+		httpError(w, http.StatusBadRequest, "`message`: %v", err)
+		return
+	}
+	if err := s.admitQueue(ctx, req.Queue); err != nil {
+		httpError(w, admitStatus(err), "%v", err)
+		return
+	}
+
+	start := time.Now()
+	next, acked, coalesced, err := s.store.AckAndEnqueue(ctx, rc, msg, delay)
+	metrics.EnqueueDuration.WithLabelValues(req.Queue).Observe(time.Since(start).Seconds())
+	switch {
+	case errors.Is(err, queue.ErrStaleReceipt):
+		httpError(w, http.StatusForbidden, "%v", err)
+		return
+	case errors.Is(err, queue.ErrGroupOrder), errors.Is(err, queue.ErrGroupSealed):
+		httpError(w, http.StatusConflict, "%v; nothing was acked or enqueued", err)
+		return
+	case errors.Is(err, queue.ErrInvalidBody):
+		httpError(w, http.StatusBadRequest, "`message`: %v", err)
+		return
+	case err != nil:
+		httpError(w, http.StatusInternalServerError, "ack-and-enqueue failed: %v", err)
+		return
+	case !acked:
+		httpError(w, http.StatusNotFound, "message not found")
+		return
+	}
+	metrics.MessagesAcked.Inc()
+	// a coalesced successor was already pending: hand back its ID
+	code := http.StatusOK
+	if !coalesced {
+		metrics.MessagesEnqueued.WithLabelValues(req.Queue).Inc()
+		code = http.StatusCreated
+	}
+	nextID, err := s.showIDOf(ctx, next)
+	if err != nil {
+		httpError(w, http.StatusInternalServerError, "%v", err)
+		return
+	}
+	writeJSON(w, code, &ackAndEnqueueResponse{OK: true, ID: nextID})
+}
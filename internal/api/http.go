@@ -3,15 +3,25 @@ package api
 import(
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"math/rand/v2"
+	"mime"
 	"net/http"
+	"slices"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 
+	"github.com/aridsondez/AWS-SQS-LITE/internal/config"
 	"github.com/aridsondez/AWS-SQS-LITE/internal/metrics"
 	"github.com/aridsondez/AWS-SQS-LITE/internal/queue"
 	"github.com/aridsondez/AWS-SQS-LITE/internal/queue/store"
@@ -21,81 +31,497 @@ type Server struct {
 	store store.Store
 	addr  string
 	timeout time.Duration
+	visibility      time.Duration // default lease when a receive doesn't ask for one
+	receiveMax      int           // largest batch a single receive may claim
+	maxMessageBytes int           // largest message body accepted on enqueue
+	idempotencyTTL  time.Duration
+	ackIDTTL        time.Duration // 0 ignores ack_id
+	transformer     Transformer
+	resolver        BodyResolver // inlines claim-check bodies at receive; nil leaves them to consumers
+	bodyRefPrefixes []string     // BODY_REF_ALLOWED_PREFIXES; empty accepts any body_ref
+	maxLifetime     time.Duration // MAX_MESSAGE_LIFETIME; 0 = no limit
+	ulidIDs         bool          // show messages by ULID instead of serial ID
+	adminToken      string       // authorizes forced cancels; empty allows none
+	envelope        bool // wrap every receive response, not only when asked
+	accessLog       *slog.Logger
+	workerMetrics   bool          // count receives by X-Worker-ID
+	depths          depthCache // backs X-Approx-Queue-Depth
+	legacyDelay     sync.Map   // queues already warned about the deprecated `delay` key
+	maxQueues       int        // MAX_QUEUES; 0 = no cap
+	registered      sync.Map   // queues admitQueue has seen in the registry
+	maxTotalInFlight int       // MAX_TOTAL_IN_FLIGHT; 0 = no cap
+	defaultMaxRetries int      // DEFAULT_MAX_RETRIES, for messages and queues that set none
+	inFlight        inFlightCount
+	strictQueues    bool       // enqueue only to created queues
+	// closed when the server begins shutting down so that
+	// long-lived streams can end instead of blocking Shutdown.
+	shutdown chan struct{}
 }
 
-func NewServer(addr string, s store.Store) *http.Server {
+// newServer holds the settings the HTTP and gRPC transports share.
+func newServer(addr string, s store.Store, cfg *config.Config, opts ...Option) *Server {
 	srv := &Server{
 		store: s,
 		addr:  addr,
 		timeout: 5 * time.Second,
+		visibility:      cfg.VisibilityTimeout,
+		receiveMax:      cfg.ReceiveMax,
+		maxMessageBytes: cfg.MaxMessageBytes,
+		idempotencyTTL:  cfg.IdempotencyTTL,
+		ackIDTTL:        cfg.AckIDTTL,
+		transformer:     NopTransformer{},
+		envelope:        cfg.ReceiveEnvelope,
+		accessLog:       slog.Default(),
+		adminToken:      cfg.AdminToken,
+		workerMetrics:   cfg.WorkerMetrics,
+		maxQueues:       cfg.MaxQueues,
+		maxTotalInFlight: cfg.MaxTotalInFlight,
+		defaultMaxRetries: cfg.DefaultMaxRetries,
+		strictQueues:    cfg.StrictQueues,
+		bodyRefPrefixes: cfg.BodyRefPrefixes,
+		maxLifetime:     cfg.MaxMessageLifetime,
+		ulidIDs:         cfg.MessageIDFormat == config.MessageIDULID,
+		shutdown: make(chan struct{}),
 	}
+	if cfg.BodyRefTimeout > 0 {
+		srv.resolver = NewHTTPBodyResolver(cfg.BodyRefTimeout, cfg.MaxMessageBytes, cfg.BodyRefPrefixes)
+	}
+	for _, opt := range opts {
+		opt(srv)
+	}
+	return srv
+}
+
+func NewServer(addr string, s store.Store, cfg *config.Config, opts ...Option) *http.Server {
+	srv := newServer(addr, s, cfg, opts...)
 	r:= chi.NewRouter()
 	r.Use(middleware.RequestID)
 	r.Use(middleware.RealIP)
-	r.Use(middleware.Logger)
+	r.Use(srv.logAccess)
 	r.Use(middleware.Recoverer)
-	r.Use(middleware.Timeout(srv.timeout))
 
-	r.Get("/healthz", func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusOK)
-		_,_ = w.Write([]byte("ok"))
-	})
+	r.Group(func(r chi.Router) {
+		r.Use(middleware.Timeout(srv.timeout))
+
+		r.Get("/healthz", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+			_,_ = w.Write([]byte("ok"))
+		})
 
-	r.Handle("/metrics", promhttp.Handler())
+		r.Handle("/metrics", promhttp.Handler())
+	})
 
 	r.Route("/v1", func(r chi.Router) {
-		// enqueue: POST /v1/queues/{queue}/messages
-		r.Post("/queues/{queue}/messages", srv.handleEnqueue)
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.Timeout(srv.timeout))
+
+			// enqueue: POST /v1/queues/{queue}/messages
+			r.Post("/queues/{queue}/messages", srv.handleEnqueue)
+
+			// batch enqueue: POST /v1/queues/{queue}/messages:batch
+			r.Post("/queues/{queue}/messages:batch", srv.handleEnqueueBatch)
+
+			// receive-and-delete: POST /v1/queues/{queue}:receive-and-delete
+			r.Post("/queues/{queue}:receive-and-delete", srv.handleReceiveAndDelete)
+
+			// multi-queue receive: POST /v1/queues:receive
+			r.Post("/queues:receive", srv.handleReceiveMulti)
+
+			// ack: POST /v1/messages/{id}:ack
+			r.Post("/messages/{id}:ack", srv.handleAck)
+
+			// ack and enqueue the next step atomically: POST /v1/messages/{id}:ack-and-enqueue
+			r.Post("/messages/{id}:ack-and-enqueue", srv.handleAckAndEnqueue)
+
+			// nack: POST /v1/messages/{id}:nack
+			r.Post("/messages/{id}:nack", srv.handleNack)
+
+			// targeted claim: POST /v1/messages/{id}:claim
+			r.Post("/messages/{id}:claim", srv.handleClaim)
+
+			// batch ack: POST /v1/messages:ack-batch
+			r.Post("/messages:ack-batch", srv.handleAckBatch)
+
+			// hand leases back: POST /v1/messages:release-batch
+			r.Post("/messages:release-batch", srv.handleReleaseBatch)
+
+			// extend leases: POST /v1/messages:extend-batch
+			r.Post("/messages:extend-batch", srv.handleExtendBatch)
+
+			// list queues: GET /v1/queues
+			r.Get("/queues", srv.handleListQueues)
+
+			// attributes: GET /v1/queues/{queue}/attributes
+			r.Get("/queues/{queue}/attributes", srv.handleAttributes)
+
+			// why nothing is claimable: GET /v1/queues/{queue}/diagnose
+			r.Get("/queues/{queue}/diagnose", srv.handleDiagnose)
+
+			// filtered count: GET /v1/queues/{queue}/count
+			r.Get("/queues/{queue}/count", srv.handleCount)
+
+			// scheduled messages: GET /v1/queues/{queue}/delayed
+			r.Get("/queues/{queue}/delayed", srv.handleDelayed)
+
+			// cancel an undelivered message: DELETE /v1/messages/{id}
+			r.Delete("/messages/{id}", srv.handleCancelMessage)
+
+			// edit an undelivered message's body: PATCH /v1/messages/{id}
+			r.Patch("/messages/{id}", srv.handleUpdateMessage)
+
+			// dead letters with failure context: GET /v1/queues/{queue}/failures
+			r.Get("/queues/{queue}/failures", srv.handleFailures)
+
+			// DLQ redrive: POST /v1/queues/{queue}:redrive
+			r.Post("/queues/{queue}:redrive", srv.handleRedrive)
+
+			// enqueue archived messages again: POST /v1/archive/replay
+			r.Post("/archive/replay", srv.handleReplay)
+
+			// rename or merge a queue: POST /v1/queues/{queue}:rename
+			r.Post("/queues/{queue}:rename", srv.handleRenameQueue)
+
+			// queue config: GET/PUT /v1/queues/{queue}/config
+			r.Get("/queues/{queue}/config", srv.handleGetQueueConfig)
+			r.Put("/queues/{queue}/config", srv.handlePutQueueConfig)
 
-		// receive: POST /v1/queues/{queue}:receive
-		r.Post("/queues/{queue}:receive", srv.handleReceive)
+			// stop/restart delivery: POST /v1/queues/{queue}:pause, :resume
+			r.Post("/queues/{queue}:pause", srv.handlePause)
+			r.Post("/queues/{queue}:resume", srv.handleResume)
 
-		// ack: POST /v1/messages/{id}:ack
-		r.Post("/messages/{id}:ack", srv.handleAck)
+			// end a message group: POST /v1/queues/{queue}/groups/{group}:seal
+			r.Post("/queues/{queue}/groups/{group}:seal", srv.handleSealGroup)
+
+			// publish to a topic: POST /v1/topics/{topic}/messages
+			r.Post("/topics/{topic}/messages", srv.handlePublish)
+
+			// topic subscriptions: GET/PUT /v1/topics/{topic}/subscriptions
+			r.Get("/topics/{topic}/subscriptions", srv.handleGetSubscriptions)
+			r.Put("/topics/{topic}/subscriptions", srv.handlePutSubscriptions)
+
+			// push subscription: GET /v1/queues/{queue}/subscriptions; changing
+			// it is admin only, as the server POSTs messages wherever it points
+			r.Get("/queues/{queue}/subscriptions", srv.handleGetPushSubscription)
+
+			// log-style reads: POST /v1/queues/{queue}/checkpoints/{group}:read,
+			// GET/PUT /v1/queues/{queue}/checkpoints/{group}
+			r.Post("/queues/{queue}/checkpoints/{group}:read", srv.handleRead)
+			r.Get("/queues/{queue}/checkpoints/{group}", srv.handleGetCheckpoint)
+			r.Put("/queues/{queue}/checkpoints/{group}", srv.handlePutCheckpoint)
+		})
+
+		r.Group(func(r chi.Router) {
+			// long polls may hold for up to maxReceiveWait before claiming
+			r.Use(middleware.Timeout(srv.timeout + maxReceiveWait))
+
+			// receive: POST /v1/queues/{queue}:receive
+			r.Post("/queues/{queue}:receive", srv.handleReceive)
+		})
+
+		// events: GET /v1/queues/{queue}/events (SSE, outlives the request timeout)
+		r.Get("/queues/{queue}/events", srv.handleEvents)
+
+		if cfg.AdminToken != "" {
+			r.Group(func(r chi.Router) {
+				r.Use(requireAdmin(cfg.AdminToken))
+				r.Use(middleware.Timeout(srv.timeout))
+
+				// reset retries: POST /v1/messages/{id}:reset (admin only)
+				r.Post("/messages/{id}:reset", srv.handleResetDeliveryCount)
+
+				// create/delete a queue: PUT/DELETE /v1/queues/{queue} (admin only)
+				r.Put("/queues/{queue}", srv.handleCreateQueue)
+				r.Delete("/queues/{queue}", srv.handleDeleteQueue)
+
+				// end every lease: POST /v1/queues/{queue}:release-leases (admin only)
+				r.Post("/queues/{queue}:release-leases", srv.handleReleaseLeases)
+
+				// set/remove a push subscription: POST/DELETE /v1/queues/{queue}/subscriptions (admin only)
+				r.Post("/queues/{queue}/subscriptions", srv.handlePutPushSubscription)
+				r.Delete("/queues/{queue}/subscriptions", srv.handleDeletePushSubscription)
+			})
+		}
 	})
 
-	return &http.Server{
+	// profiling: /debug/pprof/* (admin only; CPU profiles run longer than the request timeout)
+	if cfg.EnablePprof {
+		r.Group(func(r chi.Router) {
+			r.Use(requireAdmin(cfg.AdminToken))
+			r.Mount("/debug", middleware.Profiler())
+		})
+	}
+
+	httpSrv := &http.Server{
 		Addr:    srv.addr,
 		Handler: r,
 	}
+	httpSrv.RegisterOnShutdown(func() { close(srv.shutdown) })
+	return httpSrv
 }
 
 type enqueueRequest struct {
 	Body  json.RawMessage `json:"body"`
-	DelayMS int64          `json:"delay,omitempty"` // miliseconds
+	BodyRef *string        `json:"body_ref,omitempty"` // claim check: where the body is kept, instead of `body`
+	DelayMS *int64         `json:"delay_ms,omitempty"` // nil takes the queue's default_delay_ms
+	LegacyDelayMS *int64   `json:"delay,omitempty"` // deprecated spelling of delay_ms
+	DelayJitterMS int64   `json:"delay_jitter_ms,omitempty"` // random extra delay in [0, jitter]
 	MaxRetries int        `json:"max_retries,omitempty"`
 	DLQ       *string     `json:"dlq,omitempty"`
 	TraceID   *string     `json:"trace_id,omitempty"`
+	DedupID   *string     `json:"dedup_id,omitempty"` // drop repeats within the queue's dedup window
+	DLQRules  []dlqRule   `json:"dlq_rules,omitempty"`
+	GroupID   *string     `json:"group_id,omitempty"` // acks within the group must follow enqueue order
+	CoalesceKey *string   `json:"coalesce_key,omitempty"` // at most one pending message per key
+	NotBefore *time.Time  `json:"not_before,omitempty"` // RFC 3339; absolute alternative to delay_ms
+}
+
+// dlqRule picks the DLQ by delivery count when the message is dead-lettered.
+// Zero bounds are open; the first matching rule wins, else `dlq` is used.
+type dlqRule struct {
+	MinDeliveries int    `json:"min_deliveries,omitempty"`
+	MaxDeliveries int    `json:"max_deliveries,omitempty"`
+	DLQ           string `json:"dlq"`
 }
 
 type enqueueResponse struct {
-	ID int64 `json:"id"`
+	ID msgID `json:"id"`
+}
+
+type enqueueBatchRequest struct {
+	// Entries are decoded one at a time so a malformed entry is reported
+	// on its own instead of failing the whole request.
+	Entries []json.RawMessage `json:"entries"`
+	Atomic  bool              `json:"atomic,omitempty"` // all-or-nothing
+
+	DelayJitterMS int64 `json:"delay_jitter_ms,omitempty"` // for entries that don't set their own
+}
+
+type batchEntryResult struct {
+	Index int    `json:"index"`
+	ID    *msgID `json:"id,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+type enqueueBatchResponse struct {
+	Results []batchEntryResult `json:"results"`
 }
 
 type receiveRequest struct {
-	Max          int   `json:"max"`             // e.g., 1..32
-	VisibilityMS int64 `json:"visibility_ms"`   // e.g., 30000
+	Max          int   `json:"max"`                    // 1..cfg.ReceiveMax
+	VisibilityMS int64 `json:"visibility_ms"`          // e.g., 30000
+	WaitMS       *int64 `json:"wait_ms,omitempty"`     // long poll: hold up to this long for messages; set, even to 0, it beats Prefer: wait
+	MinMessages  int   `json:"min_messages,omitempty"` // with wait_ms: hold until this many are available (default 1)
+}
+
+type receiveMultiRequest struct {
+	Queues []struct {
+		Name   string `json:"name"`
+		Weight int    `json:"weight,omitempty"` // default 1
+	} `json:"queues"`
+	Max          int   `json:"max"`           // len(queues)..cfg.ReceiveMax
+	VisibilityMS int64 `json:"visibility_ms"` // optional; else each queue's default
 }
 
 type receivedMessage struct {
-	ID            int64           `json:"id"`
+	ID            msgID           `json:"id"`
+	Queue         string          `json:"queue"`
 	Body          json.RawMessage `json:"body"`
-	Receipt       string          `json:"receipt"` // for now, just the ID as string
-	LeaseUntil    *time.Time      `json:"lease_until,omitempty"`
+	Receipt       string          `json:"receipt,omitempty"` // opaque; required to ack
+	EnqueuedAt    timestamp       `json:"enqueued_at"`
+	NotBefore     timestamp       `json:"not_before"`
+	LeaseUntil    *timestamp      `json:"lease_until,omitempty"`
 	DeliveryCount int             `json:"delivery_count"`
 	MaxRetries    int             `json:"max_retries"`
 	DLQ           *string         `json:"dlq,omitempty"`
 	TraceID       *string         `json:"trace_id,omitempty"`
+	TraceParent   string          `json:"traceparent,omitempty"` // trace_id, when it is a W3C traceparent
+	GroupID       *string         `json:"group_id,omitempty"`
+	GroupSealed   bool            `json:"group_sealed,omitempty"` // last message of a sealed group
+	BodyRef       *string         `json:"body_ref,omitempty"`     // set on claim-check messages, resolved or not
+	CoalesceKey   *string         `json:"coalesce_key,omitempty"`
+
+	// Claims over the message's whole life, across requeues, dead-lettering
+	// and redrive; delivery_count counts only those since the last reset.
+	ApproximateReceiveCount int `json:"approximate_receive_count"`
+
+	// Set when the message is a dead letter: it failed in another queue and
+	// the sweeper moved it here.
+	DeadLetteredAt *timestamp `json:"dead_lettered_at,omitempty"`
+}
+
+// receiveEnvelope is the object form of a receive response, for clients that
+// can't take a bare top-level array.
+type receiveEnvelope struct {
+	Messages []receivedMessage `json:"messages"`
+	Count    int               `json:"count"`
 }
 
 type ackRequest struct {
-	Receipt string `json:"receipt,omitempty"` // placeholder for future opaque receipts
+	Receipt string `json:"receipt"` // from the receive that leased the message
+	// AckID names this ack so that a retry of it succeeds even though the
+	// first attempt already deleted the message.
+	AckID string `json:"ack_id,omitempty"`
+}
+
+// Leases are named by receipt rather than bare ID so that, as with ack, a
+// worker whose lease was taken over can't extend the new holder's lease.
+type extendBatchRequest struct {
+	Receipts     []string `json:"receipts"`
+	VisibilityMS int64    `json:"visibility_ms"`
+}
+
+type extendBatchResponse struct {
+	Extended []msgID          `json:"extended"`
+	Receipts map[msgID]string `json:"receipts"` // by id: replaces the receipt for the longer lease
+	Failed   []extendFailed   `json:"failed"`
+}
+
+type extendFailed struct {
+	Receipt string `json:"receipt"`
+	Error   string `json:"error"`
 }
 
 type ackResponse struct {
 	OK bool `json:"ok"`
 }
 
+type releaseBatchRequest struct {
+	Receipts []string `json:"receipts"`
+}
+
+type releaseBatchResponse struct {
+	Released []msgID        `json:"released"`
+	Failed   []extendFailed `json:"failed"`
+}
+
+type ackBatchRequest struct {
+	Receipts []string `json:"receipts"`
+}
+
+type ackBatchResponse struct {
+	Acked    []msgID       `json:"acked"`
+	Rejected []ackRejected `json:"rejected"`
+}
+
+type ackRejected struct {
+	Receipt string `json:"receipt"`
+	Error   string `json:"error"`
+}
+
+type resetResponse struct {
+	OK bool `json:"ok"`
+}
+
+type queueConfigRequest struct {
+	Partitions    int     `json:"partitions"`
+	VisibilityMS  int64   `json:"visibility_ms,omitempty"`
+	MaxRetries    int     `json:"max_retries,omitempty"`
+	DLQ           *string `json:"dlq,omitempty"`
+	DedupWindowMS int64   `json:"dedup_window_ms,omitempty"`
+
+	RequireObjectBody bool   `json:"require_object_body,omitempty"`
+	ClaimOrder        string `json:"claim_order,omitempty"` // "id" (default), "visible_at" or "fifo"
+	Role              string `json:"role,omitempty"`        // "dlq" tags a dead-letter queue
+	SkipAfterAttempts int    `json:"skip_after_attempts,omitempty"`
+	Paused            bool   `json:"paused,omitempty"` // receives get nothing until resumed
+	MaxInFlight       int    `json:"max_in_flight,omitempty"`
+	RequeueInPlace    bool   `json:"requeue_in_place,omitempty"` // requeues skip backoff and keep their place
+	CloudEvents       bool   `json:"cloudevents,omitempty"`      // bodies are CloudEvents envelopes
+	DefaultDelayMS    int64  `json:"default_delay_ms,omitempty"` // delay for enqueues without delay_ms or not_before
+	Archive           bool   `json:"archive,omitempty"`          // acked messages are kept for replay
+}
+
+type queueConfigResponse struct {
+	Queue         string  `json:"queue"`
+	Partitions    int     `json:"partitions"`
+	VisibilityMS  int64   `json:"visibility_ms,omitempty"`
+	MaxRetries    int     `json:"max_retries,omitempty"`
+	DLQ           *string `json:"dlq,omitempty"`
+	DedupWindowMS int64   `json:"dedup_window_ms,omitempty"`
+
+	RequireObjectBody bool   `json:"require_object_body,omitempty"`
+	ClaimOrder        string `json:"claim_order,omitempty"` // "id" (default), "visible_at" or "fifo"
+	Role              string `json:"role,omitempty"`        // "dlq" tags a dead-letter queue
+	SkipAfterAttempts int    `json:"skip_after_attempts,omitempty"`
+	Paused            bool   `json:"paused,omitempty"`
+	MaxInFlight       int    `json:"max_in_flight,omitempty"`
+	RequeueInPlace    bool   `json:"requeue_in_place,omitempty"`
+	CloudEvents       bool   `json:"cloudevents,omitempty"`
+	DefaultDelayMS    int64  `json:"default_delay_ms,omitempty"`
+	Archive           bool   `json:"archive,omitempty"`
+}
+
+type listQueuesResponse struct {
+	Queues []string `json:"queues"`
+}
+
+// attributesResponse mirrors SQS GetQueueAttributes: the effective settings a
+// producer or consumer will get, plus approximate counts.
+type attributesResponse struct {
+	Queue           string  `json:"queue"`
+	VisibilityMS    int64   `json:"visibility_timeout_ms"`
+	MaxRetries      int     `json:"max_retries"`
+	DLQ             *string `json:"dlq"`
+	MaxMessageBytes int     `json:"max_message_bytes"`
+	DedupWindowMS   int64   `json:"dedup_window_ms"`
+	DefaultDelayMS  int64   `json:"default_delay_ms"`
+	RequireObject   bool    `json:"require_object_body"`
+	Partitions      int     `json:"partitions"`
+	ApproxDepth     int64   `json:"approximate_depth"`
+	InFlight        int64   `json:"in_flight"`
+	Delayed         int64   `json:"delayed"`
+	Paused          bool    `json:"paused"`
+}
+
+type countResponse struct {
+	Queue  string `json:"queue"`
+	Count  int64  `json:"count"`
+	Capped bool   `json:"capped,omitempty"` // more than maxCount match; count is maxCount
+}
+
+// maxCount bounds how many rows one count request may scan.
+const maxCount = 100000
+
+const (
+	// maxEnvelopeBytes is headroom for the non-body fields of an enqueue request.
+	maxEnvelopeBytes = 4 << 10
+
+	// maxBatchEntries caps a batch enqueue, as in SQS SendMessageBatch.
+	maxBatchEntries = 10
+)
+
+const (
+	// idempotencyScope namespaces Idempotency-Key values in the key store.
+	idempotencyScope     = "idempotency"
+	maxIdempotencyKeyLen = 255
+
+	// dedupScope namespaces dedup_id values; they share the key store but
+	// expire after the queue's dedup window instead of IDEMPOTENCY_TTL.
+	dedupScope         = "dedup"
+	defaultDedupWindow = 5 * time.Minute
+)
+
+// maxPartitions bounds how many partitions a claim may have to walk.
+const maxPartitions = 64
+
+// maxExtendBatch bounds how many leases one extend-batch or release-batch
+// may touch.
+const maxExtendBatch = 100
+
+// maxAckBatch bounds how many receipts one ack-batch may carry.
+const maxAckBatch = 100
+
+// maxNotBeforePast is how far in the past an enqueue's not_before may be,
+// to allow for clock differences with the sender; such a message is
+// available at once.
+const maxNotBeforePast = time.Minute
+
+// maxGroupIDLen bounds a message's group_id.
+const maxGroupIDLen = 128
+
+// maxMultiQueues bounds how many queues one multi-receive may poll.
+const maxMultiQueues = 10
+
 // ---------- Handlers ----------
 
 
@@ -106,35 +532,271 @@ func (s *Server) handleEnqueue(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	var req enqueueRequest
+	r.Body = http.MaxBytesReader(w, r.Body, int64(s.maxMessageBytes)+maxEnvelopeBytes)
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			httpError(w, http.StatusRequestEntityTooLarge, "request exceeds %d bytes", tooLarge.Limit)
+			return
+		}
 		httpError(w, http.StatusBadRequest, "invalid json: %v", err)
 		return
 	}
-	if len(req.Body) == 0 || string(req.Body) == "null" {
-		httpError(w, http.StatusBadRequest, "`body` is required")
+	if tp := traceParentIn(r); tp != "" && req.TraceID == nil {
+		req.TraceID = &tp // an explicit trace_id wins
+	}
+
+	ctx := r.Context()
+	qcfg, err := s.store.GetQueueConfig(ctx, qname)
+	if err != nil {
+		httpError(w, http.StatusInternalServerError, "get config failed: %v", err)
 		return
 	}
-	if req.MaxRetries <= 0 {
-		req.MaxRetries = 5
+	msg, delay, err := s.newMessage(qname, qcfg, req)
+	if err != nil {
+		code := http.StatusBadRequest
+		if errors.Is(err, errMessageTooLarge) {
+			code = http.StatusRequestEntityTooLarge
+		}
+		httpError(w, code, "%v", err)
+		return
+	}
+	if err := s.transformIn(ctx, &msg); err != nil {
+		httpError(w, http.StatusBadRequest, "%v", err)
+		return
 	}
-	delay := time.Duration(req.DelayMS) * time.Millisecond
 
-	msg := queue.Message{
-		Queue:      qname,
-		Body:       []byte(req.Body),
-		MaxRetries: req.MaxRetries,
-		DLQ:        req.DLQ,
-		TraceID:    req.TraceID,
+	var key *queue.EnqueueKey
+	if k := r.Header.Get("Idempotency-Key"); k != "" {
+		if len(k) > maxIdempotencyKeyLen {
+			httpError(w, http.StatusBadRequest, "Idempotency-Key longer than %d bytes", maxIdempotencyKeyLen)
+			return
+		}
+		key = &queue.EnqueueKey{Scope: idempotencyScope, Key: k, TTL: s.idempotencyTTL}
+	}
+	if req.DedupID != nil {
+		if key != nil {
+			httpError(w, http.StatusBadRequest, "use either Idempotency-Key or `dedup_id`, not both")
+			return
+		}
+		if *req.DedupID == "" || len(*req.DedupID) > maxIdempotencyKeyLen {
+			httpError(w, http.StatusBadRequest, "`dedup_id` must be 1 to %d bytes", maxIdempotencyKeyLen)
+			return
+		}
+		window := qcfg.DedupWindow
+		if window <= 0 {
+			window = defaultDedupWindow
+		}
+		key = &queue.EnqueueKey{Scope: dedupScope, Key: *req.DedupID, TTL: window}
+	}
+	if key != nil && req.CoalesceKey != nil {
+		httpError(w, http.StatusBadRequest, "`coalesce_key` can't be combined with Idempotency-Key or `dedup_id`")
+		return
+	}
+	if err := s.admitQueue(ctx, qname); err != nil {
+		httpError(w, admitStatus(err), "%v", err)
+		return
 	}
 
-	ctx := r.Context()
-	id, err := s.store.Enqueue(ctx, msg, delay)
+	start := time.Now()
+	if key != nil {
+		id, replayed, err := s.store.EnqueueKeyed(ctx, msg, delay, *key)
+		metrics.EnqueueDuration.WithLabelValues(qname).Observe(time.Since(start).Seconds())
+		if errors.Is(err, queue.ErrGroupSealed) {
+			httpError(w, http.StatusConflict, "%v", err)
+			return
+		}
+		if errors.Is(err, queue.ErrInvalidBody) {
+			httpError(w, http.StatusBadRequest, "%v", err)
+			return
+		}
+		if err != nil {
+			httpError(w, http.StatusInternalServerError, "enqueue failed: %v", err)
+			return
+		}
+		code := http.StatusCreated
+		if replayed {
+			// same key seen before: hand back the original result, don't enqueue again
+			code = http.StatusOK
+		} else {
+			metrics.MessagesEnqueued.WithLabelValues(qname).Inc()
+		}
+		s.writeEnqueued(w, r, code, id)
+		return
+	}
+
+	var (
+		id        int64
+		coalesced bool
+	)
+	if msg.CoalesceKey != nil {
+		id, coalesced, err = s.store.EnqueueCoalesced(ctx, msg, delay)
+	} else {
+		id, err = s.store.Enqueue(ctx, msg, delay)
+	}
+	metrics.EnqueueDuration.WithLabelValues(qname).Observe(time.Since(start).Seconds())
+	if errors.Is(err, queue.ErrGroupSealed) {
+		httpError(w, http.StatusConflict, "%v", err)
+		return
+	}
+	if errors.Is(err, queue.ErrInvalidBody) {
+		httpError(w, http.StatusBadRequest, "%v", err)
+		return
+	}
 	if err != nil {
 		httpError(w, http.StatusInternalServerError, "enqueue failed: %v", err)
 		return
 	}
+	if coalesced {
+		// one is already pending under the key: hand back its ID
+		s.writeEnqueued(w, r, http.StatusOK, id)
+		return
+	}
 	metrics.MessagesEnqueued.WithLabelValues(qname).Inc()
-	writeJSON(w, http.StatusCreated, &enqueueResponse{ID: id})
+	s.writeEnqueued(w, r, http.StatusCreated, id)
+}
+
+// writeEnqueued answers an enqueue with the message's ID as the API shows it.
+func (s *Server) writeEnqueued(w http.ResponseWriter, r *http.Request, code int, id int64) {
+	shown, err := s.showIDOf(r.Context(), id)
+	if err != nil {
+		httpError(w, http.StatusInternalServerError, "enqueued message %d: %v", id, err)
+		return
+	}
+	writeJSON(w, code, &enqueueResponse{ID: shown})
+}
+
+// handleEnqueueBatch enqueues up to maxBatchEntries messages. With
+// "atomic": true any invalid entry fails the whole batch; otherwise valid
+// entries are enqueued and the response reports each entry's outcome.
+func (s *Server) handleEnqueueBatch(w http.ResponseWriter, r *http.Request) {
+	qname := chi.URLParam(r, "queue")
+	if qname == "" {
+		httpError(w, http.StatusBadRequest, "missing queue path param")
+		return
+	}
+	var req enqueueBatchRequest
+	r.Body = http.MaxBytesReader(w, r.Body, maxBatchEntries*(int64(s.maxMessageBytes)+maxEnvelopeBytes))
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			httpError(w, http.StatusRequestEntityTooLarge, "request exceeds %d bytes", tooLarge.Limit)
+			return
+		}
+		httpError(w, http.StatusBadRequest, "invalid json: %v", err)
+		return
+	}
+	if len(req.Entries) == 0 || len(req.Entries) > maxBatchEntries {
+		httpError(w, http.StatusBadRequest, "`entries` must have between 1 and %d items", maxBatchEntries)
+		return
+	}
+
+	ctx := r.Context()
+	qcfg, err := s.store.GetQueueConfig(ctx, qname)
+	if err != nil {
+		httpError(w, http.StatusInternalServerError, "get config failed: %v", err)
+		return
+	}
+
+	// Validate every entry first so we can report all problems at once.
+	traceParent := traceParentIn(r)
+	results := make([]batchEntryResult, len(req.Entries))
+	entries := make([]queue.EnqueueEntry, 0, len(req.Entries))
+	valid := make([]int, 0, len(req.Entries)) // index into results for each entry
+	for i, raw := range req.Entries {
+		results[i].Index = i
+		var er enqueueRequest
+		if err := json.Unmarshal(raw, &er); err != nil {
+			results[i].Error = fmt.Sprintf("invalid json: %v", err)
+			continue
+		}
+		if er.DedupID != nil {
+			results[i].Error = "`dedup_id` is not supported in batch enqueue"
+			continue
+		}
+		if er.DelayJitterMS == 0 {
+			er.DelayJitterMS = req.DelayJitterMS
+		}
+		if traceParent != "" && er.TraceID == nil {
+			er.TraceID = &traceParent
+		}
+		msg, delay, err := s.newMessage(qname, qcfg, er)
+		if err == nil {
+			err = s.transformIn(ctx, &msg)
+		}
+		if err != nil {
+			results[i].Error = err.Error()
+			continue
+		}
+		entries = append(entries, queue.EnqueueEntry{Message: msg, Delay: delay})
+		valid = append(valid, i)
+	}
+
+	failed := len(req.Entries) - len(entries)
+	if req.Atomic && failed > 0 {
+		for _, i := range valid {
+			results[i].Error = "not enqueued: batch is atomic"
+		}
+		writeJSON(w, http.StatusBadRequest, &enqueueBatchResponse{Results: results})
+		return
+	}
+
+	if len(entries) > 0 {
+		if err := s.admitQueue(ctx, qname); err != nil {
+			httpError(w, admitStatus(err), "%v", err)
+			return
+		}
+		start := time.Now()
+		ids, err := s.store.EnqueueBatch(ctx, entries)
+		// Best effort: an entry the store refuses, such as one for a sealed
+		// group, fails on its own and the rest are tried again without it.
+		// Anything else failed the batch as a whole, so nothing went in.
+		var refused *queue.EntryError
+		for !req.Atomic && errors.As(err, &refused) {
+			results[valid[refused.Index]].Error = refused.Err.Error()
+			failed++
+			entries = slices.Delete(entries, refused.Index, refused.Index+1)
+			valid = slices.Delete(valid, refused.Index, refused.Index+1)
+			ids, err = nil, nil
+			if len(entries) > 0 {
+				ids, err = s.store.EnqueueBatch(ctx, entries)
+			}
+		}
+		metrics.EnqueueDuration.WithLabelValues(qname).Observe(time.Since(start).Seconds())
+		if errors.As(err, &refused) {
+			for j, i := range valid {
+				results[i].Error = "not enqueued: batch is atomic"
+				if j == refused.Index {
+					results[i].Error = refused.Err.Error()
+				}
+			}
+			code := http.StatusBadRequest
+			if errors.Is(refused, queue.ErrGroupSealed) {
+				code = http.StatusConflict
+			}
+			writeJSON(w, code, &enqueueBatchResponse{Results: results})
+			return
+		}
+		if err != nil {
+			httpError(w, http.StatusInternalServerError, "enqueue failed: %v", err)
+			return
+		}
+		metrics.MessagesEnqueued.WithLabelValues(qname).Add(float64(len(ids)))
+		shown, err := s.showIDs(ctx, ids, nil)
+		if err != nil {
+			httpError(w, http.StatusInternalServerError, "%v", err)
+			return
+		}
+		for j, i := range valid {
+			results[i].ID = &shown[j]
+		}
+	}
+
+	code := http.StatusCreated
+	if failed > 0 {
+		code = http.StatusMultiStatus
+	}
+	writeJSON(w, code, &enqueueBatchResponse{Results: results})
 }
 
 func (s *Server) handleReceive(w http.ResponseWriter, r *http.Request) {
@@ -144,62 +806,271 @@ func (s *Server) handleReceive(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	var req receiveRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	// no body takes every default, so a Prefer header alone can long poll
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
 		httpError(w, http.StatusBadRequest, "invalid json: %v", err)
 		return
 	}
-	if req.Max <= 0 || req.Max > 32 {
-		req.Max = 1
+	if n := s.receiveLimit(req.Max); n != req.Max {
+		if req.Max > 0 {
+			w.Header().Set("X-Receive-Max", strconv.Itoa(n))
+		}
+		req.Max = n
+	}
+	var wait time.Duration
+	preferred := false
+	if req.WaitMS != nil {
+		wait = time.Duration(*req.WaitMS) * time.Millisecond
+		if wait < 0 || wait > maxReceiveWait {
+			httpError(w, http.StatusBadRequest, "`wait_ms` must be between 0 and %d", maxReceiveWait.Milliseconds())
+			return
+		}
+	} else {
+		pw, ok, err := preferWait(r)
+		if err != nil {
+			httpError(w, http.StatusBadRequest, "%v", err)
+			return
+		}
+		wait, preferred = pw, ok
+	}
+	wait = s.waitBefore(r.Context(), wait)
+	if preferred {
+		// the wait as cut short, not as asked for; rounded, since the time
+		// already spent on the request shaves a little off every wait
+		w.Header().Set("Preference-Applied", fmt.Sprintf("wait=%d", int(wait.Round(time.Second)/time.Second)))
+	}
+	if req.MinMessages < 0 || req.MinMessages > req.Max {
+		httpError(w, http.StatusBadRequest, "`min_messages` must be between 0 and `max` (%d)", req.Max)
+		return
+	}
+	ctx := r.Context()
+	qcfg, err := s.store.GetQueueConfig(ctx, qname)
+	if err != nil {
+		httpError(w, http.StatusInternalServerError, "get config failed: %v", err)
+		return
 	}
 	vis := time.Duration(req.VisibilityMS) * time.Millisecond
 	if vis <= 0 {
-		vis = 30 * time.Second
+		vis = s.visibilityFor(qcfg)
 	}
 
-	ctx := r.Context()
+	if wait > 0 {
+		// Past the deadline we return whatever is there, possibly nothing.
+		if err := s.waitForMessages(ctx, qname, max(req.MinMessages, 1), wait); err != nil {
+			httpError(w, http.StatusInternalServerError, "wait failed: %v", err)
+			return
+		}
+	}
+
+	if qcfg.MaxInFlight > 0 {
+		// The store enforces the budget either way; trimming here tells the
+		// client why it got fewer than it asked for.
+		st, err := s.store.Stats(ctx, qname)
+		if err != nil {
+			httpError(w, http.StatusInternalServerError, "stats failed: %v", err)
+			return
+		}
+		if left := max(int64(qcfg.MaxInFlight)-st.InFlight, 0); left < int64(req.Max) {
+			req.Max = int(left)
+			w.Header().Set("X-Receive-Max", strconv.Itoa(req.Max))
+		}
+	}
+	var ok bool
+	if req.Max, ok = s.limitInFlight(ctx, w, req.Max); !ok {
+		return
+	}
+
+	start := time.Now()
 	out, err := s.store.Claim(ctx, queue.ClaimOptions{
 		Queue:      qname,
 		Limit:      req.Max,
 		Visibility: vis,
 	})
+	metrics.ReceiveDuration.WithLabelValues(qname).Observe(time.Since(start).Seconds())
 	if err != nil {
 		httpError(w, http.StatusInternalServerError, "claim failed: %v", err)
 		return
 	}
+	s.noteLeased(len(out))
+	if s.abandoned(ctx, out) {
+		return
+	}
+	if s.draining(ctx, out) {
+		out = nil
+	}
+	out = s.resolveBodies(ctx, out)
+	if err := s.transformOut(ctx, out); err != nil {
+		httpError(w, http.StatusInternalServerError, "%v", err)
+		return
+	}
 
 	resp := make([]receivedMessage, 0, len(out))
 	for _, m := range out {
-		resp = append(resp, receivedMessage{
-			ID:            m.ID,
-			Body:          json.RawMessage(m.Body),
-			Receipt:       strconv.FormatInt(m.ID, 10),
-			LeaseUntil:    m.LeaseUntil,
-			DeliveryCount: m.DeliveryCount,
-			MaxRetries:    m.MaxRetries,
-			DLQ:           m.DLQ,
-			TraceID:       m.TraceID,
-		})
-		metrics.MessagesReceived.WithLabelValues(qname).Inc()
+		resp = append(resp, s.toReceivedMessage(m))
+		observeReceived(m)
 	}
-	writeJSON(w, http.StatusOK, resp)
+	s.countByWorker(r, out)
+	if qcfg.Role != "" {
+		// informational: lets consumers notice they're reading dead letters
+		w.Header().Set("X-Queue-Role", string(qcfg.Role))
+	}
+	s.setDepthHeader(ctx, w, qname, len(out))
+	s.writeMessages(w, r, resp)
 }
 
-func (s *Server) handleAck(w http.ResponseWriter, r *http.Request) {
-	idStr := chi.URLParam(r, "id")
-	if idStr == "" {
-		httpError(w, http.StatusBadRequest, "missing message id")
+// handleReceiveAndDelete hands out messages and deletes them in the same
+// statement, for at-most-once consumers: no lease, no receipt, no ack, and no
+// redelivery if the client drops them.
+func (s *Server) handleReceiveAndDelete(w http.ResponseWriter, r *http.Request) {
+	qname := chi.URLParam(r, "queue")
+	if qname == "" {
+		httpError(w, http.StatusBadRequest, "missing queue path param")
 		return
 	}
-	id, err := strconv.ParseInt(idStr, 10, 64)
+	var req receiveRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httpError(w, http.StatusBadRequest, "invalid json: %v", err)
+		return
+	}
+	if n := s.receiveLimit(req.Max); n != req.Max {
+		if req.Max > 0 {
+			w.Header().Set("X-Receive-Max", strconv.Itoa(n))
+		}
+		req.Max = n
+	}
+
+	start := time.Now()
+	out, err := s.store.ClaimAndDelete(r.Context(), qname, req.Max)
+	metrics.ReceiveDuration.WithLabelValues(qname).Observe(time.Since(start).Seconds())
+	if err != nil {
+		httpError(w, http.StatusInternalServerError, "receive failed: %v", err)
+		return
+	}
+	if err := s.transformOut(r.Context(), out); err != nil {
+		// already deleted: these messages are lost
+		httpError(w, http.StatusInternalServerError, "%v", err)
+		return
+	}
+
+	resp := make([]receivedMessage, 0, len(out))
+	for _, m := range out {
+		rm := s.toReceivedMessage(m)
+		rm.Receipt = "" // nothing left to ack
+		resp = append(resp, rm)
+		observeReceived(m)
+	}
+	s.countByWorker(r, out)
+	s.writeMessages(w, r, resp)
+}
+
+// handleReceiveMulti claims from several queues in one call, splitting `max`
+// across them by weight so a busy queue can't starve a quiet one.
+func (s *Server) handleReceiveMulti(w http.ResponseWriter, r *http.Request) {
+	var req receiveMultiRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httpError(w, http.StatusBadRequest, "invalid json: %v", err)
+		return
+	}
+	if len(req.Queues) == 0 || len(req.Queues) > maxMultiQueues {
+		httpError(w, http.StatusBadRequest, "`queues` must list between 1 and %d queues", maxMultiQueues)
+		return
+	}
+	if req.Max < len(req.Queues) || req.Max > s.receiveMax {
+		httpError(w, http.StatusBadRequest, "`max` must be between the number of queues (%d) and %d", len(req.Queues), s.receiveMax)
+		return
+	}
+
+	ctx := r.Context()
+	opts := queue.ClaimMultiOptions{Limit: req.Max}
+	seen := make(map[string]bool, len(req.Queues))
+	for _, q := range req.Queues {
+		if q.Name == "" || seen[q.Name] {
+			httpError(w, http.StatusBadRequest, "queue names must be non-empty and unique")
+			return
+		}
+		seen[q.Name] = true
+		if q.Weight < 0 {
+			httpError(w, http.StatusBadRequest, "`weight` must not be negative")
+			return
+		}
+
+		vis := time.Duration(req.VisibilityMS) * time.Millisecond
+		if vis <= 0 {
+			qcfg, err := s.store.GetQueueConfig(ctx, q.Name)
+			if err != nil {
+				httpError(w, http.StatusInternalServerError, "get config failed: %v", err)
+				return
+			}
+			vis = s.visibilityFor(qcfg)
+		}
+		opts.Queues = append(opts.Queues, queue.WeightedQueue{Queue: q.Name, Weight: max(q.Weight, 1), Visibility: vis})
+	}
+	var ok bool
+	if opts.Limit, ok = s.limitInFlight(ctx, w, opts.Limit); !ok {
+		return
+	}
+
+	out, err := s.store.ClaimMulti(ctx, opts)
+	if err != nil {
+		httpError(w, http.StatusInternalServerError, "claim failed: %v", err)
+		return
+	}
+	s.noteLeased(len(out))
+	if s.abandoned(ctx, out) {
+		return
+	}
+	if s.draining(ctx, out) {
+		out = nil
+	}
+	out = s.resolveBodies(ctx, out)
+	if err := s.transformOut(ctx, out); err != nil {
+		httpError(w, http.StatusInternalServerError, "%v", err)
+		return
+	}
+
+	resp := make([]receivedMessage, 0, len(out))
+	for _, m := range out {
+		resp = append(resp, s.toReceivedMessage(m))
+		observeReceived(m)
+	}
+	s.countByWorker(r, out)
+	s.writeMessages(w, r, resp)
+}
+
+func (s *Server) handleAck(w http.ResponseWriter, r *http.Request) {
+	id, err := s.pathID(r)
 	if err != nil {
-		httpError(w, http.StatusBadRequest, "invalid id: %v", err)
+		pathIDError(w, err)
 		return
 	}
-	// Optionally verify receipt body, currently ignored:
 	var req ackRequest
-	_ = json.NewDecoder(r.Body).Decode(&req)
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		httpError(w, http.StatusBadRequest, "invalid json: %v", err)
+		return
+	}
+	rc, ok := s.checkReceipt(r.Context(), w, req.Receipt, id)
+	if !ok {
+		return
+	}
+	if len(req.AckID) > maxIdempotencyKeyLen {
+		httpError(w, http.StatusBadRequest, "ack_id longer than %d bytes", maxIdempotencyKeyLen)
+		return
+	}
 
-	ok, err := s.store.Ack(r.Context(), id)
+	replayed := false
+	if req.AckID != "" && s.ackIDTTL > 0 {
+		ok, replayed, err = s.store.AckOnce(r.Context(), rc, req.AckID, s.ackIDTTL)
+	} else {
+		ok, err = s.store.Ack(r.Context(), rc)
+	}
+	if errors.Is(err, queue.ErrStaleReceipt) {
+		httpError(w, http.StatusForbidden, "%v", err)
+		return
+	}
+	if errors.Is(err, queue.ErrGroupOrder) {
+		httpError(w, http.StatusConflict, "%v", err)
+		return
+	}
 	if err != nil {
 		httpError(w, http.StatusInternalServerError, "ack failed: %v", err)
 		return
@@ -209,12 +1080,739 @@ func (s *Server) handleAck(w http.ResponseWriter, r *http.Request) {
 		httpError(w, http.StatusNotFound, "message not found")
 		return
 	}
-	metrics.MessagesAcked.Inc()
+	if !replayed {
+		metrics.MessagesAcked.Inc()
+	}
 	writeJSON(w, http.StatusOK, &ackResponse{OK: true})
 }
 
+// handleResetDeliveryCount gives a message a fresh set of retries in place,
+// for recovery once whatever made it fail is fixed.
+func (s *Server) handleResetDeliveryCount(w http.ResponseWriter, r *http.Request) {
+	id, err := s.pathID(r)
+	if err != nil {
+		pathIDError(w, err)
+		return
+	}
+	ok, err := s.store.ResetDeliveryCount(r.Context(), id.serial)
+	if err != nil {
+		httpError(w, http.StatusInternalServerError, "reset failed: %v", err)
+		return
+	}
+	if !ok {
+		httpError(w, http.StatusNotFound, "message not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, &resetResponse{OK: true})
+}
+
+// handleAckBatch acks what it can and reports the rest per receipt. Receipts
+// are applied in ID order, so a batch holding a group's messages acks them
+// in sequence, while one whose earlier group-mate failed is refused.
+func (s *Server) handleAckBatch(w http.ResponseWriter, r *http.Request) {
+	var req ackBatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httpError(w, http.StatusBadRequest, "invalid json: %v", err)
+		return
+	}
+	if len(req.Receipts) == 0 || len(req.Receipts) > maxAckBatch {
+		httpError(w, http.StatusBadRequest, "`receipts` must have between 1 and %d items", maxAckBatch)
+		return
+	}
+
+	ctx := r.Context()
+	parsed, errs, names, err := s.parseReceipts(ctx, req.Receipts)
+	if err != nil {
+		httpError(w, http.StatusInternalServerError, "%v", err)
+		return
+	}
+	resp := &ackBatchResponse{Acked: []msgID{}, Rejected: []ackRejected{}}
+	rcs := make([]queue.Receipt, 0, len(req.Receipts))
+	raws := make(map[int64]string, len(req.Receipts))
+	now := time.Now()
+	for i, raw := range req.Receipts {
+		rc, err := parsed[i], errs[i]
+		if err == nil && rc.Expired(now) {
+			err = queue.ErrReceiptExpired
+		}
+		if err != nil {
+			resp.Rejected = append(resp.Rejected, ackRejected{Receipt: raw, Error: err.Error()})
+			continue
+		}
+		if _, dup := raws[rc.ID]; dup {
+			resp.Rejected = append(resp.Rejected, ackRejected{Receipt: raw, Error: "duplicate message id in batch"})
+			continue
+		}
+		rcs = append(rcs, rc)
+		raws[rc.ID] = raw
+	}
+
+	if len(rcs) > 0 {
+		acked, rejected, err := s.store.AckBatch(ctx, rcs)
+		if err != nil {
+			httpError(w, http.StatusInternalServerError, "ack failed: %v", err)
+			return
+		}
+		metrics.MessagesAcked.Add(float64(len(acked)))
+		if resp.Acked, err = s.showIDs(ctx, acked, names); err != nil {
+			httpError(w, http.StatusInternalServerError, "%v", err)
+			return
+		}
+		for _, rc := range rcs {
+			if err, ok := rejected[rc.ID]; ok {
+				resp.Rejected = append(resp.Rejected, ackRejected{Receipt: raws[rc.ID], Error: err.Error()})
+			}
+		}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// handleReleaseBatch is a batch nack for messages a consumer received but
+// never started on, e.g. a worker's prefetch buffer when it shuts down. They
+// are available again at once and the delivery isn't counted.
+func (s *Server) handleReleaseBatch(w http.ResponseWriter, r *http.Request) {
+	var req releaseBatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httpError(w, http.StatusBadRequest, "invalid json: %v", err)
+		return
+	}
+	if len(req.Receipts) == 0 || len(req.Receipts) > maxExtendBatch {
+		httpError(w, http.StatusBadRequest, "`receipts` must have between 1 and %d items", maxExtendBatch)
+		return
+	}
+
+	ctx := r.Context()
+	parsed, errs, names, err := s.parseReceipts(ctx, req.Receipts)
+	if err != nil {
+		httpError(w, http.StatusInternalServerError, "%v", err)
+		return
+	}
+	resp := &releaseBatchResponse{Released: []msgID{}, Failed: []extendFailed{}}
+	rcs := make([]queue.Receipt, 0, len(req.Receipts))
+	pending := make(map[int64]string, len(req.Receipts)) // id -> receipt not yet released
+	for i, raw := range req.Receipts {
+		rc, err := parsed[i], errs[i]
+		if err != nil {
+			resp.Failed = append(resp.Failed, extendFailed{Receipt: raw, Error: err.Error()})
+			continue
+		}
+		rcs = append(rcs, rc)
+		pending[rc.ID] = raw
+	}
+
+	if len(rcs) > 0 {
+		ids, err := s.store.Release(ctx, rcs)
+		if err != nil {
+			httpError(w, http.StatusInternalServerError, "release failed: %v", err)
+			return
+		}
+		if resp.Released, err = s.showIDs(ctx, ids, names); err != nil {
+			httpError(w, http.StatusInternalServerError, "%v", err)
+			return
+		}
+		for _, id := range ids {
+			delete(pending, id)
+		}
+		for _, rc := range rcs {
+			if raw, ok := pending[rc.ID]; ok {
+				resp.Failed = append(resp.Failed, extendFailed{Receipt: raw, Error: "not leased or receipt is stale"})
+				delete(pending, rc.ID)
+			}
+		}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) handleExtendBatch(w http.ResponseWriter, r *http.Request) {
+	var req extendBatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httpError(w, http.StatusBadRequest, "invalid json: %v", err)
+		return
+	}
+	if len(req.Receipts) == 0 || len(req.Receipts) > maxExtendBatch {
+		httpError(w, http.StatusBadRequest, "`receipts` must have between 1 and %d items", maxExtendBatch)
+		return
+	}
+	if req.VisibilityMS <= 0 {
+		httpError(w, http.StatusBadRequest, "`visibility_ms` must be positive")
+		return
+	}
+
+	ctx := r.Context()
+	parsed, errs, names, err := s.parseReceipts(ctx, req.Receipts)
+	if err != nil {
+		httpError(w, http.StatusInternalServerError, "%v", err)
+		return
+	}
+	resp := &extendBatchResponse{Extended: []msgID{}, Receipts: map[msgID]string{}, Failed: []extendFailed{}}
+	rcs := make([]queue.Receipt, 0, len(req.Receipts))
+	pending := make(map[int64]string, len(req.Receipts)) // id -> receipt not yet extended
+	now := time.Now()
+	for i, raw := range req.Receipts {
+		rc, err := parsed[i], errs[i]
+		if err == nil && rc.Expired(now) {
+			err = queue.ErrReceiptExpired
+		}
+		if err != nil {
+			resp.Failed = append(resp.Failed, extendFailed{Receipt: raw, Error: err.Error()})
+			continue
+		}
+		rcs = append(rcs, rc)
+		pending[rc.ID] = raw
+	}
+
+	if len(rcs) > 0 {
+		vis := time.Duration(req.VisibilityMS) * time.Millisecond
+		// taken before the store sets the lease, so never later than it
+		expires := time.Now().Add(vis)
+		ids, err := s.store.ExtendBatch(ctx, rcs, vis)
+		if err != nil {
+			httpError(w, http.StatusInternalServerError, "extend failed: %v", err)
+			return
+		}
+		shown, err := s.showIDs(ctx, ids, names)
+		if err != nil {
+			httpError(w, http.StatusInternalServerError, "%v", err)
+			return
+		}
+		resp.Extended = shown
+		for _, rc := range rcs {
+			if j := slices.Index(ids, rc.ID); j >= 0 {
+				rc.Expires = expires
+				resp.Receipts[shown[j]] = s.showReceipt(rc, shown[j].ulid)
+			}
+		}
+		for _, id := range ids {
+			delete(pending, id)
+		}
+		for _, rc := range rcs {
+			if raw, ok := pending[rc.ID]; ok {
+				resp.Failed = append(resp.Failed, extendFailed{Receipt: raw, Error: "lease expired or receipt is stale"})
+				delete(pending, rc.ID)
+			}
+		}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) handleGetQueueConfig(w http.ResponseWriter, r *http.Request) {
+	qname := chi.URLParam(r, "queue")
+	if qname == "" {
+		httpError(w, http.StatusBadRequest, "missing queue path param")
+		return
+	}
+	cfg, err := s.store.GetQueueConfig(r.Context(), qname)
+	if err != nil {
+		httpError(w, http.StatusInternalServerError, "get config failed: %v", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, toQueueConfigResponse(cfg))
+}
+
+func (s *Server) handlePutQueueConfig(w http.ResponseWriter, r *http.Request) {
+	qname := chi.URLParam(r, "queue")
+	if qname == "" {
+		httpError(w, http.StatusBadRequest, "missing queue path param")
+		return
+	}
+	var req queueConfigRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httpError(w, http.StatusBadRequest, "invalid json: %v", err)
+		return
+	}
+	if req.Partitions == 0 {
+		req.Partitions = 1
+	}
+	if req.Partitions < 1 || req.Partitions > maxPartitions {
+		httpError(w, http.StatusBadRequest, "`partitions` must be between 1 and %d", maxPartitions)
+		return
+	}
+	switch queue.ClaimOrder(req.ClaimOrder) {
+	case "", queue.ClaimOrderID, queue.ClaimOrderVisibleAt, queue.ClaimOrderFIFO:
+	default:
+		httpError(w, http.StatusBadRequest, "`claim_order` must be %q, %q or %q",
+			queue.ClaimOrderID, queue.ClaimOrderVisibleAt, queue.ClaimOrderFIFO)
+		return
+	}
+	if queue.ClaimOrder(req.ClaimOrder) == queue.ClaimOrderFIFO && req.Partitions > 1 {
+		httpError(w, http.StatusBadRequest, "`claim_order` %q needs a single partition", queue.ClaimOrderFIFO)
+		return
+	}
+	if role := queue.QueueRole(req.Role); role != "" && role != queue.QueueRoleDLQ {
+		httpError(w, http.StatusBadRequest, "`role` must be empty or %q", queue.QueueRoleDLQ)
+		return
+	}
+	if req.VisibilityMS < 0 || req.MaxRetries < 0 || req.DedupWindowMS < 0 || req.SkipAfterAttempts < 0 || req.MaxInFlight < 0 || req.DefaultDelayMS < 0 {
+		httpError(w, http.StatusBadRequest, "`visibility_ms`, `max_retries`, `dedup_window_ms`, `skip_after_attempts`, `max_in_flight` and `default_delay_ms` must not be negative")
+		return
+	}
+	if req.DLQ != nil && *req.DLQ == qname {
+		httpError(w, http.StatusBadRequest, "`dlq` must not be the queue itself")
+		return
+	}
+
+	cfg := queue.QueueConfig{
+		Queue:       qname,
+		Partitions:  req.Partitions,
+		Visibility:  time.Duration(req.VisibilityMS) * time.Millisecond,
+		MaxRetries:  req.MaxRetries,
+		DLQ:         req.DLQ,
+		DedupWindow: time.Duration(req.DedupWindowMS) * time.Millisecond,
+
+		RequireObjectBody: req.RequireObjectBody,
+		ClaimOrder:        queue.ClaimOrder(req.ClaimOrder),
+		Role:              queue.QueueRole(req.Role),
+		SkipAfterAttempts: req.SkipAfterAttempts,
+		Paused:            req.Paused,
+		MaxInFlight:       req.MaxInFlight,
+		RequeueInPlace:    req.RequeueInPlace,
+		CloudEvents:       req.CloudEvents,
+		DefaultDelay:      time.Duration(req.DefaultDelayMS) * time.Millisecond,
+		Archive:           req.Archive,
+	}
+	if err := s.store.PutQueueConfig(r.Context(), cfg); err != nil {
+		httpError(w, http.StatusInternalServerError, "put config failed: %v", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, toQueueConfigResponse(cfg))
+}
+
+func (s *Server) handleListQueues(w http.ResponseWriter, r *http.Request) {
+	names, err := s.store.ListQueues(r.Context())
+	if err != nil {
+		httpError(w, http.StatusInternalServerError, "list queues failed: %v", err)
+		return
+	}
+	if names == nil {
+		names = []string{}
+	}
+	writeJSON(w, http.StatusOK, &listQueuesResponse{Queues: names})
+}
+
+// handleCount counts the queue's messages matching the query filters:
+// state, min_delivery_count and group_id.
+func (s *Server) handleCount(w http.ResponseWriter, r *http.Request) {
+	qname := chi.URLParam(r, "queue")
+	if qname == "" {
+		httpError(w, http.StatusBadRequest, "missing queue path param")
+		return
+	}
+	q := r.URL.Query()
+	filter := queue.CountFilter{Limit: maxCount + 1}
+	switch state := queue.MessageState(q.Get("state")); state {
+	case "", queue.StateAvailable, queue.StateInFlight, queue.StateDelayed:
+		filter.State = state
+	default:
+		httpError(w, http.StatusBadRequest, "`state` must be one of %q, %q, %q",
+			queue.StateAvailable, queue.StateInFlight, queue.StateDelayed)
+		return
+	}
+	if v := q.Get("min_delivery_count"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			httpError(w, http.StatusBadRequest, "`min_delivery_count` must be a non-negative integer")
+			return
+		}
+		filter.MinDeliveryCount = n
+	}
+	if q.Has("group_id") {
+		g := q.Get("group_id")
+		filter.GroupID = &g
+	}
+
+	n, err := s.store.Count(r.Context(), qname, filter)
+	if err != nil {
+		httpError(w, http.StatusInternalServerError, "count failed: %v", err)
+		return
+	}
+	resp := &countResponse{Queue: qname, Count: n}
+	if n > maxCount {
+		resp.Count, resp.Capped = maxCount, true
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) handleAttributes(w http.ResponseWriter, r *http.Request) {
+	qname := chi.URLParam(r, "queue")
+	if qname == "" {
+		httpError(w, http.StatusBadRequest, "missing queue path param")
+		return
+	}
+	ctx := r.Context()
+	cfg, err := s.store.GetQueueConfig(ctx, qname)
+	if err != nil {
+		httpError(w, http.StatusInternalServerError, "get config failed: %v", err)
+		return
+	}
+	st, err := s.store.Stats(ctx, qname)
+	if err != nil {
+		httpError(w, http.StatusInternalServerError, "stats failed: %v", err)
+		return
+	}
+
+	maxRetries := cfg.MaxRetries
+	if maxRetries <= 0 {
+		maxRetries = s.defaultMaxRetries
+	}
+	dedupWindow := cfg.DedupWindow
+	if dedupWindow <= 0 {
+		dedupWindow = defaultDedupWindow
+	}
+	writeJSON(w, http.StatusOK, &attributesResponse{
+		Queue:           qname,
+		VisibilityMS:    s.visibilityFor(cfg).Milliseconds(),
+		MaxRetries:      maxRetries,
+		DLQ:             cfg.DLQ,
+		MaxMessageBytes: s.maxMessageBytes,
+		DedupWindowMS:   dedupWindow.Milliseconds(),
+		DefaultDelayMS:  cfg.DefaultDelay.Milliseconds(),
+		RequireObject:   cfg.RequireObjectBody,
+		Partitions:      cfg.Partitions,
+		ApproxDepth:     st.Available,
+		InFlight:        st.InFlight,
+		Delayed:         st.Delayed,
+		Paused:          cfg.Paused,
+	})
+}
+
 // ---------- helpers ----------
 
+// errMessageTooLarge marks enqueue bodies over the server's size limit.
+var errMessageTooLarge = errors.New("message too large")
+
+// newMessage validates an enqueue request and builds the message from it,
+// filling unset fields from the queue's config.
+func (s *Server) newMessage(qname string, qcfg queue.QueueConfig, req enqueueRequest) (queue.Message, time.Duration, error) {
+	// Any JSON value is a valid body, including {} and []; only a missing
+	// body or an explicit null counts as "no body".
+	hasBody := len(req.Body) > 0 && string(req.Body) != "null"
+	if req.BodyRef != nil {
+		if hasBody {
+			return queue.Message{}, 0, errors.New("use either `body` or `body_ref`, not both")
+		}
+		if *req.BodyRef == "" || len(*req.BodyRef) > maxBodyRefLen {
+			return queue.Message{}, 0, fmt.Errorf("`body_ref` must be 1 to %d bytes", maxBodyRefLen)
+		}
+		if len(s.bodyRefPrefixes) > 0 && !refAllowed(s.bodyRefPrefixes, *req.BodyRef) {
+			return queue.Message{}, 0, errBodyRefNotAllowed
+		}
+		// the body isn't known until it is fetched, so it stands in as null
+		req.Body = json.RawMessage("null")
+	} else if !hasBody {
+		return queue.Message{}, 0, errors.New("`body` is required")
+	} else if err := queue.ValidateBody(req.Body); err != nil {
+		return queue.Message{}, 0, fmt.Errorf("`body`: %w", err)
+	}
+	if qcfg.RequireObjectBody && req.BodyRef == nil && req.Body[0] != '{' {
+		return queue.Message{}, 0, errors.New("`body` must be a JSON object on this queue")
+	}
+	if qcfg.CloudEvents {
+		if req.BodyRef != nil {
+			return queue.Message{}, 0, errors.New("`body_ref` is not supported on a cloudevents queue")
+		}
+		event, err := toCloudEvent(qname, req.Body)
+		if err != nil {
+			return queue.Message{}, 0, err
+		}
+		req.Body = event
+	}
+	if len(req.Body) > s.maxMessageBytes {
+		return queue.Message{}, 0, fmt.Errorf("%w: `body` is %d bytes, max is %d", errMessageTooLarge, len(req.Body), s.maxMessageBytes)
+	}
+	if req.LegacyDelayMS != nil {
+		if req.DelayMS != nil {
+			return queue.Message{}, 0, errors.New("use `delay_ms` only; `delay` is its deprecated spelling")
+		}
+		req.DelayMS = req.LegacyDelayMS
+		if _, warned := s.legacyDelay.LoadOrStore(qname, true); !warned {
+			log.Printf("queue %s: enqueue used the deprecated `delay` key; send `delay_ms` instead, `delay` will be removed in the next release", qname)
+		}
+	}
+	if req.DelayMS != nil && *req.DelayMS < 0 {
+		return queue.Message{}, 0, errors.New("`delay_ms` must not be negative")
+	}
+	if req.DelayJitterMS < 0 {
+		return queue.Message{}, 0, errors.New("`delay_jitter_ms` must not be negative")
+	}
+	var delayMS int64
+	if req.NotBefore != nil {
+		if req.DelayMS != nil && *req.DelayMS != 0 {
+			return queue.Message{}, 0, errors.New("use either `delay_ms` or `not_before`, not both")
+		}
+		if time.Until(*req.NotBefore) < -maxNotBeforePast {
+			return queue.Message{}, 0, fmt.Errorf("`not_before` is more than %s in the past", maxNotBeforePast)
+		}
+	} else if req.DelayMS != nil {
+		delayMS = *req.DelayMS
+	} else {
+		// a delay queue holds back whatever isn't scheduled explicitly
+		delayMS = qcfg.DefaultDelay.Milliseconds()
+	}
+	if req.DelayJitterMS > 0 {
+		// spread messages sent together so they don't all become visible at once
+		delayMS += rand.Int64N(req.DelayJitterMS + 1)
+	}
+	if req.MaxRetries <= 0 {
+		req.MaxRetries = qcfg.MaxRetries
+	}
+	if req.MaxRetries <= 0 {
+		req.MaxRetries = s.defaultMaxRetries
+	}
+	if req.DLQ == nil {
+		req.DLQ = qcfg.DLQ
+	}
+	// dead-lettering into the source would put an exhausted message straight
+	// back in line, forever
+	if req.DLQ != nil && *req.DLQ == qname {
+		return queue.Message{}, 0, errors.New("`dlq` must not be the queue itself")
+	}
+	if req.GroupID != nil && (*req.GroupID == "" || len(*req.GroupID) > maxGroupIDLen) {
+		return queue.Message{}, 0, fmt.Errorf("`group_id` must be 1 to %d bytes", maxGroupIDLen)
+	}
+	if req.CoalesceKey != nil && (*req.CoalesceKey == "" || len(*req.CoalesceKey) > maxIdempotencyKeyLen) {
+		return queue.Message{}, 0, fmt.Errorf("`coalesce_key` must be 1 to %d bytes", maxIdempotencyKeyLen)
+	}
+	rules := make([]queue.DLQRule, 0, len(req.DLQRules))
+	for i, r := range req.DLQRules {
+		if r.DLQ == "" {
+			return queue.Message{}, 0, fmt.Errorf("`dlq_rules[%d].dlq` is required", i)
+		}
+		if r.DLQ == qname {
+			return queue.Message{}, 0, fmt.Errorf("`dlq_rules[%d].dlq` must not be the queue itself", i)
+		}
+		if r.MinDeliveries < 0 || r.MaxDeliveries < 0 ||
+			(r.MaxDeliveries > 0 && r.MaxDeliveries < r.MinDeliveries) {
+			return queue.Message{}, 0, fmt.Errorf("`dlq_rules[%d]` has an invalid delivery range", i)
+		}
+		rules = append(rules, queue.DLQRule{MinDeliveries: r.MinDeliveries, MaxDeliveries: r.MaxDeliveries, DLQ: r.DLQ})
+	}
+	msg := queue.Message{
+		Queue:      qname,
+		Body:       []byte(req.Body),
+		MaxRetries: req.MaxRetries,
+		DLQ:        req.DLQ,
+		TraceID:    req.TraceID,
+		DLQRules:   rules,
+		GroupID:    req.GroupID,
+		BodyRef:    req.BodyRef,
+		CoalesceKey: req.CoalesceKey,
+	}
+	delay := time.Duration(delayMS) * time.Millisecond
+	wait := delay
+	if req.NotBefore != nil {
+		// jitter, if any, is on top of the given time
+		msg.NotBefore = req.NotBefore.Add(delay)
+		delay = 0
+		wait = time.Until(msg.NotBefore)
+	}
+	// the sweeper would delete it before it ever became visible
+	if s.maxLifetime > 0 && wait >= s.maxLifetime {
+		return queue.Message{}, 0, fmt.Errorf("message would become visible %s from now, after MAX_MESSAGE_LIFETIME of %s", wait.Round(time.Second), s.maxLifetime)
+	}
+	return msg, delay, nil
+}
+
+func (s *Server) toReceivedMessage(m queue.Message) receivedMessage {
+	return receivedMessage{
+		ID:            s.showID(m),
+		Queue:         m.Queue,
+		Body:          json.RawMessage(m.Body),
+		Receipt:       s.showReceipt(m.Receipt(), m.ULID),
+		EnqueuedAt:    timestamp(m.EnqueuedAt),
+		NotBefore:     timestamp(m.NotBefore),
+		LeaseUntil:    optionalTimestamp(m.LeaseUntil),
+		DeliveryCount: m.DeliveryCount,
+		MaxRetries:    m.MaxRetries,
+		DLQ:           m.DLQ,
+		TraceID:       m.TraceID,
+		TraceParent:   traceParentOf(m.TraceID),
+		GroupID:       m.GroupID,
+		GroupSealed:   m.GroupSealed,
+		BodyRef:       m.BodyRef,
+		CoalesceKey:   m.CoalesceKey,
+
+		ApproximateReceiveCount: m.ReceiveCount,
+
+		DeadLetteredAt: optionalTimestamp(m.DLQdAt),
+	}
+}
+
+// observeReceived counts m as delivered and records how long it had waited
+// since it was enqueued.
+func observeReceived(m queue.Message) {
+	metrics.MessagesReceived.WithLabelValues(m.Queue).Inc()
+	metrics.MessageAgeAtReceive.WithLabelValues(m.Queue).Observe(time.Since(m.EnqueuedAt).Seconds())
+}
+
+// maxWorkerIDLen bounds the X-Worker-ID kept from a request.
+const maxWorkerIDLen = 128
+
+// workerID is the X-Worker-ID the request came with, cut to maxWorkerIDLen.
+func workerID(r *http.Request) string {
+	id := r.Header.Get("X-Worker-ID")
+	if len(id) > maxWorkerIDLen {
+		id = id[:maxWorkerIDLen]
+	}
+	return id
+}
+
+// countByWorker attributes the messages out to the worker that received
+// them, when worker metrics are on and the request names its worker.
+func (s *Server) countByWorker(r *http.Request, out []queue.Message) {
+	id := workerID(r)
+	if !s.workerMetrics || id == "" {
+		return
+	}
+	for _, m := range out {
+		metrics.MessagesReceivedByWorker.WithLabelValues(m.Queue, id).Inc()
+	}
+}
+
+// receiveLimit is how many messages a receive asking for n may claim: at
+// most receiveMax, and 1 when it doesn't ask for a positive number.
+func (s *Server) receiveLimit(n int) int {
+	if n <= 0 {
+		return 1
+	}
+	return min(n, s.receiveMax)
+}
+
+// visibilityFor is the lease a receive gets on the queue when it doesn't ask
+// for one: the queue's own default, else the server's.
+func (s *Server) visibilityFor(cfg queue.QueueConfig) time.Duration {
+	if cfg.Visibility > 0 {
+		return cfg.Visibility
+	}
+	return s.visibility
+}
+
+// envelopeMediaType, in Accept, asks for a receiveEnvelope instead of a bare array.
+const envelopeMediaType = "application/vnd.sqslite.envelope+json"
+
+// writeMessages writes a receive response: a bare array by default, or a
+// receiveEnvelope if the server is configured for it or the request asks.
+func (s *Server) writeMessages(w http.ResponseWriter, r *http.Request, msgs []receivedMessage) {
+	setTraceParentHeader(w, msgs)
+	if s.envelope || acceptsEnvelope(r) {
+		writeJSON(w, http.StatusOK, &receiveEnvelope{Messages: msgs, Count: len(msgs)})
+		return
+	}
+	writeJSON(w, http.StatusOK, msgs)
+}
+
+func acceptsEnvelope(r *http.Request) bool {
+	for _, accept := range r.Header.Values("Accept") {
+		for _, part := range strings.Split(accept, ",") {
+			if mt, _, err := mime.ParseMediaType(part); err == nil && mt == envelopeMediaType {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// releaseTimeout bounds handing back leases after the request context is done.
+const releaseTimeout = 5 * time.Second
+
+// abandoned reports whether the caller went away (disconnect or timeout)
+// while out was being claimed. If so it hands the leases back, so they
+// aren't held for a caller that will never see them until the sweeper
+// notices.
+func (s *Server) abandoned(ctx context.Context, out []queue.Message) bool {
+	if ctx.Err() == nil {
+		return false
+	}
+	s.release(ctx, out)
+	return true
+}
+
+// draining reports whether the server has begun shutting down, and if so
+// hands the leases in out back. A response written now may be cut off when
+// the process exits; released, the messages are available to the next
+// server at once instead of after the visibility timeout.
+func (s *Server) draining(ctx context.Context, out []queue.Message) bool {
+	select {
+	case <-s.shutdown:
+		s.release(ctx, out)
+		return true
+	default:
+		return false
+	}
+}
+
+// release returns msgs to their queues uncounted. It runs even though ctx
+// is usually already done.
+func (s *Server) release(ctx context.Context, msgs []queue.Message) {
+	if len(msgs) == 0 {
+		return
+	}
+	rcs := make([]queue.Receipt, len(msgs))
+	for i, m := range msgs {
+		rcs[i] = m.Receipt()
+	}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), releaseTimeout)
+	defer cancel()
+	if _, err := s.store.Release(ctx, rcs); err != nil {
+		log.Printf("release %d undelivered lease(s): %v", len(rcs), err)
+	}
+}
+
+// checkReceipt validates a receipt presented for message id, writing the
+// error response and returning false if it can't be used:
+// missing or malformed → 400, issued for a different message or past its
+// lease → 403.
+func (s *Server) checkReceipt(ctx context.Context, w http.ResponseWriter, raw string, id msgID) (queue.Receipt, bool) {
+	if raw == "" {
+		httpError(w, http.StatusBadRequest, "receipt required")
+		return queue.Receipt{}, false
+	}
+	rcs, errs, _, err := s.parseReceipts(ctx, []string{raw})
+	if err != nil {
+		httpError(w, http.StatusInternalServerError, "%v", err)
+		return queue.Receipt{}, false
+	}
+	rc := rcs[0]
+	if err := errs[0]; errors.Is(err, queue.ErrMessageNotFound) {
+		httpError(w, http.StatusNotFound, "message not found")
+		return queue.Receipt{}, false
+	} else if err != nil {
+		httpError(w, http.StatusBadRequest, "%v", err)
+		return queue.Receipt{}, false
+	}
+	if rc.ID != id.serial {
+		httpError(w, http.StatusForbidden, "receipt does not match message %s", id)
+		return queue.Receipt{}, false
+	}
+	if rc.Expired(time.Now()) {
+		httpError(w, http.StatusForbidden, "%v", queue.ErrReceiptExpired)
+		return queue.Receipt{}, false
+	}
+	return rc, true
+}
+
+func toQueueConfigResponse(cfg queue.QueueConfig) *queueConfigResponse {
+	return &queueConfigResponse{
+		Queue:         cfg.Queue,
+		Partitions:    cfg.Partitions,
+		VisibilityMS:  cfg.Visibility.Milliseconds(),
+		MaxRetries:    cfg.MaxRetries,
+		DLQ:           cfg.DLQ,
+		DedupWindowMS: cfg.DedupWindow.Milliseconds(),
+
+		RequireObjectBody: cfg.RequireObjectBody,
+		ClaimOrder:        string(cfg.ClaimOrder),
+		Role:              string(cfg.Role),
+		SkipAfterAttempts: cfg.SkipAfterAttempts,
+		Paused:            cfg.Paused,
+		MaxInFlight:       cfg.MaxInFlight,
+		RequeueInPlace:    cfg.RequeueInPlace,
+		CloudEvents:       cfg.CloudEvents,
+		DefaultDelayMS:    cfg.DefaultDelay.Milliseconds(),
+		Archive:           cfg.Archive,
+	}
+}
+
+
 func httpError(w http.ResponseWriter, code int, format string, args ...any) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(code)
@@ -0,0 +1,218 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/aridsondez/AWS-SQS-LITE/internal/queue"
+)
+
+// crockford is the ULID alphabet.
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// errSerialOnly refuses the endpoints built on ID order, which a ULID doesn't
+// give away.
+var errSerialOnly = errors.New("consumer checkpoints need MESSAGE_ID_FORMAT=serial")
+
+// errIDLookup marks a failed translation between ULIDs and serial IDs.
+var errIDLookup = errors.New("look up message IDs")
+
+// msgID is a message ID as the API shows it: the serial ID, or the message's
+// ULID when the server runs with MESSAGE_ID_FORMAT=ulid.
+type msgID struct {
+	serial int64
+	ulid   string
+}
+
+func (id msgID) MarshalJSON() ([]byte, error) {
+	if id.ulid != "" {
+		return json.Marshal(id.ulid)
+	}
+	return strconv.AppendInt(nil, id.serial, 10), nil
+}
+
+// MarshalText names map keys, which JSON quotes either way.
+func (id msgID) MarshalText() ([]byte, error) {
+	return []byte(id.String()), nil
+}
+
+func (id msgID) String() string {
+	if id.ulid != "" {
+		return id.ulid
+	}
+	return strconv.FormatInt(id.serial, 10)
+}
+
+// parseULID upper-cases s and reports whether it is a well-formed ULID.
+func parseULID(s string) (string, bool) {
+	s = strings.ToUpper(s)
+	if len(s) != 26 || s[0] > '7' { // 26 characters hold 130 bits; a ULID is 128
+		return "", false
+	}
+	for i := 0; i < len(s); i++ {
+		if strings.IndexByte(crockford, s[i]) < 0 {
+			return "", false
+		}
+	}
+	return s, true
+}
+
+// idNames holds the ULIDs a request has already resolved, by serial ID, so
+// its response can name the same messages without looking them up again.
+type idNames map[int64]string
+
+// showID names message m as the API shows it.
+func (s *Server) showID(m queue.Message) msgID {
+	if !s.ulidIDs {
+		return msgID{serial: m.ID}
+	}
+	return msgID{serial: m.ID, ulid: m.ULID}
+}
+
+// showIDs names bare serial IDs as the API shows them, looking up in one
+// query the ULIDs known doesn't already hold.
+func (s *Server) showIDs(ctx context.Context, ids []int64, known idNames) ([]msgID, error) {
+	out := make([]msgID, len(ids))
+	var missing []int64
+	for i, id := range ids {
+		out[i].serial = id
+		if s.ulidIDs {
+			if out[i].ulid = known[id]; out[i].ulid == "" {
+				missing = append(missing, id)
+			}
+		}
+	}
+	if len(missing) == 0 {
+		return out, nil
+	}
+	found, err := s.store.MessageULIDs(ctx, missing)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errIDLookup, err)
+	}
+	for i := range out {
+		if out[i].ulid == "" {
+			if out[i].ulid = found[out[i].serial]; out[i].ulid == "" {
+				// never shown without its ULID, even if it can't be named
+				return nil, fmt.Errorf("no ULID for message %d", out[i].serial)
+			}
+		}
+	}
+	return out, nil
+}
+
+// showIDOf is showIDs for a single ID.
+func (s *Server) showIDOf(ctx context.Context, id int64) (msgID, error) {
+	ids, err := s.showIDs(ctx, []int64{id}, nil)
+	if err != nil {
+		return msgID{}, err
+	}
+	return ids[0], nil
+}
+
+// pathID reads the {id} path param as the API shows it and resolves it to
+// the serial ID the store works with. An unknown ULID is
+// queue.ErrMessageNotFound.
+func (s *Server) pathID(r *http.Request) (msgID, error) {
+	raw := chi.URLParam(r, "id")
+	if raw == "" {
+		return msgID{}, errors.New("missing message id")
+	}
+	if !s.ulidIDs {
+		id, err := strconv.ParseInt(raw, 10, 64)
+		return msgID{serial: id}, err
+	}
+	ulid, ok := parseULID(raw)
+	if !ok {
+		return msgID{}, fmt.Errorf("%q is not a ULID", raw)
+	}
+	found, err := s.store.MessageIDsByULID(r.Context(), []string{ulid})
+	if err != nil {
+		return msgID{}, fmt.Errorf("%w: %v", errIDLookup, err)
+	}
+	id, ok := found[ulid]
+	if !ok {
+		return msgID{}, queue.ErrMessageNotFound
+	}
+	return msgID{serial: id, ulid: ulid}, nil
+}
+
+// pathIDError answers for a pathID error: 404 for an unknown message, 500
+// when the lookup failed and 400 otherwise.
+func pathIDError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, queue.ErrMessageNotFound):
+		httpError(w, http.StatusNotFound, "message not found")
+	case errors.Is(err, errIDLookup):
+		httpError(w, http.StatusInternalServerError, "%v", err)
+	default:
+		httpError(w, http.StatusBadRequest, "invalid id: %v", err)
+	}
+}
+
+// showReceipt writes rc as the API shows it. With ULIDs the receipt leads
+// with the message's ULID instead of its serial ID.
+func (s *Server) showReceipt(rc queue.Receipt, ulid string) string {
+	raw := rc.String()
+	if !s.ulidIDs {
+		return raw
+	}
+	_, rest, _ := strings.Cut(raw, ".")
+	return ulid + "." + rest
+}
+
+// parseReceipts decodes receipts as showReceipt writes them, resolving any
+// ULIDs in one query. errs[i] says why raws[i] is no good, with rcs[i] then
+// zero; err is set only if the lookup itself failed. names holds the ULIDs
+// resolved.
+func (s *Server) parseReceipts(ctx context.Context, raws []string) (rcs []queue.Receipt, errs []error, names idNames, err error) {
+	rcs = make([]queue.Receipt, len(raws))
+	errs = make([]error, len(raws))
+	if !s.ulidIDs {
+		for i, raw := range raws {
+			rcs[i], errs[i] = queue.ParseReceipt(raw)
+		}
+		return rcs, errs, nil, nil
+	}
+
+	ulids := make([]string, len(raws))
+	var lookup []string
+	for i, raw := range raws {
+		head, _, _ := strings.Cut(raw, ".")
+		ulid, ok := parseULID(head)
+		if !ok {
+			errs[i] = queue.ErrMalformedReceipt
+			continue
+		}
+		ulids[i] = ulid
+		lookup = append(lookup, ulid)
+	}
+	found := map[string]int64{}
+	if len(lookup) > 0 {
+		if found, err = s.store.MessageIDsByULID(ctx, lookup); err != nil {
+			return nil, nil, nil, fmt.Errorf("%w: %v", errIDLookup, err)
+		}
+	}
+	names = make(idNames, len(found))
+	for i, raw := range raws {
+		if errs[i] != nil {
+			continue
+		}
+		id, ok := found[ulids[i]]
+		if !ok {
+			errs[i] = queue.ErrMessageNotFound
+			continue
+		}
+		_, rest, _ := strings.Cut(raw, ".")
+		if rcs[i], errs[i] = queue.ParseReceipt(strconv.FormatInt(id, 10) + "." + rest); errs[i] == nil {
+			names[id] = ulids[i]
+		}
+	}
+	return rcs, errs, names, nil
+}
@@ -0,0 +1,83 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// inFlightCacheTTL is how long the server-wide lease count is reused before
+// it is counted again.
+const inFlightCacheTTL = time.Second
+
+// errTotalInFlight is returned to receives while MAX_TOTAL_IN_FLIGHT
+// messages are leased.
+var errTotalInFlight = errors.New("server-wide in-flight limit reached (MAX_TOTAL_IN_FLIGHT)")
+
+// inFlightCount remembers how many messages are leased across all queues
+// for inFlightCacheTTL, so MAX_TOTAL_IN_FLIGHT costs one count per second
+// rather than one per receive. Claims in between add what they leased; acks
+// and expiries in between aren't seen until the next count, which errs on
+// the side of throttling.
+type inFlightCount struct {
+	mu        sync.Mutex
+	n         int64
+	countedAt time.Time
+}
+
+// inFlightLeft is how many more messages may be leased under
+// MAX_TOTAL_IN_FLIGHT, or -1 when there is no cap.
+func (s *Server) inFlightLeft(ctx context.Context) (int, error) {
+	if s.maxTotalInFlight <= 0 {
+		return -1, nil
+	}
+	c := &s.inFlight
+	c.mu.Lock()
+	n, fresh := c.n, time.Since(c.countedAt) < inFlightCacheTTL
+	c.mu.Unlock()
+	if !fresh {
+		counted, err := s.store.CountInFlight(ctx)
+		if err != nil {
+			return 0, err
+		}
+		c.mu.Lock()
+		c.n, c.countedAt = counted, time.Now()
+		c.mu.Unlock()
+		n = counted
+	}
+	return int(max(int64(s.maxTotalInFlight)-n, 0)), nil
+}
+
+// noteLeased adds n messages just claimed to the cached count.
+func (s *Server) noteLeased(n int) {
+	if s.maxTotalInFlight <= 0 || n == 0 {
+		return
+	}
+	s.inFlight.mu.Lock()
+	s.inFlight.n += int64(n)
+	s.inFlight.mu.Unlock()
+}
+
+// limitInFlight trims a receive's max to what MAX_TOTAL_IN_FLIGHT leaves,
+// setting X-Receive-Max when it does. With nothing left it answers 429 and
+// returns false.
+func (s *Server) limitInFlight(ctx context.Context, w http.ResponseWriter, n int) (int, bool) {
+	left, err := s.inFlightLeft(ctx)
+	if err != nil {
+		httpError(w, http.StatusInternalServerError, "count in flight failed: %v", err)
+		return 0, false
+	}
+	if left < 0 || left >= n {
+		return n, true
+	}
+	if left == 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(inFlightCacheTTL.Seconds())))
+		httpError(w, http.StatusTooManyRequests, "%v", errTotalInFlight)
+		return 0, false
+	}
+	w.Header().Set("X-Receive-Max", strconv.Itoa(left))
+	return left, true
+}
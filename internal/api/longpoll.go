@@ -0,0 +1,99 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aridsondez/AWS-SQS-LITE/internal/events"
+)
+
+// maxReceiveWait bounds how long a receive may hold waiting for messages.
+const maxReceiveWait = 20 * time.Second
+
+// longPollRecheck is how often a waiting receive re-counts the queue.
+// Enqueues on this server wake it immediately; messages enqueued through
+// another server, delays running out and requeued leases are only seen here.
+const longPollRecheck = 500 * time.Millisecond
+
+// preferWait is the long poll wait asked for with an RFC 7240 `Prefer:
+// wait=N` header, N in seconds, for clients that can't easily send wait_ms
+// in a body. It is clamped to maxReceiveWait; ok is false if there is no
+// wait preference.
+func preferWait(r *http.Request) (wait time.Duration, ok bool, err error) {
+	for _, h := range r.Header.Values("Prefer") {
+		for _, pref := range strings.Split(h, ",") {
+			pref, _, _ = strings.Cut(pref, ";") // parameters don't apply to wait
+			name, value, _ := strings.Cut(pref, "=")
+			if !strings.EqualFold(strings.TrimSpace(name), "wait") {
+				continue
+			}
+			n, err := strconv.ParseInt(strings.Trim(strings.TrimSpace(value), `"`), 10, 64)
+			if err != nil || n < 0 {
+				return 0, false, fmt.Errorf("`Prefer: wait` must be a whole number of seconds, got %q", strings.TrimSpace(value))
+			}
+			return min(time.Duration(min(n, 1<<31))*time.Second, maxReceiveWait), true, nil
+		}
+	}
+	return 0, false, nil
+}
+
+// waitBefore shortens wait so it ends while the request still has the
+// server's usual timeout left to claim and respond in, rather than running
+// into the deadline and failing the receive.
+func (s *Server) waitBefore(ctx context.Context, wait time.Duration) time.Duration {
+	if dl, ok := ctx.Deadline(); ok {
+		wait = min(wait, time.Until(dl)-s.timeout)
+	}
+	return max(wait, 0)
+}
+
+// waitForMessages blocks until at least min messages are available in the
+// queue, wait elapses, or the server shuts down. It only counts; whatever
+// is available when it returns is claimed by the caller, so a competing
+// consumer may still leave fewer than min. While the queue is paused it
+// keeps waiting, so pollers don't spin on empty claims.
+func (s *Server) waitForMessages(ctx context.Context, qname string, min int, wait time.Duration) error {
+	// Subscribe before the first count so an enqueue in between isn't missed.
+	ch, unsubscribe := events.Subscribe(qname)
+	defer unsubscribe()
+
+	deadline := time.NewTimer(wait)
+	defer deadline.Stop()
+	recheck := time.NewTicker(longPollRecheck)
+	defer recheck.Stop()
+
+	for {
+		st, err := s.store.Stats(ctx, qname)
+		if err != nil {
+			return err
+		}
+		if st.Available >= int64(min) {
+			cfg, err := s.store.GetQueueConfig(ctx, qname)
+			if err != nil {
+				return err
+			}
+			if !cfg.Paused {
+				return nil
+			}
+		}
+
+		for woke := false; !woke; {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-s.shutdown:
+				return nil
+			case <-deadline.C:
+				return nil
+			case <-recheck.C:
+				woke = true
+			case ev := <-ch:
+				woke = ev.Type == events.Enqueued
+			}
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type pauseResponse struct {
+	Queue  string `json:"queue"`
+	Paused bool   `json:"paused"`
+}
+
+// handlePause stops delivery from a queue without purging it: receives
+// return nothing while enqueues keep landing.
+func (s *Server) handlePause(w http.ResponseWriter, r *http.Request) {
+	s.setPaused(w, r, true)
+}
+
+// handleResume lets a paused queue deliver again.
+func (s *Server) handleResume(w http.ResponseWriter, r *http.Request) {
+	s.setPaused(w, r, false)
+}
+
+func (s *Server) setPaused(w http.ResponseWriter, r *http.Request, paused bool) {
+	qname := chi.URLParam(r, "queue")
+	if qname == "" {
+		httpError(w, http.StatusBadRequest, "missing queue path param")
+		return
+	}
+	if err := s.store.SetPaused(r.Context(), qname, paused); err != nil {
+		httpError(w, http.StatusInternalServerError, "set paused failed: %v", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, &pauseResponse{Queue: qname, Paused: paused})
+}
@@ -0,0 +1,301 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/aridsondez/AWS-SQS-LITE/internal/config"
+	"github.com/aridsondez/AWS-SQS-LITE/internal/metrics"
+	"github.com/aridsondez/AWS-SQS-LITE/internal/queue"
+	"github.com/aridsondez/AWS-SQS-LITE/internal/queue/store"
+)
+
+const (
+	// maxWebhookURLLen bounds a push subscription's `url`.
+	maxWebhookURLLen = 2048
+
+	// maxWebhookResponse is how much of a webhook's response is read, to
+	// reuse the connection and to quote in a nack reason.
+	maxWebhookResponse = 64 << 10
+)
+
+type pushSubscriptionRequest struct {
+	URL string `json:"url"`
+}
+
+type pushSubscriptionResponse struct {
+	Queue     string    `json:"queue"`
+	URL       string    `json:"url"`
+	CreatedAt timestamp `json:"created_at"`
+}
+
+type deletePushSubscriptionResponse struct {
+	Queue   string `json:"queue"`
+	Deleted bool   `json:"deleted"`
+}
+
+// handlePutPushSubscription registers the queue's webhook, replacing any
+// earlier one. The server starts POSTing the queue's messages to it on the
+// next delivery round.
+func (s *Server) handlePutPushSubscription(w http.ResponseWriter, r *http.Request) {
+	qname := chi.URLParam(r, "queue")
+	var req pushSubscriptionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httpError(w, http.StatusBadRequest, "invalid json: %v", err)
+		return
+	}
+	if len(req.URL) > maxWebhookURLLen {
+		httpError(w, http.StatusBadRequest, "`url` is longer than %d bytes", maxWebhookURLLen)
+		return
+	}
+	if u, err := url.Parse(req.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		httpError(w, http.StatusBadRequest, "`url` must be an absolute http or https URL")
+		return
+	}
+
+	sub, err := s.store.PutPushSubscription(r.Context(), qname, req.URL)
+	if err != nil {
+		httpError(w, http.StatusInternalServerError, "put push subscription failed: %v", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, toPushSubscriptionResponse(sub))
+}
+
+func (s *Server) handleGetPushSubscription(w http.ResponseWriter, r *http.Request) {
+	qname := chi.URLParam(r, "queue")
+	subs, err := s.store.PushSubscriptions(r.Context())
+	if err != nil {
+		httpError(w, http.StatusInternalServerError, "get push subscriptions failed: %v", err)
+		return
+	}
+	for _, sub := range subs {
+		if sub.Queue == qname {
+			writeJSON(w, http.StatusOK, toPushSubscriptionResponse(sub))
+			return
+		}
+	}
+	httpError(w, http.StatusNotFound, "queue %q has no push subscription", qname)
+}
+
+// handleDeletePushSubscription stops pushing the queue's messages. Messages
+// already POSTed are still acked or nacked by their delivery.
+func (s *Server) handleDeletePushSubscription(w http.ResponseWriter, r *http.Request) {
+	qname := chi.URLParam(r, "queue")
+	deleted, err := s.store.DeletePushSubscription(r.Context(), qname)
+	if err != nil {
+		httpError(w, http.StatusInternalServerError, "delete push subscription failed: %v", err)
+		return
+	}
+	if !deleted {
+		httpError(w, http.StatusNotFound, "queue %q has no push subscription", qname)
+		return
+	}
+	writeJSON(w, http.StatusOK, &deletePushSubscriptionResponse{Queue: qname, Deleted: true})
+}
+
+func toPushSubscriptionResponse(sub queue.PushSubscription) *pushSubscriptionResponse {
+	return &pushSubscriptionResponse{Queue: sub.Queue, URL: sub.URL, CreatedAt: timestamp(sub.CreatedAt)}
+}
+
+// PushDeliverer consumes the queues that have a push subscription on their
+// webhooks' behalf. Every interval it claims a batch from each one and POSTs
+// every message, as a receive would return it, to the queue's URL. A 2xx
+// acks the message; anything else nacks it with the reason, so it is
+// retried, and in the end dead-lettered, under the queue's max_retries like
+// a message a consumer failed.
+type PushDeliverer struct {
+	srv      *Server
+	client   *http.Client
+	interval time.Duration
+	timeout  time.Duration
+	stopCh   chan struct{}
+}
+
+// NewPushDeliverer returns a deliverer that runs every cfg.PushInterval.
+// Transformers and body resolvers given in opts apply as they do to receives.
+func NewPushDeliverer(s store.Store, cfg *config.Config, opts ...Option) *PushDeliverer {
+	return &PushDeliverer{
+		srv:      newServer("", s, cfg, opts...),
+		client:   &http.Client{}, // each POST is bounded by its context instead
+		interval: cfg.PushInterval,
+		timeout:  cfg.PushTimeout,
+		stopCh:   make(chan struct{}),
+	}
+}
+
+func (d *PushDeliverer) Start(ctx context.Context) {
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	log.Printf("Push delivery started, interval: %s", d.interval)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-d.stopCh:
+			return
+		case <-ticker.C:
+			d.deliverAll(ctx)
+		}
+	}
+}
+
+func (d *PushDeliverer) Stop() {
+	close(d.stopCh)
+}
+
+// deliverAll runs one round over every push subscription, queues in
+// parallel, and returns once all of them are done.
+func (d *PushDeliverer) deliverAll(ctx context.Context) {
+	subs, err := d.srv.store.PushSubscriptions(ctx)
+	if err != nil {
+		log.Printf("list push subscriptions: %v", err)
+		return
+	}
+	var wg sync.WaitGroup
+	for _, sub := range subs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			d.deliverQueue(ctx, sub)
+		}()
+	}
+	wg.Wait()
+}
+
+// deliverQueue claims a batch from sub's queue and POSTs it. Messages go out
+// in parallel, except that members of a group are sent one at a time in
+// order; once one of them fails, the rest of the group is handed back
+// rather than delivered out of order.
+func (d *PushDeliverer) deliverQueue(ctx context.Context, sub queue.PushSubscription) {
+	s := d.srv
+	qcfg, err := s.store.GetQueueConfig(ctx, sub.Queue)
+	if err != nil {
+		log.Printf("push %s: get config: %v", sub.Queue, err)
+		return
+	}
+	out, err := s.store.Claim(ctx, queue.ClaimOptions{
+		Queue:      sub.Queue,
+		Limit:      s.receiveMax,
+		Visibility: s.visibilityFor(qcfg),
+	})
+	if err != nil {
+		log.Printf("push %s: claim: %v", sub.Queue, err)
+		return
+	}
+	out = s.resolveBodies(ctx, out)
+	if err := s.transformOut(ctx, out); err != nil {
+		log.Printf("push %s: %v", sub.Queue, err)
+		s.release(ctx, out)
+		return
+	}
+
+	var (
+		runs   [][]queue.Message
+		groups = make(map[string]int) // group ID -> index in runs
+	)
+	for _, m := range out {
+		observeReceived(m)
+		if m.GroupID == nil {
+			runs = append(runs, []queue.Message{m})
+			continue
+		}
+		i, ok := groups[*m.GroupID]
+		if !ok {
+			i = len(runs)
+			groups[*m.GroupID] = i
+			runs = append(runs, nil)
+		}
+		runs[i] = append(runs[i], m)
+	}
+
+	var wg sync.WaitGroup
+	for _, run := range runs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i, m := range run {
+				if !d.push(ctx, sub.URL, m) {
+					s.release(ctx, run[i+1:])
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// push POSTs m to url and acks or nacks it by the outcome, reporting
+// whether the webhook took it. The POST must finish within the deliverer's
+// timeout and before m's lease runs out.
+func (d *PushDeliverer) push(ctx context.Context, url string, m queue.Message) bool {
+	rm := d.srv.toReceivedMessage(m)
+	rm.Receipt = "" // the deliverer acks; the webhook has no use for it
+	body, err := json.Marshal(rm)
+	if err != nil {
+		return d.failed(ctx, m, fmt.Sprintf("encode message: %v", err))
+	}
+
+	deadline := time.Now().Add(d.timeout)
+	if m.LeaseUntil != nil && m.LeaseUntil.Before(deadline) {
+		deadline = *m.LeaseUntil
+	}
+	pctx, cancel := context.WithDeadline(ctx, deadline)
+	defer cancel()
+	req, err := http.NewRequestWithContext(pctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return d.failed(ctx, m, fmt.Sprintf("webhook request: %v", err))
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Message-ID", strconv.FormatInt(m.ID, 10))
+	if rm.TraceParent != "" {
+		req.Header.Set(traceParentHeader, rm.TraceParent)
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return d.failed(ctx, m, fmt.Sprintf("webhook failed: %v", err))
+	}
+	reply, _ := io.ReadAll(io.LimitReader(resp.Body, maxWebhookResponse))
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		reason := "webhook returned " + resp.Status
+		if len(reply) > 0 {
+			reason += ": " + string(reply)
+		}
+		return d.failed(ctx, m, reason)
+	}
+
+	metrics.PushDeliveries.WithLabelValues(m.Queue, "ok").Inc()
+	ok, err := d.srv.store.Ack(ctx, m.Receipt())
+	switch {
+	case err != nil:
+		log.Printf("push %s: ack message %d: %v", m.Queue, m.ID, err)
+	case !ok:
+		log.Printf("push %s: message %d delivered after its lease ran out", m.Queue, m.ID)
+	default:
+		metrics.MessagesAcked.Inc()
+	}
+	return true
+}
+
+// failed nacks m with reason and returns false.
+func (d *PushDeliverer) failed(ctx context.Context, m queue.Message, reason string) bool {
+	metrics.PushDeliveries.WithLabelValues(m.Queue, "failed").Inc()
+	if _, err := d.srv.store.Nack(ctx, m.Receipt(), reason); err != nil {
+		log.Printf("push %s: nack message %d after %s: %v", m.Queue, m.ID, reason, err)
+	}
+	return false
+}
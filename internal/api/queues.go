@@ -0,0 +1,122 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/aridsondez/AWS-SQS-LITE/internal/queue"
+)
+
+// errQueueNotCreated is returned in strict mode for a queue that has not
+// been created with PUT /v1/queues/{queue}.
+var errQueueNotCreated = errors.New("queue does not exist; create it first")
+
+type createQueueResponse struct {
+	Queue   string `json:"queue"`
+	Created bool   `json:"created"` // false if it already existed
+}
+
+type deleteQueueResponse struct {
+	Queue   string `json:"queue"`
+	Deleted bool   `json:"deleted"`
+}
+
+type releaseLeasesResponse struct {
+	Queue    string `json:"queue"`
+	Released int64  `json:"released"`
+}
+
+// admitQueue lets an enqueue to qname go ahead. Outside strict mode a new
+// name is registered as it is first used, which fails with
+// queue.ErrTooManyQueues at the MAX_QUEUES limit; in strict mode only
+// created queues are admitted. Names registered once are remembered, so
+// later enqueues to them skip the registry; strict mode, which must notice
+// a deleted queue, always asks it.
+func (s *Server) admitQueue(ctx context.Context, qname string) error {
+	if s.strictQueues {
+		ok, err := s.store.QueueRegistered(ctx, qname)
+		if err == nil && !ok {
+			err = fmt.Errorf("queue %q: %w", qname, errQueueNotCreated)
+		}
+		return err
+	}
+	if _, ok := s.registered.Load(qname); ok {
+		return nil
+	}
+	_, err := s.store.RegisterQueue(ctx, qname, s.maxQueues)
+	if errors.Is(err, queue.ErrTooManyQueues) {
+		return fmt.Errorf("queue %q: %w (MAX_QUEUES is %d)", qname, err, s.maxQueues)
+	}
+	if err == nil {
+		s.registered.Store(qname, struct{}{})
+	}
+	return err
+}
+
+// admitStatus is the HTTP status for an admitQueue error.
+func admitStatus(err error) int {
+	switch {
+	case errors.Is(err, errQueueNotCreated):
+		return http.StatusNotFound
+	case errors.Is(err, queue.ErrTooManyQueues):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// handleCreateQueue registers a queue so it can be enqueued to in strict
+// mode. Creating a queue that exists is a no-op.
+func (s *Server) handleCreateQueue(w http.ResponseWriter, r *http.Request) {
+	qname := chi.URLParam(r, "queue")
+	created, err := s.store.RegisterQueue(r.Context(), qname, s.maxQueues)
+	if errors.Is(err, queue.ErrTooManyQueues) {
+		httpError(w, http.StatusBadRequest, "%v (MAX_QUEUES is %d)", err, s.maxQueues)
+		return
+	}
+	if err != nil {
+		httpError(w, http.StatusInternalServerError, "create queue failed: %v", err)
+		return
+	}
+	code := http.StatusOK
+	if created {
+		code = http.StatusCreated
+	}
+	writeJSON(w, code, &createQueueResponse{Queue: qname, Created: created})
+}
+
+// handleDeleteQueue drops a queue with all its messages, freeing its place
+// under MAX_QUEUES.
+func (s *Server) handleDeleteQueue(w http.ResponseWriter, r *http.Request) {
+	qname := chi.URLParam(r, "queue")
+	deleted, err := s.store.DeleteQueue(r.Context(), qname)
+	s.registered.Delete(qname)
+	if err != nil {
+		httpError(w, http.StatusInternalServerError, "delete queue failed: %v", err)
+		return
+	}
+	if !deleted {
+		httpError(w, http.StatusNotFound, "queue %q not found", qname)
+		return
+	}
+	writeJSON(w, http.StatusOK, &deleteQueueResponse{Queue: qname, Deleted: true})
+}
+
+// handleReleaseLeases ends every lease in the queue, so messages held by
+// workers that crashed together can be claimed now rather than one
+// visibility timeout later.
+func (s *Server) handleReleaseLeases(w http.ResponseWriter, r *http.Request) {
+	qname := chi.URLParam(r, "queue")
+	n, err := s.store.ReleaseLeases(r.Context(), qname)
+	if err != nil {
+		httpError(w, http.StatusInternalServerError, "release leases failed: %v", err)
+		return
+	}
+	log.Printf("queue %s: released %d leases", qname, n)
+	writeJSON(w, http.StatusOK, &releaseLeasesResponse{Queue: qname, Released: n})
+}
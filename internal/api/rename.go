@@ -0,0 +1,53 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/aridsondez/AWS-SQS-LITE/internal/queue"
+)
+
+type renameQueueRequest struct {
+	NewName string `json:"new_name"`
+	Merge   bool   `json:"merge,omitempty"` // allow a new name that already has messages
+}
+
+type renameQueueResponse struct {
+	Queue   string `json:"queue"`
+	NewName string `json:"new_name"`
+	Moved   int64  `json:"moved"`
+}
+
+// handleRenameQueue moves a queue and its messages to a new name, e.g. to
+// fix a typo, without losing anything in flight.
+func (s *Server) handleRenameQueue(w http.ResponseWriter, r *http.Request) {
+	qname := chi.URLParam(r, "queue")
+	if qname == "" {
+		httpError(w, http.StatusBadRequest, "missing queue path param")
+		return
+	}
+	var req renameQueueRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httpError(w, http.StatusBadRequest, "invalid json: %v", err)
+		return
+	}
+	if req.NewName == "" || req.NewName == qname {
+		httpError(w, http.StatusBadRequest, "`new_name` must be set and differ from the queue's name")
+		return
+	}
+
+	moved, err := s.store.RenameQueue(r.Context(), qname, req.NewName, req.Merge)
+	s.registered.Delete(qname)
+	if errors.Is(err, queue.ErrQueueNotEmpty) {
+		httpError(w, http.StatusConflict, "%v; pass \"merge\": true to merge into it", err)
+		return
+	}
+	if err != nil {
+		httpError(w, http.StatusInternalServerError, "rename failed: %v", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, &renameQueueResponse{Queue: qname, NewName: req.NewName, Moved: moved})
+}
@@ -0,0 +1,27 @@
+package api
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// timestampLayout is RFC 3339 with a fixed nine fractional digits, so every
+// timestamp in a response has the same width and sorts as a string.
+const timestampLayout = "2006-01-02T15:04:05.000000000Z07:00"
+
+// timestamp is a time.Time that always marshals in UTC with timestampLayout,
+// whatever zone the database session returned it in.
+type timestamp time.Time
+
+func (t timestamp) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Time(t).UTC().Format(timestampLayout))
+}
+
+// optionalTimestamp converts a nullable database time.
+func optionalTimestamp(t *time.Time) *timestamp {
+	if t == nil {
+		return nil
+	}
+	ts := timestamp(*t)
+	return &ts
+}
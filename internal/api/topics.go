@@ -0,0 +1,171 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/aridsondez/AWS-SQS-LITE/internal/metrics"
+	"github.com/aridsondez/AWS-SQS-LITE/internal/queue"
+)
+
+// maxSubscriptions bounds how many queues one publish may fan out to.
+const maxSubscriptions = 10
+
+type subscriptionsRequest struct {
+	Queues []string `json:"queues"`
+}
+
+type subscriptionsResponse struct {
+	Topic  string   `json:"topic"`
+	Queues []string `json:"queues"`
+}
+
+type publishResponse struct {
+	Messages []publishedMessage `json:"messages"`
+}
+
+type publishedMessage struct {
+	Queue string `json:"queue"`
+	ID    msgID  `json:"id"`
+}
+
+// handlePublish enqueues a copy of the message into every queue subscribed
+// to the topic, in one transaction: either every subscriber gets it or none
+// does. Each copy takes its queue's defaults and validation.
+func (s *Server) handlePublish(w http.ResponseWriter, r *http.Request) {
+	topic := chi.URLParam(r, "topic")
+	var req enqueueRequest
+	r.Body = http.MaxBytesReader(w, r.Body, int64(s.maxMessageBytes)+maxEnvelopeBytes)
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			httpError(w, http.StatusRequestEntityTooLarge, "request exceeds %d bytes", tooLarge.Limit)
+			return
+		}
+		httpError(w, http.StatusBadRequest, "invalid json: %v", err)
+		return
+	}
+	if req.DedupID != nil {
+		httpError(w, http.StatusBadRequest, "`dedup_id` is not supported when publishing to a topic")
+		return
+	}
+
+	ctx := r.Context()
+	queues, err := s.store.GetSubscriptions(ctx, topic)
+	if err != nil {
+		httpError(w, http.StatusInternalServerError, "get subscriptions failed: %v", err)
+		return
+	}
+	if len(queues) == 0 {
+		httpError(w, http.StatusNotFound, "topic %q has no subscriptions", topic)
+		return
+	}
+
+	entries := make([]queue.EnqueueEntry, 0, len(queues))
+	for _, qname := range queues {
+		qcfg, err := s.store.GetQueueConfig(ctx, qname)
+		if err != nil {
+			httpError(w, http.StatusInternalServerError, "get config failed: %v", err)
+			return
+		}
+		msg, delay, err := s.newMessage(qname, qcfg, req)
+		if err == nil {
+			err = s.transformIn(ctx, &msg)
+		}
+		if err != nil {
+			code := http.StatusBadRequest
+			if errors.Is(err, errMessageTooLarge) {
+				code = http.StatusRequestEntityTooLarge
+			}
+			httpError(w, code, "queue %s: %v", qname, err)
+			return
+		}
+		entries = append(entries, queue.EnqueueEntry{Message: msg, Delay: delay})
+	}
+	for _, qname := range queues {
+		if err := s.admitQueue(ctx, qname); err != nil {
+			httpError(w, admitStatus(err), "%v", err)
+			return
+		}
+	}
+
+	start := time.Now()
+	ids, err := s.store.EnqueueBatch(ctx, entries)
+	elapsed := time.Since(start).Seconds()
+	var refused *queue.EntryError
+	if errors.As(err, &refused) {
+		// publishing is all or nothing, so one refused copy fails them all
+		code := http.StatusBadRequest
+		if errors.Is(refused, queue.ErrGroupSealed) {
+			code = http.StatusConflict
+		}
+		httpError(w, code, "queue %s: %v", queues[refused.Index], refused.Err)
+		return
+	}
+	if err != nil {
+		httpError(w, http.StatusInternalServerError, "publish failed: %v", err)
+		return
+	}
+	for _, q := range queues {
+		metrics.EnqueueDuration.WithLabelValues(q).Observe(elapsed)
+		metrics.MessagesEnqueued.WithLabelValues(q).Inc()
+	}
+	shown, err := s.showIDs(ctx, ids, nil)
+	if err != nil {
+		httpError(w, http.StatusInternalServerError, "published, but %v", err)
+		return
+	}
+	resp := &publishResponse{Messages: make([]publishedMessage, len(ids))}
+	for i, id := range shown {
+		resp.Messages[i] = publishedMessage{Queue: queues[i], ID: id}
+	}
+	writeJSON(w, http.StatusCreated, resp)
+}
+
+func (s *Server) handleGetSubscriptions(w http.ResponseWriter, r *http.Request) {
+	topic := chi.URLParam(r, "topic")
+	queues, err := s.store.GetSubscriptions(r.Context(), topic)
+	if err != nil {
+		httpError(w, http.StatusInternalServerError, "get subscriptions failed: %v", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, &subscriptionsResponse{Topic: topic, Queues: queues})
+}
+
+// handlePutSubscriptions replaces the topic's subscriber list. An empty list
+// removes every subscription.
+func (s *Server) handlePutSubscriptions(w http.ResponseWriter, r *http.Request) {
+	topic := chi.URLParam(r, "topic")
+	var req subscriptionsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httpError(w, http.StatusBadRequest, "invalid json: %v", err)
+		return
+	}
+	if len(req.Queues) > maxSubscriptions {
+		httpError(w, http.StatusBadRequest, "`queues` may list at most %d queues", maxSubscriptions)
+		return
+	}
+	seen := make(map[string]bool, len(req.Queues))
+	for _, q := range req.Queues {
+		if q == "" || seen[q] {
+			httpError(w, http.StatusBadRequest, "queue names must be non-empty and unique")
+			return
+		}
+		seen[q] = true
+	}
+
+	if err := s.store.PutSubscriptions(r.Context(), topic, req.Queues); err != nil {
+		httpError(w, http.StatusInternalServerError, "put subscriptions failed: %v", err)
+		return
+	}
+	queues, err := s.store.GetSubscriptions(r.Context(), topic)
+	if err != nil {
+		httpError(w, http.StatusInternalServerError, "get subscriptions failed: %v", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, &subscriptionsResponse{Topic: topic, Queues: queues})
+}
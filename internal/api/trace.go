@@ -0,0 +1,75 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+)
+
+// traceParentHeader is the W3C Trace Context header. An enqueue's header is
+// kept in the message's trace_id and handed back on receive, so the consumer
+// continues the producer's trace.
+const traceParentHeader = "traceparent"
+
+// validTraceParent reports whether s is a traceparent the W3C Trace Context
+// spec accepts: version-traceid-parentid-flags in lowercase hex, with
+// nonzero IDs. Versions after 00 may append fields; ff is forbidden.
+func validTraceParent(s string) bool {
+	if len(s) < 55 || (len(s) > 55 && (s[:2] == "00" || s[55] != '-')) {
+		return false
+	}
+	if s[2] != '-' || s[35] != '-' || s[52] != '-' {
+		return false
+	}
+	version, traceID, parentID, flags := s[:2], s[3:35], s[36:52], s[53:55]
+	for _, f := range []string{version, traceID, parentID, flags} {
+		if !isLowerHex(f) {
+			return false
+		}
+	}
+	return version != "ff" &&
+		strings.Trim(traceID, "0") != "" &&
+		strings.Trim(parentID, "0") != ""
+}
+
+func isLowerHex(s string) bool {
+	for _, c := range []byte(s) {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// traceParentIn is the request's traceparent header, or "" if it has none or
+// it is malformed; the spec has a malformed one ignored, not rejected.
+func traceParentIn(r *http.Request) string {
+	if tp := strings.TrimSpace(r.Header.Get(traceParentHeader)); validTraceParent(tp) {
+		return tp
+	}
+	return ""
+}
+
+// traceParentOf is traceID if it is a traceparent, else "": freeform
+// trace IDs are still stored and returned as trace_id, but aren't propagated.
+func traceParentOf(traceID *string) string {
+	if traceID != nil && validTraceParent(*traceID) {
+		return *traceID
+	}
+	return ""
+}
+
+// setTraceParentHeader sets traceparent on a receive response when every
+// message in it carries the same one, which is always the case for a
+// single-message receive. Otherwise each message's traceparent field is the
+// only place to find it.
+func setTraceParentHeader(w http.ResponseWriter, msgs []receivedMessage) {
+	if len(msgs) == 0 || msgs[0].TraceParent == "" {
+		return
+	}
+	for _, m := range msgs[1:] {
+		if m.TraceParent != msgs[0].TraceParent {
+			return
+		}
+	}
+	w.Header().Set(traceParentHeader, msgs[0].TraceParent)
+}
@@ -0,0 +1,77 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/aridsondez/AWS-SQS-LITE/internal/queue"
+)
+
+// Transformer rewrites message bodies on their way into and out of the
+// store, for server-side redaction or enrichment. Both hooks receive the
+// queue name and the body as JSON and must return valid JSON.
+type Transformer interface {
+	// BeforeEnqueue runs after a message is validated and before it is
+	// stored. An error rejects the message.
+	BeforeEnqueue(ctx context.Context, qname string, body []byte) ([]byte, error)
+
+	// AfterReceive runs after a message is claimed and before it is returned.
+	// An error fails the receive; the claimed messages stay leased until
+	// their visibility runs out and are then redelivered.
+	AfterReceive(ctx context.Context, qname string, body []byte) ([]byte, error)
+}
+
+// NopTransformer returns bodies unchanged. It is the default.
+type NopTransformer struct{}
+
+func (NopTransformer) BeforeEnqueue(_ context.Context, _ string, body []byte) ([]byte, error) {
+	return body, nil
+}
+
+func (NopTransformer) AfterReceive(_ context.Context, _ string, body []byte) ([]byte, error) {
+	return body, nil
+}
+
+// Option configures a Server at construction.
+type Option func(*Server)
+
+// WithTransformer runs t on every message enqueued or received through the
+// server.
+func WithTransformer(t Transformer) Option {
+	return func(s *Server) { s.transformer = t }
+}
+
+var errTransformedBody = errors.New("transformer returned a body that is not JSON")
+
+// transformIn runs the BeforeEnqueue hook on msg's body.
+func (s *Server) transformIn(ctx context.Context, msg *queue.Message) error {
+	if msg.BodyRef != nil {
+		return nil // nothing to transform until the body is fetched
+	}
+	body, err := s.transformer.BeforeEnqueue(ctx, msg.Queue, msg.Body)
+	if err != nil {
+		return fmt.Errorf("transform failed: %w", err)
+	}
+	if err := queue.ValidateBody(body); err != nil {
+		return fmt.Errorf("transformer returned an unstorable body: %w", err)
+	}
+	msg.Body = body
+	return nil
+}
+
+// transformOut runs the AfterReceive hook on each message's body in place.
+func (s *Server) transformOut(ctx context.Context, msgs []queue.Message) error {
+	for i := range msgs {
+		body, err := s.transformer.AfterReceive(ctx, msgs[i].Queue, msgs[i].Body)
+		if err != nil {
+			return fmt.Errorf("transform failed: %w", err)
+		}
+		if !json.Valid(body) {
+			return errTransformedBody
+		}
+		msgs[i].Body = body
+	}
+	return nil
+}
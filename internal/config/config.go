@@ -3,23 +3,65 @@ package config
 import (
 	"errors"
 	"fmt"
+	"log/slog"
+	"net/url"
 	"os"
 	"strconv"
+	"strings"
 	"time"
+
+	"github.com/aridsondez/AWS-SQS-LITE/internal/queue"
 )
 
 // Config holds all environment configuration
 type Config struct {
-	Port                int
-	DatabaseURL         string
-	VisibilityTimeout   time.Duration
-	ReceiveMax          int
-	SweepInterval       time.Duration
-	LogLevel            string
-	DBConnectionTimeout time.Duration
-	SweeperInterval     time.Duration
+	Port                 int
+	GRPCPort             int // 0 disables the gRPC server
+	DatabaseURL          string
+	VisibilityTimeout    time.Duration
+	ReceiveMax           int
+	SweepInterval        time.Duration
+	LogLevel             string
+	DBConnectionTimeout  time.Duration
+	SweeperInterval      time.Duration
+	IdempotencyTTL       time.Duration
+	AckIDTTL             time.Duration // how long an ack_id is remembered; 0 ignores ack_ids
+	MaxMessageBytes      int
+	DLQRetention         time.Duration // 0 keeps dead letters forever
+	ArchiveRetention     time.Duration // 0 keeps archived copies of acked messages forever
+	MaxMessageLifetime   time.Duration // any live message older than this is deleted; 0 keeps messages forever
+	MaxDeliveries        int           // server-wide cap on max_retries; 0 = none
+	DefaultMaxRetries    int           // max_retries for messages whose request and queue set none
+	EnablePprof          bool
+	AdminToken           string        // bearer token for admin endpoints; empty disables them
+	ClockSkewWarn        time.Duration // warn at startup if the DB clock is further off; 0 disables
+	SlowQueryThreshold   time.Duration // log store calls slower than this; 0 disables
+	SerializationRetries int           // retries for store calls hitting 40001/40P01; 0 disables
+	ReceiveEnvelope      bool          // wrap receive responses in {"messages", "count"} by default
+	RetryStrategy        string        // fixed, linear or exponential redelivery backoff
+	RetryBackoff         time.Duration // backoff after the first delivery; 0 redelivers at once
+	RetryBackoffMax      time.Duration // cap on any one backoff; 0 = none
+	MaintenanceInterval  time.Duration // how often to check messages table bloat; 0 disables
+	MaintenanceDeadPct   int           // dead tuple percentage that counts as bloat
+	MaintenanceVacuum    bool          // vacuum a bloated table instead of only logging
+	BodyRefTimeout       time.Duration // fetch claim-check bodies at receive, each within this; 0 leaves them to consumers
+	BodyRefPrefixes      []string      // URL prefixes a body_ref must start with; required with BODY_REF_TIMEOUT
+	WorkerMetrics        bool          // count receives per X-Worker-ID; one series per worker process
+	PushInterval         time.Duration // how often to deliver to push subscriptions; 0 disables push
+	PushTimeout          time.Duration // longest a webhook may take to answer one message
+	MaxQueues            int           // cap on registered queues; 0 = no cap
+	MaxTotalInFlight     int           // cap on leased messages across all queues; 0 = no cap
+	SweeperBatch         int           // expired leases requeued, and dead-lettered, per sweep transaction
+	StrictQueues         bool          // enqueue only to queues created with PUT /v1/queues/{queue}
+	MessageIDFormat      string        // serial shows message IDs as numbers, ulid as opaque strings
 }
 
+// Message ID formats for MESSAGE_ID_FORMAT.
+const (
+	MessageIDSerial = "serial"
+	MessageIDULID   = "ulid"
+)
+
 // helper: read env var as int seconds → convert to duration
 func getEnvAsDuration(name string, defaultVal time.Duration) time.Duration {
 	if value, exists := os.LookupEnv(name); exists {
@@ -30,6 +72,16 @@ func getEnvAsDuration(name string, defaultVal time.Duration) time.Duration {
 	return defaultVal
 }
 
+// helper: read env var as int milliseconds → convert to duration
+func getEnvAsMillis(name string, defaultVal time.Duration) time.Duration {
+	if value, exists := os.LookupEnv(name); exists {
+		if i, err := strconv.Atoi(value); err == nil {
+			return time.Duration(i) * time.Millisecond
+		}
+	}
+	return defaultVal
+}
+
 func getEnvAsInt(name string, defaultVal int) int {
 	if value, exists := os.LookupEnv(name); exists {
 		if intValue, err := strconv.Atoi(value); err == nil {
@@ -39,6 +91,26 @@ func getEnvAsInt(name string, defaultVal int) int {
 	return defaultVal
 }
 
+func getEnvAsBool(name string, defaultVal bool) bool {
+	if value, exists := os.LookupEnv(name); exists {
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	}
+	return defaultVal
+}
+
+// helper: read env var as a comma-separated list, skipping empty items
+func getEnvAsList(name string) []string {
+	var out []string
+	for _, item := range strings.Split(os.Getenv(name), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
 func getEnv(name, defaultVal string) string {
 	if value, exists := os.LookupEnv(name); exists {
 		return value
@@ -48,14 +120,45 @@ func getEnv(name, defaultVal string) string {
 
 func LoadConfig() (*Config, error) {
 	cfg := &Config{
-		Port:                getEnvAsInt("PORT", 8080),
-		DatabaseURL:         getEnv("DATABASE_URL", ""),
-		VisibilityTimeout:   getEnvAsDuration("VISIBILITY_TIMEOUT", 30*time.Second),
-		ReceiveMax:          getEnvAsInt("RECEIVE_MAX", 10),
-		SweepInterval:       getEnvAsDuration("SWEEP_INTERVAL", 60*time.Second),
-		LogLevel:            getEnv("LOG_LEVEL", "info"),
-		DBConnectionTimeout: getEnvAsDuration("DB_CONNECTION_TIMEOUT", 5*time.Second),
-		SweeperInterval:     getEnvAsDuration("SWEEPER_INTERVAL", 1*time.Minute),
+		Port:                 getEnvAsInt("PORT", 8080),
+		GRPCPort:             getEnvAsInt("GRPC_PORT", 0),
+		DatabaseURL:          getEnv("DATABASE_URL", ""),
+		VisibilityTimeout:    getEnvAsDuration("VISIBILITY_TIMEOUT", 30*time.Second),
+		ReceiveMax:           getEnvAsInt("RECEIVE_MAX", 10),
+		SweepInterval:        getEnvAsDuration("SWEEP_INTERVAL", 60*time.Second),
+		LogLevel:             getEnv("LOG_LEVEL", "info"),
+		DBConnectionTimeout:  getEnvAsDuration("DB_CONNECTION_TIMEOUT", 5*time.Second),
+		SweeperInterval:      getEnvAsDuration("SWEEPER_INTERVAL", 1*time.Minute),
+		IdempotencyTTL:       getEnvAsDuration("IDEMPOTENCY_TTL", 24*time.Hour),
+		AckIDTTL:             getEnvAsDuration("ACK_ID_TTL", 10*time.Minute),
+		MaxMessageBytes:      getEnvAsInt("MAX_MESSAGE_BYTES", 256*1024),
+		DLQRetention:         getEnvAsDuration("DLQ_RETENTION", 0),
+		ArchiveRetention:     getEnvAsDuration("ARCHIVE_RETENTION", 0),
+		MaxMessageLifetime:   getEnvAsDuration("MAX_MESSAGE_LIFETIME", 0),
+		MaxDeliveries:        getEnvAsInt("MAX_DELIVERY_ATTEMPTS_CEILING", 0),
+		DefaultMaxRetries:    getEnvAsInt("DEFAULT_MAX_RETRIES", 5),
+		EnablePprof:          getEnvAsBool("ENABLE_PPROF", false),
+		AdminToken:           getEnv("ADMIN_TOKEN", ""),
+		ClockSkewWarn:        getEnvAsDuration("CLOCK_SKEW_WARN", 1*time.Second),
+		SlowQueryThreshold:   getEnvAsMillis("SLOW_QUERY_THRESHOLD", 500*time.Millisecond),
+		SerializationRetries: getEnvAsInt("SERIALIZATION_RETRIES", 3),
+		ReceiveEnvelope:      getEnvAsBool("RECEIVE_ENVELOPE", false),
+		RetryStrategy:        getEnv("RETRY_STRATEGY", string(queue.RetryExponential)),
+		RetryBackoff:         getEnvAsDuration("RETRY_BACKOFF", 1*time.Second),
+		RetryBackoffMax:      getEnvAsDuration("RETRY_BACKOFF_MAX", 5*time.Minute),
+		MaintenanceInterval:  getEnvAsDuration("MAINTENANCE_INTERVAL", 0),
+		MaintenanceDeadPct:   getEnvAsInt("MAINTENANCE_DEAD_TUPLE_PCT", 20),
+		MaintenanceVacuum:    getEnvAsBool("MAINTENANCE_VACUUM", false),
+		BodyRefTimeout:       getEnvAsDuration("BODY_REF_TIMEOUT", 0),
+		BodyRefPrefixes:      getEnvAsList("BODY_REF_ALLOWED_PREFIXES"),
+		WorkerMetrics:        getEnvAsBool("WORKER_METRICS", false),
+		PushInterval:         getEnvAsMillis("PUSH_INTERVAL", 0),
+		PushTimeout:          getEnvAsDuration("PUSH_TIMEOUT", 10*time.Second),
+		MaxQueues:            getEnvAsInt("MAX_QUEUES", 0),
+		MaxTotalInFlight:     getEnvAsInt("MAX_TOTAL_IN_FLIGHT", 0),
+		SweeperBatch:         getEnvAsInt("SWEEPER_BATCH", queue.DefaultSweepBatch),
+		StrictQueues:         getEnvAsBool("STRICT_QUEUES", false),
+		MessageIDFormat:      getEnv("MESSAGE_ID_FORMAT", MessageIDSerial),
 	}
 
 	// Basic validation
@@ -65,9 +168,100 @@ func LoadConfig() (*Config, error) {
 	if cfg.Port <= 0 || cfg.Port > 65535 {
 		return nil, fmt.Errorf("invalid PORT: %d", cfg.Port)
 	}
+	if cfg.GRPCPort < 0 || cfg.GRPCPort > 65535 || (cfg.GRPCPort != 0 && cfg.GRPCPort == cfg.Port) {
+		return nil, fmt.Errorf("invalid GRPC_PORT: %d", cfg.GRPCPort)
+	}
 	if cfg.ReceiveMax <= 0 {
 		return nil, fmt.Errorf("invalid RECEIVE_MAX: %d", cfg.ReceiveMax)
 	}
+	if cfg.MaxMessageBytes <= 0 {
+		return nil, fmt.Errorf("invalid MAX_MESSAGE_BYTES: %d", cfg.MaxMessageBytes)
+	}
+	if cfg.VisibilityTimeout <= 0 {
+		return nil, fmt.Errorf("invalid VISIBILITY_TIMEOUT: %s", cfg.VisibilityTimeout)
+	}
+
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(cfg.LogLevel)); err != nil {
+		return nil, fmt.Errorf("invalid LOG_LEVEL: %q (want debug, info, warn or error)", cfg.LogLevel)
+	}
+	if cfg.ClockSkewWarn < 0 {
+		return nil, fmt.Errorf("invalid CLOCK_SKEW_WARN: %s", cfg.ClockSkewWarn)
+	}
+	if cfg.SlowQueryThreshold < 0 {
+		return nil, fmt.Errorf("invalid SLOW_QUERY_THRESHOLD: %s", cfg.SlowQueryThreshold)
+	}
+	if cfg.SerializationRetries < 0 {
+		return nil, fmt.Errorf("invalid SERIALIZATION_RETRIES: %d", cfg.SerializationRetries)
+	}
+	if !queue.RetryStrategy(cfg.RetryStrategy).Valid() {
+		return nil, fmt.Errorf("invalid RETRY_STRATEGY: %q (want %s, %s or %s)", cfg.RetryStrategy,
+			queue.RetryFixed, queue.RetryLinear, queue.RetryExponential)
+	}
+	if cfg.RetryBackoff < 0 || cfg.RetryBackoffMax < 0 {
+		return nil, fmt.Errorf("invalid RETRY_BACKOFF/RETRY_BACKOFF_MAX: %s/%s", cfg.RetryBackoff, cfg.RetryBackoffMax)
+	}
+	if cfg.MaintenanceInterval < 0 {
+		return nil, fmt.Errorf("invalid MAINTENANCE_INTERVAL: %s", cfg.MaintenanceInterval)
+	}
+	if cfg.MaintenanceDeadPct < 1 || cfg.MaintenanceDeadPct > 100 {
+		return nil, fmt.Errorf("invalid MAINTENANCE_DEAD_TUPLE_PCT: %d", cfg.MaintenanceDeadPct)
+	}
+	if cfg.MaxDeliveries < 0 {
+		return nil, fmt.Errorf("invalid MAX_DELIVERY_ATTEMPTS_CEILING: %d", cfg.MaxDeliveries)
+	}
+	if cfg.DefaultMaxRetries < 1 {
+		return nil, fmt.Errorf("invalid DEFAULT_MAX_RETRIES: %d", cfg.DefaultMaxRetries)
+	}
+	if cfg.BodyRefTimeout < 0 {
+		return nil, fmt.Errorf("invalid BODY_REF_TIMEOUT: %s", cfg.BodyRefTimeout)
+	}
+	// The server GETs whatever a client puts in body_ref, so it may only
+	// do so under prefixes the operator names.
+	if cfg.BodyRefTimeout > 0 && len(cfg.BodyRefPrefixes) == 0 {
+		return nil, errors.New("BODY_REF_TIMEOUT requires BODY_REF_ALLOWED_PREFIXES")
+	}
+	for _, p := range cfg.BodyRefPrefixes {
+		if u, err := url.Parse(p); err != nil || u.Scheme == "" || u.Host == "" || !strings.HasSuffix(p, "/") {
+			return nil, fmt.Errorf("invalid BODY_REF_ALLOWED_PREFIXES entry %q (want scheme://host/path/)", p)
+		}
+	}
+	if cfg.DLQRetention < 0 {
+		return nil, fmt.Errorf("invalid DLQ_RETENTION: %s", cfg.DLQRetention)
+	}
+	if cfg.ArchiveRetention < 0 {
+		return nil, fmt.Errorf("invalid ARCHIVE_RETENTION: %s", cfg.ArchiveRetention)
+	}
+	if cfg.MaxMessageLifetime < 0 {
+		return nil, fmt.Errorf("invalid MAX_MESSAGE_LIFETIME: %s", cfg.MaxMessageLifetime)
+	}
+	if cfg.AckIDTTL < 0 {
+		return nil, fmt.Errorf("invalid ACK_ID_TTL: %s", cfg.AckIDTTL)
+	}
+	if cfg.EnablePprof && cfg.AdminToken == "" {
+		// profiles expose memory contents and can load the server; never serve them unauthenticated
+		return nil, errors.New("ENABLE_PPROF requires ADMIN_TOKEN")
+	}
+	if cfg.SweeperBatch <= 0 {
+		return nil, fmt.Errorf("invalid SWEEPER_BATCH: %d", cfg.SweeperBatch)
+	}
+	if cfg.MaxQueues < 0 {
+		return nil, fmt.Errorf("invalid MAX_QUEUES: %d", cfg.MaxQueues)
+	}
+	if cfg.MaxTotalInFlight < 0 {
+		return nil, fmt.Errorf("invalid MAX_TOTAL_IN_FLIGHT: %d", cfg.MaxTotalInFlight)
+	}
+	if cfg.StrictQueues && cfg.AdminToken == "" {
+		// queues are created with the admin token; without one none could be
+		return nil, errors.New("STRICT_QUEUES requires ADMIN_TOKEN")
+	}
+	if cfg.MessageIDFormat != MessageIDSerial && cfg.MessageIDFormat != MessageIDULID {
+		return nil, fmt.Errorf("invalid MESSAGE_ID_FORMAT: %q (want %s or %s)", cfg.MessageIDFormat, MessageIDSerial, MessageIDULID)
+	}
+	if cfg.MessageIDFormat == MessageIDULID && cfg.GRPCPort != 0 {
+		// the gRPC messages carry int64 IDs, which would give the serials away
+		return nil, errors.New("MESSAGE_ID_FORMAT=ulid is not supported with GRPC_PORT")
+	}
 
 	return cfg, nil
 }
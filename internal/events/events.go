@@ -0,0 +1,91 @@
+package events
+
+import (
+	"sync"
+	"time"
+)
+
+// Type identifies what happened to a message.
+type Type string
+
+const (
+	Enqueued     Type = "enqueued"
+	Received     Type = "received"
+	Acked        Type = "acked"
+	DeadLettered Type = "dead_lettered"
+)
+
+// Event is a single observation about a message in a queue.
+type Event struct {
+	Type  Type      `json:"type"`
+	Queue string    `json:"queue"`
+	ID    int64     `json:"id"`
+	DLQ   string    `json:"dlq,omitempty"` // set for dead_lettered events
+	At    time.Time `json:"at"`
+}
+
+// subscriberBuffer is how many events a slow subscriber may fall behind by
+// before further events are dropped for it.
+const subscriberBuffer = 64
+
+// Hub fans out events to subscribers of a queue. Publishing never blocks:
+// observers are best-effort and must not slow down the message path.
+type Hub struct {
+	mu   sync.RWMutex
+	subs map[string]map[chan Event]struct{}
+}
+
+func NewHub() *Hub {
+	return &Hub{subs: make(map[string]map[chan Event]struct{})}
+}
+
+// Default is the process-wide hub the store publishes to.
+var Default = NewHub()
+
+// Publish sends e to every subscriber of e.Queue on the default hub.
+func Publish(e Event) { Default.Publish(e) }
+
+// Subscribe registers for events on queue using the default hub.
+func Subscribe(queue string) (<-chan Event, func()) { return Default.Subscribe(queue) }
+
+func (h *Hub) Publish(e Event) {
+	if e.At.IsZero() {
+		e.At = time.Now()
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for ch := range h.subs[e.Queue] {
+		select {
+		case ch <- e:
+		default:
+			// subscriber is not keeping up; drop rather than block producers
+		}
+	}
+}
+
+// Subscribe returns a channel of events for queue and a function that
+// unsubscribes and closes the channel. The cancel func is safe to call more than once.
+func (h *Hub) Subscribe(queue string) (<-chan Event, func()) {
+	ch := make(chan Event, subscriberBuffer)
+
+	h.mu.Lock()
+	if h.subs[queue] == nil {
+		h.subs[queue] = make(map[chan Event]struct{})
+	}
+	h.subs[queue][ch] = struct{}{}
+	h.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			h.mu.Lock()
+			delete(h.subs[queue], ch)
+			if len(h.subs[queue]) == 0 {
+				delete(h.subs, queue)
+			}
+			h.mu.Unlock()
+			close(ch)
+		})
+	}
+}
@@ -5,6 +5,10 @@ import (
 	"github.com/prometheus/client_golang/prometheus/promauto"
 )
 
+// ageBuckets spans quick round trips to messages that waited half a day;
+// ages run far longer than the DefBuckets' 10s.
+var ageBuckets = prometheus.ExponentialBuckets(0.01, 4, 12) // 10ms to ~12h
+
 var (
 	// Messages enqueued counter
 	MessagesEnqueued = promauto.NewCounterVec(
@@ -48,6 +52,99 @@ var (
 		},
 	)
 
+	// DLQ messages deleted by the sweeper after DLQ_RETENTION
+	DLQPurged = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "sqs_dlq_purged_total",
+			Help: "Total number of DLQ messages purged after the retention period",
+		},
+	)
+
+	// Messages deleted by the sweeper after MAX_MESSAGE_LIFETIME
+	MessagesExpired = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "sqs_messages_expired_total",
+			Help: "Total number of messages deleted for outliving the maximum message lifetime",
+		},
+	)
+
+	// Messages deleted at MAX_DELIVERY_ATTEMPTS_CEILING because they had no DLQ
+	MessagesDropped = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "sqs_messages_dropped_total",
+			Help: "Total number of messages deleted at the delivery ceiling for lack of a DLQ",
+		},
+	)
+
+	// Store time for enqueues, excluding HTTP/gRPC handling
+	EnqueueDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "sqs_enqueue_duration_seconds",
+			Help:    "Time spent in the store enqueuing, per request",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"queue"},
+	)
+
+	// Store time for claims, excluding HTTP/gRPC handling and long-poll waits
+	ReceiveDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "sqs_receive_duration_seconds",
+			Help:    "Time spent in the store claiming messages, per request",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"queue"},
+	)
+
+	// Webhook deliveries to push subscriptions, by whether the webhook
+	// answered 2xx ("ok") or not ("failed")
+	PushDeliveries = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "sqs_push_deliveries_total",
+			Help: "Total number of messages POSTed to push subscription webhooks",
+		},
+		[]string{"queue", "outcome"},
+	)
+
+	// Receives broken down by the X-Worker-ID that claimed them, when
+	// WORKER_METRICS is set
+	MessagesReceivedByWorker = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "sqs_messages_received_by_worker_total",
+			Help: "Total number of messages received, by worker",
+		},
+		[]string{"queue", "worker"},
+	)
+
+	// Time from enqueue to each claim, including any earlier deliveries
+	MessageAgeAtReceive = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "sqs_message_age_at_receive_seconds",
+			Help:    "Time since a message was enqueued when it is claimed",
+			Buckets: ageBuckets,
+		},
+		[]string{"queue"},
+	)
+
+	// Time from enqueue to ack: end-to-end latency, retries included
+	MessageAgeAtAck = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "sqs_message_age_at_ack_seconds",
+			Help:    "Time since a message was enqueued when it is acked",
+			Buckets: ageBuckets,
+		},
+		[]string{"queue"},
+	)
+
+	// Store calls slower than SLOW_QUERY_THRESHOLD
+	SlowQueries = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "sqs_slow_queries_total",
+			Help: "Total number of store calls slower than the slow query threshold",
+		},
+		[]string{"op"},
+	)
+
 	// Sweeper run duration
 	SweeperDuration = promauto.NewHistogram(
 		prometheus.HistogramOpts{
@@ -57,6 +154,57 @@ var (
 		},
 	)
 
+	// Expired leases waiting to be swept, sampled at the start of each sweep
+	SweeperLag = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "sqs_sweeper_lag_messages",
+			Help: "Messages whose lease expired but have not yet been swept, at sweep start",
+		},
+	)
+
+	// When the sweeper last ran
+	SweeperLastRun = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "sqs_sweeper_last_run_timestamp",
+			Help: "Unix time of the last sweeper run",
+		},
+	)
+
+	// When the maintenance loop last checked the messages table
+	MaintenanceLastRun = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "sqs_maintenance_last_run_timestamp",
+			Help: "Unix time of the last messages table maintenance run",
+		},
+	)
+
+	// Dead share of the messages table's tuples, sampled by the maintenance loop
+	MessagesDeadTupleRatio = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "sqs_messages_dead_tuple_ratio",
+			Help: "Fraction of the messages table's tuples that are dead, at the last maintenance run",
+		},
+	)
+
+	// Age of each queue's oldest live lease's message, sampled at the start of
+	// each sweep; one that keeps climbing is held by a worker that stopped
+	OldestInFlightAge = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "sqs_oldest_inflight_age_seconds",
+			Help: "Time since the oldest in-flight message in the queue was enqueued, at sweep start",
+		},
+		[]string{"queue"},
+	)
+
+	// Requeues of leases that ran out soon after they were taken, by queue
+	RapidRequeues = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "sqs_rapid_requeue_total",
+			Help: "Messages the sweeper requeued within the rapid requeue window of being leased",
+		},
+		[]string{"queue"},
+	)
+
 	// Sweeper errors counter
 	SweeperErrors = promauto.NewCounter(
 		prometheus.CounterOpts{
@@ -64,4 +212,13 @@ var (
 			Help: "Total number of sweeper errors",
 		},
 	)
+
+	// Failed sweeps since the last one that succeeded; non-zero means the
+	// sweeper is degraded and backing off
+	SweeperConsecutiveErrors = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "sqs_sweeper_consecutive_errors",
+			Help: "Sweeps that have failed in a row; 0 when the last sweep succeeded",
+		},
+	)
 )
@@ -0,0 +1,60 @@
+package queue
+
+import "sort"
+
+// AllocateClaims splits a receive budget of limit messages across queues in
+// proportion to weights, giving every queue at least one slot. The caller
+// must ensure limit >= len(weights); weights <= 0 count as 1.
+//
+// Queues whose proportional share is under one slot are pinned at one and
+// the rest is re-split among the others. Leftover slots from rounding go to
+// the largest fractional shares, ties to the earlier queue, so the result
+// always sums to limit.
+func AllocateClaims(weights []int, limit int) []int {
+	n := len(weights)
+	alloc := make([]int, n)
+	pinned := make([]bool, n)
+	ws := make([]int, n)
+	for i, w := range weights {
+		ws[i] = max(w, 1)
+	}
+
+	rest, total := limit, 0
+	for _, w := range ws {
+		total += w
+	}
+	for changed := true; changed; {
+		changed = false
+		for i, w := range ws {
+			if !pinned[i] && rest*w < total {
+				pinned[i] = true
+				alloc[i] = 1
+				rest--
+				total -= w
+				changed = true
+			}
+		}
+	}
+	if total == 0 {
+		return alloc
+	}
+
+	type frac struct{ i, rem int }
+	var fracs []frac
+	given := 0
+	for i, w := range ws {
+		if pinned[i] {
+			continue
+		}
+		share := rest * w
+		alloc[i] = share / total
+		given += share / total
+		fracs = append(fracs, frac{i, share % total})
+	}
+	sort.SliceStable(fracs, func(a, b int) bool { return fracs[a].rem > fracs[b].rem })
+	for k := 0; given < rest; k++ {
+		alloc[fracs[k].i]++
+		given++
+	}
+	return alloc
+}
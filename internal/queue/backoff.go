@@ -0,0 +1,65 @@
+package queue
+
+import (
+	"math"
+	"time"
+)
+
+// RetryStrategy is how the delay before a failed message is redelivered
+// grows with its delivery count.
+type RetryStrategy string
+
+const (
+	// RetryFixed waits the base delay before every redelivery.
+	RetryFixed RetryStrategy = "fixed"
+
+	// RetryLinear waits base × delivery count.
+	RetryLinear RetryStrategy = "linear"
+
+	// RetryExponential doubles the wait on each delivery: base, 2×base,
+	// 4×base, ...
+	RetryExponential RetryStrategy = "exponential"
+)
+
+// Valid reports whether s names a known strategy.
+func (s RetryStrategy) Valid() bool {
+	switch s {
+	case RetryFixed, RetryLinear, RetryExponential:
+		return true
+	}
+	return false
+}
+
+// Backoff spaces out redeliveries of messages whose lease expired. The zero
+// value requeues them for immediate redelivery.
+type Backoff struct {
+	Strategy RetryStrategy // "" means RetryExponential
+	Base     time.Duration // delay after the first delivery; 0 disables backoff
+	Max      time.Duration // upper bound on any delay; 0 = none
+}
+
+// Delay returns how long a message that has been delivered deliveries times
+// waits before it is available again.
+func (b Backoff) Delay(deliveries int) time.Duration {
+	if b.Base <= 0 {
+		return 0
+	}
+	deliveries = max(deliveries, 1)
+
+	var d time.Duration
+	switch b.Strategy {
+	case RetryFixed:
+		d = b.Base
+	case RetryLinear:
+		d = b.Base * time.Duration(deliveries)
+	default:
+		d = b.Base
+		for i := 1; i < deliveries && (b.Max <= 0 || d < b.Max) && d <= math.MaxInt64/2; i++ {
+			d *= 2
+		}
+	}
+	if b.Max > 0 && d > b.Max {
+		d = b.Max
+	}
+	return d
+}
@@ -0,0 +1,82 @@
+package queue
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// ErrInvalidBody is returned for a message body that can't be stored.
+var ErrInvalidBody = errors.New("invalid message body")
+
+// ValidateBody reports whether body can be stored. Bodies live in a JSONB
+// column, so they must be JSON in UTF-8, and that excludes the \u0000
+// escape and unpaired surrogate escapes such as \ud800, which Postgres
+// refuses in jsonb text. Binary payloads go base64 encoded in a JSON
+// string, or behind a body_ref.
+func ValidateBody(body []byte) error {
+	if !utf8.Valid(body) {
+		return fmt.Errorf("%w: not valid UTF-8", ErrInvalidBody)
+	}
+	if !json.Valid(body) {
+		return fmt.Errorf("%w: not valid JSON", ErrInvalidBody)
+	}
+	if msg := badEscape(body); msg != "" {
+		return fmt.Errorf("%w: %s", ErrInvalidBody, msg)
+	}
+	return nil
+}
+
+// badEscape says what is wrong with the first \u escape in the JSON text
+// that jsonb won't take, or "" if there is none. Outside strings valid JSON
+// has no backslashes, so escapes can be scanned for without tracking where
+// strings start and end.
+func badEscape(body []byte) string {
+	for i := 0; i+6 <= len(body); i++ {
+		if body[i] != '\\' {
+			continue
+		}
+		if body[i+1] != 'u' {
+			i++ // skip the escaped character, e.g. the second \ of \\u0000
+			continue
+		}
+		r := unicodeEscape(body[i+2 : i+6])
+		switch {
+		case r == 0:
+			return "\\u0000 is not allowed in strings"
+		case utf16.IsSurrogate(r) && r >= 0xdc00:
+			return fmt.Sprintf("unpaired surrogate \\u%04x", r)
+		case utf16.IsSurrogate(r):
+			// a high surrogate stands only for half of the code point after it
+			if i+12 > len(body) || body[i+6] != '\\' || body[i+7] != 'u' {
+				return fmt.Sprintf("unpaired surrogate \\u%04x", r)
+			}
+			if lo := unicodeEscape(body[i+8 : i+12]); lo < 0xdc00 || lo > 0xdfff {
+				return fmt.Sprintf("unpaired surrogate \\u%04x", r)
+			}
+			i += 6
+		}
+		i += 5
+	}
+	return ""
+}
+
+// unicodeEscape decodes the four hex digits of a \u escape, which valid
+// JSON guarantees.
+func unicodeEscape(hex []byte) rune {
+	var r rune
+	for _, c := range hex {
+		switch {
+		case c >= '0' && c <= '9':
+			c -= '0'
+		case c >= 'a' && c <= 'f':
+			c -= 'a' - 10
+		default:
+			c -= 'A' - 10
+		}
+		r = r<<4 | rune(c)
+	}
+	return r
+}
@@ -0,0 +1,102 @@
+// Package maintenance keeps an eye on bloat in the messages table for
+// deployments whose autovacuum isn't tuned for a queue's insert/delete churn.
+package maintenance
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/aridsondez/AWS-SQS-LITE/internal/metrics"
+)
+
+// Store is what the maintainer needs from the database.
+type Store interface {
+	// MessageTuples returns the live and dead tuple counts of the messages table.
+	MessageTuples(ctx context.Context) (live, dead int64, err error)
+	// VacuumMessages vacuums and analyzes the messages table.
+	VacuumMessages(ctx context.Context) error
+}
+
+// Options configure a Maintainer.
+type Options struct {
+	// DeadRatio is the share of dead tuples (0-1) at which the table counts
+	// as bloated.
+	DeadRatio float64
+	// Vacuum makes the maintainer vacuum a bloated table; otherwise it only
+	// logs a recommendation.
+	Vacuum bool
+}
+
+// Maintainer periodically checks the messages table's dead tuple ratio and
+// vacuums it, or recommends doing so, past Options.DeadRatio.
+type Maintainer struct {
+	store    Store
+	interval time.Duration
+	opts     Options
+	stopCh   chan struct{}
+}
+
+func New(store Store, interval time.Duration, opts Options) *Maintainer {
+	return &Maintainer{
+		store:    store,
+		interval: interval,
+		opts:     opts,
+		stopCh:   make(chan struct{}),
+	}
+}
+
+func (m *Maintainer) Start(ctx context.Context) {
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	log.Printf("Maintenance started, interval: %s", m.interval)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-m.stopCh:
+			return
+		case <-ticker.C:
+			if err := m.Run(ctx); err != nil {
+				log.Printf("Maintenance error: %v", err)
+			}
+		}
+	}
+}
+
+// Run does one check, vacuuming if the table is bloated and vacuuming is on.
+func (m *Maintainer) Run(ctx context.Context) error {
+	defer metrics.MaintenanceLastRun.SetToCurrentTime()
+
+	live, dead, err := m.store.MessageTuples(ctx)
+	if err != nil {
+		return err
+	}
+	if live+dead == 0 {
+		metrics.MessagesDeadTupleRatio.Set(0)
+		return nil
+	}
+	ratio := float64(dead) / float64(live+dead)
+	metrics.MessagesDeadTupleRatio.Set(ratio)
+	if ratio < m.opts.DeadRatio {
+		return nil
+	}
+
+	if !m.opts.Vacuum {
+		log.Printf("messages table is %.0f%% dead tuples (%d dead, %d live); consider VACUUM or tuning autovacuum for it",
+			ratio*100, dead, live)
+		return nil
+	}
+	start := time.Now()
+	if err := m.store.VacuumMessages(ctx); err != nil {
+		return err
+	}
+	log.Printf("Vacuumed messages table (%d dead tuples) in %.2fs", dead, time.Since(start).Seconds())
+	return nil
+}
+
+func (m *Maintainer) Stop() {
+	close(m.stopCh)
+}
@@ -1,12 +1,15 @@
 package queue
 
-import "time"
+import (
+	"fmt"
+	"time"
+)
 
 // Message is the durable queue row mapped to Go.
 type Message struct {
 	ID            int64
 	Queue         string
-	Body          []byte
+	Body          []byte // JSON, stored as JSONB; see ValidateBody
 	EnqueuedAt    time.Time
 	NotBefore     time.Time
 	LeaseUntil    *time.Time
@@ -14,6 +17,278 @@ type Message struct {
 	MaxRetries    int
 	DLQ           *string
 	TraceID       *string
+	LeaseEpoch    int64 // bumped on every claim
+	DLQRules      []DLQRule
+	DLQdAt        *time.Time // when the sweeper moved it to a DLQ; nil for fresh work
+	GroupID       *string    // acks within a group must follow ID order; nil for none
+	ReceiveCount  int        // lifetime claims; unlike DeliveryCount never reset
+	GroupSealed   bool       // the last message of a sealed group
+	BodyRef       *string    // claim check: where the body is kept; Body is JSON null until resolved
+	CoalesceKey   *string    // at most one pending message per key and queue; nil for none
+	ULID          string     // opaque name the API can show instead of ID; set by the store
+
+	// Failure context. LastError is the reason given by the latest nack
+	// that had one and FailedAt the time of the latest nack; DLQSource and
+	// DLQDeliveries are set when the sweeper dead-letters the message.
+	LastError     *string
+	FailedAt      *time.Time
+	DLQSource     *string // queue the message failed in
+	DLQDeliveries *int    // its delivery count there
+}
+
+// DLQRule routes a dead-lettered message by its delivery count. The first
+// rule whose range contains the count wins; if none does, Message.DLQ is
+// used. A zero bound is open.
+type DLQRule struct {
+	MinDeliveries int
+	MaxDeliveries int
+	DLQ           string
+}
+
+// Receipt returns the receipt for the message's current lease, expiring
+// with it.
+func (m Message) Receipt() Receipt {
+	rc := Receipt{ID: m.ID, Epoch: m.LeaseEpoch}
+	if m.LeaseUntil != nil {
+		rc.Expires = *m.LeaseUntil
+	}
+	return rc
+}
+
+// EnqueueEntry is one message of a batch enqueue.
+type EnqueueEntry struct {
+	Message Message
+	Delay   time.Duration
+}
+
+// EntryError is a batch enqueue's refusal of one entry on its own account,
+// e.g. one for a sealed group or with an invalid body, as opposed to a
+// failure of the whole batch.
+// The batch is rolled back either way; without the entry it may succeed.
+type EntryError struct {
+	Index int // into the entries given
+	Err   error
+}
+
+func (e *EntryError) Error() string { return fmt.Sprintf("enqueue entry %d: %v", e.Index, e.Err) }
+
+func (e *EntryError) Unwrap() error { return e.Err }
+
+// EnqueueKey makes an enqueue conditional: while (queue, Scope, Key) is
+// remembered, enqueuing with it again returns the original message ID
+// instead of inserting a new message.
+type EnqueueKey struct {
+	Scope string // namespace so different features don't collide
+	Key   string
+	TTL   time.Duration // how long the key is remembered; 0 = forever
+}
+
+// QueueConfig holds per-queue settings. Queues without stored config use
+// DefaultQueueConfig.
+type QueueConfig struct {
+	Queue      string
+	Partitions int // physical partitions messages are spread across (>= 1)
+
+	// Defaults for requests that don't set their own; zero/nil means the
+	// server-wide default applies.
+	Visibility   time.Duration
+	MaxRetries   int
+	DLQ          *string
+	DedupWindow  time.Duration // how long a dedup_id is remembered
+	DefaultDelay time.Duration // delay for enqueues that don't set delay_ms or not_before
+
+	RequireObjectBody bool // reject message bodies that aren't JSON objects
+
+	ClaimOrder ClaimOrder // "" means ClaimOrderID
+
+	Role QueueRole // informational; "" is an ordinary queue
+
+	// SkipAfterAttempts lets a group message that has been delivered this
+	// many times stop blocking acks of its later group-mates; 0 never skips.
+	SkipAfterAttempts int
+
+	Paused bool // claims return nothing; enqueues still land
+
+	// MaxInFlight caps how many of the queue's messages may be leased at
+	// once; claims are trimmed to the remaining budget. 0 is unbounded.
+	MaxInFlight int
+
+	// RequeueInPlace skips the redelivery backoff when the sweeper requeues
+	// one of the queue's messages, so it keeps its place in claim order
+	// instead of going behind everything that became visible meanwhile.
+	RequeueInPlace bool
+
+	// CloudEvents makes every body on the queue a CloudEvents envelope:
+	// events sent to it must be valid, and other bodies are wrapped in one.
+	CloudEvents bool
+
+	// Archive keeps a copy of each message acked on the queue, so it can be
+	// replayed later; see SweepOptions.ArchiveRetention.
+	Archive bool
+}
+
+// ArchivedMessage is the copy of an acked message kept by a queue with
+// Archive set. Only the fields a replay needs are filled in.
+type ArchivedMessage struct {
+	Message
+	AckedAt time.Time
+}
+
+// ArchiveFilter picks archived messages. Zero fields don't filter.
+type ArchiveFilter struct {
+	Queue string    // archived from this queue
+	From  time.Time // acked at or after this
+	To    time.Time // acked before this
+	IDs   []int64   // only these serial IDs of the original messages
+	ULIDs []string  // only these ULIDs of the original messages
+}
+
+// QueueRole tags what a queue is used for. It never changes how the queue
+// behaves, only what receivers are told about it.
+type QueueRole string
+
+// QueueRoleDLQ marks a queue that collects dead letters.
+const QueueRoleDLQ QueueRole = "dlq"
+
+// ClaimOrder is the order a queue's available messages are claimed in.
+type ClaimOrder string
+
+const (
+	// ClaimOrderID claims in insertion order.
+	ClaimOrderID ClaimOrder = "id"
+
+	// ClaimOrderVisibleAt claims in the order messages became visible, so a
+	// delayed message waits behind ones that were ready before it.
+	ClaimOrderVisibleAt ClaimOrder = "visible_at"
+
+	// ClaimOrderFIFO claims strictly in enqueue time order: no message is
+	// handed out while a message enqueued before it is available. A delayed
+	// message is skipped until its delay runs out and then goes ahead of
+	// everything enqueued after it.
+	ClaimOrderFIFO ClaimOrder = "fifo"
+)
+
+// Stats are approximate message counts for one queue.
+type Stats struct {
+	Available int64 // visible and not leased
+	InFlight  int64 // leased by a worker
+	Delayed   int64 // not leased, not_before still in the future
+}
+
+// Diagnosis breaks a queue's messages down by why they can or can't be
+// claimed right now. Unlike Stats it splits in-flight messages whose lease
+// ran out but that the sweeper hasn't requeued yet, and delayed messages
+// that are waiting out a redelivery backoff from ones never delivered.
+type Diagnosis struct {
+	Available    int64      // claimable now, unless the queue is paused or at max_in_flight
+	Leased       int64      // lease still running
+	LeaseExpired int64      // lease ran out; claimable once the sweeper requeues it
+	Delayed      int64      // never delivered, not_before in the future
+	BackedOff    int64      // delivered before, waiting out its redelivery delay
+	NextVisible  *time.Time // earliest not_before of the delayed and backed off, if any
+}
+
+// MessageState is the lease state of a message as Stats buckets it.
+type MessageState string
+
+const (
+	StateAvailable MessageState = "available"
+	StateInFlight  MessageState = "in_flight"
+	StateDelayed   MessageState = "delayed"
+)
+
+// CountFilter restricts which of a queue's messages Count counts. The zero
+// value counts all of them.
+type CountFilter struct {
+	State            MessageState // "" for any state
+	MinDeliveryCount int          // only messages delivered at least this often
+	GroupID          *string      // only messages of this group
+	Limit            int          // stop counting here; 0 = no limit
+}
+
+// DefaultQueueConfig returns the settings a queue has until configured.
+func DefaultQueueConfig(name string) QueueConfig {
+	return QueueConfig{Queue: name, Partitions: 1}
+}
+
+// SweepOptions controls the sweeper's housekeeping.
+type SweepOptions struct {
+	DLQRetention time.Duration // purge dead letters older than this; 0 = keep forever
+
+	// ArchiveRetention deletes archived copies of acked messages once they
+	// were acked this long ago. 0 keeps them forever.
+	ArchiveRetention time.Duration
+
+	// MaxLifetime deletes any message enqueued longer ago than this that
+	// is waiting, delayed or leased. Dead letters are left to DLQRetention,
+	// so an operator can still inspect and redrive them. 0 = no limit.
+	MaxLifetime time.Duration
+
+	// MaxDeliveries caps every message's max_retries. A message delivered
+	// this many times is dead-lettered, or deleted if it has no DLQ.
+	// 0 = no cap.
+	MaxDeliveries int
+
+	// Backoff delays redelivery of requeued messages by delivery count.
+	Backoff Backoff
+
+	// Router, if set, picks the DLQ of each message that runs out of
+	// retries instead of its dlq and dlq_rules. nil routes as
+	// DefaultDLQRouter does.
+	Router DLQRouter
+
+	// BatchSize bounds how many expired leases one sweep transaction
+	// requeues, and how many it dead-letters, so a large backlog is swept
+	// in several short transactions instead of one that locks it all.
+	// 0 = DefaultSweepBatch.
+	BatchSize int
+
+	// RapidRequeueWindow is how soon after being leased a lease may run out
+	// before its requeue counts as rapid in sqs_rapid_requeue_total.
+	// 0 = DefaultRapidRequeueWindow.
+	RapidRequeueWindow time.Duration
+}
+
+// DefaultSweepBatch is the SweepOptions.BatchSize used when none is set.
+const DefaultSweepBatch = 1000
+
+// SweepBatch is o.BatchSize, or DefaultSweepBatch if that is unset.
+func (o SweepOptions) SweepBatch() int {
+	if o.BatchSize > 0 {
+		return o.BatchSize
+	}
+	return DefaultSweepBatch
+}
+
+// DefaultRapidRequeueWindow is the SweepOptions.RapidRequeueWindow used when
+// none is set.
+const DefaultRapidRequeueWindow = time.Second
+
+// RapidRequeue is o.RapidRequeueWindow, or DefaultRapidRequeueWindow if that
+// is unset.
+func (o SweepOptions) RapidRequeue() time.Duration {
+	if o.RapidRequeueWindow > 0 {
+		return o.RapidRequeueWindow
+	}
+	return DefaultRapidRequeueWindow
+}
+
+// PushSubscription is a queue's webhook: instead of consumers receiving
+// from the queue, the server POSTs each message to URL.
+type PushSubscription struct {
+	Queue     string
+	URL       string
+	CreatedAt time.Time
+}
+
+// Checkpoint is how far a consumer group has read a queue: it has
+// processed every message up to and including LastID. A group that has
+// never committed one reads from the start, LastID 0.
+type Checkpoint struct {
+	Queue     string
+	Group     string
+	LastID    int64
+	UpdatedAt time.Time // zero if never committed
 }
 
 // ClaimOptions controls how we receive messages.
@@ -22,3 +297,16 @@ type ClaimOptions struct {
 	Limit      int
 	Visibility time.Duration
 }
+
+// WeightedQueue is one queue in a multi-queue claim.
+type WeightedQueue struct {
+	Queue      string
+	Weight     int // share of the budget relative to the other queues (>= 1)
+	Visibility time.Duration
+}
+
+// ClaimMultiOptions leases up to Limit messages across several queues.
+type ClaimMultiOptions struct {
+	Queues []WeightedQueue
+	Limit  int // must be >= len(Queues) so every queue gets a slot
+}
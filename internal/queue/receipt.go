@@ -0,0 +1,113 @@
+package queue
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrMalformedReceipt is returned when a receipt string can't be parsed.
+	ErrMalformedReceipt = errors.New("invalid receipt format")
+
+	// ErrStaleReceipt is returned when the message has been re-leased since
+	// the receipt was issued, so its holder no longer owns it.
+	ErrStaleReceipt = errors.New("receipt is stale: message was leased again")
+
+	// ErrGroupOrder is returned when acking a grouped message while an
+	// earlier message of its group is still in the queue.
+	ErrGroupOrder = errors.New("an earlier message in the group has not been acked")
+
+	// ErrMessageNotFound is reported per receipt by a batch ack when the
+	// message is already gone.
+	ErrMessageNotFound = errors.New("message not found")
+
+	// ErrGroupSealed is returned when enqueueing into a group that has been
+	// sealed.
+	ErrGroupSealed = errors.New("the message group is sealed")
+
+	// ErrReceiptExpired is returned when a receipt is presented after the
+	// lease it was issued for ended.
+	ErrReceiptExpired = errors.New("receipt expired: its lease has ended")
+
+	// ErrQueueNotEmpty is returned when renaming a queue to a name that
+	// already has messages, without asking to merge.
+	ErrQueueNotEmpty = errors.New("the target queue already has messages")
+
+	// ErrMessageDelivered is returned when cancelling a message that has
+	// already been handed to a consumer.
+	ErrMessageDelivered = errors.New("message has already been delivered")
+
+	// ErrMessageLeased is returned when claiming a message by ID that a
+	// consumer currently holds.
+	ErrMessageLeased = errors.New("message is leased by another consumer")
+
+	// ErrMessageDelayed is returned when claiming a message by ID before its
+	// delay has passed.
+	ErrMessageDelayed = errors.New("message is not visible yet")
+
+	// ErrQueuePaused is returned when claiming a message by ID on a paused
+	// queue.
+	ErrQueuePaused = errors.New("the queue is paused")
+
+	// ErrMaxInFlight is returned when claiming a message by ID while its
+	// queue has max_in_flight messages leased.
+	ErrMaxInFlight = errors.New("the queue is at its max_in_flight")
+
+	// ErrTooManyQueues is returned when registering a queue would take the
+	// number of queues past the configured maximum.
+	ErrTooManyQueues = errors.New("queue limit reached")
+)
+
+// Receipt identifies the lease a worker was given on a message by a receive.
+// It must be presented back to ack the message.
+type Receipt struct {
+	ID    int64
+	Epoch int64 // lease_epoch at claim time; fences out earlier holders
+
+	// Expires is when the lease the receipt was issued for ends, to the
+	// millisecond; zero for receipts that don't carry it.
+	Expires time.Time
+}
+
+// String encodes the receipt for clients. Treat it as opaque.
+func (r Receipt) String() string {
+	s := strconv.FormatInt(r.ID, 10) + "." + strconv.FormatInt(r.Epoch, 10)
+	if !r.Expires.IsZero() {
+		s += "." + strconv.FormatInt(r.Expires.UnixMilli(), 10)
+	}
+	return s
+}
+
+// Expired reports whether the receipt's lease had ended by now. A receipt
+// without an expiry never reports expired; the epoch still fences it.
+func (r Receipt) Expired(now time.Time) bool {
+	return !r.Expires.IsZero() && !now.Before(r.Expires)
+}
+
+// ParseReceipt decodes a receipt produced by Receipt.String.
+func ParseReceipt(s string) (Receipt, error) {
+	idStr, rest, ok := strings.Cut(s, ".")
+	if !ok {
+		return Receipt{}, ErrMalformedReceipt
+	}
+	epochStr, expiresStr, hasExpiry := strings.Cut(rest, ".")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil || id <= 0 {
+		return Receipt{}, ErrMalformedReceipt
+	}
+	epoch, err := strconv.ParseInt(epochStr, 10, 64)
+	if err != nil || epoch <= 0 {
+		return Receipt{}, ErrMalformedReceipt
+	}
+	rc := Receipt{ID: id, Epoch: epoch}
+	if hasExpiry {
+		ms, err := strconv.ParseInt(expiresStr, 10, 64)
+		if err != nil || ms <= 0 {
+			return Receipt{}, ErrMalformedReceipt
+		}
+		rc.Expires = time.UnixMilli(ms)
+	}
+	return rc, nil
+}
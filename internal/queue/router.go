@@ -0,0 +1,36 @@
+package queue
+
+import "context"
+
+// DLQRoute is where a DLQRouter sends a message that has used up its
+// retries. The zero value means no DLQ: the message is requeued as one
+// without a dlq would be, or deleted at the delivery ceiling.
+type DLQRoute struct {
+	DLQ  string // dead-letter to this queue
+	Drop bool   // delete the message instead
+}
+
+// DLQRouter decides what happens to a message whose lease expired with no
+// retries left, in place of its dlq and dlq_rules. The sweeper calls it
+// with the message's row locked, so it should be quick; an error leaves the
+// message for the next sweep.
+type DLQRouter interface {
+	RouteDLQ(ctx context.Context, msg Message) (DLQRoute, error)
+}
+
+// DefaultDLQRouter routes by the message's own settings: the first of its
+// dlq_rules whose range holds its delivery count, else its dlq. The
+// sweeper does the same in SQL when no router is set.
+type DefaultDLQRouter struct{}
+
+func (DefaultDLQRouter) RouteDLQ(_ context.Context, msg Message) (DLQRoute, error) {
+	for _, r := range msg.DLQRules {
+		if msg.DeliveryCount >= r.MinDeliveries && (r.MaxDeliveries == 0 || msg.DeliveryCount <= r.MaxDeliveries) {
+			return DLQRoute{DLQ: r.DLQ}, nil
+		}
+	}
+	if msg.DLQ != nil {
+		return DLQRoute{DLQ: *msg.DLQ}, nil
+	}
+	return DLQRoute{}, nil
+}
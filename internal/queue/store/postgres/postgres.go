@@ -1,12 +1,21 @@
 package postgres
 
 import (
+	"cmp"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
+	"math/rand/v2"
+	"slices"
+	"strings"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 
+	"github.com/aridsondez/AWS-SQS-LITE/internal/events"
 	"github.com/aridsondez/AWS-SQS-LITE/internal/metrics"
 	"github.com/aridsondez/AWS-SQS-LITE/internal/queue"
 	"github.com/aridsondez/AWS-SQS-LITE/internal/queue/store"
@@ -23,6 +32,38 @@ func New(pool *pgxpool.Pool) *PostgresStore {
 	return &PostgresStore{pool: pool}
 }
 
+// ClockSkew estimates how far the database clock is ahead of this process's
+// clock (negative if behind). Every lease, delay and expiry is computed and
+// compared with the database's now(), so skew doesn't affect the queue itself,
+// but timestamps handed to clients (lease_until) are on the database clock.
+func (p *PostgresStore) ClockSkew(ctx context.Context) (time.Duration, error) {
+	var dbNow time.Time
+	start := time.Now()
+	if err := p.pool.QueryRow(ctx, `SELECT now()`).Scan(&dbNow); err != nil {
+		return 0, err
+	}
+	rtt := time.Since(start)
+	// Assume the server read its clock halfway through the round trip.
+	return dbNow.Sub(start.Add(rtt / 2)), nil
+}
+
+// MessageTuples reports the live and dead row versions the statistics
+// collector counts for the messages table. Every ack and move leaves a dead
+// tuple behind until vacuum reclaims it.
+func (p *PostgresStore) MessageTuples(ctx context.Context) (live, dead int64, err error) {
+	err = p.pool.QueryRow(ctx, `
+SELECT n_live_tup, n_dead_tup FROM pg_stat_user_tables
+WHERE relid = 'messages'::regclass`).Scan(&live, &dead)
+	return live, dead, err
+}
+
+// VacuumMessages vacuums and analyzes the messages table. It takes no lock
+// that blocks reads or writes, but does compete with them for I/O.
+func (p *PostgresStore) VacuumMessages(ctx context.Context) error {
+	_, err := p.pool.Exec(ctx, `VACUUM (ANALYZE) messages`)
+	return err
+}
+
 // helper: convert a Go duration to a Postgres interval literal like "12.500000s".
 func toInterval(d time.Duration) string {
 	// We’ll use seconds with fractional precision.
@@ -31,11 +72,20 @@ func toInterval(d time.Duration) string {
 
 // SQL templates
 const (
+	// The id is drawn up front so the partition can be assigned round-robin from it.
 	sqlEnqueue = `
-INSERT INTO messages (queue, body, not_before, max_retries, dlq, trace_id)
-VALUES ($1, $2, now() + $3::interval, $4, $5, $6)
+WITH seq AS (SELECT nextval('messages_id_seq') AS id)
+INSERT INTO messages (id, queue, body, not_before, max_retries, dlq, trace_id, dlq_rules, group_id, partition, body_ref, coalesce_key)
+SELECT seq.id, $1, $2, coalesce($9::timestamptz, now() + $3::interval), $4, $5, $6, $7, $8,
+       seq.id % COALESCE((SELECT partitions FROM queue_configs WHERE queue = $1), 1), $10, $11
+FROM seq
+WHERE NOT EXISTS (SELECT 1 FROM sealed_groups sg WHERE sg.queue = $1 AND sg.group_id = $8)
+ON CONFLICT (queue, coalesce_key) WHERE coalesce_key IS NOT NULL DO NOTHING
 RETURNING id;`
 
+	// The pending message holding coalesce key $2 in queue $1.
+	sqlGetCoalesced = `SELECT id FROM messages WHERE queue = $1 AND coalesce_key = $2;`
+
 	// Single CTE TX pattern: pick -> update -> return rows
 	sqlClaim = `
 WITH picked AS (
@@ -47,141 +97,2462 @@ WITH picked AS (
   ORDER BY id
   FOR UPDATE SKIP LOCKED
   LIMIT $2
-),
+),` + sqlClaimLease
+
+	// Same as sqlClaim but restricted to one partition ($4) of the queue.
+	sqlClaimPartition = `
+WITH picked AS (
+  SELECT id
+  FROM messages
+  WHERE queue = $1
+    AND partition = $4
+    AND lease_until IS NULL
+    AND not_before <= now()
+  ORDER BY id
+  FOR UPDATE SKIP LOCKED
+  LIMIT $2
+),` + sqlClaimLease
+
+	// Picks like sqlClaim but deletes instead of leasing. Partitions are
+	// ignored; there is no lease for them to spread contention over.
+	sqlClaimDelete = `
+WITH picked AS (
+  SELECT id
+  FROM messages
+  WHERE queue = $1
+    AND lease_until IS NULL
+    AND not_before <= now()
+  ORDER BY id
+  FOR UPDATE SKIP LOCKED
+  LIMIT $2
+)
+DELETE FROM messages m
+USING picked
+WHERE m.id = picked.id
+RETURNING ` + messageColumns + `;`
+
+	// Claim orderings. sqlClaimVisible and sqlClaimPartitionVisible are the
+	// claim statements with the first swapped for the second, and likewise
+	// the FIFO ones. Ids are drawn before the insert commits, so concurrent
+	// enqueues can commit out of id order; enqueued_at is the enqueue time.
+	claimOrderID      = `ORDER BY id`
+	claimOrderVisible = `ORDER BY greatest(not_before, enqueued_at), id`
+	claimOrderFIFO    = `ORDER BY enqueued_at, id`
+
+	sqlClaimLease = `
 updated AS (
   UPDATE messages m
   SET lease_until   = now() + $3::interval,
-      delivery_count = m.delivery_count + 1
+      leased_at      = now(),
+      delivery_count = m.delivery_count + 1,
+      receive_count  = m.receive_count + 1,
+      lease_epoch    = m.lease_epoch + 1
   FROM picked
   WHERE m.id = picked.id
-  RETURNING m.*
+  RETURNING ` + messageColumns + `
 )
 SELECT * FROM updated;`
 
-	sqlAck = `DELETE FROM messages WHERE id = $1;`
+	// Column order must match scanMessage.
+	messageColumns = `m.id, m.queue, m.body, m.enqueued_at, m.not_before, m.lease_until,
+         m.delivery_count, m.max_retries, m.dlq, m.trace_id, m.lease_epoch, m.dlq_rules, m.dlqd_at, m.group_id,
+         m.last_error, m.failed_at, m.dlq_source, m.dlq_deliveries, m.receive_count, m.body_ref, m.coalesce_key, m.ulid,
+         EXISTS (SELECT 1 FROM sealed_groups sg WHERE sg.last_id = m.id AND sg.queue = m.queue)`
+
+	// Takes the key, or re-takes it if the previous holder expired.
+	// Affects zero rows while a live holder exists.
+	sqlHoldEnqueueKey = `
+INSERT INTO enqueue_keys (queue, scope, key, message_id, message_ulid, expires_at)
+VALUES ($1, $2, $3, $4, (SELECT ulid FROM messages WHERE id = $4), now() + $5::interval)
+ON CONFLICT (queue, scope, key) DO UPDATE
+SET message_id = EXCLUDED.message_id,
+    message_ulid = EXCLUDED.message_ulid,
+    created_at = now(),
+    expires_at = EXCLUDED.expires_at
+WHERE enqueue_keys.expires_at IS NOT NULL
+  AND enqueue_keys.expires_at <= now();`
+
+	sqlGetEnqueueKey = `
+SELECT message_id FROM enqueue_keys
+WHERE queue = $1 AND scope = $2 AND key = $3;`
+
+	// Ack IDs share the key store under scope 'ack'. They aren't tied to a
+	// queue: the ID alone names the operation.
+	sqlHoldAckID = `
+INSERT INTO enqueue_keys (queue, scope, key, message_id, message_ulid, expires_at)
+VALUES ('', 'ack', $1, $2, $4, now() + $3::interval)
+ON CONFLICT (queue, scope, key) DO UPDATE
+SET message_id = EXCLUDED.message_id,
+    message_ulid = EXCLUDED.message_ulid,
+    created_at = now(),
+    expires_at = EXCLUDED.expires_at;`
+
+	sqlGetAckID = `
+SELECT message_id FROM enqueue_keys
+WHERE queue = '' AND scope = 'ack' AND key = $1
+  AND expires_at > now();`
+
+	// Backlog the sweeper is about to work through.
+	sqlSweeperLag = `
+SELECT count(*) FROM messages
+WHERE lease_until IS NOT NULL
+  AND lease_until < now();`
+
+	// Per queue, the oldest message under a lease that hasn't run out yet;
+	// expired ones are the lag above.
+	sqlOldestInFlight = `
+SELECT queue, extract(epoch FROM now() - min(enqueued_at))::float8
+FROM messages
+WHERE lease_until > now()
+GROUP BY queue;`
+
+	sqlPruneEnqueueKeys = `DELETE FROM enqueue_keys WHERE expires_at <= now();`
+
+	// Seals of groups with no messages left, once they are $1 old.
+	sqlPruneSealedGroups = `
+DELETE FROM sealed_groups sg
+WHERE sg.sealed_at < now() - $1::interval
+  AND NOT EXISTS (
+    SELECT 1 FROM messages m WHERE m.queue = sg.queue AND m.group_id = sg.group_id);`
+
+	// Dead letters past retention; ones a worker currently holds are left alone.
+	sqlPurgeDLQ = `
+DELETE FROM messages
+WHERE dlqd_at IS NOT NULL
+  AND dlqd_at < now() - $1::interval
+  AND lease_until IS NULL;`
+
+	// Up to $2 archived copies acked longer ago than $1.
+	sqlPruneArchive = `
+DELETE FROM archived_messages
+WHERE id IN (
+  SELECT id FROM archived_messages
+  WHERE acked_at < now() - $1::interval
+  LIMIT $2
+);`
+
+	// Up to $2 messages enqueued longer ago than $1, leased or not.
+	// Dead letters are the DLQ purge's to delete.
+	sqlPurgeExpired = `
+DELETE FROM messages
+WHERE id IN (
+  SELECT id FROM messages
+  WHERE enqueued_at < now() - $1::interval
+    AND dlqd_at IS NULL
+  LIMIT $2
+  FOR UPDATE SKIP LOCKED
+);`
+
+	sqlGetQueueConfig = `
+SELECT queue, partitions, visibility_ms, max_retries, dlq, dedup_window_ms, require_object_body, claim_order, role,
+       skip_after_attempts, paused, max_in_flight, requeue_in_place, cloudevents, default_delay_ms, archive
+FROM queue_configs WHERE queue = $1;`
+
+	sqlPutQueueConfig = `
+INSERT INTO queue_configs (queue, partitions, visibility_ms, max_retries, dlq, dedup_window_ms, require_object_body, claim_order, role,
+                           skip_after_attempts, paused, max_in_flight, requeue_in_place, cloudevents, default_delay_ms, archive)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+ON CONFLICT (queue) DO UPDATE
+SET partitions          = EXCLUDED.partitions,
+    visibility_ms       = EXCLUDED.visibility_ms,
+    max_retries         = EXCLUDED.max_retries,
+    dlq                 = EXCLUDED.dlq,
+    dedup_window_ms     = EXCLUDED.dedup_window_ms,
+    require_object_body = EXCLUDED.require_object_body,
+    claim_order         = EXCLUDED.claim_order,
+    role                = EXCLUDED.role,
+    skip_after_attempts = EXCLUDED.skip_after_attempts,
+    paused              = EXCLUDED.paused,
+    max_in_flight       = EXCLUDED.max_in_flight,
+    requeue_in_place    = EXCLUDED.requeue_in_place,
+    cloudevents         = EXCLUDED.cloudevents,
+    default_delay_ms    = EXCLUDED.default_delay_ms,
+    archive             = EXCLUDED.archive,
+    updated_at          = now();`
+
+	// Touches only the flag, creating the config row with defaults if needed.
+	sqlSetPaused = `
+INSERT INTO queue_configs (queue, paused)
+VALUES ($1, $2)
+ON CONFLICT (queue) DO UPDATE
+SET paused     = EXCLUDED.paused,
+    updated_at = now();`
+
+	// Held until the claiming transaction ends, so budgeted claims on a
+	// queue take turns.
+	sqlLockInFlight = `SELECT pg_advisory_xact_lock(hashtext('in_flight:' || $1));`
+
+	sqlCountInFlight = `SELECT count(*) FROM messages WHERE queue = $1 AND lease_until IS NOT NULL;`
+
+	sqlGetSubscriptions = `SELECT queue FROM topic_subscriptions WHERE topic = $1 ORDER BY queue;`
+
+	sqlDeleteSubscriptions = `DELETE FROM topic_subscriptions WHERE topic = $1;`
+
+	sqlInsertSubscriptions = `
+INSERT INTO topic_subscriptions (topic, queue)
+SELECT $1, unnest($2::text[]);`
+
+	sqlPutPushSubscription = `
+INSERT INTO push_subscriptions (queue, url)
+VALUES ($1, $2)
+ON CONFLICT (queue) DO UPDATE
+SET url        = EXCLUDED.url,
+    created_at = now()
+RETURNING queue, url, created_at;`
+
+	sqlDeletePushSubscription = `DELETE FROM push_subscriptions WHERE queue = $1;`
+
+	sqlPushSubscriptions = `SELECT queue, url, created_at FROM push_subscriptions ORDER BY queue;`
+
+	sqlReadAfter = `
+SELECT ` + messageColumns + `
+FROM messages m
+WHERE m.queue = $1
+  AND m.id > $2
+ORDER BY m.id
+LIMIT $3;`
+
+	sqlGetCheckpoint = `
+SELECT last_id, updated_at FROM consumer_checkpoints
+WHERE queue = $1 AND consumer_group = $2;`
+
+	sqlSetCheckpoint = `
+INSERT INTO consumer_checkpoints (queue, consumer_group, last_id)
+VALUES ($1, $2, $3)
+ON CONFLICT (queue, consumer_group) DO UPDATE
+SET last_id    = EXCLUDED.last_id,
+    updated_at = now()
+RETURNING last_id, updated_at;`
+
+	sqlListQueues = `
+SELECT DISTINCT queue FROM messages
+UNION
+SELECT queue FROM queue_configs
+UNION
+SELECT name FROM queues
+ORDER BY 1;`
+
+	sqlQueueRegistered = `SELECT EXISTS (SELECT 1 FROM queues WHERE name = $1);`
+
+	// Held until the registering transaction ends, so two new queues can't
+	// both take the last place under the limit.
+	sqlLockQueueRegistry = `SELECT pg_advisory_xact_lock(hashtext('queues'));`
+
+	sqlCountQueues = `SELECT count(*) FROM queues;`
+
+	sqlRegisterQueue = `INSERT INTO queues (name) VALUES ($1) ON CONFLICT DO NOTHING;`
+
+	// Every queue's leases, expired ones included until they are swept;
+	// served by idx_messages_inflight.
+	sqlCountTotalInFlight = `SELECT count(*) FROM messages WHERE lease_until IS NOT NULL;`
+
+	sqlStats = `
+SELECT
+  count(*) FILTER (WHERE lease_until IS NULL AND not_before <= now()),
+  count(*) FILTER (WHERE lease_until IS NOT NULL),
+  count(*) FILTER (WHERE lease_until IS NULL AND not_before > now())
+FROM messages
+WHERE queue = $1;`
+
+	// Delayed messages that were delivered before are waiting out a
+	// redelivery backoff or a nack's delay.
+	sqlDiagnose = `
+SELECT
+  count(*) FILTER (WHERE lease_until IS NULL AND not_before <= now()),
+  count(*) FILTER (WHERE lease_until >= now()),
+  count(*) FILTER (WHERE lease_until < now()),
+  count(*) FILTER (WHERE lease_until IS NULL AND not_before > now() AND delivery_count = 0),
+  count(*) FILTER (WHERE lease_until IS NULL AND not_before > now() AND delivery_count > 0),
+  min(not_before) FILTER (WHERE lease_until IS NULL AND not_before > now())
+FROM messages
+WHERE queue = $1;`
+
+	// Whether an earlier message of the messages row's group is still in
+	// the queue, as sqlAck checks it.
+	sqlGroupBlocked = `EXISTS (
+    SELECT 1 FROM messages e
+    WHERE messages.group_id IS NOT NULL
+      AND e.queue = messages.queue
+      AND e.group_id = messages.group_id
+      AND e.id < messages.id
+      AND e.delivery_count < coalesce(
+        (SELECT nullif(c.skip_after_attempts, 0) FROM queue_configs c WHERE c.queue = messages.queue),
+        2147483647))`
+
+	// A grouped message is only deleted once no earlier message of its
+	// group remains.
+	// An earlier group-mate that has used up the queue's skip_after_attempts
+	// no longer holds the group up.
+	// On a queue with archive set the message is copied to archived_messages
+	// as it goes.
+	sqlAck = `
+WITH acked AS (
+DELETE FROM messages m
+WHERE m.id = $1
+  AND m.lease_epoch = $2
+  AND NOT EXISTS (
+    SELECT 1 FROM messages e
+    WHERE m.group_id IS NOT NULL
+      AND e.queue = m.queue
+      AND e.group_id = m.group_id
+      AND e.id < m.id
+      AND e.delivery_count < coalesce(
+        (SELECT nullif(c.skip_after_attempts, 0) FROM queue_configs c WHERE c.queue = m.queue),
+        2147483647))
+RETURNING m.*
+), archived AS (
+INSERT INTO archived_messages (id, ulid, queue, body, body_ref, trace_id, group_id, delivery_count, enqueued_at)
+SELECT a.id, a.ulid, a.queue, a.body, a.body_ref, a.trace_id, a.group_id, a.delivery_count, a.enqueued_at
+FROM acked a
+WHERE EXISTS (SELECT 1 FROM queue_configs c WHERE c.queue = a.queue AND c.archive)
+)
+SELECT queue, enqueued_at, ulid FROM acked;`
+
+	// Up to $6 archived copies, oldest ack first; a NULL filter matches all.
+	sqlArchivedMessages = `
+SELECT id, ulid, queue, body, body_ref, trace_id, group_id, delivery_count, enqueued_at, acked_at
+FROM archived_messages
+WHERE ($1::text IS NULL OR queue = $1)
+  AND ($2::timestamptz IS NULL OR acked_at >= $2)
+  AND ($3::timestamptz IS NULL OR acked_at < $3)
+  AND ($4::bigint[] IS NULL OR id = ANY($4))
+  AND ($5::text[] IS NULL OR ulid = ANY($5))
+ORDER BY acked_at, id
+LIMIT $6;`
+
+	// Only leases that are still live and still held by the receipt.
+	// Clears leased_at: a lease its consumer renewed didn't run out on it,
+	// so it's no rapid requeue whatever it's renewed to.
+	sqlExtendBatch = `
+UPDATE messages m
+SET lease_until = now() + $3::interval,
+    leased_at   = NULL
+FROM unnest($1::bigint[], $2::bigint[]) AS r(id, epoch)
+WHERE m.id = r.id
+  AND m.lease_epoch = r.epoch
+  AND m.lease_until > now()
+RETURNING m.id;`
+
+	// Ends a live lease now; the sweeper takes it from there. A nack without
+	// a reason keeps the last one given.
+	sqlNack = `
+UPDATE messages
+SET lease_until = now(),
+    last_error  = coalesce(nullif($3, ''), last_error),
+    failed_at   = now()
+WHERE id = $1
+  AND lease_epoch = $2
+  AND lease_until > now()
+RETURNING queue;`
+
+	// A terminal nack at the retry limit: the move the sweeper's DLQ pass
+	// would make, done now and only for this live lease.
+	sqlNackToDLQ = `
+WITH doomed AS (
+  SELECT id, sqs_dlq_target(dlq, dlq_rules, delivery_count) AS dlq,
+         body, body_ref, enqueued_at, max_retries, trace_id, group_id,
+         last_error, queue AS source, delivery_count, receive_count
+  FROM messages
+  WHERE id = $1
+    AND lease_epoch = $2
+    AND lease_until > now()
+    AND delivery_count >= max_retries
+    AND sqs_dlq_target(dlq, dlq_rules, delivery_count) IS NOT NULL
+  FOR UPDATE
+),
+inserted AS (
+  INSERT INTO messages (queue, body, body_ref, enqueued_at, max_retries, trace_id, group_id, delivery_count, dlqd_at,
+                        last_error, failed_at, dlq_source, dlq_deliveries, receive_count)
+  SELECT dlq, body, body_ref, enqueued_at, max_retries, trace_id, group_id, 0, now(),
+         coalesce(nullif($3, ''), last_error), now(), source, delivery_count, receive_count
+  FROM doomed
+)
+DELETE FROM messages m
+USING doomed d
+WHERE m.id = d.id
+RETURNING m.queue, d.dlq;`
+
+	sqlLeaseEpoch = `SELECT lease_epoch FROM messages WHERE id = $1;`
+
+	// Sealing twice keeps the first seal's last_id.
+	sqlSealGroup = `
+INSERT INTO sealed_groups (queue, group_id, last_id)
+SELECT $1, $2, max(id) FROM messages WHERE queue = $1 AND group_id = $2
+ON CONFLICT (queue, group_id) DO NOTHING;`
+
+	sqlSealedGroupLast = `SELECT last_id FROM sealed_groups WHERE queue = $1 AND group_id = $2;`
+
+	sqlQueueHasMessages = `SELECT EXISTS (SELECT 1 FROM messages WHERE queue = $1);`
+
+	// A plain rename takes the old queue's config along, replacing any the
+	// new name had; a merge keeps the new name's.
+	sqlDropRenameTargetConfig = `
+DELETE FROM queue_configs
+WHERE queue = $2
+  AND EXISTS (SELECT 1 FROM queue_configs WHERE queue = $1);`
+
+	// Partitions are recomputed for the config the messages end up under.
+	// On a merge, a moved message whose coalesce key is already pending in
+	// the target keeps its place but gives up the key.
+	sqlRenameMessages = `
+UPDATE messages
+SET queue     = $2,
+    partition = id % COALESCE((SELECT partitions FROM queue_configs WHERE queue = $2), 1),
+    coalesce_key = CASE WHEN EXISTS (SELECT 1 FROM messages t WHERE t.queue = $2 AND t.coalesce_key = messages.coalesce_key)
+                        THEN NULL ELSE coalesce_key END
+WHERE queue = $1;`
+
+	sqlDeadLetters = `
+SELECT ` + messageColumns + `
+FROM messages m
+WHERE m.queue = $1
+  AND m.dlqd_at IS NOT NULL
+ORDER BY m.dlqd_at DESC, m.id
+LIMIT $2;`
+
+	sqlDelayedMessages = `
+SELECT ` + messageColumns + `
+FROM messages m
+WHERE m.queue = $1
+  AND m.lease_until IS NULL
+  AND m.not_before > now()
+ORDER BY m.not_before, m.id
+LIMIT $2;`
+
+	// receive_count, unlike delivery_count, is never reset, so a redriven
+	// or reset message still counts as delivered.
+	sqlCancelMessage = `
+DELETE FROM messages
+WHERE id = $1
+  AND ($2 OR (receive_count = 0 AND lease_until IS NULL))
+RETURNING queue;`
+
+	sqlMessageExists = `SELECT EXISTS (SELECT 1 FROM messages WHERE id = $1);`
+
+	sqlGetMessage = `SELECT ` + messageColumns + ` FROM messages m WHERE m.id = $1;`
+
+	// Gone messages are remembered by the keys that outlive them.
+	sqlMessageULIDs = `
+SELECT id, ulid FROM messages WHERE id = ANY($1)
+UNION
+SELECT message_id, message_ulid FROM enqueue_keys
+WHERE message_id = ANY($1) AND message_ulid IS NOT NULL;`
+
+	sqlMessageIDsByULID = `
+SELECT id, ulid FROM messages WHERE ulid = ANY($1)
+UNION
+SELECT message_id, message_ulid FROM enqueue_keys
+WHERE message_ulid = ANY($1);`
+
+	// Same "never delivered" test as sqlCancelMessage. The new body is
+	// inline, so a claim check the message had is dropped.
+	sqlUpdateBody = `
+UPDATE messages m
+SET body = $2, body_ref = NULL
+WHERE m.id = $1
+  AND m.receive_count = 0
+  AND m.lease_until IS NULL
+RETURNING ` + messageColumns + `;`
+
+	// Leases one message by id, like sqlClaimLease does a picked batch.
+	// A row another claim has locked is skipped, as it is about to be leased.
+	sqlClaimByID = `
+UPDATE messages m
+SET lease_until    = now() + $2::interval,
+    leased_at      = now(),
+    delivery_count = m.delivery_count + 1,
+    receive_count  = m.receive_count + 1,
+    lease_epoch    = m.lease_epoch + 1
+WHERE m.id = (
+  SELECT id FROM messages
+  WHERE id = $1
+    AND lease_until IS NULL
+    AND not_before <= now()
+    AND NOT ` + sqlGroupBlocked + `
+  FOR UPDATE SKIP LOCKED
+)
+RETURNING ` + messageColumns + `;`
+
+	// Whether sqlClaimByID can lease the message: its queue, and whether it
+	// is leased, delayed or waiting on its group. No row means it is gone.
+	sqlClaimableState = `
+SELECT queue, lease_until IS NOT NULL, not_before > now(), ` + sqlGroupBlocked + `
+FROM messages WHERE id = $1;`
+
+	// Sends dead letters back to the queue they failed in, or to $3 when
+	// set, with fresh retries, dead-lettering to this queue again if they
+	// fail there. receive_count is kept.
+	sqlRedrive = `
+WITH picked AS (
+  SELECT id FROM messages
+  WHERE queue = $1
+    AND dlq_source IS NOT NULL
+    AND lease_until IS NULL
+  ORDER BY id
+  FOR UPDATE SKIP LOCKED
+  LIMIT $2
+)
+UPDATE messages m
+SET queue          = COALESCE(NULLIF($3, ''), m.dlq_source),
+    partition      = m.id % COALESCE((SELECT partitions FROM queue_configs
+                                      WHERE queue = COALESCE(NULLIF($3, ''), m.dlq_source)), 1),
+    dlq            = m.queue,
+    delivery_count = 0,
+    not_before     = now(),
+    dlqd_at        = NULL,
+    dlq_source     = NULL,
+    dlq_deliveries = NULL,
+    lease_epoch    = m.lease_epoch + 1
+FROM picked
+WHERE m.id = picked.id
+RETURNING m.id, m.queue;`
+
+	sqlAckCheck = `
+SELECT m.lease_epoch,
+       m.group_id IS NOT NULL AND EXISTS (
+         SELECT 1 FROM messages e
+         WHERE e.queue = m.queue
+           AND e.group_id = m.group_id
+           AND e.id < m.id
+           AND e.delivery_count < coalesce(
+             (SELECT nullif(c.skip_after_attempts, 0) FROM queue_configs c WHERE c.queue = m.queue),
+             2147483647))
+FROM messages m
+WHERE m.id = $1;`
+
+	// Undoes a claim: the delivery is uncounted and the epoch bumped in case
+	// part of a response did get out.
+	sqlRelease = `
+UPDATE messages m
+SET lease_until    = NULL,
+    delivery_count = greatest(m.delivery_count - 1, 0),
+    receive_count  = greatest(m.receive_count - 1, 0),
+    lease_epoch    = m.lease_epoch + 1
+FROM unnest($1::bigint[], $2::bigint[]) AS r(id, epoch)
+WHERE m.id = r.id
+  AND m.lease_epoch = r.epoch
+  AND m.lease_until IS NOT NULL
+RETURNING m.id;`
+
+	// Ends every lease in queue $1, keeping the delivery each one counted.
+	// Messages with deliveries left are available at once; exhausted ones
+	// are left expired for the sweeper to dead-letter as usual. Bumps
+	// lease_epoch so the receipts handed out can't ack, and clears leased_at
+	// so the leases it ends aren't counted as rapid requeues.
+	sqlReleaseQueueLeases = `
+UPDATE messages
+SET lease_until = CASE WHEN delivery_count < max_retries THEN NULL ELSE least(lease_until, now()) END,
+    leased_at   = NULL,
+    lease_epoch = lease_epoch + 1
+WHERE queue = $1
+  AND lease_until IS NOT NULL;`
+
+	// Bumps lease_epoch so the receipt of a lease this ends can't ack.
+	sqlResetDeliveryCount = `
+UPDATE messages
+SET delivery_count = 0,
+    lease_until    = NULL,
+    not_before     = now(),
+    lease_epoch    = lease_epoch + 1
+WHERE id = $1
+RETURNING queue;`
 
  	sqlSweeperRequeue = `WITH expired AS (
-		SELECT id
+		-- rapid: the lease ran out within $5 of being taken; a nack ended it
+		-- on purpose, so one that failed since isn't counted, and extends and
+		-- admin releases clear leased_at
+		SELECT id, coalesce(lease_until - leased_at < $5::interval
+				AND (failed_at IS NULL OR failed_at < leased_at), false) AS rapid
 		FROM messages
 		WHERE lease_until IS NOT NULL
 			AND lease_until < now()
-			AND (delivery_count < max_retries OR dlq IS NULL)
+			AND (delivery_count < least(max_retries, nullif($1::int, 0))
+				-- with a router ($3), exhausted messages are all its to route
+				OR (NOT $3::bool
+					AND sqs_dlq_target(dlq, dlq_rules, delivery_count) IS NULL
+					AND NOT ($1 > 0 AND delivery_count >= $1)))
+		LIMIT $4
 		FOR UPDATE SKIP LOCKED
 		)
 		UPDATE messages
-		SET lease_until = NULL
-		WHERE id IN (SELECT id FROM expired)
+		SET lease_until = NULL,
+			-- $2 holds the backoff in ms by delivery count; NULL for none
+			not_before = CASE WHEN $2::bigint[] IS NULL OR EXISTS (
+					SELECT 1 FROM queue_configs c WHERE c.queue = messages.queue AND c.requeue_in_place)
+				THEN not_before
+				ELSE now() + ($2::bigint[])[least(greatest(delivery_count, 1), cardinality($2::bigint[]))]
+					* interval '1 millisecond'
+				END
+		FROM expired e
+		WHERE messages.id = e.id
+		RETURNING messages.queue, e.rapid
 		`
 	sqlSweeperDLQ = `WITH expired_for_dlq AS (
-			SELECT id, dlq, body, enqueued_at, max_retries, trace_id
+			SELECT id, sqs_dlq_target(dlq, dlq_rules, delivery_count) AS dlq,
+				body, body_ref, enqueued_at, max_retries, trace_id, group_id,
+				last_error, failed_at, queue AS source, delivery_count, receive_count
 			FROM messages
 			WHERE lease_until IS NOT NULL
 				AND lease_until < NOW()
-				AND delivery_count >= max_retries
-				AND dlq IS NOT NULL
+				AND delivery_count >= least(max_retries, nullif($1::int, 0))
+				AND sqs_dlq_target(dlq, dlq_rules, delivery_count) IS NOT NULL
+			LIMIT $2
 			FOR UPDATE SKIP LOCKED
 		),
 		inserted AS (
-			INSERT INTO messages (queue, body, enqueued_at, max_retries, trace_id, delivery_count)
-			SELECT dlq, body, enqueued_at, max_retries, trace_id,0 
+			INSERT INTO messages (queue, body, body_ref, enqueued_at, max_retries, trace_id, group_id, delivery_count, dlqd_at,
+				last_error, failed_at, dlq_source, dlq_deliveries, receive_count)
+			SELECT dlq, body, body_ref, enqueued_at, max_retries, trace_id, group_id, 0, now(),
+				last_error, failed_at, source, delivery_count, receive_count
 			FROM expired_for_dlq
 			RETURNING id
 )
-		DELETE FROM messages
-		WHERE id IN (SELECT id FROM expired_for_dlq)`
+		DELETE FROM messages m
+		USING expired_for_dlq e
+		WHERE m.id = e.id
+		RETURNING m.id, m.queue, e.dlq, e.max_retries`
+
+	// Exhausted messages for a DLQRouter to route, oldest first.
+	sqlSweeperExhausted = `
+SELECT ` + messageColumns + `
+FROM messages m
+WHERE m.lease_until IS NOT NULL
+  AND m.lease_until < now()
+  AND m.delivery_count >= least(m.max_retries, nullif($1::int, 0))
+ORDER BY m.id
+LIMIT $2
+FOR UPDATE OF m SKIP LOCKED;`
+
+	// The move sqlSweeperDLQ makes, for one message and a DLQ picked by
+	// the router.
+	sqlRouteToDLQ = `
+WITH doomed AS (
+  SELECT id, body, body_ref, enqueued_at, max_retries, trace_id, group_id,
+         last_error, failed_at, queue AS source, delivery_count, receive_count
+  FROM messages
+  WHERE id = $1
+),
+inserted AS (
+  INSERT INTO messages (queue, body, body_ref, enqueued_at, max_retries, trace_id, group_id, delivery_count, dlqd_at,
+                        last_error, failed_at, dlq_source, dlq_deliveries, receive_count)
+  SELECT $2, body, body_ref, enqueued_at, max_retries, trace_id, group_id, 0, now(),
+         last_error, failed_at, source, delivery_count, receive_count
+  FROM doomed
+)
+DELETE FROM messages m
+USING doomed d
+WHERE m.id = d.id;`
+
+	sqlRouteRequeue = `
+UPDATE messages m
+SET lease_until = NULL,
+    not_before  = CASE WHEN EXISTS (
+                    SELECT 1 FROM queue_configs c WHERE c.queue = m.queue AND c.requeue_in_place)
+                  THEN m.not_before
+                  ELSE now() + $2::bigint * interval '1 millisecond'
+                  END
+WHERE m.id = $1;`
+
+	sqlRouteDrop = `DELETE FROM messages WHERE id = $1;`
+
+	// Up to $2 messages at the delivery ceiling with nowhere to be
+	// dead-lettered; requeueing them would let them circulate forever.
+	sqlSweeperDropCapped = `
+DELETE FROM messages
+WHERE id IN (
+  SELECT id FROM messages
+  WHERE lease_until IS NOT NULL
+    AND lease_until < now()
+    AND delivery_count >= $1
+    AND sqs_dlq_target(dlq, dlq_rules, delivery_count) IS NULL
+  LIMIT $2
+  FOR UPDATE SKIP LOCKED
+)
+RETURNING queue;`
 
 )
 
+// backoffSteps is how many delivery counts backoffTable spells out; later
+// deliveries get the last entry.
+const backoffSteps = 64
+
+// backoffTable lists b's delay in milliseconds for delivery counts 1 to
+// backoffSteps, for the requeue to index; nil if b never delays.
+func backoffTable(b queue.Backoff) []int64 {
+	if b.Base <= 0 {
+		return nil
+	}
+	delays := make([]int64, backoffSteps)
+	for i := range delays {
+		delays[i] = b.Delay(i + 1).Milliseconds()
+	}
+	return delays
+}
+
 // Enqueue inserts a message with optional delay.
 func (p *PostgresStore) Enqueue(ctx context.Context, m queue.Message, delay time.Duration) (int64, error) {
-	// TODO: set sensible defaults if m.MaxRetries == 0, etc.
-	if m.MaxRetries == 0{
-		m.MaxRetries = 5
+	id, _, err := p.EnqueueCoalesced(ctx, m, delay)
+	return id, err
+}
+
+// EnqueueCoalesced inserts the message unless another one with its
+// CoalesceKey is pending in the queue, in which case that one's ID is
+// returned and nothing is added.
+func (p *PostgresStore) EnqueueCoalesced(ctx context.Context, m queue.Message, delay time.Duration) (int64, bool, error) {
+	id, coalesced, err := insertMessage(ctx, p.pool, m, delay)
+	if err != nil {
+		return 0, false, err
+	}
+	if !coalesced {
+		events.Publish(events.Event{Type: events.Enqueued, Queue: m.Queue, ID: id})
+	}
+	return id, coalesced, nil
+}
+
+// EnqueueKeyed inserts the message and records key in one transaction. If the
+// key is already held (and not expired) the insert is rolled back and the
+// message ID the key first produced is returned instead.
+func (p *PostgresStore) EnqueueKeyed(ctx context.Context, m queue.Message, delay time.Duration, key queue.EnqueueKey) (int64, bool, error) {
+	var ttl *string
+	if key.TTL > 0 {
+		iv := toInterval(key.TTL)
+		ttl = &iv
+	}
+
+	tx, err := p.pool.Begin(ctx)
+	if err != nil {
+		return 0, false, err
+	}
+	defer tx.Rollback(ctx)
+
+	id, _, err := insertMessage(ctx, tx, m, delay)
+	if err != nil {
+		return 0, false, err
+	}
+	tag, err := tx.Exec(ctx, sqlHoldEnqueueKey, m.Queue, key.Scope, key.Key, id, ttl)
+	if err != nil {
+		return 0, false, fmt.Errorf("hold enqueue key: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		// Someone else holds the key: drop our insert and report theirs.
+		_ = tx.Rollback(ctx)
+		var existing int64
+		err := p.pool.QueryRow(ctx, sqlGetEnqueueKey, m.Queue, key.Scope, key.Key).Scan(&existing)
+		if err != nil {
+			return 0, false, fmt.Errorf("lookup enqueue key: %w", err)
+		}
+		return existing, true, nil
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, false, err
+	}
+
+	events.Publish(events.Event{Type: events.Enqueued, Queue: m.Queue, ID: id})
+	return id, false, nil
+}
+
+// EnqueueBatch inserts every entry in a single transaction.
+func (p *PostgresStore) EnqueueBatch(ctx context.Context, entries []queue.EnqueueEntry) ([]int64, error) {
+	tx, err := p.pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	ids := make([]int64, len(entries))
+	added := make([]bool, len(entries)) // false for entries that coalesced
+	for i, e := range entries {
+		id, coalesced, err := insertMessage(ctx, tx, e.Message, e.Delay)
+		if errors.Is(err, queue.ErrGroupSealed) || errors.Is(err, queue.ErrInvalidBody) {
+			return nil, &queue.EntryError{Index: i, Err: err}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("enqueue entry %d: %w", i, err)
+		}
+		ids[i], added[i] = id, !coalesced
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	for i, e := range entries {
+		if !added[i] {
+			continue
+		}
+		events.Publish(events.Event{Type: events.Enqueued, Queue: e.Message.Queue, ID: ids[i]})
 	}
-	
+	return ids, nil
+}
+
+// querier is satisfied by both *pgxpool.Pool and pgx.Tx.
+type querier interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// insertMessage runs sqlEnqueue on q and returns the new ID, or the ID of
+// the pending message holding m's coalesce key, with coalesced set.
+func insertMessage(ctx context.Context, q querier, m queue.Message, delay time.Duration) (id int64, coalesced bool, err error) {
+	// MaxRetries is stored as given, 0 included: defaults are the caller's,
+	// so the server's DEFAULT_MAX_RETRIES is the only one.
 	interval := toInterval(delay)
 
-	var id int64
-	err := p.pool.QueryRow(ctx, sqlEnqueue,
-		m.Queue,
-		m.Body,
-		interval,     // $3 interval
-		m.MaxRetries, // $4
-		m.DLQ,        // $5
-		m.TraceID,    // $6
-	).Scan(&id)
-	return id, err
+	// Checked here rather than left to the jsonb cast, whose errors don't
+	// say which message or why.
+	if err := queue.ValidateBody(m.Body); err != nil {
+		return 0, false, err
+	}
+
+	rules, err := encodeDLQRules(m.DLQRules)
+	if err != nil {
+		return 0, false, err
+	}
+
+	// An absolute NotBefore is used as is, instead of the delay.
+	var notBefore *time.Time
+	if !m.NotBefore.IsZero() {
+		notBefore = &m.NotBefore
+	}
+
+	// No row back means the group is sealed or the coalesce key is held.
+	// If the key's holder is gone by the time it is looked up (acked in
+	// between), the insert is tried once more.
+	for attempt := 0; attempt < 2; attempt++ {
+		err = q.QueryRow(ctx, sqlEnqueue,
+			m.Queue,
+			m.Body,
+			interval,      // $3 interval
+			m.MaxRetries,  // $4
+			m.DLQ,         // $5
+			m.TraceID,     // $6
+			rules,         // $7 jsonb or NULL
+			m.GroupID,     // $8
+			notBefore,     // $9 timestamptz or NULL
+			m.BodyRef,     // $10
+			m.CoalesceKey, // $11
+		).Scan(&id)
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return id, false, err
+		}
+		if m.CoalesceKey == nil {
+			break
+		}
+		err = q.QueryRow(ctx, sqlGetCoalesced, m.Queue, *m.CoalesceKey).Scan(&id)
+		if err == nil {
+			return id, true, nil
+		}
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return 0, false, fmt.Errorf("lookup coalesce key: %w", err)
+		}
+	}
+	return 0, false, queue.ErrGroupSealed
+}
+
+// dlqRule is the JSON shape sqs_dlq_target reads from messages.dlq_rules.
+type dlqRule struct {
+	MinDeliveries int    `json:"min_deliveries,omitempty"`
+	MaxDeliveries int    `json:"max_deliveries,omitempty"`
+	DLQ           string `json:"dlq"`
+}
+
+// encodeDLQRules returns the dlq_rules column value: nil (NULL) for no rules.
+func encodeDLQRules(rules []queue.DLQRule) ([]byte, error) {
+	if len(rules) == 0 {
+		return nil, nil
+	}
+	rows := make([]dlqRule, len(rules))
+	for i, r := range rules {
+		rows[i] = dlqRule{MinDeliveries: r.MinDeliveries, MaxDeliveries: r.MaxDeliveries, DLQ: r.DLQ}
+	}
+	return json.Marshal(rows)
+}
+
+func decodeDLQRules(raw []byte) ([]queue.DLQRule, error) {
+	if raw == nil {
+		return nil, nil
+	}
+	var rows []dlqRule
+	if err := json.Unmarshal(raw, &rows); err != nil {
+		return nil, fmt.Errorf("decode dlq_rules: %w", err)
+	}
+	rules := make([]queue.DLQRule, len(rows))
+	for i, r := range rows {
+		rules[i] = queue.DLQRule{MinDeliveries: r.MinDeliveries, MaxDeliveries: r.MaxDeliveries, DLQ: r.DLQ}
+	}
+	return rules, nil
 }
 
+var (
+	sqlClaimVisible          = strings.Replace(sqlClaim, claimOrderID, claimOrderVisible, 1)
+	sqlClaimPartitionVisible = strings.Replace(sqlClaimPartition, claimOrderID, claimOrderVisible, 1)
+	sqlClaimDeleteVisible    = strings.Replace(sqlClaimDelete, claimOrderID, claimOrderVisible, 1)
+	sqlClaimFIFO             = strings.Replace(sqlClaim, claimOrderID, claimOrderFIFO, 1)
+	sqlClaimDeleteFIFO       = strings.Replace(sqlClaimDelete, claimOrderID, claimOrderFIFO, 1)
+)
+
 // Claim leases up to opts.Limit messages for opts.Visibility.
+//
+// For partitioned queues it starts at a random partition and walks the rest,
+// so concurrent receivers mostly lock different rows. A final unfiltered pass
+// picks up anything left in partitions beyond the current count (e.g. after
+// the queue was reconfigured with fewer partitions).
 func (p *PostgresStore) Claim(ctx context.Context, opts queue.ClaimOptions) ([]queue.Message, error) {
 	interval := toInterval(opts.Visibility)
 
-	rows, err := p.pool.Query(ctx, sqlClaim, opts.Queue, opts.Limit, interval)
+	qcfg, err := p.GetQueueConfig(ctx, opts.Queue)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
+	if qcfg.Paused {
+		return nil, nil
+	}
+
+	whole, part := sqlClaim, sqlClaimPartition
+	switch qcfg.ClaimOrder {
+	case queue.ClaimOrderVisibleAt:
+		whole, part = sqlClaimVisible, sqlClaimPartitionVisible
+	case queue.ClaimOrderFIFO:
+		whole = sqlClaimFIFO
+	}
 
 	var out []queue.Message
-	for rows.Next() {
-		var m queue.Message
-		// NOTE: Column order must match RETURNING m.* (table order).
-		err = rows.Scan(
-			&m.ID,
-			&m.Queue,
-			&m.Body,
-			&m.EnqueuedAt,
-			&m.NotBefore,
-			&m.LeaseUntil,
-			&m.DeliveryCount,
-			&m.MaxRetries,
-			&m.DLQ,
-			&m.TraceID,
-		)
+	if qcfg.MaxInFlight > 0 {
+		out, err = p.claimBudgeted(ctx, whole, opts, qcfg.MaxInFlight)
 		if err != nil {
 			return nil, err
 		}
-		out = append(out, m)
+	} else if qcfg.Partitions <= 1 || qcfg.ClaimOrder == queue.ClaimOrderFIFO {
+		// A FIFO queue is claimed in one ordered scan whatever its partitions;
+		// walking them would hand out later messages ahead of earlier ones.
+		out, err = p.claim(ctx, whole, opts.Queue, opts.Limit, interval)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		start := rand.IntN(qcfg.Partitions)
+		for i := 0; i < qcfg.Partitions && len(out) < opts.Limit; i++ {
+			n := (start + i) % qcfg.Partitions
+			got, err := p.claim(ctx, part, opts.Queue, opts.Limit-len(out), interval, n)
+			if err != nil {
+				p.abandon(ctx, out)
+				return nil, err
+			}
+			out = append(out, got...)
+		}
+		if len(out) < opts.Limit {
+			got, err := p.claim(ctx, whole, opts.Queue, opts.Limit-len(out), interval)
+			if err != nil {
+				p.abandon(ctx, out)
+				return nil, err
+			}
+			out = append(out, got...)
+		}
+	}
+	if qcfg.ClaimOrder == queue.ClaimOrderFIFO {
+		sortFIFO(out)
 	}
-	return out, rows.Err()
-}
 
-// Ack deletes the message by its ID.
-func (p *PostgresStore) Ack(ctx context.Context, id int64) (bool, error) {
-	ct, err := p.pool.Exec(ctx, sqlAck, id)
-	if err != nil {
-		return false, err
+	for _, m := range out {
+		events.Publish(events.Event{Type: events.Received, Queue: m.Queue, ID: m.ID})
 	}
-	return ct.RowsAffected() > 0, nil
+	return out, nil
 }
 
-func (p *PostgresStore) Sweeper(ctx context.Context) (int, error) {
-	var totalProcessed int
+// claimBudgeted claims at most budget minus the queue's current leases. The
+// advisory lock makes each budgeted claim count the leases the one before it
+// committed, so concurrent receives can't overshoot together. With claims
+// taking turns there's no contention left for partitions to spread, so the
+// queue is claimed in one scan.
+func (p *PostgresStore) claimBudgeted(ctx context.Context, sql string, opts queue.ClaimOptions, budget int) ([]queue.Message, error) {
+	tx, err := p.pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
 
+	if _, err := tx.Exec(ctx, sqlLockInFlight, opts.Queue); err != nil {
+		return nil, fmt.Errorf("lock in-flight budget: %w", err)
+	}
+	var inFlight int
+	if err := tx.QueryRow(ctx, sqlCountInFlight, opts.Queue).Scan(&inFlight); err != nil {
+		return nil, fmt.Errorf("count in flight: %w", err)
+	}
+	limit := min(opts.Limit, budget-inFlight)
+	if limit <= 0 {
+		return nil, nil
+	}
 
-	tag, err := p.pool.Exec(ctx, sqlSweeperRequeue)
+	out, err := claimRows(ctx, tx, sql, opts.Queue, limit, toInterval(opts.Visibility))
 	if err != nil {
-		return 0, fmt.Errorf("Sweep requeued, %w", err)
+		return nil, err
 	}
-	requeuedCount := int(tag.RowsAffected())
-	totalProcessed += requeuedCount
-	if requeuedCount > 0 {
-		metrics.MessagesRequeued.Add(float64(requeuedCount))
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
 	}
+	return out, nil
+}
 
-	// now handle dlq
+// ClaimMulti claims each queue's weighted share, then hands slots left by
+// queues that ran dry to the ones that filled their share.
+func (p *PostgresStore) ClaimMulti(ctx context.Context, opts queue.ClaimMultiOptions) ([]queue.Message, error) {
+	weights := make([]int, len(opts.Queues))
+	for i, q := range opts.Queues {
+		weights[i] = q.Weight
+	}
+	alloc := queue.AllocateClaims(weights, opts.Limit)
 
-	tag, err = p.pool.Exec(ctx, sqlSweeperDLQ)
-	if err != nil {
-		return 0, fmt.Errorf("Sweep DLQ %w", err)
+	var out []queue.Message
+	full := make([]bool, len(opts.Queues))
+	for i, q := range opts.Queues {
+		msgs, err := p.Claim(ctx, queue.ClaimOptions{Queue: q.Queue, Limit: alloc[i], Visibility: q.Visibility})
+		if err != nil {
+			p.abandon(ctx, out)
+			return nil, err
+		}
+		out = append(out, msgs...)
+		full[i] = len(msgs) == alloc[i]
+	}
+
+	for i, q := range opts.Queues {
+		spare := opts.Limit - len(out)
+		if spare <= 0 {
+			break
+		}
+		if !full[i] {
+			continue
+		}
+		msgs, err := p.Claim(ctx, queue.ClaimOptions{Queue: q.Queue, Limit: spare, Visibility: q.Visibility})
+		if err != nil {
+			p.abandon(ctx, out)
+			return nil, err
+		}
+		out = append(out, msgs...)
+	}
+	return out, nil
+}
+
+// releaseTimeout bounds a release run after the caller's context is done.
+const releaseTimeout = 5 * time.Second
+
+// abandon releases messages claimed earlier in a multi-statement claim that
+// then failed, so they aren't stuck leased to nobody until the sweeper.
+// It runs even if ctx is already cancelled, which is the usual reason.
+func (p *PostgresStore) abandon(ctx context.Context, msgs []queue.Message) {
+	if len(msgs) == 0 {
+		return
+	}
+	rcs := make([]queue.Receipt, len(msgs))
+	for i, m := range msgs {
+		rcs[i] = m.Receipt()
+	}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), releaseTimeout)
+	defer cancel()
+	if _, err := p.Release(ctx, rcs); err != nil {
+		log.Printf("release %d abandoned lease(s): %v", len(rcs), err)
+	}
+}
+
+// Release clears the leases named by rcs in a single UPDATE.
+func (p *PostgresStore) Release(ctx context.Context, rcs []queue.Receipt) ([]int64, error) {
+	ids := make([]int64, len(rcs))
+	epochs := make([]int64, len(rcs))
+	for i, rc := range rcs {
+		ids[i], epochs[i] = rc.ID, rc.Epoch
 	}
-	dlqCount := int(tag.RowsAffected())
-	totalProcessed += dlqCount
-	if dlqCount > 0 {
-		metrics.MessagesDLQd.Add(float64(dlqCount))
+	rows, err := p.pool.Query(ctx, sqlRelease, ids, epochs)
+	if err != nil {
+		return nil, err
 	}
+	return pgx.CollectRows(rows, pgx.RowTo[int64])
+}
 
-	return totalProcessed, nil
+// ReleaseLeases ends every lease in the queue in a single UPDATE.
+func (p *PostgresStore) ReleaseLeases(ctx context.Context, name string) (int64, error) {
+	tag, err := p.pool.Exec(ctx, sqlReleaseQueueLeases, name)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
 
-}
\ No newline at end of file
+// ClaimAndDelete removes and returns up to limit available messages in the
+// queue's claim order.
+func (p *PostgresStore) ClaimAndDelete(ctx context.Context, name string, limit int) ([]queue.Message, error) {
+	qcfg, err := p.GetQueueConfig(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if qcfg.Paused {
+		return nil, nil
+	}
+	sql := sqlClaimDelete
+	switch qcfg.ClaimOrder {
+	case queue.ClaimOrderVisibleAt:
+		sql = sqlClaimDeleteVisible
+	case queue.ClaimOrderFIFO:
+		sql = sqlClaimDeleteFIFO
+	}
+	out, err := p.claim(ctx, sql, name, limit)
+	if err != nil {
+		return nil, err
+	}
+	if qcfg.ClaimOrder == queue.ClaimOrderFIFO {
+		sortFIFO(out)
+	}
+	for i := range out {
+		out[i].DeliveryCount++ // this delivery; the row is gone
+		events.Publish(events.Event{Type: events.Received, Queue: name, ID: out[i].ID})
+		events.Publish(events.Event{Type: events.Acked, Queue: name, ID: out[i].ID})
+	}
+	return out, nil
+}
+
+// claim runs one of the claim statements and scans the returned rows.
+func (p *PostgresStore) claim(ctx context.Context, sql string, args ...any) ([]queue.Message, error) {
+	return claimRows(ctx, p.pool, sql, args...)
+}
+
+// rowsQuerier is a pool or a transaction, for claims that run in either.
+type rowsQuerier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+// claimRows is claim against q.
+func claimRows(ctx context.Context, q rowsQuerier, sql string, args ...any) ([]queue.Message, error) {
+	rows, err := q.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []queue.Message
+	for rows.Next() {
+		var m queue.Message
+		if err := scanMessage(rows, &m); err != nil {
+			return nil, err
+		}
+		out = append(out, m)
+	}
+	return out, rows.Err()
+}
+
+// sortFIFO puts claimed messages back in enqueue order; RETURNING doesn't
+// keep the order the rows were picked in.
+func sortFIFO(msgs []queue.Message) {
+	slices.SortFunc(msgs, func(a, b queue.Message) int {
+		if c := a.EnqueuedAt.Compare(b.EnqueuedAt); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.ID, b.ID)
+	})
+}
+
+// scanMessage reads a row selected with messageColumns.
+func scanMessage(row pgx.Row, m *queue.Message) error {
+	var rules []byte
+	err := row.Scan(
+		&m.ID,
+		&m.Queue,
+		&m.Body,
+		&m.EnqueuedAt,
+		&m.NotBefore,
+		&m.LeaseUntil,
+		&m.DeliveryCount,
+		&m.MaxRetries,
+		&m.DLQ,
+		&m.TraceID,
+		&m.LeaseEpoch,
+		&rules,
+		&m.DLQdAt,
+		&m.GroupID,
+		&m.LastError,
+		&m.FailedAt,
+		&m.DLQSource,
+		&m.DLQDeliveries,
+		&m.ReceiveCount,
+		&m.BodyRef,
+		&m.CoalesceKey,
+		&m.ULID,
+		&m.GroupSealed,
+	)
+	if err != nil {
+		return err
+	}
+	m.DLQRules, err = decodeDLQRules(rules)
+	return err
+}
+
+// Ack deletes the message if rc still matches its current lease.
+func (p *PostgresStore) Ack(ctx context.Context, rc queue.Receipt) (bool, error) {
+	var qname string
+	var enqueuedAt time.Time
+	err := p.pool.QueryRow(ctx, sqlAck, rc.ID, rc.Epoch).Scan(&qname, &enqueuedAt, nil)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, checkAck(ctx, p.pool, rc)
+	}
+	if err != nil {
+		return false, err
+	}
+	metrics.MessageAgeAtAck.WithLabelValues(qname).Observe(time.Since(enqueuedAt).Seconds())
+	events.Publish(events.Event{Type: events.Acked, Queue: qname, ID: rc.ID})
+	return true, nil
+}
+
+// AckOnce is Ack, remembering ackID for ttl in the same transaction. When
+// the message is already gone and ackID was last used to ack it, the retry
+// is reported as acked and replayed.
+func (p *PostgresStore) AckOnce(ctx context.Context, rc queue.Receipt, ackID string, ttl time.Duration) (bool, bool, error) {
+	tx, err := p.pool.Begin(ctx)
+	if err != nil {
+		return false, false, err
+	}
+	defer tx.Rollback(ctx)
+
+	var qname, ulid string
+	var enqueuedAt time.Time
+	err = tx.QueryRow(ctx, sqlAck, rc.ID, rc.Epoch).Scan(&qname, &enqueuedAt, &ulid)
+	if errors.Is(err, pgx.ErrNoRows) {
+		_ = tx.Rollback(ctx)
+		var acked int64
+		err := p.pool.QueryRow(ctx, sqlGetAckID, ackID).Scan(&acked)
+		if err == nil && acked == rc.ID {
+			return true, true, nil
+		}
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			return false, false, fmt.Errorf("lookup ack id: %w", err)
+		}
+		return false, false, checkAck(ctx, p.pool, rc)
+	}
+	if err != nil {
+		return false, false, err
+	}
+	if _, err := tx.Exec(ctx, sqlHoldAckID, ackID, rc.ID, toInterval(ttl), ulid); err != nil {
+		return false, false, fmt.Errorf("hold ack id: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return false, false, err
+	}
+
+	metrics.MessageAgeAtAck.WithLabelValues(qname).Observe(time.Since(enqueuedAt).Seconds())
+	events.Publish(events.Event{Type: events.Acked, Queue: qname, ID: rc.ID})
+	return true, false, nil
+}
+
+// AckAndEnqueue is Ack and insertMessage in one transaction; a failed insert
+// rolls the ack back, leaving the lease as it was.
+func (p *PostgresStore) AckAndEnqueue(ctx context.Context, rc queue.Receipt, m queue.Message, delay time.Duration) (int64, bool, bool, error) {
+	tx, err := p.pool.Begin(ctx)
+	if err != nil {
+		return 0, false, false, err
+	}
+	defer tx.Rollback(ctx)
+
+	var qname string
+	var enqueuedAt time.Time
+	err = tx.QueryRow(ctx, sqlAck, rc.ID, rc.Epoch).Scan(&qname, &enqueuedAt, nil)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, false, false, checkAck(ctx, tx, rc)
+	}
+	if err != nil {
+		return 0, false, false, err
+	}
+	id, coalesced, err := insertMessage(ctx, tx, m, delay)
+	if err != nil {
+		return 0, false, false, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, false, false, err
+	}
+
+	metrics.MessageAgeAtAck.WithLabelValues(qname).Observe(time.Since(enqueuedAt).Seconds())
+	events.Publish(events.Event{Type: events.Acked, Queue: qname, ID: rc.ID})
+	if !coalesced {
+		events.Publish(events.Event{Type: events.Enqueued, Queue: m.Queue, ID: id})
+	}
+	return id, true, coalesced, nil
+}
+
+// Nack ends the lease now and records reason as the last error.
+func (p *PostgresStore) Nack(ctx context.Context, rc queue.Receipt, reason string) (bool, error) {
+	var qname string
+	err := p.pool.QueryRow(ctx, sqlNack, rc.ID, rc.Epoch, reason).Scan(&qname)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, checkEpoch(ctx, p.pool, rc)
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// NackToDLQ dead-letters the message now if the receipt holds a live lease
+// and the message has used up its retries.
+func (p *PostgresStore) NackToDLQ(ctx context.Context, rc queue.Receipt, reason string) (string, bool, error) {
+	ev := events.Event{Type: events.DeadLettered, ID: rc.ID}
+	err := p.pool.QueryRow(ctx, sqlNackToDLQ, rc.ID, rc.Epoch, reason).Scan(&ev.Queue, &ev.DLQ)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	events.Publish(ev)
+	metrics.MessagesDLQd.Inc()
+	return ev.DLQ, true, nil
+}
+
+// DelayedMessages lists the queue's not-yet-visible messages, soonest first.
+func (p *PostgresStore) DelayedMessages(ctx context.Context, name string, limit int) ([]queue.Message, error) {
+	rows, err := p.pool.Query(ctx, sqlDelayedMessages, name, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []queue.Message
+	for rows.Next() {
+		var m queue.Message
+		if err := scanMessage(rows, &m); err != nil {
+			return nil, err
+		}
+		out = append(out, m)
+	}
+	return out, rows.Err()
+}
+
+// CancelMessage deletes the message if it has never been delivered, or
+// whatever its state if force is set.
+func (p *PostgresStore) CancelMessage(ctx context.Context, id int64, force bool) (string, error) {
+	var qname string
+	err := p.pool.QueryRow(ctx, sqlCancelMessage, id, force).Scan(&qname)
+	if errors.Is(err, pgx.ErrNoRows) {
+		var exists bool
+		if err := p.pool.QueryRow(ctx, sqlMessageExists, id).Scan(&exists); err != nil {
+			return "", err
+		}
+		if exists {
+			return "", queue.ErrMessageDelivered
+		}
+		return "", queue.ErrMessageNotFound
+	}
+	return qname, err
+}
+
+// GetMessage returns the message with the given id without leasing it.
+func (p *PostgresStore) GetMessage(ctx context.Context, id int64) (queue.Message, error) {
+	var m queue.Message
+	err := scanMessage(p.pool.QueryRow(ctx, sqlGetMessage, id), &m)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return queue.Message{}, queue.ErrMessageNotFound
+	}
+	return m, err
+}
+
+// MessageULIDs looks up the ULIDs of ids, including those of messages only
+// enqueue keys still remember.
+func (p *PostgresStore) MessageULIDs(ctx context.Context, ids []int64) (map[int64]string, error) {
+	out := make(map[int64]string, len(ids))
+	err := p.scanULIDs(ctx, sqlMessageULIDs, ids, func(id int64, ulid string) { out[id] = ulid })
+	return out, err
+}
+
+func (p *PostgresStore) MessageIDsByULID(ctx context.Context, ulids []string) (map[string]int64, error) {
+	out := make(map[string]int64, len(ulids))
+	err := p.scanULIDs(ctx, sqlMessageIDsByULID, ulids, func(id int64, ulid string) { out[ulid] = id })
+	return out, err
+}
+
+// scanULIDs runs a query of (id, ulid) rows and hands each to add.
+func (p *PostgresStore) scanULIDs(ctx context.Context, sql string, arg any, add func(int64, string)) error {
+	rows, err := p.pool.Query(ctx, sql, arg)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int64
+		var ulid string
+		if err := rows.Scan(&id, &ulid); err != nil {
+			return err
+		}
+		add(id, ulid)
+	}
+	return rows.Err()
+}
+
+// UpdateBody replaces the body of a message that has never been delivered.
+func (p *PostgresStore) UpdateBody(ctx context.Context, id int64, body []byte) (queue.Message, error) {
+	var m queue.Message
+	err := scanMessage(p.pool.QueryRow(ctx, sqlUpdateBody, id, body), &m)
+	if errors.Is(err, pgx.ErrNoRows) {
+		var exists bool
+		if err := p.pool.QueryRow(ctx, sqlMessageExists, id).Scan(&exists); err != nil {
+			return queue.Message{}, err
+		}
+		if exists {
+			return queue.Message{}, queue.ErrMessageDelivered
+		}
+		return queue.Message{}, queue.ErrMessageNotFound
+	}
+	return m, err
+}
+
+// ClaimByID leases the message with the given id for visibility.
+func (p *PostgresStore) ClaimByID(ctx context.Context, id int64, visibility time.Duration) (queue.Message, error) {
+	tx, err := p.pool.Begin(ctx)
+	if err != nil {
+		return queue.Message{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	var qname string
+	var leased, delayed, blocked bool
+	err = tx.QueryRow(ctx, sqlClaimableState, id).Scan(&qname, &leased, &delayed, &blocked)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return queue.Message{}, queue.ErrMessageNotFound
+	}
+	if err != nil {
+		return queue.Message{}, err
+	}
+	qcfg, err := p.GetQueueConfig(ctx, qname)
+	if err != nil {
+		return queue.Message{}, err
+	}
+	switch {
+	case qcfg.Paused:
+		return queue.Message{}, queue.ErrQueuePaused
+	case leased:
+		return queue.Message{}, queue.ErrMessageLeased
+	case delayed:
+		return queue.Message{}, queue.ErrMessageDelayed
+	case blocked:
+		return queue.Message{}, queue.ErrGroupOrder
+	}
+	if qcfg.MaxInFlight > 0 {
+		// counted under the lock claimBudgeted takes, so the two can't
+		// overshoot the budget together
+		if _, err := tx.Exec(ctx, sqlLockInFlight, qname); err != nil {
+			return queue.Message{}, fmt.Errorf("lock in-flight budget: %w", err)
+		}
+		var inFlight int
+		if err := tx.QueryRow(ctx, sqlCountInFlight, qname).Scan(&inFlight); err != nil {
+			return queue.Message{}, fmt.Errorf("count in flight: %w", err)
+		}
+		if inFlight >= qcfg.MaxInFlight {
+			return queue.Message{}, queue.ErrMaxInFlight
+		}
+	}
+
+	var m queue.Message
+	err = scanMessage(tx.QueryRow(ctx, sqlClaimByID, id, toInterval(visibility)), &m)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// locked by a claim that is leasing it now
+		return queue.Message{}, queue.ErrMessageLeased
+	}
+	if err != nil {
+		return queue.Message{}, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return queue.Message{}, err
+	}
+	events.Publish(events.Event{Type: events.Received, Queue: m.Queue, ID: m.ID})
+	return m, nil
+}
+
+// DeadLetters lists the queue's dead letters, newest first.
+func (p *PostgresStore) DeadLetters(ctx context.Context, name string, limit int) ([]queue.Message, error) {
+	rows, err := p.pool.Query(ctx, sqlDeadLetters, name, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []queue.Message
+	for rows.Next() {
+		var m queue.Message
+		if err := scanMessage(rows, &m); err != nil {
+			return nil, err
+		}
+		out = append(out, m)
+	}
+	return out, rows.Err()
+}
+
+// Renaming queue $1 to $2 moves its config, then its messages, then the
+// rest of its rows. Rows the new name already has win over the old queue's,
+// which are then dropped.
+var (
+	sqlRenameQueueConfig = []string{
+		`UPDATE queue_configs SET queue = $2, updated_at = now()
+WHERE queue = $1 AND NOT EXISTS (SELECT 1 FROM queue_configs WHERE queue = $2);`,
+		`DELETE FROM queue_configs WHERE queue = $1;`,
+	}
+
+	sqlRenameQueueRows = []string{
+		`UPDATE topic_subscriptions s SET queue = $2
+WHERE queue = $1
+  AND NOT EXISTS (SELECT 1 FROM topic_subscriptions t WHERE t.topic = s.topic AND t.queue = $2);`,
+		`DELETE FROM topic_subscriptions WHERE queue = $1;`,
+
+		`UPDATE push_subscriptions SET queue = $2
+WHERE queue = $1 AND NOT EXISTS (SELECT 1 FROM push_subscriptions WHERE queue = $2);`,
+		`DELETE FROM push_subscriptions WHERE queue = $1;`,
+
+		`UPDATE consumer_checkpoints c SET queue = $2
+WHERE queue = $1
+  AND NOT EXISTS (SELECT 1 FROM consumer_checkpoints e WHERE e.queue = $2 AND e.consumer_group = c.consumer_group);`,
+		`DELETE FROM consumer_checkpoints WHERE queue = $1;`,
+
+		`UPDATE enqueue_keys k SET queue = $2
+WHERE queue = $1
+  AND NOT EXISTS (SELECT 1 FROM enqueue_keys e WHERE e.queue = $2 AND e.scope = k.scope AND e.key = k.key);`,
+		`DELETE FROM enqueue_keys WHERE queue = $1;`,
+
+		`UPDATE sealed_groups g SET queue = $2
+WHERE queue = $1
+  AND NOT EXISTS (SELECT 1 FROM sealed_groups e WHERE e.queue = $2 AND e.group_id = g.group_id);`,
+		`DELETE FROM sealed_groups WHERE queue = $1;`,
+
+		`UPDATE queues SET name = $2
+WHERE name = $1 AND NOT EXISTS (SELECT 1 FROM queues WHERE name = $2);`,
+		`DELETE FROM queues WHERE name = $1;`,
+
+		// references to the queue by name: as a DLQ, and as a dead letter's source
+		`UPDATE queue_configs SET dlq = $2, updated_at = now() WHERE dlq = $1;`,
+		`UPDATE messages SET dlq = $2 WHERE dlq = $1;`,
+		`UPDATE messages SET dlq_source = $2 WHERE dlq_source = $1;`,
+	}
+)
+
+// RenameQueue moves the queue and everything kept for it to newName in one
+// transaction, returning how many messages moved.
+func (p *PostgresStore) RenameQueue(ctx context.Context, oldName, newName string, merge bool) (int64, error) {
+	tx, err := p.pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	if !merge {
+		var taken bool
+		if err := tx.QueryRow(ctx, sqlQueueHasMessages, newName).Scan(&taken); err != nil {
+			return 0, err
+		}
+		if taken {
+			return 0, queue.ErrQueueNotEmpty
+		}
+		if _, err := tx.Exec(ctx, sqlDropRenameTargetConfig, oldName, newName); err != nil {
+			return 0, err
+		}
+	}
+	if err := execAll(ctx, tx, sqlRenameQueueConfig, oldName, newName); err != nil {
+		return 0, err
+	}
+	tag, err := tx.Exec(ctx, sqlRenameMessages, oldName, newName)
+	if err != nil {
+		return 0, err
+	}
+	if err := execAll(ctx, tx, sqlRenameQueueRows, oldName, newName); err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// Deleting queue $1 drops its messages and every row kept for it; the
+// registry row goes last.
+var sqlDeleteQueue = []string{
+	`DELETE FROM messages WHERE queue = $1;`,
+	`DELETE FROM queue_configs WHERE queue = $1;`,
+	`DELETE FROM topic_subscriptions WHERE queue = $1;`,
+	`DELETE FROM push_subscriptions WHERE queue = $1;`,
+	`DELETE FROM consumer_checkpoints WHERE queue = $1;`,
+	`DELETE FROM enqueue_keys WHERE queue = $1;`,
+	`DELETE FROM sealed_groups WHERE queue = $1;`,
+	`DELETE FROM queues WHERE name = $1;`,
+}
+
+// RegisterQueue records name in the registry, checking the limit under a
+// lock only when the name is new.
+func (p *PostgresStore) RegisterQueue(ctx context.Context, name string, max int) (bool, error) {
+	var known bool
+	if err := p.pool.QueryRow(ctx, sqlQueueRegistered, name).Scan(&known); err != nil || known {
+		return false, err
+	}
+
+	tx, err := p.pool.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, sqlLockQueueRegistry); err != nil {
+		return false, err
+	}
+	if max > 0 {
+		var n int
+		if err := tx.QueryRow(ctx, sqlCountQueues).Scan(&n); err != nil {
+			return false, err
+		}
+		if n >= max {
+			return false, queue.ErrTooManyQueues
+		}
+	}
+	tag, err := tx.Exec(ctx, sqlRegisterQueue, name)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, tx.Commit(ctx)
+}
+
+// QueueRegistered looks name up in the registry.
+func (p *PostgresStore) QueueRegistered(ctx context.Context, name string) (bool, error) {
+	var known bool
+	err := p.pool.QueryRow(ctx, sqlQueueRegistered, name).Scan(&known)
+	return known, err
+}
+
+// DeleteQueue drops the queue and everything kept for it.
+func (p *PostgresStore) DeleteQueue(ctx context.Context, name string) (bool, error) {
+	tx, err := p.pool.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	var found bool
+	for _, sql := range sqlDeleteQueue {
+		tag, err := tx.Exec(ctx, sql, name)
+		if err != nil {
+			return false, err
+		}
+		found = found || tag.RowsAffected() > 0
+	}
+	return found, tx.Commit(ctx)
+}
+
+// execAll runs each statement in turn with the same args.
+func execAll(ctx context.Context, tx pgx.Tx, stmts []string, args ...any) error {
+	for _, sql := range stmts {
+		if _, err := tx.Exec(ctx, sql, args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Redrive moves up to limit of the queue's dead letters to target, or back
+// to their source queues when target is empty.
+func (p *PostgresStore) Redrive(ctx context.Context, name, target string, limit int) (int, error) {
+	rows, err := p.pool.Query(ctx, sqlRedrive, name, limit, target)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	var moved []events.Event
+	for rows.Next() {
+		ev := events.Event{Type: events.Enqueued}
+		if err := rows.Scan(&ev.ID, &ev.Queue); err != nil {
+			return 0, err
+		}
+		moved = append(moved, ev)
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	for _, ev := range moved {
+		events.Publish(ev)
+	}
+	return len(moved), nil
+}
+
+// ArchivedMessages returns up to limit of the archived copies f matches,
+// oldest ack first.
+func (p *PostgresStore) ArchivedMessages(ctx context.Context, f queue.ArchiveFilter, limit int) ([]queue.ArchivedMessage, error) {
+	var qname *string
+	if f.Queue != "" {
+		qname = &f.Queue
+	}
+	var from, to *time.Time
+	if !f.From.IsZero() {
+		from = &f.From
+	}
+	if !f.To.IsZero() {
+		to = &f.To
+	}
+	// nil slices go as NULL, which doesn't filter
+	rows, err := p.pool.Query(ctx, sqlArchivedMessages, qname, from, to, f.IDs, f.ULIDs, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []queue.ArchivedMessage
+	for rows.Next() {
+		var a queue.ArchivedMessage
+		if err := rows.Scan(&a.ID, &a.ULID, &a.Queue, &a.Body, &a.BodyRef, &a.TraceID, &a.GroupID,
+			&a.DeliveryCount, &a.EnqueuedAt, &a.AckedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, a)
+	}
+	return out, rows.Err()
+}
+
+// ResetDeliveryCount zeroes the message's delivery count and makes it
+// available immediately.
+func (p *PostgresStore) ResetDeliveryCount(ctx context.Context, id int64) (bool, error) {
+	var qname string
+	err := p.pool.QueryRow(ctx, sqlResetDeliveryCount, id).Scan(&qname)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// AckBatch acks the receipts in ID order in one transaction, so a group's
+// messages acked together clear the way for each other.
+func (p *PostgresStore) AckBatch(ctx context.Context, rcs []queue.Receipt) ([]int64, map[int64]error, error) {
+	sorted := slices.Clone(rcs)
+	slices.SortFunc(sorted, func(a, b queue.Receipt) int { return cmp.Compare(a.ID, b.ID) })
+
+	tx, err := p.pool.Begin(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	acked := make([]int64, 0, len(sorted))
+	queues := make([]string, 0, len(sorted))
+	enqueued := make([]time.Time, 0, len(sorted))
+	rejected := make(map[int64]error)
+	for _, rc := range sorted {
+		var qname string
+		var enqueuedAt time.Time
+		err := tx.QueryRow(ctx, sqlAck, rc.ID, rc.Epoch).Scan(&qname, &enqueuedAt, nil)
+		if errors.Is(err, pgx.ErrNoRows) {
+			err = checkAck(ctx, tx, rc)
+			switch {
+			case err == nil:
+				rejected[rc.ID] = queue.ErrMessageNotFound
+			case errors.Is(err, queue.ErrStaleReceipt), errors.Is(err, queue.ErrGroupOrder):
+				rejected[rc.ID] = err
+			default:
+				return nil, nil, err
+			}
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		acked = append(acked, rc.ID)
+		queues = append(queues, qname)
+		enqueued = append(enqueued, enqueuedAt)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, nil, err
+	}
+	for i, id := range acked {
+		metrics.MessageAgeAtAck.WithLabelValues(queues[i]).Observe(time.Since(enqueued[i]).Seconds())
+		events.Publish(events.Event{Type: events.Acked, Queue: queues[i], ID: id})
+	}
+	return acked, rejected, nil
+}
+
+// checkEpoch explains why a fenced write matched nothing: nil if the message
+// is gone, ErrStaleReceipt if it exists under a different lease.
+func checkEpoch(ctx context.Context, q querier, rc queue.Receipt) error {
+	var epoch int64
+	err := q.QueryRow(ctx, sqlLeaseEpoch, rc.ID).Scan(&epoch)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if epoch != rc.Epoch {
+		return queue.ErrStaleReceipt
+	}
+	return nil
+}
+
+// checkAck explains why an ack matched nothing: nil if the message is gone,
+// ErrStaleReceipt if it exists under a different lease, ErrGroupOrder if an
+// earlier message of its group is still queued.
+func checkAck(ctx context.Context, q querier, rc queue.Receipt) error {
+	var epoch int64
+	var blocked bool
+	err := q.QueryRow(ctx, sqlAckCheck, rc.ID).Scan(&epoch, &blocked)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if epoch != rc.Epoch {
+		return queue.ErrStaleReceipt
+	}
+	if blocked {
+		return queue.ErrGroupOrder
+	}
+	return nil
+}
+
+func (p *PostgresStore) Sweeper(ctx context.Context, opts queue.SweepOptions) (int, error) {
+	var totalProcessed int
+
+	var lag int64
+	if err := p.pool.QueryRow(ctx, sqlSweeperLag).Scan(&lag); err != nil {
+		return 0, fmt.Errorf("Sweep lag %w", err)
+	}
+	metrics.SweeperLag.Set(float64(lag))
+	// only a gauge: a failed sample mustn't hold up requeues
+	if err := p.sampleOldestInFlight(ctx); err != nil {
+		metrics.SweeperErrors.Inc()
+		log.Printf("in-flight age sample failed, sweeping on: %v", err)
+	}
+
+	// first, so nothing past its lifetime is requeued or dead-lettered
+	if opts.MaxLifetime > 0 {
+		if err := p.purgeExpired(ctx, opts); err != nil {
+			return 0, fmt.Errorf("Sweep lifetime %w", err)
+		}
+	}
+
+	// with a router, exhausted messages are left for routeExhausted
+	rapid := map[string]int{}
+	defer logRapidRequeues(rapid, opts.RapidRequeue())
+	for {
+		requeued, moved, err := p.sweepBatch(ctx, opts, rapid)
+		if err != nil {
+			return 0, err
+		}
+		for _, ev := range moved {
+			events.Publish(ev)
+		}
+		totalProcessed += requeued + len(moved)
+		if requeued > 0 {
+			metrics.MessagesRequeued.Add(float64(requeued))
+		}
+		if len(moved) > 0 {
+			metrics.MessagesDLQd.Add(float64(len(moved)))
+		}
+		if batch := opts.SweepBatch(); requeued < batch && len(moved) < batch {
+			break
+		}
+	}
+
+	if opts.Router != nil {
+		routed, err := p.routeExhausted(ctx, opts)
+		if err != nil {
+			return 0, fmt.Errorf("Sweep DLQ routing %w", err)
+		}
+		totalProcessed += routed
+		return p.sweepHousekeeping(ctx, opts, totalProcessed)
+	}
+
+	if opts.MaxDeliveries > 0 {
+		dropped, err := p.dropCapped(ctx, opts)
+		if err != nil {
+			return 0, fmt.Errorf("Sweep delivery ceiling %w", err)
+		}
+		totalProcessed += dropped
+	}
+	return p.sweepHousekeeping(ctx, opts, totalProcessed)
+}
+
+// sampleOldestInFlight sets the in-flight age gauge for every queue with a
+// live lease. Queues without one are dropped rather than left at their last
+// value, so a gauge only climbs while the lease holding it is live.
+func (p *PostgresStore) sampleOldestInFlight(ctx context.Context) error {
+	rows, err := p.pool.Query(ctx, sqlOldestInFlight)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	ages := map[string]float64{}
+	for rows.Next() {
+		var name string
+		var age float64
+		if err := rows.Scan(&name, &age); err != nil {
+			return err
+		}
+		ages[name] = age
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	metrics.OldestInFlightAge.Reset()
+	for name, age := range ages {
+		metrics.OldestInFlightAge.WithLabelValues(name).Set(age)
+	}
+	return nil
+}
+
+// sweepBatch requeues and dead-letters up to opts.SweepBatch() expired
+// leases each, set-based and in one transaction, returning how many were
+// requeued and the dead-lettering events to publish once it has committed.
+// Rapid requeues are added to rapidTotal by queue once committed. Without a
+// router both statements run; with one, only the requeue.
+func (p *PostgresStore) sweepBatch(ctx context.Context, opts queue.SweepOptions, rapidTotal map[string]int) (int, []events.Event, error) {
+	tx, err := p.pool.Begin(ctx)
+	if err != nil {
+		return 0, nil, fmt.Errorf("Sweep begin %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	batch := opts.SweepBatch()
+	window := opts.RapidRequeue()
+	rows, err := tx.Query(ctx, sqlSweeperRequeue, opts.MaxDeliveries, backoffTable(opts.Backoff), opts.Router != nil, batch, toInterval(window))
+	if err != nil {
+		return 0, nil, fmt.Errorf("Sweep requeued, %w", err)
+	}
+	var requeued int
+	rapid := map[string]int{}
+	for rows.Next() {
+		var qname string
+		var fast bool
+		if err := rows.Scan(&qname, &fast); err != nil {
+			rows.Close()
+			return 0, nil, fmt.Errorf("Sweep requeued, %w", err)
+		}
+		requeued++
+		if fast {
+			rapid[qname]++
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, nil, fmt.Errorf("Sweep requeued, %w", err)
+	}
+
+	var moved []events.Event
+	if opts.Router == nil {
+		rows, err := tx.Query(ctx, sqlSweeperDLQ, opts.MaxDeliveries, batch)
+		if err != nil {
+			return 0, nil, fmt.Errorf("Sweep DLQ %w", err)
+		}
+		for rows.Next() {
+			ev := events.Event{Type: events.DeadLettered}
+			var maxRetries int
+			if err := rows.Scan(&ev.ID, &ev.Queue, &ev.DLQ, &maxRetries); err != nil {
+				rows.Close()
+				return 0, nil, fmt.Errorf("Sweep DLQ %w", err)
+			}
+			if opts.MaxDeliveries > 0 && maxRetries > opts.MaxDeliveries {
+				log.Printf("message %d (%s): dead-lettered at the delivery ceiling %d, overriding max_retries=%d",
+					ev.ID, ev.Queue, opts.MaxDeliveries, maxRetries)
+			}
+			moved = append(moved, ev)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return 0, nil, fmt.Errorf("Sweep DLQ %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, nil, fmt.Errorf("Sweep commit %w", err)
+	}
+	for qname, n := range rapid {
+		metrics.RapidRequeues.WithLabelValues(qname).Add(float64(n))
+		rapidTotal[qname] += n
+	}
+	return requeued, moved, nil
+}
+
+// logRapidRequeues warns once per queue about the rapid requeues a sweep
+// counted across all its batches.
+func logRapidRequeues(rapid map[string]int, window time.Duration) {
+	for qname, n := range rapid {
+		log.Printf("queue %s: %d messages requeued within %s of being leased; "+
+			"a visibility timeout this short spends their retries before a worker can finish", qname, n, window)
+	}
+}
+
+// purgeExpired deletes every live message older than opts.MaxLifetime, in batches
+// of opts.SweepBatch() with one statement each. Like the DLQ purge it isn't
+// counted as processed; it has its own metric.
+func (p *PostgresStore) purgeExpired(ctx context.Context, opts queue.SweepOptions) error {
+	batch := opts.SweepBatch()
+	for {
+		tag, err := p.pool.Exec(ctx, sqlPurgeExpired, toInterval(opts.MaxLifetime), batch)
+		if err != nil {
+			return err
+		}
+		n := tag.RowsAffected()
+		if n > 0 {
+			metrics.MessagesExpired.Add(float64(n))
+		}
+		if n < int64(batch) {
+			return nil
+		}
+	}
+}
+
+// pruneArchive deletes archived copies past opts.ArchiveRetention, a batch
+// at a time.
+func (p *PostgresStore) pruneArchive(ctx context.Context, opts queue.SweepOptions) error {
+	batch := opts.SweepBatch()
+	for {
+		tag, err := p.pool.Exec(ctx, sqlPruneArchive, toInterval(opts.ArchiveRetention), batch)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() < int64(batch) {
+			return nil
+		}
+	}
+}
+
+// sealedGroupGrace is how long a group's seal outlasts its last message. A
+// group sealed with no messages at all is drained from the start, so without
+// it the seal wouldn't survive the next sweep.
+const sealedGroupGrace = time.Hour
+
+// sweepHousekeeping runs the sweep steps that don't touch live messages and
+// returns processed unchanged.
+func (p *PostgresStore) sweepHousekeeping(ctx context.Context, opts queue.SweepOptions, processed int) (int, error) {
+	// expired enqueue keys are housekeeping, not counted as processed messages
+	if _, err := p.pool.Exec(ctx, sqlPruneEnqueueKeys); err != nil {
+		return 0, fmt.Errorf("Sweep enqueue keys %w", err)
+	}
+	// A drained group's seal has nothing left to flag, and would otherwise
+	// stay forever; the grace keeps producers that are still sending to it
+	// refused for a while.
+	if _, err := p.pool.Exec(ctx, sqlPruneSealedGroups, toInterval(sealedGroupGrace)); err != nil {
+		return 0, fmt.Errorf("Sweep sealed groups %w", err)
+	}
+
+	// so is aging out dead letters; it only has its own metric
+	if opts.DLQRetention > 0 {
+		tag, err := p.pool.Exec(ctx, sqlPurgeDLQ, toInterval(opts.DLQRetention))
+		if err != nil {
+			return 0, fmt.Errorf("Sweep DLQ purge %w", err)
+		}
+		if purged := tag.RowsAffected(); purged > 0 {
+			metrics.DLQPurged.Add(float64(purged))
+		}
+	}
+
+	if opts.ArchiveRetention > 0 {
+		if err := p.pruneArchive(ctx, opts); err != nil {
+			return 0, fmt.Errorf("Sweep archive %w", err)
+		}
+	}
+
+	return processed, nil
+}
+
+// routeBatch is how many exhausted messages routeExhausted locks per
+// transaction.
+const routeBatch = 100
+
+// routeExhausted hands each message that has run out of retries to
+// opts.Router and dead-letters, deletes or requeues it as told. A message at
+// the delivery ceiling that the router gives no DLQ is deleted, so it can't
+// circulate forever. Returns how many messages it moved.
+func (p *PostgresStore) routeExhausted(ctx context.Context, opts queue.SweepOptions) (int, error) {
+	var total int
+	for {
+		n, full, err := p.routeBatch(ctx, opts)
+		total += n
+		if err != nil || !full {
+			return total, err
+		}
+	}
+}
+
+// routeBatch routes one batch in a transaction and reports whether to go
+// on: the batch was full, so more may be waiting, and none of it was left
+// behind to be picked again.
+func (p *PostgresStore) routeBatch(ctx context.Context, opts queue.SweepOptions) (int, bool, error) {
+	tx, err := p.pool.Begin(ctx)
+	if err != nil {
+		return 0, false, err
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, sqlSweeperExhausted, opts.MaxDeliveries, routeBatch)
+	if err != nil {
+		return 0, false, err
+	}
+	var msgs []queue.Message
+	for rows.Next() {
+		var m queue.Message
+		if err := scanMessage(rows, &m); err != nil {
+			rows.Close()
+			return 0, false, err
+		}
+		msgs = append(msgs, m)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, false, err
+	}
+
+	var moved []events.Event
+	var dlqd, dropped, requeued, failed int
+	for _, m := range msgs {
+		route, err := opts.Router.RouteDLQ(ctx, m)
+		if err == nil && route.DLQ == m.Queue {
+			err = errors.New("routed to its own queue")
+		}
+		if err != nil {
+			log.Printf("message %d (%s): DLQ routing failed, will retry next sweep: %v", m.ID, m.Queue, err)
+			failed++
+			continue
+		}
+		atCeiling := opts.MaxDeliveries > 0 && m.DeliveryCount >= opts.MaxDeliveries
+		switch {
+		case route.DLQ != "":
+			_, err = tx.Exec(ctx, sqlRouteToDLQ, m.ID, route.DLQ)
+			moved = append(moved, events.Event{Type: events.DeadLettered, ID: m.ID, Queue: m.Queue, DLQ: route.DLQ})
+			dlqd++
+		case route.Drop:
+			_, err = tx.Exec(ctx, sqlRouteDrop, m.ID)
+			log.Printf("message %d (%s): deleted by the DLQ router", m.ID, m.Queue)
+		case atCeiling:
+			_, err = tx.Exec(ctx, sqlRouteDrop, m.ID)
+			log.Printf("message %d (%s): deleted at the delivery ceiling %d (max_retries=%d, no DLQ)",
+				m.ID, m.Queue, opts.MaxDeliveries, m.MaxRetries)
+			dropped++
+		default:
+			_, err = tx.Exec(ctx, sqlRouteRequeue, m.ID, opts.Backoff.Delay(m.DeliveryCount).Milliseconds())
+			requeued++
+		}
+		if err != nil {
+			return 0, false, err
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, false, err
+	}
+
+	for _, ev := range moved {
+		events.Publish(ev)
+	}
+	metrics.MessagesDLQd.Add(float64(dlqd))
+	metrics.MessagesDropped.Add(float64(dropped))
+	metrics.MessagesRequeued.Add(float64(requeued))
+	return len(msgs) - failed, len(msgs) == routeBatch && failed == 0, nil
+}
+
+// dropCapped deletes messages that hit the opts.MaxDeliveries ceiling
+// without a DLQ, a batch at a time, and logs how many each queue lost.
+func (p *PostgresStore) dropCapped(ctx context.Context, opts queue.SweepOptions) (int, error) {
+	batch := opts.SweepBatch()
+	perQueue := map[string]int{}
+	var total int
+	defer func() {
+		for qname, n := range perQueue {
+			log.Printf("queue %s: deleted %d messages at the delivery ceiling %d (no DLQ)",
+				qname, n, opts.MaxDeliveries)
+		}
+		if total > 0 {
+			metrics.MessagesDropped.Add(float64(total))
+		}
+	}()
+	for {
+		rows, err := p.pool.Query(ctx, sqlSweeperDropCapped, opts.MaxDeliveries, batch)
+		if err != nil {
+			return total, err
+		}
+		queues, err := pgx.CollectRows(rows, pgx.RowTo[string])
+		if err != nil {
+			return total, err
+		}
+		for _, qname := range queues {
+			perQueue[qname]++
+		}
+		total += len(queues)
+		if len(queues) < batch {
+			return total, nil
+		}
+	}
+}
+
+// ListQueues returns every queue name seen in messages or queue_configs.
+func (p *PostgresStore) ListQueues(ctx context.Context) ([]string, error) {
+	rows, err := p.pool.Query(ctx, sqlListQueues)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowTo[string])
+}
+
+// ExtendBatch renews the leases named by rcs in a single UPDATE.
+func (p *PostgresStore) ExtendBatch(ctx context.Context, rcs []queue.Receipt, visibility time.Duration) ([]int64, error) {
+	ids := make([]int64, len(rcs))
+	epochs := make([]int64, len(rcs))
+	for i, rc := range rcs {
+		ids[i], epochs[i] = rc.ID, rc.Epoch
+	}
+	rows, err := p.pool.Query(ctx, sqlExtendBatch, ids, epochs, toInterval(visibility))
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowTo[int64])
+}
+
+// Stats counts the queue's messages by state in a single scan.
+func (p *PostgresStore) Stats(ctx context.Context, name string) (queue.Stats, error) {
+	var st queue.Stats
+	err := p.pool.QueryRow(ctx, sqlStats, name).Scan(&st.Available, &st.InFlight, &st.Delayed)
+	return st, err
+}
+
+// CountInFlight counts leased messages across all queues.
+func (p *PostgresStore) CountInFlight(ctx context.Context) (int64, error) {
+	var n int64
+	err := p.pool.QueryRow(ctx, sqlCountTotalInFlight).Scan(&n)
+	return n, err
+}
+
+// Diagnose runs sqlDiagnose.
+func (p *PostgresStore) Diagnose(ctx context.Context, name string) (queue.Diagnosis, error) {
+	var d queue.Diagnosis
+	err := p.pool.QueryRow(ctx, sqlDiagnose, name).Scan(
+		&d.Available, &d.Leased, &d.LeaseExpired, &d.Delayed, &d.BackedOff, &d.NextVisible)
+	return d, err
+}
+
+// statePredicates are the Stats definitions of each lease state, in the
+// same shape as the partial indexes so the planner can use them.
+var statePredicates = map[queue.MessageState]string{
+	queue.StateAvailable: "lease_until IS NULL AND not_before <= now()",
+	queue.StateInFlight:  "lease_until IS NOT NULL",
+	queue.StateDelayed:   "lease_until IS NULL AND not_before > now()",
+}
+
+// Count counts the queue's messages matching filter. Only the filters in use
+// go into the WHERE clause, and the LIMIT stops the scan at filter.Limit.
+func (p *PostgresStore) Count(ctx context.Context, name string, filter queue.CountFilter) (int64, error) {
+	args := []any{name}
+	where := []string{"queue = $1"}
+	if filter.State != "" {
+		pred, ok := statePredicates[filter.State]
+		if !ok {
+			return 0, fmt.Errorf("unknown message state %q", filter.State)
+		}
+		where = append(where, pred)
+	}
+	if filter.MinDeliveryCount > 0 {
+		args = append(args, filter.MinDeliveryCount)
+		where = append(where, fmt.Sprintf("delivery_count >= $%d", len(args)))
+	}
+	if filter.GroupID != nil {
+		args = append(args, *filter.GroupID)
+		where = append(where, fmt.Sprintf("group_id = $%d", len(args)))
+	}
+	var limit *int
+	if filter.Limit > 0 {
+		limit = &filter.Limit
+	}
+	args = append(args, limit) // LIMIT NULL is no limit
+	sql := fmt.Sprintf("SELECT count(*) FROM (SELECT 1 FROM messages WHERE %s LIMIT $%d) m;",
+		strings.Join(where, " AND "), len(args))
+
+	var n int64
+	err := p.pool.QueryRow(ctx, sql, args...).Scan(&n)
+	return n, err
+}
+
+// GetQueueConfig returns the stored config for a queue, or the defaults.
+func (p *PostgresStore) GetQueueConfig(ctx context.Context, name string) (queue.QueueConfig, error) {
+	cfg := queue.DefaultQueueConfig(name)
+	var visibilityMS, dedupWindowMS *int64
+	var maxRetries *int
+	var defaultDelayMS int64
+	err := p.pool.QueryRow(ctx, sqlGetQueueConfig, name).Scan(
+		&cfg.Queue,
+		&cfg.Partitions,
+		&visibilityMS,
+		&maxRetries,
+		&cfg.DLQ,
+		&dedupWindowMS,
+		&cfg.RequireObjectBody,
+		&cfg.ClaimOrder,
+		&cfg.Role,
+		&cfg.SkipAfterAttempts,
+		&cfg.Paused,
+		&cfg.MaxInFlight,
+		&cfg.RequeueInPlace,
+		&cfg.CloudEvents,
+		&defaultDelayMS,
+		&cfg.Archive,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return queue.DefaultQueueConfig(name), nil
+	}
+	if err != nil {
+		return queue.QueueConfig{}, err
+	}
+	if visibilityMS != nil {
+		cfg.Visibility = time.Duration(*visibilityMS) * time.Millisecond
+	}
+	if maxRetries != nil {
+		cfg.MaxRetries = *maxRetries
+	}
+	if dedupWindowMS != nil {
+		cfg.DedupWindow = time.Duration(*dedupWindowMS) * time.Millisecond
+	}
+	cfg.DefaultDelay = time.Duration(defaultDelayMS) * time.Millisecond
+	return cfg, nil
+}
+
+// PutQueueConfig upserts the config row for cfg.Queue.
+func (p *PostgresStore) PutQueueConfig(ctx context.Context, cfg queue.QueueConfig) error {
+	var visibilityMS *int64
+	if cfg.Visibility > 0 {
+		ms := cfg.Visibility.Milliseconds()
+		visibilityMS = &ms
+	}
+	var maxRetries *int
+	if cfg.MaxRetries > 0 {
+		maxRetries = &cfg.MaxRetries
+	}
+	claimOrder := cfg.ClaimOrder
+	if claimOrder == "" {
+		claimOrder = queue.ClaimOrderID
+	}
+	var dedupWindowMS *int64
+	if cfg.DedupWindow > 0 {
+		ms := cfg.DedupWindow.Milliseconds()
+		dedupWindowMS = &ms
+	}
+	_, err := p.pool.Exec(ctx, sqlPutQueueConfig,
+		cfg.Queue,
+		cfg.Partitions,
+		visibilityMS,
+		maxRetries,
+		cfg.DLQ,
+		dedupWindowMS,
+		cfg.RequireObjectBody,
+		claimOrder,
+		cfg.Role,
+		cfg.SkipAfterAttempts,
+		cfg.Paused,
+		cfg.MaxInFlight,
+		cfg.RequeueInPlace,
+		cfg.CloudEvents,
+		cfg.DefaultDelay.Milliseconds(),
+		cfg.Archive,
+	)
+	return err
+}
+
+// SealGroup seals the queue's group and returns the ID of its last message,
+// nil if the group had no messages left when first sealed.
+func (p *PostgresStore) SealGroup(ctx context.Context, name, group string) (*int64, error) {
+	if _, err := p.pool.Exec(ctx, sqlSealGroup, name, group); err != nil {
+		return nil, err
+	}
+	var last *int64
+	err := p.pool.QueryRow(ctx, sqlSealedGroupLast, name, group).Scan(&last)
+	return last, err
+}
+
+// SetPaused pauses or resumes the queue, leaving the rest of its config as is.
+func (p *PostgresStore) SetPaused(ctx context.Context, name string, paused bool) error {
+	_, err := p.pool.Exec(ctx, sqlSetPaused, name, paused)
+	return err
+}
+
+// GetSubscriptions returns the queues subscribed to topic.
+func (p *PostgresStore) GetSubscriptions(ctx context.Context, topic string) ([]string, error) {
+	rows, err := p.pool.Query(ctx, sqlGetSubscriptions, topic)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowTo[string])
+}
+
+// PutSubscriptions swaps topic's subscriptions for queues in one transaction.
+func (p *PostgresStore) PutSubscriptions(ctx context.Context, topic string, queues []string) error {
+	tx, err := p.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, sqlDeleteSubscriptions, topic); err != nil {
+		return err
+	}
+	if len(queues) > 0 {
+		if _, err := tx.Exec(ctx, sqlInsertSubscriptions, topic, queues); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+// PutPushSubscription upserts the queue's webhook.
+func (p *PostgresStore) PutPushSubscription(ctx context.Context, name, url string) (queue.PushSubscription, error) {
+	var sub queue.PushSubscription
+	err := p.pool.QueryRow(ctx, sqlPutPushSubscription, name, url).Scan(&sub.Queue, &sub.URL, &sub.CreatedAt)
+	return sub, err
+}
+
+// DeletePushSubscription drops the queue's webhook.
+func (p *PostgresStore) DeletePushSubscription(ctx context.Context, name string) (bool, error) {
+	tag, err := p.pool.Exec(ctx, sqlDeletePushSubscription, name)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// PushSubscriptions lists the webhooks of all queues.
+func (p *PostgresStore) PushSubscriptions(ctx context.Context) ([]queue.PushSubscription, error) {
+	rows, err := p.pool.Query(ctx, sqlPushSubscriptions)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (queue.PushSubscription, error) {
+		var sub queue.PushSubscription
+		err := row.Scan(&sub.Queue, &sub.URL, &sub.CreatedAt)
+		return sub, err
+	})
+}
+
+// ReadAfter lists the queue's messages past afterID, lowest ID first.
+func (p *PostgresStore) ReadAfter(ctx context.Context, name string, afterID int64, limit int) ([]queue.Message, error) {
+	rows, err := p.pool.Query(ctx, sqlReadAfter, name, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []queue.Message
+	for rows.Next() {
+		var m queue.Message
+		if err := scanMessage(rows, &m); err != nil {
+			return nil, err
+		}
+		out = append(out, m)
+	}
+	return out, rows.Err()
+}
+
+// GetCheckpoint reads the group's checkpoint, if it has committed one.
+func (p *PostgresStore) GetCheckpoint(ctx context.Context, name, group string) (queue.Checkpoint, error) {
+	cp := queue.Checkpoint{Queue: name, Group: group}
+	err := p.pool.QueryRow(ctx, sqlGetCheckpoint, name, group).Scan(&cp.LastID, &cp.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return cp, nil
+	}
+	return cp, err
+}
+
+// SetCheckpoint upserts the group's checkpoint.
+func (p *PostgresStore) SetCheckpoint(ctx context.Context, name, group string, lastID int64) (queue.Checkpoint, error) {
+	cp := queue.Checkpoint{Queue: name, Group: group}
+	err := p.pool.QueryRow(ctx, sqlSetCheckpoint, name, group, lastID).Scan(&cp.LastID, &cp.UpdatedAt)
+	return cp, err
+}
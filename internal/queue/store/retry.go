@@ -0,0 +1,306 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/aridsondez/AWS-SQS-LITE/internal/queue"
+)
+
+// retryBackoff is the base wait before a retry; attempt n waits n times this,
+// plus up to as much again in jitter so colliding callers spread out.
+const retryBackoff = 10 * time.Millisecond
+
+// RetryStore wraps a Store and retries calls that fail with a serialization
+// failure (40001) or deadlock (40P01). Postgres rolls the whole transaction
+// back in both cases, so running the call again is safe. Any other error is
+// returned as is.
+type RetryStore struct {
+	next       Store
+	maxRetries int
+}
+
+var _ Store = (*RetryStore)(nil)
+
+// NewRetryStore returns next wrapped to retry each call up to maxRetries times.
+func NewRetryStore(next Store, maxRetries int) *RetryStore {
+	return &RetryStore{next: next, maxRetries: maxRetries}
+}
+
+// retryable reports whether err is a Postgres error that is safe to retry.
+func retryable(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	return pgErr.Code == "40001" || pgErr.Code == "40P01"
+}
+
+// withRetry runs f until it succeeds, fails with a non-retryable error, runs
+// out of retries, or ctx is done; it returns f's last result.
+func withRetry[T any](ctx context.Context, r *RetryStore, f func() (T, error)) (T, error) {
+	v, err := f()
+	for attempt := 1; attempt <= r.maxRetries && retryable(err); attempt++ {
+		wait := time.Duration(attempt) * retryBackoff
+		wait += rand.N(wait)
+		select {
+		case <-ctx.Done():
+			return v, err
+		case <-time.After(wait):
+		}
+		v, err = f()
+	}
+	return v, err
+}
+
+func (r *RetryStore) Enqueue(ctx context.Context, m queue.Message, delay time.Duration) (int64, error) {
+	return withRetry(ctx, r, func() (int64, error) { return r.next.Enqueue(ctx, m, delay) })
+}
+
+func (r *RetryStore) EnqueueKeyed(ctx context.Context, m queue.Message, delay time.Duration, key queue.EnqueueKey) (int64, bool, error) {
+	type keyed struct {
+		id       int64
+		replayed bool
+	}
+	res, err := withRetry(ctx, r, func() (keyed, error) {
+		id, replayed, err := r.next.EnqueueKeyed(ctx, m, delay, key)
+		return keyed{id, replayed}, err
+	})
+	return res.id, res.replayed, err
+}
+
+func (r *RetryStore) EnqueueCoalesced(ctx context.Context, m queue.Message, delay time.Duration) (int64, bool, error) {
+	type coalesced struct {
+		id        int64
+		coalesced bool
+	}
+	res, err := withRetry(ctx, r, func() (coalesced, error) {
+		id, ok, err := r.next.EnqueueCoalesced(ctx, m, delay)
+		return coalesced{id, ok}, err
+	})
+	return res.id, res.coalesced, err
+}
+
+func (r *RetryStore) EnqueueBatch(ctx context.Context, entries []queue.EnqueueEntry) ([]int64, error) {
+	return withRetry(ctx, r, func() ([]int64, error) { return r.next.EnqueueBatch(ctx, entries) })
+}
+
+func (r *RetryStore) Claim(ctx context.Context, opts queue.ClaimOptions) ([]queue.Message, error) {
+	return withRetry(ctx, r, func() ([]queue.Message, error) { return r.next.Claim(ctx, opts) })
+}
+
+func (r *RetryStore) ClaimMulti(ctx context.Context, opts queue.ClaimMultiOptions) ([]queue.Message, error) {
+	return withRetry(ctx, r, func() ([]queue.Message, error) { return r.next.ClaimMulti(ctx, opts) })
+}
+
+func (r *RetryStore) ClaimAndDelete(ctx context.Context, name string, limit int) ([]queue.Message, error) {
+	return withRetry(ctx, r, func() ([]queue.Message, error) { return r.next.ClaimAndDelete(ctx, name, limit) })
+}
+
+func (r *RetryStore) Ack(ctx context.Context, rc queue.Receipt) (bool, error) {
+	return withRetry(ctx, r, func() (bool, error) { return r.next.Ack(ctx, rc) })
+}
+
+func (r *RetryStore) AckOnce(ctx context.Context, rc queue.Receipt, ackID string, ttl time.Duration) (bool, bool, error) {
+	type once struct {
+		acked    bool
+		replayed bool
+	}
+	res, err := withRetry(ctx, r, func() (once, error) {
+		acked, replayed, err := r.next.AckOnce(ctx, rc, ackID, ttl)
+		return once{acked, replayed}, err
+	})
+	return res.acked, res.replayed, err
+}
+
+func (r *RetryStore) AckAndEnqueue(ctx context.Context, rc queue.Receipt, m queue.Message, delay time.Duration) (int64, bool, bool, error) {
+	type handoff struct {
+		id        int64
+		acked     bool
+		coalesced bool
+	}
+	res, err := withRetry(ctx, r, func() (handoff, error) {
+		id, acked, coalesced, err := r.next.AckAndEnqueue(ctx, rc, m, delay)
+		return handoff{id, acked, coalesced}, err
+	})
+	return res.id, res.acked, res.coalesced, err
+}
+
+func (r *RetryStore) AckBatch(ctx context.Context, rcs []queue.Receipt) ([]int64, map[int64]error, error) {
+	type batch struct {
+		acked    []int64
+		rejected map[int64]error
+	}
+	res, err := withRetry(ctx, r, func() (batch, error) {
+		acked, rejected, err := r.next.AckBatch(ctx, rcs)
+		return batch{acked, rejected}, err
+	})
+	return res.acked, res.rejected, err
+}
+
+func (r *RetryStore) Nack(ctx context.Context, rc queue.Receipt, reason string) (bool, error) {
+	return withRetry(ctx, r, func() (bool, error) { return r.next.Nack(ctx, rc, reason) })
+}
+
+func (r *RetryStore) NackToDLQ(ctx context.Context, rc queue.Receipt, reason string) (string, bool, error) {
+	type deadLettered struct {
+		dlq   string
+		moved bool
+	}
+	res, err := withRetry(ctx, r, func() (deadLettered, error) {
+		dlq, moved, err := r.next.NackToDLQ(ctx, rc, reason)
+		return deadLettered{dlq, moved}, err
+	})
+	return res.dlq, res.moved, err
+}
+
+func (r *RetryStore) DelayedMessages(ctx context.Context, name string, limit int) ([]queue.Message, error) {
+	return withRetry(ctx, r, func() ([]queue.Message, error) { return r.next.DelayedMessages(ctx, name, limit) })
+}
+
+func (r *RetryStore) CancelMessage(ctx context.Context, id int64, force bool) (string, error) {
+	return withRetry(ctx, r, func() (string, error) { return r.next.CancelMessage(ctx, id, force) })
+}
+
+func (r *RetryStore) GetMessage(ctx context.Context, id int64) (queue.Message, error) {
+	return withRetry(ctx, r, func() (queue.Message, error) { return r.next.GetMessage(ctx, id) })
+}
+
+func (r *RetryStore) UpdateBody(ctx context.Context, id int64, body []byte) (queue.Message, error) {
+	return withRetry(ctx, r, func() (queue.Message, error) { return r.next.UpdateBody(ctx, id, body) })
+}
+
+func (r *RetryStore) MessageULIDs(ctx context.Context, ids []int64) (map[int64]string, error) {
+	return withRetry(ctx, r, func() (map[int64]string, error) { return r.next.MessageULIDs(ctx, ids) })
+}
+
+func (r *RetryStore) MessageIDsByULID(ctx context.Context, ulids []string) (map[string]int64, error) {
+	return withRetry(ctx, r, func() (map[string]int64, error) { return r.next.MessageIDsByULID(ctx, ulids) })
+}
+
+func (r *RetryStore) ClaimByID(ctx context.Context, id int64, visibility time.Duration) (queue.Message, error) {
+	return withRetry(ctx, r, func() (queue.Message, error) { return r.next.ClaimByID(ctx, id, visibility) })
+}
+
+func (r *RetryStore) DeadLetters(ctx context.Context, name string, limit int) ([]queue.Message, error) {
+	return withRetry(ctx, r, func() ([]queue.Message, error) { return r.next.DeadLetters(ctx, name, limit) })
+}
+
+func (r *RetryStore) Sweeper(ctx context.Context, opts queue.SweepOptions) (int, error) {
+	return withRetry(ctx, r, func() (int, error) { return r.next.Sweeper(ctx, opts) })
+}
+
+func (r *RetryStore) ExtendBatch(ctx context.Context, rcs []queue.Receipt, visibility time.Duration) ([]int64, error) {
+	return withRetry(ctx, r, func() ([]int64, error) { return r.next.ExtendBatch(ctx, rcs, visibility) })
+}
+
+func (r *RetryStore) Release(ctx context.Context, rcs []queue.Receipt) ([]int64, error) {
+	return withRetry(ctx, r, func() ([]int64, error) { return r.next.Release(ctx, rcs) })
+}
+
+func (r *RetryStore) ReleaseLeases(ctx context.Context, name string) (int64, error) {
+	return withRetry(ctx, r, func() (int64, error) { return r.next.ReleaseLeases(ctx, name) })
+}
+
+func (r *RetryStore) RenameQueue(ctx context.Context, oldName, newName string, merge bool) (int64, error) {
+	return withRetry(ctx, r, func() (int64, error) { return r.next.RenameQueue(ctx, oldName, newName, merge) })
+}
+
+func (r *RetryStore) Redrive(ctx context.Context, name, target string, limit int) (int, error) {
+	return withRetry(ctx, r, func() (int, error) { return r.next.Redrive(ctx, name, target, limit) })
+}
+
+func (r *RetryStore) ArchivedMessages(ctx context.Context, f queue.ArchiveFilter, limit int) ([]queue.ArchivedMessage, error) {
+	return withRetry(ctx, r, func() ([]queue.ArchivedMessage, error) { return r.next.ArchivedMessages(ctx, f, limit) })
+}
+
+func (r *RetryStore) ResetDeliveryCount(ctx context.Context, id int64) (bool, error) {
+	return withRetry(ctx, r, func() (bool, error) { return r.next.ResetDeliveryCount(ctx, id) })
+}
+
+func (r *RetryStore) ListQueues(ctx context.Context) ([]string, error) {
+	return withRetry(ctx, r, func() ([]string, error) { return r.next.ListQueues(ctx) })
+}
+
+func (r *RetryStore) Stats(ctx context.Context, name string) (queue.Stats, error) {
+	return withRetry(ctx, r, func() (queue.Stats, error) { return r.next.Stats(ctx, name) })
+}
+
+func (r *RetryStore) CountInFlight(ctx context.Context) (int64, error) {
+	return withRetry(ctx, r, func() (int64, error) { return r.next.CountInFlight(ctx) })
+}
+
+func (r *RetryStore) Diagnose(ctx context.Context, name string) (queue.Diagnosis, error) {
+	return withRetry(ctx, r, func() (queue.Diagnosis, error) { return r.next.Diagnose(ctx, name) })
+}
+
+func (r *RetryStore) Count(ctx context.Context, name string, filter queue.CountFilter) (int64, error) {
+	return withRetry(ctx, r, func() (int64, error) { return r.next.Count(ctx, name, filter) })
+}
+
+func (r *RetryStore) GetQueueConfig(ctx context.Context, name string) (queue.QueueConfig, error) {
+	return withRetry(ctx, r, func() (queue.QueueConfig, error) { return r.next.GetQueueConfig(ctx, name) })
+}
+
+func (r *RetryStore) PutQueueConfig(ctx context.Context, cfg queue.QueueConfig) error {
+	_, err := withRetry(ctx, r, func() (struct{}, error) { return struct{}{}, r.next.PutQueueConfig(ctx, cfg) })
+	return err
+}
+
+func (r *RetryStore) SetPaused(ctx context.Context, name string, paused bool) error {
+	_, err := withRetry(ctx, r, func() (struct{}, error) { return struct{}{}, r.next.SetPaused(ctx, name, paused) })
+	return err
+}
+
+func (r *RetryStore) SealGroup(ctx context.Context, name, group string) (*int64, error) {
+	return withRetry(ctx, r, func() (*int64, error) { return r.next.SealGroup(ctx, name, group) })
+}
+
+func (r *RetryStore) GetSubscriptions(ctx context.Context, topic string) ([]string, error) {
+	return withRetry(ctx, r, func() ([]string, error) { return r.next.GetSubscriptions(ctx, topic) })
+}
+
+func (r *RetryStore) PutSubscriptions(ctx context.Context, topic string, queues []string) error {
+	_, err := withRetry(ctx, r, func() (struct{}, error) { return struct{}{}, r.next.PutSubscriptions(ctx, topic, queues) })
+	return err
+}
+
+func (r *RetryStore) PutPushSubscription(ctx context.Context, name, url string) (queue.PushSubscription, error) {
+	return withRetry(ctx, r, func() (queue.PushSubscription, error) { return r.next.PutPushSubscription(ctx, name, url) })
+}
+
+func (r *RetryStore) DeletePushSubscription(ctx context.Context, name string) (bool, error) {
+	return withRetry(ctx, r, func() (bool, error) { return r.next.DeletePushSubscription(ctx, name) })
+}
+
+func (r *RetryStore) PushSubscriptions(ctx context.Context) ([]queue.PushSubscription, error) {
+	return withRetry(ctx, r, func() ([]queue.PushSubscription, error) { return r.next.PushSubscriptions(ctx) })
+}
+
+func (r *RetryStore) RegisterQueue(ctx context.Context, name string, max int) (bool, error) {
+	return withRetry(ctx, r, func() (bool, error) { return r.next.RegisterQueue(ctx, name, max) })
+}
+
+func (r *RetryStore) QueueRegistered(ctx context.Context, name string) (bool, error) {
+	return withRetry(ctx, r, func() (bool, error) { return r.next.QueueRegistered(ctx, name) })
+}
+
+func (r *RetryStore) DeleteQueue(ctx context.Context, name string) (bool, error) {
+	return withRetry(ctx, r, func() (bool, error) { return r.next.DeleteQueue(ctx, name) })
+}
+
+func (r *RetryStore) ReadAfter(ctx context.Context, name string, afterID int64, limit int) ([]queue.Message, error) {
+	return withRetry(ctx, r, func() ([]queue.Message, error) { return r.next.ReadAfter(ctx, name, afterID, limit) })
+}
+
+func (r *RetryStore) GetCheckpoint(ctx context.Context, name, group string) (queue.Checkpoint, error) {
+	return withRetry(ctx, r, func() (queue.Checkpoint, error) { return r.next.GetCheckpoint(ctx, name, group) })
+}
+
+func (r *RetryStore) SetCheckpoint(ctx context.Context, name, group string, lastID int64) (queue.Checkpoint, error) {
+	return withRetry(ctx, r, func() (queue.Checkpoint, error) { return r.next.SetCheckpoint(ctx, name, group, lastID) })
+}
@@ -0,0 +1,289 @@
+package store
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/aridsondez/AWS-SQS-LITE/internal/metrics"
+	"github.com/aridsondez/AWS-SQS-LITE/internal/queue"
+)
+
+// SlowQueryStore wraps a Store and logs every call that takes longer than
+// its threshold. Calls are timed whole, so a slow call may be one slow query
+// or several, and includes time spent waiting for a pool connection.
+type SlowQueryStore struct {
+	next      Store
+	threshold time.Duration
+}
+
+var _ Store = (*SlowQueryStore)(nil)
+
+// NewSlowQueryStore returns next wrapped to log calls slower than threshold.
+func NewSlowQueryStore(next Store, threshold time.Duration) *SlowQueryStore {
+	return &SlowQueryStore{next: next, threshold: threshold}
+}
+
+// observe logs and counts op if it has run longer than the threshold. Use as
+// defer s.observe("Op", queue, time.Now()).
+func (s *SlowQueryStore) observe(op, qname string, start time.Time) {
+	d := time.Since(start)
+	if d < s.threshold {
+		return
+	}
+	metrics.SlowQueries.WithLabelValues(op).Inc()
+	if qname == "" {
+		log.Printf("WARNING: slow store call: %s took %s", op, d.Round(time.Millisecond))
+		return
+	}
+	log.Printf("WARNING: slow store call: %s on %s took %s", op, qname, d.Round(time.Millisecond))
+}
+
+func (s *SlowQueryStore) Enqueue(ctx context.Context, m queue.Message, delay time.Duration) (int64, error) {
+	defer s.observe("Enqueue", m.Queue, time.Now())
+	return s.next.Enqueue(ctx, m, delay)
+}
+
+func (s *SlowQueryStore) EnqueueKeyed(ctx context.Context, m queue.Message, delay time.Duration, key queue.EnqueueKey) (int64, bool, error) {
+	defer s.observe("EnqueueKeyed", m.Queue, time.Now())
+	return s.next.EnqueueKeyed(ctx, m, delay, key)
+}
+
+func (s *SlowQueryStore) EnqueueCoalesced(ctx context.Context, m queue.Message, delay time.Duration) (int64, bool, error) {
+	defer s.observe("EnqueueCoalesced", m.Queue, time.Now())
+	return s.next.EnqueueCoalesced(ctx, m, delay)
+}
+
+func (s *SlowQueryStore) EnqueueBatch(ctx context.Context, entries []queue.EnqueueEntry) ([]int64, error) {
+	var qname string
+	if len(entries) > 0 {
+		qname = entries[0].Message.Queue
+	}
+	defer s.observe("EnqueueBatch", qname, time.Now())
+	return s.next.EnqueueBatch(ctx, entries)
+}
+
+func (s *SlowQueryStore) Claim(ctx context.Context, opts queue.ClaimOptions) ([]queue.Message, error) {
+	defer s.observe("Claim", opts.Queue, time.Now())
+	return s.next.Claim(ctx, opts)
+}
+
+func (s *SlowQueryStore) ClaimMulti(ctx context.Context, opts queue.ClaimMultiOptions) ([]queue.Message, error) {
+	defer s.observe("ClaimMulti", "", time.Now())
+	return s.next.ClaimMulti(ctx, opts)
+}
+
+func (s *SlowQueryStore) ClaimAndDelete(ctx context.Context, name string, limit int) ([]queue.Message, error) {
+	defer s.observe("ClaimAndDelete", name, time.Now())
+	return s.next.ClaimAndDelete(ctx, name, limit)
+}
+
+func (s *SlowQueryStore) Ack(ctx context.Context, rc queue.Receipt) (bool, error) {
+	defer s.observe("Ack", "", time.Now())
+	return s.next.Ack(ctx, rc)
+}
+
+func (s *SlowQueryStore) AckOnce(ctx context.Context, rc queue.Receipt, ackID string, ttl time.Duration) (bool, bool, error) {
+	defer s.observe("AckOnce", "", time.Now())
+	return s.next.AckOnce(ctx, rc, ackID, ttl)
+}
+
+func (s *SlowQueryStore) AckAndEnqueue(ctx context.Context, rc queue.Receipt, m queue.Message, delay time.Duration) (int64, bool, bool, error) {
+	defer s.observe("AckAndEnqueue", m.Queue, time.Now())
+	return s.next.AckAndEnqueue(ctx, rc, m, delay)
+}
+
+func (s *SlowQueryStore) AckBatch(ctx context.Context, rcs []queue.Receipt) ([]int64, map[int64]error, error) {
+	defer s.observe("AckBatch", "", time.Now())
+	return s.next.AckBatch(ctx, rcs)
+}
+
+func (s *SlowQueryStore) Nack(ctx context.Context, rc queue.Receipt, reason string) (bool, error) {
+	defer s.observe("Nack", "", time.Now())
+	return s.next.Nack(ctx, rc, reason)
+}
+
+func (s *SlowQueryStore) NackToDLQ(ctx context.Context, rc queue.Receipt, reason string) (string, bool, error) {
+	defer s.observe("NackToDLQ", "", time.Now())
+	return s.next.NackToDLQ(ctx, rc, reason)
+}
+
+func (s *SlowQueryStore) DelayedMessages(ctx context.Context, name string, limit int) ([]queue.Message, error) {
+	defer s.observe("DelayedMessages", name, time.Now())
+	return s.next.DelayedMessages(ctx, name, limit)
+}
+
+func (s *SlowQueryStore) CancelMessage(ctx context.Context, id int64, force bool) (string, error) {
+	defer s.observe("CancelMessage", "", time.Now())
+	return s.next.CancelMessage(ctx, id, force)
+}
+
+func (s *SlowQueryStore) GetMessage(ctx context.Context, id int64) (queue.Message, error) {
+	defer s.observe("GetMessage", "", time.Now())
+	return s.next.GetMessage(ctx, id)
+}
+
+func (s *SlowQueryStore) UpdateBody(ctx context.Context, id int64, body []byte) (queue.Message, error) {
+	defer s.observe("UpdateBody", "", time.Now())
+	return s.next.UpdateBody(ctx, id, body)
+}
+
+func (s *SlowQueryStore) MessageULIDs(ctx context.Context, ids []int64) (map[int64]string, error) {
+	defer s.observe("MessageULIDs", "", time.Now())
+	return s.next.MessageULIDs(ctx, ids)
+}
+
+func (s *SlowQueryStore) MessageIDsByULID(ctx context.Context, ulids []string) (map[string]int64, error) {
+	defer s.observe("MessageIDsByULID", "", time.Now())
+	return s.next.MessageIDsByULID(ctx, ulids)
+}
+
+func (s *SlowQueryStore) ClaimByID(ctx context.Context, id int64, visibility time.Duration) (queue.Message, error) {
+	defer s.observe("ClaimByID", "", time.Now())
+	return s.next.ClaimByID(ctx, id, visibility)
+}
+
+func (s *SlowQueryStore) DeadLetters(ctx context.Context, name string, limit int) ([]queue.Message, error) {
+	defer s.observe("DeadLetters", name, time.Now())
+	return s.next.DeadLetters(ctx, name, limit)
+}
+
+func (s *SlowQueryStore) Sweeper(ctx context.Context, opts queue.SweepOptions) (int, error) {
+	defer s.observe("Sweeper", "", time.Now())
+	return s.next.Sweeper(ctx, opts)
+}
+
+func (s *SlowQueryStore) ExtendBatch(ctx context.Context, rcs []queue.Receipt, visibility time.Duration) ([]int64, error) {
+	defer s.observe("ExtendBatch", "", time.Now())
+	return s.next.ExtendBatch(ctx, rcs, visibility)
+}
+
+func (s *SlowQueryStore) Release(ctx context.Context, rcs []queue.Receipt) ([]int64, error) {
+	defer s.observe("Release", "", time.Now())
+	return s.next.Release(ctx, rcs)
+}
+
+func (s *SlowQueryStore) ReleaseLeases(ctx context.Context, name string) (int64, error) {
+	defer s.observe("ReleaseLeases", name, time.Now())
+	return s.next.ReleaseLeases(ctx, name)
+}
+
+func (s *SlowQueryStore) RenameQueue(ctx context.Context, oldName, newName string, merge bool) (int64, error) {
+	defer s.observe("RenameQueue", oldName, time.Now())
+	return s.next.RenameQueue(ctx, oldName, newName, merge)
+}
+
+func (s *SlowQueryStore) Redrive(ctx context.Context, name, target string, limit int) (int, error) {
+	defer s.observe("Redrive", name, time.Now())
+	return s.next.Redrive(ctx, name, target, limit)
+}
+
+func (s *SlowQueryStore) ArchivedMessages(ctx context.Context, f queue.ArchiveFilter, limit int) ([]queue.ArchivedMessage, error) {
+	defer s.observe("ArchivedMessages", f.Queue, time.Now())
+	return s.next.ArchivedMessages(ctx, f, limit)
+}
+
+func (s *SlowQueryStore) ResetDeliveryCount(ctx context.Context, id int64) (bool, error) {
+	defer s.observe("ResetDeliveryCount", "", time.Now())
+	return s.next.ResetDeliveryCount(ctx, id)
+}
+
+func (s *SlowQueryStore) ListQueues(ctx context.Context) ([]string, error) {
+	defer s.observe("ListQueues", "", time.Now())
+	return s.next.ListQueues(ctx)
+}
+
+func (s *SlowQueryStore) Stats(ctx context.Context, name string) (queue.Stats, error) {
+	defer s.observe("Stats", name, time.Now())
+	return s.next.Stats(ctx, name)
+}
+
+func (s *SlowQueryStore) CountInFlight(ctx context.Context) (int64, error) {
+	defer s.observe("CountInFlight", "", time.Now())
+	return s.next.CountInFlight(ctx)
+}
+
+func (s *SlowQueryStore) Diagnose(ctx context.Context, name string) (queue.Diagnosis, error) {
+	defer s.observe("Diagnose", name, time.Now())
+	return s.next.Diagnose(ctx, name)
+}
+
+func (s *SlowQueryStore) Count(ctx context.Context, name string, filter queue.CountFilter) (int64, error) {
+	defer s.observe("Count", name, time.Now())
+	return s.next.Count(ctx, name, filter)
+}
+
+func (s *SlowQueryStore) GetQueueConfig(ctx context.Context, name string) (queue.QueueConfig, error) {
+	defer s.observe("GetQueueConfig", name, time.Now())
+	return s.next.GetQueueConfig(ctx, name)
+}
+
+func (s *SlowQueryStore) PutQueueConfig(ctx context.Context, cfg queue.QueueConfig) error {
+	defer s.observe("PutQueueConfig", cfg.Queue, time.Now())
+	return s.next.PutQueueConfig(ctx, cfg)
+}
+
+func (s *SlowQueryStore) SetPaused(ctx context.Context, name string, paused bool) error {
+	defer s.observe("SetPaused", name, time.Now())
+	return s.next.SetPaused(ctx, name, paused)
+}
+
+func (s *SlowQueryStore) SealGroup(ctx context.Context, name, group string) (*int64, error) {
+	defer s.observe("SealGroup", name, time.Now())
+	return s.next.SealGroup(ctx, name, group)
+}
+
+func (s *SlowQueryStore) GetSubscriptions(ctx context.Context, topic string) ([]string, error) {
+	defer s.observe("GetSubscriptions", "", time.Now())
+	return s.next.GetSubscriptions(ctx, topic)
+}
+
+func (s *SlowQueryStore) PutSubscriptions(ctx context.Context, topic string, queues []string) error {
+	defer s.observe("PutSubscriptions", "", time.Now())
+	return s.next.PutSubscriptions(ctx, topic, queues)
+}
+
+func (s *SlowQueryStore) PutPushSubscription(ctx context.Context, name, url string) (queue.PushSubscription, error) {
+	defer s.observe("PutPushSubscription", name, time.Now())
+	return s.next.PutPushSubscription(ctx, name, url)
+}
+
+func (s *SlowQueryStore) DeletePushSubscription(ctx context.Context, name string) (bool, error) {
+	defer s.observe("DeletePushSubscription", name, time.Now())
+	return s.next.DeletePushSubscription(ctx, name)
+}
+
+func (s *SlowQueryStore) PushSubscriptions(ctx context.Context) ([]queue.PushSubscription, error) {
+	defer s.observe("PushSubscriptions", "", time.Now())
+	return s.next.PushSubscriptions(ctx)
+}
+
+func (s *SlowQueryStore) RegisterQueue(ctx context.Context, name string, max int) (bool, error) {
+	defer s.observe("RegisterQueue", name, time.Now())
+	return s.next.RegisterQueue(ctx, name, max)
+}
+
+func (s *SlowQueryStore) QueueRegistered(ctx context.Context, name string) (bool, error) {
+	defer s.observe("QueueRegistered", name, time.Now())
+	return s.next.QueueRegistered(ctx, name)
+}
+
+func (s *SlowQueryStore) DeleteQueue(ctx context.Context, name string) (bool, error) {
+	defer s.observe("DeleteQueue", name, time.Now())
+	return s.next.DeleteQueue(ctx, name)
+}
+
+func (s *SlowQueryStore) ReadAfter(ctx context.Context, name string, afterID int64, limit int) ([]queue.Message, error) {
+	defer s.observe("ReadAfter", name, time.Now())
+	return s.next.ReadAfter(ctx, name, afterID, limit)
+}
+
+func (s *SlowQueryStore) GetCheckpoint(ctx context.Context, name, group string) (queue.Checkpoint, error) {
+	defer s.observe("GetCheckpoint", name, time.Now())
+	return s.next.GetCheckpoint(ctx, name, group)
+}
+
+func (s *SlowQueryStore) SetCheckpoint(ctx context.Context, name, group string, lastID int64) (queue.Checkpoint, error) {
+	defer s.observe("SetCheckpoint", name, time.Now())
+	return s.next.SetCheckpoint(ctx, name, group, lastID)
+}
@@ -9,14 +9,238 @@ import (
 
 // Store is the DB-agnostic interface the rest of the app uses.
 type Store interface {
-	// Enqueue inserts a message (delay can be 0).
+	// Enqueue inserts a message (delay can be 0). A non-zero m.NotBefore is
+	// used as the time it becomes visible instead of now+delay. A body that
+	// fails queue.ValidateBody is refused with queue.ErrInvalidBody.
+	// m.MaxRetries is stored as given; the store applies no default, so a
+	// message left at 0 gets one delivery and no retries.
 	Enqueue(ctx context.Context, m queue.Message, delay time.Duration) (int64, error)
 
+	// EnqueueKeyed inserts a message unless key is already held for the queue,
+	// in which case it returns the original message ID and replayed=true.
+	EnqueueKeyed(ctx context.Context, m queue.Message, delay time.Duration, key queue.EnqueueKey) (id int64, replayed bool, err error)
+
+	// EnqueueCoalesced inserts a message unless one with the same
+	// m.CoalesceKey is pending in the queue, in which case it returns that
+	// message's ID and coalesced=true. Enqueue and EnqueueBatch coalesce the
+	// same way but don't say so.
+	EnqueueCoalesced(ctx context.Context, m queue.Message, delay time.Duration) (id int64, coalesced bool, err error)
+
+	// EnqueueBatch inserts all entries in one transaction and returns their IDs
+	// in order. Either every entry is enqueued or none is. An entry for a
+	// sealed group, or with a body queue.ValidateBody refuses, fails the
+	// batch with a *queue.EntryError naming it.
+	EnqueueBatch(ctx context.Context, entries []queue.EnqueueEntry) ([]int64, error)
+
 	// Claim atomically leases up to Limit messages from a queue.
 	Claim(ctx context.Context, opts queue.ClaimOptions) ([]queue.Message, error)
 
-	// Ack deletes the message by ID; returns true if deleted.
-	Ack(ctx context.Context, id int64) (bool, error)
+	// ClaimMulti leases up to opts.Limit messages across opts.Queues, splitting
+	// the limit by weight (see queue.AllocateClaims). Budget a queue can't use
+	// is offered to the others.
+	ClaimMulti(ctx context.Context, opts queue.ClaimMultiOptions) ([]queue.Message, error)
+
+	// ClaimAndDelete removes up to limit available messages and returns them,
+	// in one statement. Nothing is leased: a message lost by the caller is
+	// gone for good.
+	ClaimAndDelete(ctx context.Context, name string, limit int) ([]queue.Message, error)
+
+	// Ack deletes the message the receipt was issued for; returns true if deleted.
+	// Returns queue.ErrStaleReceipt if the message has been leased again since.
+	// Returns queue.ErrGroupOrder if the message has a group and an earlier
+	// message of the group is still in the queue.
+	Ack(ctx context.Context, rc queue.Receipt) (bool, error)
+
+	// AckOnce is Ack keyed by a caller-chosen ackID, remembered for ttl: a
+	// retry of an ack that already went through reports acked and replayed
+	// instead of not found.
+	AckOnce(ctx context.Context, rc queue.Receipt, ackID string, ttl time.Duration) (acked, replayed bool, err error)
+
+	// AckAndEnqueue acks rc and enqueues m in one transaction, so a workflow
+	// step hands off to the next without losing or repeating it: if either
+	// fails, neither happens. acked is false, with no enqueue, when Ack would
+	// report not found; Ack's errors apply, and Enqueue's. coalesced is set
+	// when m's coalesce key was already pending and id names that message.
+	AckAndEnqueue(ctx context.Context, rc queue.Receipt, m queue.Message, delay time.Duration) (id int64, acked, coalesced bool, err error)
+
+	// AckBatch acks every receipt it can in one transaction, in ID order, so
+	// a batch may ack a group's messages in sequence. Returns the IDs acked
+	// and, by ID, why each other receipt was refused: queue.ErrStaleReceipt,
+	// queue.ErrGroupOrder or queue.ErrMessageNotFound.
+	AckBatch(ctx context.Context, rcs []queue.Receipt) (acked []int64, rejected map[int64]error, err error)
+
+	// Nack gives up the lease at once, recording reason as the message's
+	// last error; the sweeper then requeues or dead-letters it as for an
+	// expired lease. Returns false if the lease has already expired, and
+	// queue.ErrStaleReceipt if the message has been leased again since.
+	Nack(ctx context.Context, rc queue.Receipt, reason string) (bool, error)
+
+	// NackToDLQ is a terminal nack: if the receipt holds a live lease and
+	// the message's delivery count has reached its max_retries, the message
+	// moves to its DLQ at once, as the sweeper would move it, and NackToDLQ
+	// returns the DLQ and true. Otherwise nothing changes and it returns
+	// false; the caller can fall back to Nack.
+	NackToDLQ(ctx context.Context, rc queue.Receipt, reason string) (dlq string, moved bool, err error)
+
+	// DeadLetters returns up to limit of the messages the sweeper moved into
+	// the queue, most recently dead-lettered first, without leasing them.
+	DeadLetters(ctx context.Context, name string, limit int) ([]queue.Message, error)
+
+	// DelayedMessages returns up to limit of the queue's messages that
+	// aren't visible yet, soonest first, without leasing them.
+	DelayedMessages(ctx context.Context, name string, limit int) ([]queue.Message, error)
+
+	// CancelMessage deletes a message that has never been delivered and
+	// returns its queue: queue.ErrMessageDelivered if it has been, unless
+	// force is set, and queue.ErrMessageNotFound if it is gone.
+	CancelMessage(ctx context.Context, id int64, force bool) (string, error)
+
+	// GetMessage returns a message by ID without leasing it, or
+	// queue.ErrMessageNotFound if it is gone.
+	GetMessage(ctx context.Context, id int64) (queue.Message, error)
+
+	// UpdateBody replaces the body of a message that has never been
+	// delivered and returns it: queue.ErrMessageDelivered if it has been or
+	// is leased, and queue.ErrMessageNotFound if it is gone.
+	UpdateBody(ctx context.Context, id int64, body []byte) (queue.Message, error)
+
+	// MessageULIDs returns the ULIDs of the messages with the given IDs, by
+	// ID. A message that is gone is still found while an idempotency key or
+	// ack ID remembers it; otherwise it is left out.
+	MessageULIDs(ctx context.Context, ids []int64) (map[int64]string, error)
+
+	// MessageIDsByULID is the reverse of MessageULIDs: IDs by ULID.
+	MessageIDsByULID(ctx context.Context, ulids []string) (map[string]int64, error)
+
+	// ClaimByID leases the one message with the given ID, if it is visible,
+	// as a receive would: queue.ErrMessageLeased if a consumer holds it,
+	// queue.ErrMessageDelayed if it isn't due yet, and
+	// queue.ErrMessageNotFound if it is gone. Like a receive it leases
+	// nothing while the queue is paused (queue.ErrQueuePaused) or at its
+	// max_in_flight (queue.ErrMaxInFlight), nor a grouped message ahead of an
+	// earlier one still queued (queue.ErrGroupOrder).
+	ClaimByID(ctx context.Context, id int64, visibility time.Duration) (queue.Message, error)
+
+	// Sweeper requeues expired leases, moves exhausted messages to their DLQ
+	// and does housekeeping; returns how many messages it requeued or moved.
+	Sweeper(ctx context.Context, opts queue.SweepOptions) (int, error)
+
+	// ExtendBatch sets the lease of every message whose receipt is still
+	// current to now+visibility, in one statement. Returns the IDs extended;
+	// receipts that are stale or whose lease already expired are skipped.
+	ExtendBatch(ctx context.Context, rcs []queue.Receipt, visibility time.Duration) ([]int64, error)
+
+	// Release hands back leases that never reached a consumer, e.g. because
+	// the client went away mid-receive: each message whose receipt is current
+	// is available again at once and the delivery isn't counted. Returns the
+	// IDs released.
+	Release(ctx context.Context, rcs []queue.Receipt) ([]int64, error)
+
+	// ReleaseLeases ends every lease in the queue at once, e.g. after a mass
+	// worker crash, instead of waiting out each visibility timeout. Unlike
+	// Release the deliveries still count: a message with retries left is
+	// available again, an exhausted one is left expired for the sweeper.
+	// Outstanding receipts go stale. Returns how many leases ended.
+	ReleaseLeases(ctx context.Context, name string) (int64, error)
+
+	// Redrive moves up to limit of the dead letters in the queue to target,
+	// or when target is empty back to the queue each failed in, with a fresh
+	// delivery count and the queue as their DLQ. Leased dead letters are
+	// left alone. Returns how many moved.
+	Redrive(ctx context.Context, name, target string, limit int) (int, error)
+
+	// ArchivedMessages returns up to limit of the copies kept of messages
+	// acked on queues with archive set that f matches, oldest ack first.
+	// Reading them leaves them archived.
+	ArchivedMessages(ctx context.Context, f queue.ArchiveFilter, limit int) ([]queue.ArchivedMessage, error)
+
+	// RenameQueue moves every message of oldName to newName, along with its
+	// config, subscriptions, enqueue keys, sealed groups, registry entry and
+	// the references to it as a DLQ, in one transaction. Returns how many messages moved.
+	// Renaming onto a queue that has messages fails with
+	// queue.ErrQueueNotEmpty unless merge is set; when merging, the new
+	// name's own config and rows take precedence.
+	RenameQueue(ctx context.Context, oldName, newName string, merge bool) (int64, error)
+
+	// RegisterQueue adds name to the queue registry, reporting whether it
+	// is new. With max above 0, a new name is refused with
+	// queue.ErrTooManyQueues once max queues are registered.
+	RegisterQueue(ctx context.Context, name string, max int) (bool, error)
+
+	// QueueRegistered reports whether name is in the queue registry.
+	QueueRegistered(ctx context.Context, name string) (bool, error)
+
+	// DeleteQueue removes the queue from the registry along with its
+	// messages, config, subscriptions, checkpoints and keys, in one
+	// transaction. Returns false if there was no such queue.
+	DeleteQueue(ctx context.Context, name string) (bool, error)
+
+	// ResetDeliveryCount gives a message a fresh set of retries: its delivery
+	// count goes to 0 and it is made available now, ending any lease (the
+	// holder's receipt goes stale). Returns false if there is no such message.
+	ResetDeliveryCount(ctx context.Context, id int64) (bool, error)
+
+	// ListQueues returns the names of queues that are registered, hold
+	// messages or have stored config, sorted.
+	ListQueues(ctx context.Context) ([]string, error)
+
+	// Stats returns approximate counts of the queue's messages by state.
+	Stats(ctx context.Context, name string) (queue.Stats, error)
+
+	// CountInFlight counts the messages leased across every queue, expired
+	// leases included until they are swept.
+	CountInFlight(ctx context.Context) (int64, error)
+
+	// Diagnose counts the queue's messages by why they can or can't be
+	// claimed, in one scan.
+	Diagnose(ctx context.Context, name string) (queue.Diagnosis, error)
+
+	// Count returns how many of the queue's messages match filter, at most
+	// filter.Limit if that is set.
+	Count(ctx context.Context, name string, filter queue.CountFilter) (int64, error)
+
+	// GetQueueConfig returns the queue's settings, or the defaults if none are stored.
+	GetQueueConfig(ctx context.Context, name string) (queue.QueueConfig, error)
+
+	// PutQueueConfig creates or replaces the queue's settings.
+	PutQueueConfig(ctx context.Context, cfg queue.QueueConfig) error
+
+	// SetPaused pauses or resumes the queue without touching its other
+	// settings. Claims on a paused queue return nothing.
+	SetPaused(ctx context.Context, name string, paused bool) error
+
+	// SealGroup marks the queue's group as complete: later enqueues into it
+	// fail with queue.ErrGroupSealed, and its newest message is received
+	// with GroupSealed set. Returns that message's ID, nil if the group had
+	// none left. Sealing again is a no-op.
+	SealGroup(ctx context.Context, name, group string) (lastID *int64, err error)
+
+	// GetSubscriptions returns the queues subscribed to topic, sorted; none
+	// if the topic has no subscriptions.
+	GetSubscriptions(ctx context.Context, topic string) ([]string, error)
+
+	// PutSubscriptions replaces topic's subscribed queues with queues.
+	PutSubscriptions(ctx context.Context, topic string, queues []string) error
+
+	// PutPushSubscription makes url the queue's webhook, replacing any it had.
+	PutPushSubscription(ctx context.Context, name, url string) (queue.PushSubscription, error)
+
+	// DeletePushSubscription removes the queue's webhook; false if it had none.
+	DeletePushSubscription(ctx context.Context, name string) (bool, error)
+
+	// PushSubscriptions lists every queue's webhook, by queue.
+	PushSubscriptions(ctx context.Context) ([]queue.PushSubscription, error)
+
+	// ReadAfter returns up to limit of the queue's messages with IDs above
+	// afterID, in ID order, whatever their lease state. Nothing is leased
+	// or changed.
+	ReadAfter(ctx context.Context, name string, afterID int64, limit int) ([]queue.Message, error)
+
+	// GetCheckpoint returns the group's checkpoint on the queue, with
+	// LastID 0 if it has none.
+	GetCheckpoint(ctx context.Context, name, group string) (queue.Checkpoint, error)
 
-	Sweeper(ctx context.Context) (int, error)
+	// SetCheckpoint moves the group's checkpoint to lastID, forward to
+	// commit progress or back to replay.
+	SetCheckpoint(ctx context.Context, name, group string, lastID int64) (queue.Checkpoint, error)
 }
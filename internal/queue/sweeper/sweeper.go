@@ -3,31 +3,61 @@ package sweeper
 import (
 	"context"
 	"log"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/aridsondez/AWS-SQS-LITE/internal/metrics"
+	"github.com/aridsondez/AWS-SQS-LITE/internal/queue"
 	"github.com/aridsondez/AWS-SQS-LITE/internal/queue/store"
 )
 
+// maxErrorBackoff caps how long the sweeper waits after repeated failures.
+const maxErrorBackoff = 5 * time.Minute
+
 type Sweeper struct {
 	store store.Store
 	interval time.Duration
+	opts     queue.SweepOptions
 	stopCh chan struct{}
+	done     chan struct{} // closed when Start returns
+	mu       sync.Mutex    // orders Start against Stop
+	started  bool
+	stopped  bool
+	failures atomic.Int64 // consecutive failed sweeps
 }
 
 
-func New(store store.Store, interval time.Duration) *Sweeper {
+func New(store store.Store, interval time.Duration, opts queue.SweepOptions) *Sweeper {
 
 	return &Sweeper{
 		store: store,
 		interval: interval,
+		opts:     opts,
 		stopCh: make(chan struct{}),
+		done:     make(chan struct{}),
 	}
 }
 
+// Start sweeps every interval until ctx is done or Stop is called. While
+// sweeps keep failing, e.g. because the database is unreachable, the wait
+// doubles after each failure up to maxErrorBackoff, so a recovering
+// database isn't hammered and the log gets one line per attempt rather than
+// per tick. The first successful sweep goes back to the interval.
+//
+// Started after Stop, as a goroutine spawned just before shutdown can be, it
+// returns at once without sweeping.
 func (s *Sweeper) Start(ctx context.Context) {
-	ticker := time.NewTicker(s.interval)
-	defer ticker.Stop()
+	s.mu.Lock()
+	if s.stopped {
+		s.mu.Unlock()
+		return
+	}
+	s.started = true
+	s.mu.Unlock()
+	defer close(s.done)
+	timer := time.NewTimer(s.interval)
+	defer timer.Stop()
 
 	log.Printf("Sweeper started, interval: %s", s.interval)
 
@@ -42,24 +72,55 @@ func (s *Sweeper) Start(ctx context.Context) {
 			log.Printf("Sweeper Stopped(stop signal)")
 			return 
 		
-		case <-ticker.C:
+		case <-timer.C:
 			start := time.Now()
-			count, err := s.store.Sweeper(ctx)
+			count, err := s.store.Sweeper(ctx, s.opts)
 			duration := time.Since(start).Seconds()
 			metrics.SweeperDuration.Observe(duration)
+			metrics.SweeperLastRun.SetToCurrentTime()
 
 			if err != nil {
-				log.Printf("Sweeper error: %v", err)
+				failures := s.failures.Add(1)
 				metrics.SweeperErrors.Inc()
-			} else if count > 0 {
+				metrics.SweeperConsecutiveErrors.Set(float64(failures))
+				wait := s.backoff(failures)
+				log.Printf("Sweeper error (%d in a row), retrying in %s: %v", failures, wait, err)
+				timer.Reset(wait)
+				continue
+			}
+			if failures := s.failures.Swap(0); failures > 0 {
+				metrics.SweeperConsecutiveErrors.Set(0)
+				log.Printf("Sweeper recovered after %d failed sweeps", failures)
+			}
+			if count > 0 {
 				log.Printf("Sweeper processed %d messages in %.2fs", count, duration)
 			}
 			// If count == 0, silently continue (no messages to process)
+			timer.Reset(s.interval)
 		}
 
 	}
 }
 
+// backoff is the wait after the given number of consecutive failures:
+// twice the interval after the first, doubling from there.
+func (s *Sweeper) backoff(failures int64) time.Duration {
+	wait := s.interval
+	for i := int64(0); i < failures && wait < maxErrorBackoff; i++ {
+		wait *= 2
+	}
+	return max(min(wait, maxErrorBackoff), s.interval)
+}
+
+// Stop ends Start and, if it is running, waits for it to return: a sweep
+// in progress finishes first, so once Stop returns no sweep is running.
 func (s *Sweeper) Stop() {
+	s.mu.Lock()
+	s.stopped = true
+	started := s.started
+	s.mu.Unlock()
 	close(s.stopCh)
-}
\ No newline at end of file
+	if started {
+		<-s.done
+	}
+}
@@ -0,0 +1,20 @@
+-- 0002_partitions.sql
+-- Optional sharding of a logical queue into N physical partitions.
+
+-- Each message lands in one partition of its queue. Unpartitioned queues
+-- keep everything in partition 0, so existing rows need no backfill.
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS partition INT NOT NULL DEFAULT 0;
+
+-- Per-queue settings. A queue without a row uses the defaults.
+CREATE TABLE IF NOT EXISTS queue_configs (
+  queue        TEXT        PRIMARY KEY,
+  partitions   INT         NOT NULL DEFAULT 1,      -- physical partitions for the queue
+  updated_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+
+  CONSTRAINT chk_partitions_pos CHECK (partitions >= 1)
+);
+
+-- Receivers on a partitioned queue scan one partition at a time.
+CREATE INDEX IF NOT EXISTS idx_messages_available_partition
+  ON messages (queue, partition, not_before, id)
+  WHERE lease_until IS NULL;
@@ -0,0 +1,21 @@
+-- 0003_enqueue_keys.sql
+-- Remembered keys that make an enqueue conditional (e.g. Idempotency-Key).
+
+-- A key maps to the message it first produced. Replaying the key while the
+-- row is live returns that message instead of inserting another. The message
+-- itself may since have been acked; the mapping outlives it until expiry.
+CREATE TABLE IF NOT EXISTS enqueue_keys (
+  queue        TEXT        NOT NULL,
+  scope        TEXT        NOT NULL,                  -- namespace, e.g. 'idempotency'
+  key          TEXT        NOT NULL,
+  message_id   BIGINT      NOT NULL,
+  created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+  expires_at   TIMESTAMPTZ,                           -- NULL => never expires
+
+  PRIMARY KEY (queue, scope, key)
+);
+
+-- For the sweeper to prune expired keys:
+CREATE INDEX IF NOT EXISTS idx_enqueue_keys_expires
+  ON enqueue_keys (expires_at)
+  WHERE expires_at IS NOT NULL;
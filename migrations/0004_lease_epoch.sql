@@ -0,0 +1,6 @@
+-- 0004_lease_epoch.sql
+-- Lease fencing: every claim bumps lease_epoch, and the epoch travels in the
+-- receipt. Acks must present the current epoch, so a worker whose lease
+-- expired (and was re-leased to someone else) can no longer delete the row.
+
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS lease_epoch BIGINT NOT NULL DEFAULT 0;
@@ -0,0 +1,7 @@
+-- 0005_queue_defaults.sql
+-- Per-queue defaults applied when a request doesn't specify its own.
+-- NULL means "use the server default".
+
+ALTER TABLE queue_configs ADD COLUMN IF NOT EXISTS visibility_ms BIGINT;   -- default receive visibility
+ALTER TABLE queue_configs ADD COLUMN IF NOT EXISTS max_retries   INT;      -- default max_retries on enqueue
+ALTER TABLE queue_configs ADD COLUMN IF NOT EXISTS dlq           TEXT;     -- default DLQ on enqueue
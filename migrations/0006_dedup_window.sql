@@ -0,0 +1,7 @@
+-- 0006_dedup_window.sql
+-- Per-queue dedup window: how long a message's dedup_id is remembered.
+-- NULL means "use the server default".
+
+ALTER TABLE queue_configs ADD COLUMN IF NOT EXISTS dedup_window_ms BIGINT;
+-- Dedup keys expire through idx_enqueue_keys_expires (0003), like
+-- idempotency keys.
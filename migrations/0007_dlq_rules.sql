@@ -0,0 +1,20 @@
+-- 0007_dlq_rules.sql
+-- Optional per-message DLQ routing rules, e.g.
+--   [{"max_deliveries": 1, "dlq": "fast-fail"}, {"min_deliveries": 2, "dlq": "slow-fail"}]
+-- The first rule whose delivery-count range contains the message's
+-- delivery_count picks the DLQ; if none match, the plain `dlq` column is used.
+
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS dlq_rules JSONB;
+
+CREATE OR REPLACE FUNCTION sqs_dlq_target(fallback TEXT, rules JSONB, deliveries INT)
+RETURNS TEXT
+LANGUAGE sql IMMUTABLE AS $$
+  SELECT COALESCE(
+    (SELECT r->>'dlq'
+     FROM jsonb_array_elements(COALESCE(rules, '[]'::jsonb)) WITH ORDINALITY AS t(r, n)
+     WHERE deliveries >= COALESCE((r->>'min_deliveries')::int, 0)
+       AND deliveries <= COALESCE((r->>'max_deliveries')::int, 2147483647)
+     ORDER BY n
+     LIMIT 1),
+    fallback)
+$$;
@@ -0,0 +1,4 @@
+-- 0008_require_object_body.sql
+-- Queues that only accept JSON objects as message bodies.
+
+ALTER TABLE queue_configs ADD COLUMN IF NOT EXISTS require_object_body BOOLEAN NOT NULL DEFAULT false;
@@ -0,0 +1,9 @@
+-- 0009_dlqd_at.sql
+-- When the sweeper moved a message into its DLQ. NULL for messages that
+-- were enqueued normally; used to purge DLQs after DLQ_RETENTION.
+
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS dlqd_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_messages_dlqd_at
+  ON messages (dlqd_at)
+  WHERE dlqd_at IS NOT NULL;
@@ -0,0 +1,9 @@
+-- 0010_claim_order.sql
+-- Per-queue claim ordering: 'id' (insertion order, the default) or
+-- 'visible_at' (when the message became claimable).
+
+ALTER TABLE queue_configs ADD COLUMN IF NOT EXISTS claim_order TEXT NOT NULL DEFAULT 'id';
+
+CREATE INDEX IF NOT EXISTS idx_messages_available_visible
+  ON messages (queue, greatest(not_before, enqueued_at), id)
+  WHERE lease_until IS NULL;
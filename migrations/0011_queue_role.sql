@@ -0,0 +1,4 @@
+-- 0011_queue_role.sql
+-- Informational queue role ('' or 'dlq'), reported to receivers.
+
+ALTER TABLE queue_configs ADD COLUMN IF NOT EXISTS role TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,10 @@
+-- 0012_topic_subscriptions.sql
+-- Fan-out: a message published to a topic is enqueued to each subscribed queue.
+
+CREATE TABLE IF NOT EXISTS topic_subscriptions (
+  topic        TEXT        NOT NULL,
+  queue        TEXT        NOT NULL,
+  created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+
+  PRIMARY KEY (topic, queue)
+);
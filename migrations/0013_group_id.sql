@@ -0,0 +1,9 @@
+-- 0013_group_id.sql
+-- Optional message group. Within a group a message can only be acked once
+-- every earlier message of the group is gone.
+
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS group_id TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_messages_group
+  ON messages (queue, group_id, id)
+  WHERE group_id IS NOT NULL;
@@ -0,0 +1,6 @@
+-- 0014_delivery_count_index.sql
+-- Lets count queries by delivery count find retried messages without
+-- scanning the whole queue.
+
+CREATE INDEX IF NOT EXISTS idx_messages_delivery_count
+  ON messages (queue, delivery_count);
@@ -0,0 +1,13 @@
+-- 0015_failure_context.sql
+-- Why a message failed, recorded by nack, and where a dead letter came
+-- from, recorded by the sweeper when it moves the message to its DLQ.
+
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS last_error TEXT;
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS failed_at TIMESTAMPTZ;
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS dlq_source TEXT;
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS dlq_deliveries INT;
+
+-- Failure peeks list a DLQ's dead letters newest first.
+CREATE INDEX IF NOT EXISTS idx_messages_dead_letters
+  ON messages (queue, dlqd_at DESC, id)
+  WHERE dlqd_at IS NOT NULL;
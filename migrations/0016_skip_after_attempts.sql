@@ -0,0 +1,5 @@
+-- Per-queue threshold after which a message stops holding up its group:
+-- once its delivery_count reaches skip_after_attempts, later group-mates can
+-- be acked ahead of it. 0 keeps strict group order.
+
+ALTER TABLE queue_configs ADD COLUMN IF NOT EXISTS skip_after_attempts INT NOT NULL DEFAULT 0;
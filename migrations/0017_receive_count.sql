@@ -0,0 +1,7 @@
+-- Lifetime receive count. Unlike delivery_count it is never reset: it
+-- carries over when the sweeper dead-letters a message and when the message
+-- is redriven back to its source queue.
+
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS receive_count INT NOT NULL DEFAULT 0;
+
+UPDATE messages SET receive_count = delivery_count WHERE receive_count < delivery_count;
@@ -0,0 +1,3 @@
+-- Paused queues accept enqueues but hand out nothing until resumed.
+
+ALTER TABLE queue_configs ADD COLUMN IF NOT EXISTS paused BOOLEAN NOT NULL DEFAULT false;
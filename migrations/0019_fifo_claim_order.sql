@@ -0,0 +1,5 @@
+-- claim_order 'fifo' claims available messages by enqueued_at.
+
+CREATE INDEX IF NOT EXISTS idx_messages_available_enqueued
+  ON messages (queue, enqueued_at, id)
+  WHERE lease_until IS NULL;
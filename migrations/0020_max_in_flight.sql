@@ -0,0 +1,4 @@
+-- Per-queue cap on leased messages: receives are trimmed so the queue never
+-- has more than max_in_flight in flight. 0 leaves it unbounded.
+
+ALTER TABLE queue_configs ADD COLUMN IF NOT EXISTS max_in_flight INT NOT NULL DEFAULT 0;
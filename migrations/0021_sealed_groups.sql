@@ -0,0 +1,13 @@
+-- Sealed message groups: the producer has said no more messages will arrive
+-- for the group. last_id is the group's newest message at sealing time (NULL
+-- if it had none left); receives flag that message as the group's last.
+
+CREATE TABLE IF NOT EXISTS sealed_groups (
+  queue     TEXT NOT NULL,
+  group_id  TEXT NOT NULL,
+  last_id   BIGINT,
+  sealed_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  PRIMARY KEY (queue, group_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_sealed_groups_last_id ON sealed_groups (last_id);
@@ -0,0 +1,5 @@
+-- Claim-check messages: the producer enqueues a reference to where the
+-- body is kept instead of the body itself. body holds JSON null until a
+-- receive resolves the reference.
+
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS body_ref TEXT;
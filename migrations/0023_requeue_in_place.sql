@@ -0,0 +1,5 @@
+-- Queues whose requeued messages keep their place in line: the sweeper
+-- skips the redelivery backoff for them, so a message whose lease expired is
+-- claimed again ahead of everything enqueued after it.
+
+ALTER TABLE queue_configs ADD COLUMN IF NOT EXISTS requeue_in_place BOOLEAN NOT NULL DEFAULT false;
@@ -0,0 +1,5 @@
+-- Queues whose message bodies are CloudEvents (structured JSON mode):
+-- events enqueued to them are validated, and other bodies are wrapped in
+-- an event, so receivers always get an envelope.
+
+ALTER TABLE queue_configs ADD COLUMN IF NOT EXISTS cloudevents BOOLEAN NOT NULL DEFAULT false;
@@ -0,0 +1,8 @@
+-- Push mode: the server claims a subscribed queue's messages itself and
+-- POSTs each one to the queue's webhook, acking on success.
+
+CREATE TABLE IF NOT EXISTS push_subscriptions (
+  queue        TEXT        PRIMARY KEY,
+  url          TEXT        NOT NULL,
+  created_at   TIMESTAMPTZ NOT NULL DEFAULT now()
+);
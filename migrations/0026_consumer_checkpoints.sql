@@ -0,0 +1,11 @@
+-- Log-style consumption: each consumer group reads a queue forward by
+-- message ID without claiming, and records here how far it has got.
+
+CREATE TABLE IF NOT EXISTS consumer_checkpoints (
+  queue          TEXT        NOT NULL,
+  consumer_group TEXT        NOT NULL,
+  last_id        BIGINT      NOT NULL,
+  updated_at     TIMESTAMPTZ NOT NULL DEFAULT now(),
+
+  PRIMARY KEY (queue, consumer_group)
+);
@@ -0,0 +1,14 @@
+-- Queue registry: every queue that has been enqueued to or created, so the
+-- number of queues can be capped (MAX_QUEUES) and, in strict mode, queues
+-- must be created before use.
+
+CREATE TABLE IF NOT EXISTS queues (
+  name         TEXT        PRIMARY KEY,
+  created_at   TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+INSERT INTO queues (name)
+SELECT DISTINCT queue FROM messages
+UNION
+SELECT queue FROM queue_configs
+ON CONFLICT DO NOTHING;
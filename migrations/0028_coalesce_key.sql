@@ -0,0 +1,10 @@
+-- 0028_coalesce_key.sql
+-- Optional coalescing key: while a message with the key is pending (not yet
+-- acked, deleted or dead-lettered), another enqueue with it to the same
+-- queue returns that message instead of adding one.
+
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS coalesce_key TEXT;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_messages_coalesce_key
+  ON messages (queue, coalesce_key)
+  WHERE coalesce_key IS NOT NULL;
@@ -0,0 +1,6 @@
+-- 0029_enqueued_at_index.sql
+-- The sweeper deletes messages older than MAX_MESSAGE_LIFETIME by
+-- enqueued_at, whatever their state.
+
+CREATE INDEX IF NOT EXISTS idx_messages_enqueued_at
+  ON messages (enqueued_at);
@@ -0,0 +1,4 @@
+-- Delay queues: every enqueue that doesn't set its own delay_ms or
+-- not_before is delayed by the queue's default, as with SQS DelaySeconds.
+
+ALTER TABLE queue_configs ADD COLUMN IF NOT EXISTS default_delay_ms BIGINT NOT NULL DEFAULT 0;
@@ -0,0 +1,5 @@
+-- When the current (or last) lease began, so the sweeper can tell how long
+-- a lease lasted when it requeues the message: leases that run out almost
+-- as soon as they're taken point at a visibility timeout that is too short.
+
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS leased_at TIMESTAMPTZ;
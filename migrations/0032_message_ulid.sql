@@ -0,0 +1,45 @@
+-- An opaque, unguessable name for each message, shown by the API instead of
+-- its id when MESSAGE_ID_FORMAT=ulid. The bigint id stays the key messages
+-- sort, lease and join on; the ULID only stands in for it at the API.
+
+-- 48 bits of millisecond time then 80 random bits, in Crockford base32.
+CREATE OR REPLACE FUNCTION gen_ulid() RETURNS TEXT
+LANGUAGE plpgsql VOLATILE AS $$
+DECLARE
+  alphabet CONSTANT TEXT := '0123456789ABCDEFGHJKMNPQRSTVWXYZ';
+  ms     BIGINT := floor(extract(epoch FROM clock_timestamp()) * 1000);
+  u      BYTEA  := uuid_send(gen_random_uuid());
+  -- bytes 7 and 9 of a v4 UUID carry its version and variant, so skip them
+  rnd    BYTEA  := substring(u FROM 1 FOR 6) || substring(u FROM 10 FOR 4);
+  result TEXT   := '';
+  acc    BIGINT;
+BEGIN
+  FOR i IN REVERSE 9..0 LOOP
+    result := result || substr(alphabet, ((ms >> (i * 5)) & 31)::INT + 1, 1);
+  END LOOP;
+  -- 40 bits at a time, 8 characters each
+  FOR half IN 0..1 LOOP
+    acc := 0;
+    FOR j IN 0..4 LOOP
+      acc := (acc << 8) | get_byte(rnd, half * 5 + j);
+    END LOOP;
+    FOR j IN REVERSE 7..0 LOOP
+      result := result || substr(alphabet, ((acc >> (j * 5)) & 31)::INT + 1, 1);
+    END LOOP;
+  END LOOP;
+  RETURN result;
+END;
+$$;
+
+-- Rewrites the table once to give existing messages theirs; every insert
+-- path, dead-lettering included, gets one from the default after that.
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS ulid TEXT NOT NULL DEFAULT gen_ulid();
+CREATE UNIQUE INDEX IF NOT EXISTS idx_messages_ulid ON messages (ulid);
+
+-- Idempotency keys and ack ids outlive their message, and a replay has to
+-- name it the way the first answer did.
+ALTER TABLE enqueue_keys ADD COLUMN IF NOT EXISTS message_ulid TEXT;
+CREATE INDEX IF NOT EXISTS idx_enqueue_keys_message_ulid
+  ON enqueue_keys (message_ulid) WHERE message_ulid IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_enqueue_keys_message_id
+  ON enqueue_keys (message_id) WHERE message_ulid IS NOT NULL;
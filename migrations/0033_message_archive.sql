@@ -0,0 +1,26 @@
+-- Archive: on queues with archive set, an acked message is copied here in
+-- the same statement that deletes it, so it can be replayed later. Copies
+-- are kept until ARCHIVE_RETENTION, or for good when that is 0.
+
+ALTER TABLE queue_configs ADD COLUMN IF NOT EXISTS archive BOOLEAN NOT NULL DEFAULT false;
+
+CREATE TABLE IF NOT EXISTS archived_messages (
+  id             BIGINT PRIMARY KEY,                    -- the acked message's id
+  ulid           TEXT NOT NULL,
+  queue          TEXT NOT NULL,
+  body           JSONB NOT NULL,
+  body_ref       TEXT,
+  trace_id       TEXT,
+  group_id       TEXT,
+  delivery_count INT NOT NULL,
+  enqueued_at    TIMESTAMPTZ NOT NULL,
+  acked_at       TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- replay filters by queue and ack time; retention by ack time alone
+CREATE INDEX IF NOT EXISTS idx_archived_messages_queue_acked
+  ON archived_messages (queue, acked_at);
+CREATE INDEX IF NOT EXISTS idx_archived_messages_acked
+  ON archived_messages (acked_at);
+CREATE INDEX IF NOT EXISTS idx_archived_messages_ulid
+  ON archived_messages (ulid);
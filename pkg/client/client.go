@@ -5,9 +5,10 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 	"time"
+
+	"github.com/aridsondez/AWS-SQS-LITE/pkg/codec"
 )
 
 // Client for enqueueing messages to SQS Lite
@@ -27,9 +28,11 @@ func NewClient(baseURL string) *Client {
 // EnqueueOptions for customizing message enqueue
 type EnqueueOptions struct {
 	Delay      time.Duration 
-	MaxRetries int           // Max retry attempts (default: 5)
+	MaxRetries int           // Max retry attempts (default: the queue's, else the server's DEFAULT_MAX_RETRIES)
 	DLQ        string        // Dead letter queue name
 	TraceID    string        // Optional trace ID for correlation
+	TraceParent string       // W3C traceparent to continue on receive; sent as the traceparent header
+	Codec      codec.Codec   // Encodes body (default: codec.JSON); consumers decode with Message.Decode
 }
 
 // Enqueue sends a message to a queue
@@ -38,7 +41,7 @@ func (c *Client) Enqueue(ctx context.Context, queue string, body interface{}, op
 		opts = &EnqueueOptions{}
 	}
 
-	bodyJSON, err := json.Marshal(body)
+	bodyJSON, err := codec.Encode(opts.Codec, body)
 	if err != nil {
 		return 0, fmt.Errorf("marshal body: %w", err)
 	}
@@ -48,7 +51,7 @@ func (c *Client) Enqueue(ctx context.Context, queue string, body interface{}, op
 	}
 
 	if opts.Delay > 0 {
-		req["delay"] = int(opts.Delay.Milliseconds())
+		req["delay_ms"] = int(opts.Delay.Milliseconds())
 	}
 	if opts.MaxRetries > 0 {
 		req["max_retries"] = opts.MaxRetries
@@ -60,35 +63,105 @@ func (c *Client) Enqueue(ctx context.Context, queue string, body interface{}, op
 		req["trace_id"] = opts.TraceID
 	}
 
-	reqBody, err := json.Marshal(req)
-	if err != nil {
+	var result struct {
+		ID int64 `json:"id"`
+	}
+	url := fmt.Sprintf("%s/v1/queues/%s/messages", c.baseURL, queue)
+	var header http.Header
+	if opts.TraceParent != "" {
+		header = http.Header{"Traceparent": {opts.TraceParent}}
+	}
+	if err := c.post(ctx, "enqueue", url, header, req, http.StatusCreated, &result); err != nil {
 		return 0, err
 	}
 
-	url := fmt.Sprintf("%s/v1/queues/%s/messages", c.baseURL, queue)
+	return result.ID, nil
+}
+
+// ReceiveOptions for customizing a receive
+type ReceiveOptions struct {
+	Max        int           // Messages to claim at most (default: 1)
+	Visibility time.Duration // Lease length (default: the queue's)
+}
+
+// Message is a message leased by Receive
+type Message struct {
+	ID            int64           `json:"id"`
+	Queue         string          `json:"queue"`
+	Body          json.RawMessage `json:"body"`
+	Receipt       string          `json:"receipt"`
+	LeaseUntil    *time.Time      `json:"lease_until,omitempty"`
+	DeliveryCount int             `json:"delivery_count"`
+	MaxRetries    int             `json:"max_retries"`
+	TraceParent   string          `json:"traceparent,omitempty"` // set if it was enqueued with one
+}
+
+// Decode decodes the body into v with the codec it was enqueued with
+func (m *Message) Decode(v interface{}) error {
+	return codec.Decode(m.Body, v)
+}
+
+// Receive leases up to opts.Max messages from a queue
+func (c *Client) Receive(ctx context.Context, queue string, opts *ReceiveOptions) ([]Message, error) {
+	if opts == nil {
+		opts = &ReceiveOptions{}
+	}
+	req := map[string]interface{}{"max": max(opts.Max, 1)}
+	if opts.Visibility > 0 {
+		req["visibility_ms"] = opts.Visibility.Milliseconds()
+	}
+
+	var raw json.RawMessage
+	url := fmt.Sprintf("%s/v1/queues/%s:receive", c.baseURL, queue)
+	if err := c.post(ctx, "receive", url, nil, req, http.StatusOK, &raw); err != nil {
+		return nil, err
+	}
+	// a server with RECEIVE_ENVELOPE set wraps the array in an object
+	if len(raw) > 0 && raw[0] == '{' {
+		var envelope struct {
+			Messages []Message `json:"messages"`
+		}
+		err := json.Unmarshal(raw, &envelope)
+		return envelope.Messages, err
+	}
+	var msgs []Message
+	err := json.Unmarshal(raw, &msgs)
+	return msgs, err
+}
+
+// Ack deletes a received message; receipt is the one Receive returned with it
+func (c *Client) Ack(ctx context.Context, id int64, receipt string) error {
+	url := fmt.Sprintf("%s/v1/messages/%d:ack", c.baseURL, id)
+	return c.post(ctx, "ack", url, nil, map[string]string{"receipt": receipt}, http.StatusOK, nil)
+}
+
+// post sends payload as JSON, with any extra header, and decodes the response
+// into out, or returns a *StatusError if the status isn't want.
+func (c *Client) post(ctx context.Context, op, url string, header http.Header, payload interface{}, want int, out interface{}) error {
+	reqBody, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
 	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(reqBody))
 	if err != nil {
-		return 0, err
+		return err
+	}
+	for k, v := range header {
+		httpReq.Header[k] = v
 	}
 	httpReq.Header.Set("Content-Type", "application/json")
 
 	resp, err := c.client.Do(httpReq)
 	if err != nil {
-		return 0, err
+		return err
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusCreated {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		return 0, fmt.Errorf("enqueue failed: %s - %s", resp.Status, string(bodyBytes))
+	if resp.StatusCode != want {
+		return statusError(op, resp)
 	}
-
-	var result struct {
-		ID int64 `json:"id"`
+	if out == nil {
+		return nil
 	}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return 0, err
-	}
-
-	return result.ID, nil
+	return json.NewDecoder(resp.Body).Decode(out)
 }
@@ -0,0 +1,58 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Error classes for non-success responses. Every *StatusError wraps one of
+// them, so callers can branch with errors.Is(err, client.ErrRateLimited).
+var (
+	ErrBadRequest  = errors.New("bad request")  // 4xx: the request was refused as sent
+	ErrNotFound    = errors.New("not found")    // 404: no such message or lease
+	ErrRateLimited = errors.New("rate limited") // 429: retry later
+	ErrServer      = errors.New("server error") // 5xx: the server failed
+)
+
+// StatusError is a non-success response from the server.
+type StatusError struct {
+	Op         string // "enqueue", "receive", "ack"
+	StatusCode int
+	Message    string // the server's error message, or the raw body
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s failed: %d %s - %s", e.Op, e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
+
+// Unwrap returns the error class for the status code, nil if it has none.
+func (e *StatusError) Unwrap() error {
+	switch {
+	case e.StatusCode == http.StatusTooManyRequests:
+		return ErrRateLimited
+	case e.StatusCode == http.StatusNotFound:
+		return ErrNotFound
+	case e.StatusCode >= 500:
+		return ErrServer
+	case e.StatusCode >= 400:
+		return ErrBadRequest
+	}
+	return nil
+}
+
+// statusError reads resp's body into a *StatusError for op.
+func statusError(op string, resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	msg := strings.TrimSpace(string(body))
+	var apiErr struct {
+		Error string `json:"error"`
+	}
+	if json.Unmarshal(body, &apiErr) == nil && apiErr.Error != "" {
+		msg = apiErr.Error
+	}
+	return &StatusError{Op: op, StatusCode: resp.StatusCode, Message: msg}
+}
@@ -0,0 +1,139 @@
+// Package codec encodes message bodies for the client and decodes them for
+// workers. The server stores bodies as JSON, so a body encoded any other way
+// travels as a small JSON envelope naming its content type, with the encoded
+// bytes in base64:
+//
+//	{"datacontenttype": "application/x-gob", "data_base64": "..."}
+//
+// The attribute names are the ones CloudEvents gives binary data.
+package codec
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// Codec turns bodies into bytes and back.
+type Codec interface {
+	// ContentType is recorded with each body the codec encodes, so the
+	// consumer knows how to decode it.
+	ContentType() string
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+// JSON is the default codec. Its bodies are stored as they are, with no
+// envelope, so consumers that don't use this package read them unchanged.
+var JSON Codec = jsonCodec{}
+
+// Gob encodes bodies with encoding/gob, which is faster and smaller than
+// JSON for Go-only pipelines. Both sides must agree on the Go types.
+var Gob Codec = gobCodec{}
+
+type jsonCodec struct{}
+
+func (jsonCodec) ContentType() string                { return "application/json" }
+func (jsonCodec) Marshal(v any) ([]byte, error)      { return json.Marshal(v) }
+func (jsonCodec) Unmarshal(data []byte, v any) error { return json.Unmarshal(data, v) }
+
+type gobCodec struct{}
+
+func (gobCodec) ContentType() string { return "application/x-gob" }
+
+func (gobCodec) Marshal(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(v)
+	return buf.Bytes(), err
+}
+
+func (gobCodec) Unmarshal(data []byte, v any) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+var (
+	mu     sync.RWMutex
+	codecs = map[string]Codec{
+		JSON.ContentType(): JSON,
+		Gob.ContentType():  Gob,
+	}
+)
+
+// Register makes c available to Decode under its content type, replacing
+// any codec registered for it before. JSON and Gob are always registered;
+// register others, such as a MessagePack codec, on both the producing and
+// consuming side.
+func Register(c Codec) {
+	mu.Lock()
+	defer mu.Unlock()
+	codecs[c.ContentType()] = c
+}
+
+func lookup(contentType string) (Codec, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	c, ok := codecs[contentType]
+	return c, ok
+}
+
+// envelope carries a body that isn't JSON.
+type envelope struct {
+	DataContentType string `json:"datacontenttype"`
+	DataBase64      []byte `json:"data_base64"` // encoding/json base64s []byte
+}
+
+// Encode returns v encoded with c, ready to be sent as a message body. A nil
+// c is JSON.
+func Encode(c Codec, v any) (json.RawMessage, error) {
+	if c == nil || c.ContentType() == JSON.ContentType() {
+		return json.Marshal(v)
+	}
+	data, err := c.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(&envelope{DataContentType: c.ContentType(), DataBase64: data})
+}
+
+// Decode decodes body into v with the codec it was encoded with. A body
+// without an envelope is JSON.
+func Decode(body json.RawMessage, v any) error {
+	env, ok := unwrap(body)
+	if !ok {
+		return json.Unmarshal(body, v)
+	}
+	c, ok := lookup(env.DataContentType)
+	if !ok {
+		return fmt.Errorf("codec: no codec registered for %q", env.DataContentType)
+	}
+	return c.Unmarshal(env.DataBase64, v)
+}
+
+// ContentType is the content type body was encoded with.
+func ContentType(body json.RawMessage) string {
+	if env, ok := unwrap(body); ok {
+		return env.DataContentType
+	}
+	return JSON.ContentType()
+}
+
+// unwrap returns body's envelope, if it is one: an object with exactly the
+// two envelope attributes.
+func unwrap(body json.RawMessage) (*envelope, bool) {
+	body = bytes.TrimSpace(body)
+	if len(body) == 0 || body[0] != '{' {
+		return nil, false
+	}
+	var attrs map[string]json.RawMessage
+	if json.Unmarshal(body, &attrs) != nil || len(attrs) != 2 ||
+		attrs["datacontenttype"] == nil || attrs["data_base64"] == nil {
+		return nil, false
+	}
+	var env envelope
+	if json.Unmarshal(body, &env) != nil || env.DataContentType == "" {
+		return nil, false
+	}
+	return &env, true
+}
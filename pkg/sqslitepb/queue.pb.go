@@ -0,0 +1,884 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.8
+// 	protoc        (unknown)
+// source: sqslite/v1/queue.proto
+
+package sqslitepb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type EnqueueRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Queue         string                 `protobuf:"bytes,1,opt,name=queue,proto3" json:"queue,omitempty"`
+	Body          []byte                 `protobuf:"bytes,2,opt,name=body,proto3" json:"body,omitempty"` // JSON
+	DelayMs       int64                  `protobuf:"varint,3,opt,name=delay_ms,json=delayMs,proto3" json:"delay_ms,omitempty"`
+	MaxRetries    int32                  `protobuf:"varint,4,opt,name=max_retries,json=maxRetries,proto3" json:"max_retries,omitempty"`
+	Dlq           *string                `protobuf:"bytes,5,opt,name=dlq,proto3,oneof" json:"dlq,omitempty"`
+	TraceId       *string                `protobuf:"bytes,6,opt,name=trace_id,json=traceId,proto3,oneof" json:"trace_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EnqueueRequest) Reset() {
+	*x = EnqueueRequest{}
+	mi := &file_sqslite_v1_queue_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EnqueueRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EnqueueRequest) ProtoMessage() {}
+
+func (x *EnqueueRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_sqslite_v1_queue_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EnqueueRequest.ProtoReflect.Descriptor instead.
+func (*EnqueueRequest) Descriptor() ([]byte, []int) {
+	return file_sqslite_v1_queue_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *EnqueueRequest) GetQueue() string {
+	if x != nil {
+		return x.Queue
+	}
+	return ""
+}
+
+func (x *EnqueueRequest) GetBody() []byte {
+	if x != nil {
+		return x.Body
+	}
+	return nil
+}
+
+func (x *EnqueueRequest) GetDelayMs() int64 {
+	if x != nil {
+		return x.DelayMs
+	}
+	return 0
+}
+
+func (x *EnqueueRequest) GetMaxRetries() int32 {
+	if x != nil {
+		return x.MaxRetries
+	}
+	return 0
+}
+
+func (x *EnqueueRequest) GetDlq() string {
+	if x != nil && x.Dlq != nil {
+		return *x.Dlq
+	}
+	return ""
+}
+
+func (x *EnqueueRequest) GetTraceId() string {
+	if x != nil && x.TraceId != nil {
+		return *x.TraceId
+	}
+	return ""
+}
+
+type EnqueueResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EnqueueResponse) Reset() {
+	*x = EnqueueResponse{}
+	mi := &file_sqslite_v1_queue_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EnqueueResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EnqueueResponse) ProtoMessage() {}
+
+func (x *EnqueueResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_sqslite_v1_queue_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EnqueueResponse.ProtoReflect.Descriptor instead.
+func (*EnqueueResponse) Descriptor() ([]byte, []int) {
+	return file_sqslite_v1_queue_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *EnqueueResponse) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+type ReceiveRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Queue         string                 `protobuf:"bytes,1,opt,name=queue,proto3" json:"queue,omitempty"`
+	Max           int32                  `protobuf:"varint,2,opt,name=max,proto3" json:"max,omitempty"`
+	VisibilityMs  int64                  `protobuf:"varint,3,opt,name=visibility_ms,json=visibilityMs,proto3" json:"visibility_ms,omitempty"` // 0 = the queue's default
+	WaitMs        int64                  `protobuf:"varint,4,opt,name=wait_ms,json=waitMs,proto3" json:"wait_ms,omitempty"`                   // hold up to this long for messages
+	MinMessages   int32                  `protobuf:"varint,5,opt,name=min_messages,json=minMessages,proto3" json:"min_messages,omitempty"`    // with wait_ms: hold until this many are available
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReceiveRequest) Reset() {
+	*x = ReceiveRequest{}
+	mi := &file_sqslite_v1_queue_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReceiveRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReceiveRequest) ProtoMessage() {}
+
+func (x *ReceiveRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_sqslite_v1_queue_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReceiveRequest.ProtoReflect.Descriptor instead.
+func (*ReceiveRequest) Descriptor() ([]byte, []int) {
+	return file_sqslite_v1_queue_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *ReceiveRequest) GetQueue() string {
+	if x != nil {
+		return x.Queue
+	}
+	return ""
+}
+
+func (x *ReceiveRequest) GetMax() int32 {
+	if x != nil {
+		return x.Max
+	}
+	return 0
+}
+
+func (x *ReceiveRequest) GetVisibilityMs() int64 {
+	if x != nil {
+		return x.VisibilityMs
+	}
+	return 0
+}
+
+func (x *ReceiveRequest) GetWaitMs() int64 {
+	if x != nil {
+		return x.WaitMs
+	}
+	return 0
+}
+
+func (x *ReceiveRequest) GetMinMessages() int32 {
+	if x != nil {
+		return x.MinMessages
+	}
+	return 0
+}
+
+type ReceiveResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Messages      []*Message             `protobuf:"bytes,1,rep,name=messages,proto3" json:"messages,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReceiveResponse) Reset() {
+	*x = ReceiveResponse{}
+	mi := &file_sqslite_v1_queue_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReceiveResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReceiveResponse) ProtoMessage() {}
+
+func (x *ReceiveResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_sqslite_v1_queue_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReceiveResponse.ProtoReflect.Descriptor instead.
+func (*ReceiveResponse) Descriptor() ([]byte, []int) {
+	return file_sqslite_v1_queue_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *ReceiveResponse) GetMessages() []*Message {
+	if x != nil {
+		return x.Messages
+	}
+	return nil
+}
+
+type StreamReceiveRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Queue         string                 `protobuf:"bytes,1,opt,name=queue,proto3" json:"queue,omitempty"`
+	Batch         int32                  `protobuf:"varint,2,opt,name=batch,proto3" json:"batch,omitempty"`                                   // messages claimed at a time
+	VisibilityMs  int64                  `protobuf:"varint,3,opt,name=visibility_ms,json=visibilityMs,proto3" json:"visibility_ms,omitempty"` // 0 = the queue's default
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StreamReceiveRequest) Reset() {
+	*x = StreamReceiveRequest{}
+	mi := &file_sqslite_v1_queue_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamReceiveRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamReceiveRequest) ProtoMessage() {}
+
+func (x *StreamReceiveRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_sqslite_v1_queue_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamReceiveRequest.ProtoReflect.Descriptor instead.
+func (*StreamReceiveRequest) Descriptor() ([]byte, []int) {
+	return file_sqslite_v1_queue_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *StreamReceiveRequest) GetQueue() string {
+	if x != nil {
+		return x.Queue
+	}
+	return ""
+}
+
+func (x *StreamReceiveRequest) GetBatch() int32 {
+	if x != nil {
+		return x.Batch
+	}
+	return 0
+}
+
+func (x *StreamReceiveRequest) GetVisibilityMs() int64 {
+	if x != nil {
+		return x.VisibilityMs
+	}
+	return 0
+}
+
+type Message struct {
+	state                   protoimpl.MessageState `protogen:"open.v1"`
+	Id                      int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Queue                   string                 `protobuf:"bytes,2,opt,name=queue,proto3" json:"queue,omitempty"`
+	Body                    []byte                 `protobuf:"bytes,3,opt,name=body,proto3" json:"body,omitempty"` // JSON
+	Receipt                 string                 `protobuf:"bytes,4,opt,name=receipt,proto3" json:"receipt,omitempty"`
+	LeaseUntil              *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=lease_until,json=leaseUntil,proto3" json:"lease_until,omitempty"`
+	DeliveryCount           int32                  `protobuf:"varint,6,opt,name=delivery_count,json=deliveryCount,proto3" json:"delivery_count,omitempty"`
+	MaxRetries              int32                  `protobuf:"varint,7,opt,name=max_retries,json=maxRetries,proto3" json:"max_retries,omitempty"`
+	Dlq                     *string                `protobuf:"bytes,8,opt,name=dlq,proto3,oneof" json:"dlq,omitempty"`
+	TraceId                 *string                `protobuf:"bytes,9,opt,name=trace_id,json=traceId,proto3,oneof" json:"trace_id,omitempty"`
+	DeadLetteredAt          *timestamppb.Timestamp `protobuf:"bytes,10,opt,name=dead_lettered_at,json=deadLetteredAt,proto3" json:"dead_lettered_at,omitempty"`                             // set on dead letters
+	ApproximateReceiveCount int32                  `protobuf:"varint,11,opt,name=approximate_receive_count,json=approximateReceiveCount,proto3" json:"approximate_receive_count,omitempty"` // lifetime claims; never reset
+	unknownFields           protoimpl.UnknownFields
+	sizeCache               protoimpl.SizeCache
+}
+
+func (x *Message) Reset() {
+	*x = Message{}
+	mi := &file_sqslite_v1_queue_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Message) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Message) ProtoMessage() {}
+
+func (x *Message) ProtoReflect() protoreflect.Message {
+	mi := &file_sqslite_v1_queue_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Message.ProtoReflect.Descriptor instead.
+func (*Message) Descriptor() ([]byte, []int) {
+	return file_sqslite_v1_queue_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *Message) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Message) GetQueue() string {
+	if x != nil {
+		return x.Queue
+	}
+	return ""
+}
+
+func (x *Message) GetBody() []byte {
+	if x != nil {
+		return x.Body
+	}
+	return nil
+}
+
+func (x *Message) GetReceipt() string {
+	if x != nil {
+		return x.Receipt
+	}
+	return ""
+}
+
+func (x *Message) GetLeaseUntil() *timestamppb.Timestamp {
+	if x != nil {
+		return x.LeaseUntil
+	}
+	return nil
+}
+
+func (x *Message) GetDeliveryCount() int32 {
+	if x != nil {
+		return x.DeliveryCount
+	}
+	return 0
+}
+
+func (x *Message) GetMaxRetries() int32 {
+	if x != nil {
+		return x.MaxRetries
+	}
+	return 0
+}
+
+func (x *Message) GetDlq() string {
+	if x != nil && x.Dlq != nil {
+		return *x.Dlq
+	}
+	return ""
+}
+
+func (x *Message) GetTraceId() string {
+	if x != nil && x.TraceId != nil {
+		return *x.TraceId
+	}
+	return ""
+}
+
+func (x *Message) GetDeadLetteredAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.DeadLetteredAt
+	}
+	return nil
+}
+
+func (x *Message) GetApproximateReceiveCount() int32 {
+	if x != nil {
+		return x.ApproximateReceiveCount
+	}
+	return 0
+}
+
+type AckRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Receipt       string                 `protobuf:"bytes,1,opt,name=receipt,proto3" json:"receipt,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AckRequest) Reset() {
+	*x = AckRequest{}
+	mi := &file_sqslite_v1_queue_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AckRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AckRequest) ProtoMessage() {}
+
+func (x *AckRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_sqslite_v1_queue_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AckRequest.ProtoReflect.Descriptor instead.
+func (*AckRequest) Descriptor() ([]byte, []int) {
+	return file_sqslite_v1_queue_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *AckRequest) GetReceipt() string {
+	if x != nil {
+		return x.Receipt
+	}
+	return ""
+}
+
+type AckResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AckResponse) Reset() {
+	*x = AckResponse{}
+	mi := &file_sqslite_v1_queue_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AckResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AckResponse) ProtoMessage() {}
+
+func (x *AckResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_sqslite_v1_queue_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AckResponse.ProtoReflect.Descriptor instead.
+func (*AckResponse) Descriptor() ([]byte, []int) {
+	return file_sqslite_v1_queue_proto_rawDescGZIP(), []int{7}
+}
+
+type NackRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Receipt       string                 `protobuf:"bytes,1,opt,name=receipt,proto3" json:"receipt,omitempty"`
+	Error         string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`        // why processing failed; kept as the message's last error
+	Terminal      bool                   `protobuf:"varint,3,opt,name=terminal,proto3" json:"terminal,omitempty"` // at the retry limit, dead-letter now instead of at the next sweep
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *NackRequest) Reset() {
+	*x = NackRequest{}
+	mi := &file_sqslite_v1_queue_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *NackRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NackRequest) ProtoMessage() {}
+
+func (x *NackRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_sqslite_v1_queue_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NackRequest.ProtoReflect.Descriptor instead.
+func (*NackRequest) Descriptor() ([]byte, []int) {
+	return file_sqslite_v1_queue_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *NackRequest) GetReceipt() string {
+	if x != nil {
+		return x.Receipt
+	}
+	return ""
+}
+
+func (x *NackRequest) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *NackRequest) GetTerminal() bool {
+	if x != nil {
+		return x.Terminal
+	}
+	return false
+}
+
+type NackResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Dlq           *string                `protobuf:"bytes,1,opt,name=dlq,proto3,oneof" json:"dlq,omitempty"` // set if a terminal nack dead-lettered the message
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *NackResponse) Reset() {
+	*x = NackResponse{}
+	mi := &file_sqslite_v1_queue_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *NackResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NackResponse) ProtoMessage() {}
+
+func (x *NackResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_sqslite_v1_queue_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NackResponse.ProtoReflect.Descriptor instead.
+func (*NackResponse) Descriptor() ([]byte, []int) {
+	return file_sqslite_v1_queue_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *NackResponse) GetDlq() string {
+	if x != nil && x.Dlq != nil {
+		return *x.Dlq
+	}
+	return ""
+}
+
+type ExtendRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Receipt       string                 `protobuf:"bytes,1,opt,name=receipt,proto3" json:"receipt,omitempty"`
+	VisibilityMs  int64                  `protobuf:"varint,2,opt,name=visibility_ms,json=visibilityMs,proto3" json:"visibility_ms,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ExtendRequest) Reset() {
+	*x = ExtendRequest{}
+	mi := &file_sqslite_v1_queue_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExtendRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExtendRequest) ProtoMessage() {}
+
+func (x *ExtendRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_sqslite_v1_queue_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExtendRequest.ProtoReflect.Descriptor instead.
+func (*ExtendRequest) Descriptor() ([]byte, []int) {
+	return file_sqslite_v1_queue_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *ExtendRequest) GetReceipt() string {
+	if x != nil {
+		return x.Receipt
+	}
+	return ""
+}
+
+func (x *ExtendRequest) GetVisibilityMs() int64 {
+	if x != nil {
+		return x.VisibilityMs
+	}
+	return 0
+}
+
+type ExtendResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Receipt       string                 `protobuf:"bytes,1,opt,name=receipt,proto3" json:"receipt,omitempty"` // replaces the request's receipt for the longer lease
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ExtendResponse) Reset() {
+	*x = ExtendResponse{}
+	mi := &file_sqslite_v1_queue_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExtendResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExtendResponse) ProtoMessage() {}
+
+func (x *ExtendResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_sqslite_v1_queue_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExtendResponse.ProtoReflect.Descriptor instead.
+func (*ExtendResponse) Descriptor() ([]byte, []int) {
+	return file_sqslite_v1_queue_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *ExtendResponse) GetReceipt() string {
+	if x != nil {
+		return x.Receipt
+	}
+	return ""
+}
+
+var File_sqslite_v1_queue_proto protoreflect.FileDescriptor
+
+const file_sqslite_v1_queue_proto_rawDesc = "" +
+	"\n" +
+	"\x16sqslite/v1/queue.proto\x12\n" +
+	"sqslite.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"\xc2\x01\n" +
+	"\x0eEnqueueRequest\x12\x14\n" +
+	"\x05queue\x18\x01 \x01(\tR\x05queue\x12\x12\n" +
+	"\x04body\x18\x02 \x01(\fR\x04body\x12\x19\n" +
+	"\bdelay_ms\x18\x03 \x01(\x03R\adelayMs\x12\x1f\n" +
+	"\vmax_retries\x18\x04 \x01(\x05R\n" +
+	"maxRetries\x12\x15\n" +
+	"\x03dlq\x18\x05 \x01(\tH\x00R\x03dlq\x88\x01\x01\x12\x1e\n" +
+	"\btrace_id\x18\x06 \x01(\tH\x01R\atraceId\x88\x01\x01B\x06\n" +
+	"\x04_dlqB\v\n" +
+	"\t_trace_id\"!\n" +
+	"\x0fEnqueueResponse\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\"\x99\x01\n" +
+	"\x0eReceiveRequest\x12\x14\n" +
+	"\x05queue\x18\x01 \x01(\tR\x05queue\x12\x10\n" +
+	"\x03max\x18\x02 \x01(\x05R\x03max\x12#\n" +
+	"\rvisibility_ms\x18\x03 \x01(\x03R\fvisibilityMs\x12\x17\n" +
+	"\await_ms\x18\x04 \x01(\x03R\x06waitMs\x12!\n" +
+	"\fmin_messages\x18\x05 \x01(\x05R\vminMessages\"B\n" +
+	"\x0fReceiveResponse\x12/\n" +
+	"\bmessages\x18\x01 \x03(\v2\x13.sqslite.v1.MessageR\bmessages\"g\n" +
+	"\x14StreamReceiveRequest\x12\x14\n" +
+	"\x05queue\x18\x01 \x01(\tR\x05queue\x12\x14\n" +
+	"\x05batch\x18\x02 \x01(\x05R\x05batch\x12#\n" +
+	"\rvisibility_ms\x18\x03 \x01(\x03R\fvisibilityMs\"\xb0\x03\n" +
+	"\aMessage\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12\x14\n" +
+	"\x05queue\x18\x02 \x01(\tR\x05queue\x12\x12\n" +
+	"\x04body\x18\x03 \x01(\fR\x04body\x12\x18\n" +
+	"\areceipt\x18\x04 \x01(\tR\areceipt\x12;\n" +
+	"\vlease_until\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"leaseUntil\x12%\n" +
+	"\x0edelivery_count\x18\x06 \x01(\x05R\rdeliveryCount\x12\x1f\n" +
+	"\vmax_retries\x18\a \x01(\x05R\n" +
+	"maxRetries\x12\x15\n" +
+	"\x03dlq\x18\b \x01(\tH\x00R\x03dlq\x88\x01\x01\x12\x1e\n" +
+	"\btrace_id\x18\t \x01(\tH\x01R\atraceId\x88\x01\x01\x12D\n" +
+	"\x10dead_lettered_at\x18\n" +
+	" \x01(\v2\x1a.google.protobuf.TimestampR\x0edeadLetteredAt\x12:\n" +
+	"\x19approximate_receive_count\x18\v \x01(\x05R\x17approximateReceiveCountB\x06\n" +
+	"\x04_dlqB\v\n" +
+	"\t_trace_id\"&\n" +
+	"\n" +
+	"AckRequest\x12\x18\n" +
+	"\areceipt\x18\x01 \x01(\tR\areceipt\"\r\n" +
+	"\vAckResponse\"Y\n" +
+	"\vNackRequest\x12\x18\n" +
+	"\areceipt\x18\x01 \x01(\tR\areceipt\x12\x14\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error\x12\x1a\n" +
+	"\bterminal\x18\x03 \x01(\bR\bterminal\"-\n" +
+	"\fNackResponse\x12\x15\n" +
+	"\x03dlq\x18\x01 \x01(\tH\x00R\x03dlq\x88\x01\x01B\x06\n" +
+	"\x04_dlq\"N\n" +
+	"\rExtendRequest\x12\x18\n" +
+	"\areceipt\x18\x01 \x01(\tR\areceipt\x12#\n" +
+	"\rvisibility_ms\x18\x02 \x01(\x03R\fvisibilityMs\"*\n" +
+	"\x0eExtendResponse\x12\x18\n" +
+	"\areceipt\x18\x01 \x01(\tR\areceipt2\x94\x03\n" +
+	"\fQueueService\x12B\n" +
+	"\aEnqueue\x12\x1a.sqslite.v1.EnqueueRequest\x1a\x1b.sqslite.v1.EnqueueResponse\x12B\n" +
+	"\aReceive\x12\x1a.sqslite.v1.ReceiveRequest\x1a\x1b.sqslite.v1.ReceiveResponse\x12H\n" +
+	"\rStreamReceive\x12 .sqslite.v1.StreamReceiveRequest\x1a\x13.sqslite.v1.Message0\x01\x126\n" +
+	"\x03Ack\x12\x16.sqslite.v1.AckRequest\x1a\x17.sqslite.v1.AckResponse\x129\n" +
+	"\x04Nack\x12\x17.sqslite.v1.NackRequest\x1a\x18.sqslite.v1.NackResponse\x12?\n" +
+	"\x06Extend\x12\x19.sqslite.v1.ExtendRequest\x1a\x1a.sqslite.v1.ExtendResponseB<Z:github.com/aridsondez/AWS-SQS-LITE/pkg/sqslitepb;sqslitepbb\x06proto3"
+
+var (
+	file_sqslite_v1_queue_proto_rawDescOnce sync.Once
+	file_sqslite_v1_queue_proto_rawDescData []byte
+)
+
+func file_sqslite_v1_queue_proto_rawDescGZIP() []byte {
+	file_sqslite_v1_queue_proto_rawDescOnce.Do(func() {
+		file_sqslite_v1_queue_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_sqslite_v1_queue_proto_rawDesc), len(file_sqslite_v1_queue_proto_rawDesc)))
+	})
+	return file_sqslite_v1_queue_proto_rawDescData
+}
+
+var file_sqslite_v1_queue_proto_msgTypes = make([]protoimpl.MessageInfo, 12)
+var file_sqslite_v1_queue_proto_goTypes = []any{
+	(*EnqueueRequest)(nil),        // 0: sqslite.v1.EnqueueRequest
+	(*EnqueueResponse)(nil),       // 1: sqslite.v1.EnqueueResponse
+	(*ReceiveRequest)(nil),        // 2: sqslite.v1.ReceiveRequest
+	(*ReceiveResponse)(nil),       // 3: sqslite.v1.ReceiveResponse
+	(*StreamReceiveRequest)(nil),  // 4: sqslite.v1.StreamReceiveRequest
+	(*Message)(nil),               // 5: sqslite.v1.Message
+	(*AckRequest)(nil),            // 6: sqslite.v1.AckRequest
+	(*AckResponse)(nil),           // 7: sqslite.v1.AckResponse
+	(*NackRequest)(nil),           // 8: sqslite.v1.NackRequest
+	(*NackResponse)(nil),          // 9: sqslite.v1.NackResponse
+	(*ExtendRequest)(nil),         // 10: sqslite.v1.ExtendRequest
+	(*ExtendResponse)(nil),        // 11: sqslite.v1.ExtendResponse
+	(*timestamppb.Timestamp)(nil), // 12: google.protobuf.Timestamp
+}
+var file_sqslite_v1_queue_proto_depIdxs = []int32{
+	5,  // 0: sqslite.v1.ReceiveResponse.messages:type_name -> sqslite.v1.Message
+	12, // 1: sqslite.v1.Message.lease_until:type_name -> google.protobuf.Timestamp
+	12, // 2: sqslite.v1.Message.dead_lettered_at:type_name -> google.protobuf.Timestamp
+	0,  // 3: sqslite.v1.QueueService.Enqueue:input_type -> sqslite.v1.EnqueueRequest
+	2,  // 4: sqslite.v1.QueueService.Receive:input_type -> sqslite.v1.ReceiveRequest
+	4,  // 5: sqslite.v1.QueueService.StreamReceive:input_type -> sqslite.v1.StreamReceiveRequest
+	6,  // 6: sqslite.v1.QueueService.Ack:input_type -> sqslite.v1.AckRequest
+	8,  // 7: sqslite.v1.QueueService.Nack:input_type -> sqslite.v1.NackRequest
+	10, // 8: sqslite.v1.QueueService.Extend:input_type -> sqslite.v1.ExtendRequest
+	1,  // 9: sqslite.v1.QueueService.Enqueue:output_type -> sqslite.v1.EnqueueResponse
+	3,  // 10: sqslite.v1.QueueService.Receive:output_type -> sqslite.v1.ReceiveResponse
+	5,  // 11: sqslite.v1.QueueService.StreamReceive:output_type -> sqslite.v1.Message
+	7,  // 12: sqslite.v1.QueueService.Ack:output_type -> sqslite.v1.AckResponse
+	9,  // 13: sqslite.v1.QueueService.Nack:output_type -> sqslite.v1.NackResponse
+	11, // 14: sqslite.v1.QueueService.Extend:output_type -> sqslite.v1.ExtendResponse
+	9,  // [9:15] is the sub-list for method output_type
+	3,  // [3:9] is the sub-list for method input_type
+	3,  // [3:3] is the sub-list for extension type_name
+	3,  // [3:3] is the sub-list for extension extendee
+	0,  // [0:3] is the sub-list for field type_name
+}
+
+func init() { file_sqslite_v1_queue_proto_init() }
+func file_sqslite_v1_queue_proto_init() {
+	if File_sqslite_v1_queue_proto != nil {
+		return
+	}
+	file_sqslite_v1_queue_proto_msgTypes[0].OneofWrappers = []any{}
+	file_sqslite_v1_queue_proto_msgTypes[5].OneofWrappers = []any{}
+	file_sqslite_v1_queue_proto_msgTypes[9].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_sqslite_v1_queue_proto_rawDesc), len(file_sqslite_v1_queue_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   12,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_sqslite_v1_queue_proto_goTypes,
+		DependencyIndexes: file_sqslite_v1_queue_proto_depIdxs,
+		MessageInfos:      file_sqslite_v1_queue_proto_msgTypes,
+	}.Build()
+	File_sqslite_v1_queue_proto = out.File
+	file_sqslite_v1_queue_proto_goTypes = nil
+	file_sqslite_v1_queue_proto_depIdxs = nil
+}
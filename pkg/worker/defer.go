@@ -0,0 +1,79 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"log"
+)
+
+// Deferred is the result of a handler whose work is confirmed elsewhere
+// later, e.g. by a job it started. See Defer.
+type Deferred struct {
+	done <-chan error
+}
+
+func (d *Deferred) Error() string { return "processing in progress" }
+
+// Defer returns a handler result that leaves msg in progress instead of
+// acking it. The handler's slot is freed at once, while the worker keeps
+// renewing the lease every Visibility/2 until done receives: nil acks the
+// message, an error leaves it to be redelivered once the lease runs out. If
+// the worker stops first, the message is redelivered too.
+//
+//	w.Handle("exports", func(ctx context.Context, msg *worker.Message) error {
+//	    done := make(chan error, 1)
+//	    startExport(msg.Body, func(err error) { done <- err })
+//	    return worker.Defer(done)
+//	})
+func Defer(done <-chan error) error {
+	return &Deferred{done: done}
+}
+
+// awaitDeferred keeps msg leased in the background until its confirmation
+// arrives, then acks it. The lease is renewed straight away, since the
+// handler may have used most of it, and after that by extendLoop.
+func (w *Worker) awaitDeferred(ctx context.Context, msg *Message, d *Deferred) {
+	w.track(msg)
+	w.running.Add(1)
+	go func() {
+		defer w.running.Done()
+		defer w.untrack(msg)
+
+		w.mu.Lock()
+		receipt := msg.Receipt
+		w.mu.Unlock()
+		extended, renewed, err := w.extendLeases(ctx, []string{receipt})
+		if err != nil || len(extended) == 0 {
+			log.Printf("Could not extend deferred message %d from %s; it may be redelivered: %v", msg.ID, msg.Queue, err)
+			return
+		}
+		w.mu.Lock()
+		if r, ok := renewed[msg.ID]; ok {
+			msg.Receipt = r
+		}
+		w.mu.Unlock()
+
+		select {
+		case err := <-d.done:
+			if err != nil {
+				log.Printf("Deferred message %d from %s failed: %v (will requeue)", msg.ID, msg.Queue, err)
+				return
+			}
+		case <-ctx.Done():
+			log.Printf("Stopped waiting for deferred message %d from %s; it will be redelivered", msg.ID, msg.Queue)
+			return
+		}
+		if err := w.ackMessage(ctx, msg); err != nil {
+			log.Printf("Error acking message %d: %v", msg.ID, err)
+			return
+		}
+		log.Printf("✓ Successfully processed deferred message %d from %s", msg.ID, msg.Queue)
+	}()
+}
+
+// asDeferred reports whether a handler's result defers its ack.
+func asDeferred(err error) (*Deferred, bool) {
+	var d *Deferred
+	ok := errors.As(err, &d)
+	return d, ok
+}
@@ -0,0 +1,37 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// healthLoop runs the health check every healthInterval until ctx is done.
+// run has already done the first check, so polling never starts against a
+// downstream that is already known to be down.
+func (w *Worker) healthLoop(ctx context.Context) {
+	ticker := time.NewTicker(w.healthInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.checkHealth(ctx)
+		}
+	}
+}
+
+// checkHealth runs the health check, bounded by healthInterval, and records
+// whether the queues may be polled, logging when that changes.
+func (w *Worker) checkHealth(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, w.healthInterval)
+	defer cancel()
+	err := w.healthCheck(ctx)
+	switch was := w.healthy.Swap(err == nil); {
+	case err != nil && was:
+		log.Printf("Health check failed, pausing polling: %v", err)
+	case err == nil && !was:
+		log.Printf("Health check passed, resuming polling")
+	}
+}
@@ -0,0 +1,58 @@
+package worker
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+)
+
+// do sends req to the server, tagged with the worker's ID and its context's
+// traceparent, waiting first for a free slot when MaxConcurrentRequests is
+// set. The slot is held until the response body is closed, so it covers
+// reading the response as well.
+func (w *Worker) do(req *http.Request) (*http.Response, error) {
+	req.Header.Set("X-Worker-ID", w.workerID)
+	if tp := TraceParent(req.Context()); tp != "" {
+		req.Header.Set("traceparent", tp)
+	}
+	if w.requests == nil {
+		return w.client.Do(req)
+	}
+	select {
+	case w.requests <- struct{}{}:
+	case <-req.Context().Done():
+		return nil, req.Context().Err()
+	}
+	resp, err := w.client.Do(req)
+	if err != nil {
+		<-w.requests
+		return nil, err
+	}
+	resp.Body = &slotBody{ReadCloser: resp.Body, release: func() { <-w.requests }}
+	return resp, nil
+}
+
+// defaultWorkerID is hostname-pid, which is distinct per replica and per
+// process on a shared host.
+func defaultWorkerID() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	return fmt.Sprintf("%s-%d", host, os.Getpid())
+}
+
+// slotBody gives back a request slot when the response body is closed.
+type slotBody struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+func (b *slotBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
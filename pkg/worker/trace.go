@@ -0,0 +1,31 @@
+package worker
+
+import "context"
+
+type traceParentKey struct{}
+
+// ContextWithTraceParent returns ctx carrying a W3C traceparent. Requests
+// the worker makes with it send the traceparent header, so calls made under
+// Run's context join the caller's trace. The handler's context carries the
+// message's own traceparent, which its ack is sent with as well.
+//
+// To bridge from a tracing library, format its active span as a traceparent
+// and pass it here.
+func ContextWithTraceParent(ctx context.Context, traceParent string) context.Context {
+	return context.WithValue(ctx, traceParentKey{}, traceParent)
+}
+
+// TraceParent returns the traceparent carried by ctx, or "" if there is none.
+// In a handler it is the one the message was enqueued with.
+func TraceParent(ctx context.Context) string {
+	tp, _ := ctx.Value(traceParentKey{}).(string)
+	return tp
+}
+
+// withMessageTrace continues msg's trace in ctx, if it has one.
+func withMessageTrace(ctx context.Context, msg *Message) context.Context {
+	if msg.TraceParent == "" {
+		return ctx
+	}
+	return ContextWithTraceParent(ctx, msg.TraceParent)
+}
@@ -0,0 +1,78 @@
+package worker
+
+import (
+	"math"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	// visibilityQuantile is the share of recent messages the adaptive
+	// visibility is sized to cover before the safety factor.
+	visibilityQuantile = 0.95
+
+	// visibilityWindow is how many recent durations the estimate uses.
+	visibilityWindow = 100
+
+	// minVisibilitySamples is how many durations are needed before the
+	// estimate replaces the fallback.
+	minVisibilitySamples = 10
+
+	// minAdaptiveVisibility keeps very fast handlers from getting leases
+	// shorter than a round trip to the server.
+	minAdaptiveVisibility = time.Second
+)
+
+// VisibilityEstimator sizes a queue's visibility timeout from how long its
+// recent messages took: the P95 of the last 100 durations times a safety
+// factor, clamped to [1s, max]. Until it has seen 10 durations it returns
+// the fallback. It is safe for concurrent use.
+type VisibilityEstimator struct {
+	fallback time.Duration
+	max      time.Duration
+	factor   float64
+
+	mu      sync.Mutex
+	samples []time.Duration // ring buffer of the last visibilityWindow durations
+	next    int
+}
+
+// NewVisibilityEstimator returns an estimator that starts at fallback and
+// never exceeds maxVis.
+func NewVisibilityEstimator(fallback, maxVis time.Duration, factor float64) *VisibilityEstimator {
+	return &VisibilityEstimator{
+		fallback: fallback,
+		max:      maxVis,
+		factor:   factor,
+		samples:  make([]time.Duration, 0, visibilityWindow),
+	}
+}
+
+// Observe records how long a message took.
+func (e *VisibilityEstimator) Observe(d time.Duration) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if len(e.samples) < visibilityWindow {
+		e.samples = append(e.samples, d)
+		return
+	}
+	e.samples[e.next] = d
+	e.next = (e.next + 1) % visibilityWindow
+}
+
+// Visibility returns the visibility timeout to request next.
+func (e *VisibilityEstimator) Visibility() time.Duration {
+	e.mu.Lock()
+	if len(e.samples) < minVisibilitySamples {
+		e.mu.Unlock()
+		return e.fallback
+	}
+	sorted := slices.Clone(e.samples)
+	e.mu.Unlock()
+
+	slices.Sort(sorted)
+	i := int(math.Ceil(visibilityQuantile*float64(len(sorted)))) - 1
+	vis := time.Duration(float64(sorted[i]) * e.factor)
+	return min(max(vis, minAdaptiveVisibility), e.max)
+}
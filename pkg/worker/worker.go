@@ -3,12 +3,19 @@ package worker
 import (
 	"bytes"
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
 	"log"
+	"maps"
 	"net/http"
+	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/aridsondez/AWS-SQS-LITE/pkg/codec"
 )
 
 // HandlerFunc processes a message and returns an error if processing failed.
@@ -20,29 +27,141 @@ type HandlerFunc func(ctx context.Context, msg *Message) error
 type Message struct {
 	ID            int64           `json:"id"`
 	Body          json.RawMessage `json:"body"`
-	Receipt       string          `json:"receipt"`
+	Receipt       string          `json:"receipt"` // auto-extend renews it while the handler runs
 	LeaseUntil    *time.Time      `json:"lease_until,omitempty"`
 	DeliveryCount int             `json:"delivery_count"`
 	MaxRetries    int             `json:"max_retries"`
-	Queue         string          `json:"-"` // Set by worker
+	EnqueuedAt    time.Time       `json:"enqueued_at"`
+	GroupID       *string         `json:"group_id,omitempty"`
+	GroupSealed   bool            `json:"group_sealed,omitempty"` // last message of a sealed group
+	BodyRef       *string         `json:"body_ref,omitempty"`     // claim check; Body is null unless the server fetched it
+	TraceParent   string          `json:"traceparent,omitempty"`  // W3C trace context from the producer; see TraceParent
+	Queue         string          `json:"-"`                      // Set by worker
+
+	received   time.Time     // when the worker got it
+	visibility time.Duration // the lease it was received with
+}
+
+// Decode decodes the body into v with the codec it was enqueued with; see
+// package codec. Bodies enqueued as plain JSON decode as JSON.
+func (m *Message) Decode(v any) error {
+	return codec.Decode(m.Body, v)
 }
 
+// ContentType is the content type the body was encoded with.
+func (m *Message) ContentType() string {
+	return codec.ContentType(m.Body)
+}
+
+// maxExtendBatch matches the server's limit on receipts per extend-batch
+// and release-batch call.
+const maxExtendBatch = 100
+
+// releaseTimeout bounds handing buffered messages back at shutdown.
+const releaseTimeout = 5 * time.Second
+
+// ackAttempts bounds how often ackMessage sends an ack that failed in
+// transit or with a server error before giving up on it.
+const ackAttempts = 3
+
 // Worker manages message processing from queues
 type Worker struct {
-	baseURL   string
-	client    *http.Client
-	handlers  map[string]HandlerFunc
-	pollDelay time.Duration
-	batchSize int
-	visibility time.Duration
+	baseURL     string
+	client      *http.Client
+	handlers    map[string]HandlerFunc
+	fallback    HandlerFunc // for discovered queues without their own handler
+	discovery   time.Duration
+	pollDelay   time.Duration
+	batchSize   int
+	prefetch    int
+	lowWater    int
+	concurrency int
+	visibility  time.Duration
+	autoExtend  bool
+	workerID    string
+	requests    chan struct{} // MaxConcurrentRequests slots; nil for no limit
+
+	panicHandler func(msg *Message, recovered any)
+	onReceive    func(queue string, msgs []*Message)
+
+	healthCheck    func(ctx context.Context) error
+	healthInterval time.Duration
+	healthy        atomic.Bool // last health check passed; polling pauses while false
+
+	adaptive      bool
+	maxVisibility time.Duration
+	visFactor     float64
+
+	mu       sync.Mutex
+	active   map[int64]*Message // messages whose handler is running, for auto-extend
+	buffered map[int64]*Message // received but no handler started yet
+	closing  bool               // shutting down: buffered messages go back, not to handlers
+	budget   int                // handlers RunN may still start; -1 for no limit
+	spent    func()             // called when budget reaches 0
+	running  sync.WaitGroup     // handlers started and not yet returned
 }
 
-// Config for creating a new worker
+// Config for creating a new worker.
+//
+// Every buffered message is already leased, and its visibility timeout keeps
+// running while it waits. Keep Prefetch small enough that the last buffered
+// message can start well within Visibility: roughly
+// Prefetch / Concurrency * (handler time) < Visibility.
 type Config struct {
-	BaseURL    string        // SQS Lite server URL
-	PollDelay  time.Duration // Time between polling attempts (default: 1s)
-	BatchSize  int           // Max messages to fetch per poll (default: 10)
-	Visibility time.Duration // Visibility timeout (default: 30s)
+	BaseURL     string        // SQS Lite server URL
+	PollDelay   time.Duration // Time between polling attempts (default: 1s)
+	BatchSize   int           // Max messages to fetch per poll (default: 10)
+	Prefetch    int           // Max messages held locally waiting for a handler (default: BatchSize)
+	LowWater    int           // Fetch more only when the buffer is at or below this (default: 0, i.e. empty)
+	Concurrency int           // Handlers run at once per queue (default: 1)
+	Visibility  time.Duration // Visibility timeout (default: 30s)
+
+	// Renew the leases of running handlers every Visibility/2, so a handler
+	// can run longer than Visibility (default: false)
+	AutoExtend bool
+
+	// Size each receive's visibility from the queue's history instead of
+	// using Visibility: the P95 of how long recent messages took, from
+	// receive to handler return (so including time in the prefetch buffer),
+	// times VisibilityFactor, capped at MaxVisibility. Visibility is used
+	// until 10 messages have finished (default: false)
+	AdaptiveVisibility bool
+	MaxVisibility      time.Duration // Cap for adaptive visibility (default: 10 * Visibility)
+	VisibilityFactor   float64       // Safety factor over the P95 (default: 1.5)
+
+	// How often to list queues for HandleDefault (default: 30s)
+	DiscoveryInterval time.Duration
+
+	// Cap on HTTP calls to the server in flight at once, across all queues:
+	// receives, acks, extends and releases wait for a free slot. Bounds the
+	// load a worker with many queues or a high Concurrency puts on the
+	// server (default: 0, no limit)
+	MaxConcurrentRequests int
+
+	// Sent as X-Worker-ID on every request, so the server's logs and
+	// metrics can tell replicas apart (default: hostname-pid)
+	WorkerID string
+
+	// Called with the recovered value when a handler panics, instead of
+	// logging it. The message isn't acked either way, so it is retried
+	// unless the handler deals with it, e.g. by dead-lettering it. Panicking
+	// again from here crashes the process (default: nil, log and continue)
+	PanicHandler func(msg *Message, recovered any)
+
+	// Called with every non-empty batch received, before any of it is
+	// handled, e.g. to record batch sizes or lag from EnqueuedAt. It runs
+	// in its own goroutine so it never holds up processing; batches can
+	// arrive out of order. The messages are copies, so handlers renewing
+	// receipts don't race with it (default: nil)
+	OnReceive func(queue string, msgs []*Message)
+
+	// Checks the handlers' downstream dependencies, e.g. pings their
+	// database. It runs when the worker starts and every HealthInterval;
+	// while it returns an error no queue is polled, so no new messages are
+	// leased only to fail and use up retries. Messages already received
+	// still go to handlers (default: nil, always poll)
+	HealthCheck    func(ctx context.Context) error
+	HealthInterval time.Duration // How often to run HealthCheck, and its timeout (default: 5s)
 }
 
 // New creates a new Worker with the given configuration
@@ -53,18 +172,69 @@ func New(cfg Config) *Worker {
 	if cfg.BatchSize == 0 {
 		cfg.BatchSize = 10
 	}
+	if cfg.Prefetch == 0 {
+		cfg.Prefetch = cfg.BatchSize
+	}
+	if cfg.LowWater >= cfg.Prefetch {
+		cfg.LowWater = cfg.Prefetch - 1
+	}
+	if cfg.Concurrency == 0 {
+		cfg.Concurrency = 1
+	}
 	if cfg.Visibility == 0 {
 		cfg.Visibility = 30 * time.Second
 	}
+	if cfg.MaxVisibility == 0 {
+		cfg.MaxVisibility = 10 * cfg.Visibility
+	}
+	if cfg.VisibilityFactor == 0 {
+		cfg.VisibilityFactor = 1.5
+	}
+	if cfg.DiscoveryInterval == 0 {
+		cfg.DiscoveryInterval = 30 * time.Second
+	}
+	if cfg.WorkerID == "" {
+		cfg.WorkerID = defaultWorkerID()
+	}
+	if cfg.HealthInterval == 0 {
+		cfg.HealthInterval = 5 * time.Second
+	}
 
-	return &Worker{
-		baseURL:    cfg.BaseURL,
-		client:     &http.Client{Timeout: 10 * time.Second},
-		handlers:   make(map[string]HandlerFunc),
-		pollDelay:  cfg.PollDelay,
-		batchSize:  cfg.BatchSize,
-		visibility: cfg.Visibility,
+	var requests chan struct{}
+	if cfg.MaxConcurrentRequests > 0 {
+		requests = make(chan struct{}, cfg.MaxConcurrentRequests)
 	}
+
+	w := &Worker{
+		baseURL:     cfg.BaseURL,
+		client:      &http.Client{Timeout: 10 * time.Second},
+		handlers:    make(map[string]HandlerFunc),
+		discovery:   cfg.DiscoveryInterval,
+		pollDelay:   cfg.PollDelay,
+		batchSize:   cfg.BatchSize,
+		prefetch:    cfg.Prefetch,
+		lowWater:    cfg.LowWater,
+		concurrency: cfg.Concurrency,
+		visibility:  cfg.Visibility,
+		autoExtend:  cfg.AutoExtend,
+		workerID:    cfg.WorkerID,
+		requests:    requests,
+		active:      make(map[int64]*Message),
+		buffered:    make(map[int64]*Message),
+		budget:      -1,
+
+		panicHandler: cfg.PanicHandler,
+		onReceive:    cfg.OnReceive,
+
+		healthCheck:    cfg.HealthCheck,
+		healthInterval: cfg.HealthInterval,
+
+		adaptive:      cfg.AdaptiveVisibility,
+		maxVisibility: cfg.MaxVisibility,
+		visFactor:     cfg.VisibilityFactor,
+	}
+	w.healthy.Store(true)
+	return w
 }
 
 // Handle registers a handler function for a specific queue
@@ -73,30 +243,185 @@ func (w *Worker) Handle(queue string, handler HandlerFunc) {
 	log.Printf("Registered handler for queue: %s", queue)
 }
 
+// HandleDefault registers a handler for every queue that has no handler of
+// its own. The worker lists the server's queues every DiscoveryInterval and
+// starts polling new ones with it. Handlers registered with Handle always
+// take precedence.
+func (w *Worker) HandleDefault(handler HandlerFunc) {
+	w.fallback = handler
+	log.Printf("Registered default handler")
+}
+
 // Run starts the worker and blocks until context is cancelled
 func (w *Worker) Run(ctx context.Context) error {
-	if len(w.handlers) == 0 {
+	return w.run(ctx, ctx, false)
+}
+
+// RunN is Run for batch jobs: once handlers have been started for
+// maxMessages messages, across all queues, it stops polling, waits for the
+// running handlers to finish and returns. Messages received beyond that are
+// released. Cancelling ctx stops it early, as for Run.
+func (w *Worker) RunN(ctx context.Context, maxMessages int) error {
+	if maxMessages <= 0 {
+		return fmt.Errorf("maxMessages must be positive, got %d", maxMessages)
+	}
+	stopCtx, stop := context.WithCancel(ctx)
+	defer stop()
+
+	w.mu.Lock()
+	w.budget, w.spent = maxMessages, stop
+	w.mu.Unlock()
+	defer func() {
+		w.mu.Lock()
+		w.budget, w.spent = -1, nil
+		w.mu.Unlock()
+	}()
+	return w.run(stopCtx, ctx, true)
+}
+
+// RunFor is Run for batch jobs: after d it stops polling, waits for the
+// running handlers to finish and returns. Cancelling ctx stops it early, as
+// for Run.
+func (w *Worker) RunFor(ctx context.Context, d time.Duration) error {
+	stopCtx, stop := context.WithTimeout(ctx, d)
+	defer stop()
+	return w.run(stopCtx, ctx, true)
+}
+
+// run polls until ctx is done. Handlers and their acks run under work, so a
+// batch run can stop polling while its handlers finish; with drain set it
+// waits for them before returning.
+func (w *Worker) run(ctx, work context.Context, drain bool) error {
+	if len(w.handlers) == 0 && w.fallback == nil {
 		return fmt.Errorf("no handlers registered")
 	}
 
 	log.Printf("Worker starting with %d queue(s)", len(w.handlers))
 
+	w.mu.Lock()
+	w.closing = false
+	w.mu.Unlock()
+
+	if w.healthCheck != nil {
+		w.checkHealth(ctx)
+		go w.healthLoop(ctx)
+	}
+
 	// Start a goroutine for each queue
 	for queue, handler := range w.handlers {
-		go w.pollQueue(ctx, queue, handler)
+		go w.pollQueue(ctx, work, queue, handler)
+	}
+	if w.fallback != nil {
+		go w.discoverQueues(ctx, work)
 	}
+	// Deferred messages are renewed here even without auto-extend.
+	extendCtx, stopExtending := context.WithCancel(work)
+	defer stopExtending()
+	go w.extendLoop(extendCtx)
 
 	// Wait for context cancellation
 	<-ctx.Done()
 	log.Println("Worker shutting down...")
+	w.releaseBuffered()
+	if drain {
+		w.running.Wait()
+	}
 	return nil
 }
 
-// pollQueue continuously polls a queue and processes messages
-func (w *Worker) pollQueue(ctx context.Context, queue string, handler HandlerFunc) {
+// releaseBuffered hands back every message still waiting for a handler, so
+// the server can redeliver it at once instead of after its visibility
+// timeout. Handlers already running are left to finish or time out.
+func (w *Worker) releaseBuffered() {
+	w.mu.Lock()
+	w.closing = true
+	receipts := make([]string, 0, len(w.buffered))
+	for _, msg := range w.buffered {
+		receipts = append(receipts, msg.Receipt)
+	}
+	w.buffered = make(map[int64]*Message)
+	w.mu.Unlock()
+
+	w.release(receipts)
+}
+
+// release returns leases to the server, uncounted; ctx is already done at
+// shutdown, so it gets its own deadline.
+func (w *Worker) release(receipts []string) {
+	if len(receipts) == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), releaseTimeout)
+	defer cancel()
+	released, err := w.releaseLeases(ctx, receipts)
+	if err != nil {
+		log.Printf("Error releasing %d buffered message(s): %v", len(receipts), err)
+		return
+	}
+	log.Printf("Released %d buffered message(s) for redelivery", len(released))
+}
+
+// hold records msg as buffered. It returns false once the worker is
+// shutting down, in which case msg must be released by the caller.
+func (w *Worker) hold(msg *Message) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closing {
+		return false
+	}
+	w.buffered[msg.ID] = msg
+	return true
+}
+
+// take claims a buffered message for a handler, which must call
+// w.running.Done when it returns. It returns false once the worker is
+// shutting down (ctx may be done before Run has noticed) or RunN's budget is
+// spent; the message then stays buffered and is released instead.
+func (w *Worker) take(ctx context.Context, msg *Message) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closing || ctx.Err() != nil || w.budget == 0 {
+		return false
+	}
+	if w.budget > 0 {
+		w.budget--
+		if w.budget == 0 {
+			w.spent()
+		}
+	}
+	delete(w.buffered, msg.ID)
+	w.running.Add(1)
+	return true
+}
+
+// unbudgeted is how many more messages RunN's budget has room for beyond
+// those already buffered, or -1 without a budget.
+func (w *Worker) unbudgeted() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.budget < 0 {
+		return -1
+	}
+	return max(w.budget-len(w.buffered), 0)
+}
+
+// pollQueue keeps up to prefetch messages buffered for the queue's
+// processors, fetching more only once the buffer drains to the low-water mark.
+// Handlers run under work.
+func (w *Worker) pollQueue(ctx, work context.Context, queue string, handler HandlerFunc) {
 	ticker := time.NewTicker(w.pollDelay)
 	defer ticker.Stop()
 
+	var est *VisibilityEstimator
+	if w.adaptive {
+		est = NewVisibilityEstimator(w.visibility, w.maxVisibility, w.visFactor)
+	}
+
+	buf := make(chan *Message, w.prefetch)
+	for i := 0; i < w.concurrency; i++ {
+		go w.processLoop(ctx, work, buf, handler, est)
+	}
+
 	log.Printf("Started polling queue: %s", queue)
 
 	for {
@@ -106,7 +431,30 @@ func (w *Worker) pollQueue(ctx context.Context, queue string, handler HandlerFun
 			return
 
 		case <-ticker.C:
-			messages, err := w.receiveMessages(ctx, queue)
+			if !w.healthy.Load() {
+				continue // downstream is down; lease nothing until it recovers
+			}
+			buffered := len(buf)
+			if buffered > w.lowWater {
+				continue // still enough work queued locally
+			}
+
+			vis := w.visibility
+			if est != nil {
+				vis = est.Visibility()
+				if w.autoExtend {
+					// the next extend tick can be up to visibility/2 away
+					vis = max(vis, w.visibility*3/4)
+				}
+			}
+			n := min(w.batchSize, w.prefetch-buffered)
+			if left := w.unbudgeted(); left >= 0 {
+				n = min(n, left)
+			}
+			if n == 0 {
+				continue
+			}
+			messages, err := w.receiveMessages(ctx, queue, n, vis)
 			if err != nil {
 				log.Printf("Error receiving from %s: %v", queue, err)
 				continue
@@ -118,10 +466,82 @@ func (w *Worker) pollQueue(ctx context.Context, queue string, handler HandlerFun
 
 			log.Printf("Received %d message(s) from %s", len(messages), queue)
 
-			// Process each message
+			// Only this loop sends and we asked for no more than the free
+			// space, so these never block.
+			now := time.Now()
 			for _, msg := range messages {
 				msg.Queue = queue
-				w.processMessage(ctx, msg, handler)
+				msg.received = now
+				msg.visibility = vis
+			}
+			w.notifyReceive(queue, messages)
+			var late []string // arrived after shutdown began
+			for _, msg := range messages {
+				if !w.hold(msg) {
+					late = append(late, msg.Receipt)
+					continue
+				}
+				buf <- msg
+			}
+			w.release(late)
+		}
+	}
+}
+
+// discoverQueues starts polling, with the default handler, each queue on the
+// server that isn't already being polled.
+func (w *Worker) discoverQueues(ctx, work context.Context) {
+	ticker := time.NewTicker(w.discovery)
+	defer ticker.Stop()
+
+	polled := make(map[string]bool, len(w.handlers))
+	for queue := range w.handlers {
+		polled[queue] = true
+	}
+
+	for {
+		queues, err := w.listQueues(ctx)
+		if err != nil {
+			log.Printf("Error listing queues: %v", err)
+		}
+		for _, queue := range queues {
+			if polled[queue] {
+				continue
+			}
+			polled[queue] = true
+			log.Printf("Discovered queue: %s (default handler)", queue)
+			go w.pollQueue(ctx, work, queue, w.fallback)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// processLoop hands buffered messages to handler one at a time, until ctx is
+// done. est, if set, is told how long each one took.
+func (w *Worker) processLoop(ctx, work context.Context, buf <-chan *Message, handler HandlerFunc, est *VisibilityEstimator) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg := <-buf:
+			if !w.take(ctx, msg) {
+				return
+			}
+			if msg.LeaseUntil != nil && time.Now().After(*msg.LeaseUntil) {
+				// Waited too long in the buffer; someone else may own it now.
+				log.Printf("Skipping message %d from %s: lease expired before processing", msg.ID, msg.Queue)
+				w.running.Done()
+				continue
+			}
+			w.processMessage(work, msg, handler)
+			w.running.Done()
+			if est != nil {
+				est.Observe(time.Since(msg.received))
 			}
 		}
 	}
@@ -129,13 +549,41 @@ func (w *Worker) pollQueue(ctx context.Context, queue string, handler HandlerFun
 
 // processMessage handles a single message with error recovery
 func (w *Worker) processMessage(ctx context.Context, msg *Message, handler HandlerFunc) {
-	// Create a timeout context for the handler
-	handlerCtx, cancel := context.WithTimeout(ctx, w.visibility-5*time.Second)
-	defer cancel()
+	// Registered first so it runs last, once the handler's own tracking is
+	// undone.
+	var deferred *Deferred
+	defer func() {
+		if deferred != nil {
+			w.awaitDeferred(ctx, msg, deferred)
+		}
+	}()
+
+	// The handler and the ack continue the producer's trace.
+	ctx = withMessageTrace(ctx, msg)
+
+	// Without auto-extend the lease is gone after visibility, so stop the
+	// handler a little before that.
+	handlerCtx := ctx
+	if w.autoExtend {
+		w.track(msg)
+		defer w.untrack(msg)
+	} else {
+		vis := msg.visibility
+		if vis == 0 {
+			vis = w.visibility
+		}
+		var cancel context.CancelFunc
+		handlerCtx, cancel = context.WithTimeout(ctx, vis-min(5*time.Second, vis/4))
+		defer cancel()
+	}
 
 	// Recover from panics
 	defer func() {
 		if r := recover(); r != nil {
+			if w.panicHandler != nil {
+				w.panicHandler(msg, r)
+				return
+			}
 			log.Printf("PANIC processing message %d from %s: %v (will requeue)",
 				msg.ID, msg.Queue, r)
 			// Don't ack - let it requeue
@@ -145,6 +593,10 @@ func (w *Worker) processMessage(ctx context.Context, msg *Message, handler Handl
 	// Call the handler
 	err := handler(handlerCtx, msg)
 
+	if d, ok := asDeferred(err); ok {
+		deferred = d
+		return
+	}
 	if err != nil {
 		log.Printf("Error processing message %d from %s (attempt %d/%d): %v",
 			msg.ID, msg.Queue, msg.DeliveryCount, msg.MaxRetries, err)
@@ -153,7 +605,7 @@ func (w *Worker) processMessage(ctx context.Context, msg *Message, handler Handl
 	}
 
 	// Success - acknowledge the message
-	if err := w.ackMessage(ctx, msg.ID); err != nil {
+	if err := w.ackMessage(ctx, msg); err != nil {
 		log.Printf("Error acking message %d: %v", msg.ID, err)
 		return
 	}
@@ -161,11 +613,64 @@ func (w *Worker) processMessage(ctx context.Context, msg *Message, handler Handl
 	log.Printf("✓ Successfully processed message %d from %s", msg.ID, msg.Queue)
 }
 
+func (w *Worker) track(msg *Message) {
+	w.mu.Lock()
+	w.active[msg.ID] = msg
+	w.mu.Unlock()
+}
+
+func (w *Worker) untrack(msg *Message) {
+	w.mu.Lock()
+	delete(w.active, msg.ID)
+	w.mu.Unlock()
+}
+
+// extendLoop renews the leases of all running handlers with one
+// extend-batch call every half visibility timeout.
+func (w *Worker) extendLoop(ctx context.Context) {
+	ticker := time.NewTicker(w.visibility / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.mu.Lock()
+			receipts := make([]string, 0, len(w.active))
+			for _, msg := range w.active {
+				receipts = append(receipts, msg.Receipt)
+			}
+			w.mu.Unlock()
+			if len(receipts) == 0 {
+				continue
+			}
+
+			extended, renewed, err := w.extendLeases(ctx, receipts)
+			// Receipts expire with their lease, so acks need the renewed ones.
+			w.mu.Lock()
+			for id, receipt := range renewed {
+				if msg, ok := w.active[id]; ok {
+					msg.Receipt = receipt
+				}
+			}
+			w.mu.Unlock()
+			if err != nil {
+				log.Printf("Error extending leases: %v", err)
+				continue
+			}
+			if n := len(receipts) - len(extended); n > 0 {
+				log.Printf("Could not extend %d lease(s); they may be redelivered", n)
+			}
+		}
+	}
+}
+
 // receiveMessages fetches messages from a queue
-func (w *Worker) receiveMessages(ctx context.Context, queue string) ([]*Message, error) {
+func (w *Worker) receiveMessages(ctx context.Context, queue string, max int, visibility time.Duration) ([]*Message, error) {
 	reqBody := map[string]interface{}{
-		"max":           w.batchSize,
-		"visibility_ms": int(w.visibility.Milliseconds()),
+		"max":           max,
+		"visibility_ms": int(visibility.Milliseconds()),
 	}
 
 	body, err := json.Marshal(reqBody)
@@ -180,7 +685,7 @@ func (w *Worker) receiveMessages(ctx context.Context, queue string) ([]*Message,
 	}
 	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := w.client.Do(req)
+	resp, err := w.do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -191,34 +696,207 @@ func (w *Worker) receiveMessages(ctx context.Context, queue string) ([]*Message,
 		return nil, fmt.Errorf("receive failed: %s - %s", resp.Status, string(bodyBytes))
 	}
 
+	return decodeMessages(resp.Body)
+}
+
+// decodeMessages reads a receive response in either shape the server sends:
+// a bare array, or {"messages": [...], "count": N} when it is configured to
+// wrap responses.
+func decodeMessages(r io.Reader) ([]*Message, error) {
+	var raw json.RawMessage
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return nil, err
+	}
+	if len(raw) > 0 && raw[0] == '{' {
+		var envelope struct {
+			Messages []*Message `json:"messages"`
+		}
+		err := json.Unmarshal(raw, &envelope)
+		return envelope.Messages, err
+	}
 	var messages []*Message
-	if err := json.NewDecoder(resp.Body).Decode(&messages); err != nil {
+	err := json.Unmarshal(raw, &messages)
+	return messages, err
+}
+
+// listQueues fetches the names of the server's queues
+func (w *Worker) listQueues(ctx context.Context) ([]string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", w.baseURL+"/v1/queues", nil)
+	if err != nil {
 		return nil, err
 	}
 
-	return messages, nil
+	resp, err := w.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("list queues failed: %s - %s", resp.Status, string(bodyBytes))
+	}
+
+	var result struct {
+		Queues []string `json:"queues"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	return result.Queues, nil
 }
 
-// ackMessage acknowledges a message
-func (w *Worker) ackMessage(ctx context.Context, messageID int64) error {
-	url := fmt.Sprintf("%s/v1/messages/%d:ack", w.baseURL, messageID)
+// extendLeases renews the given receipts' leases by the visibility timeout
+// and returns the IDs of the messages that were extended, along with their
+// renewed receipts by ID
+func (w *Worker) extendLeases(ctx context.Context, receipts []string) ([]int64, map[int64]string, error) {
+	renewed := make(map[int64]string)
+	extended, err := w.postReceipts(ctx, "extend", receipts, map[string]interface{}{
+		"visibility_ms": int(w.visibility.Milliseconds()),
+	}, func(raw []byte) ([]int64, error) {
+		var result struct {
+			Extended []int64          `json:"extended"`
+			Receipts map[int64]string `json:"receipts"`
+		}
+		err := json.Unmarshal(raw, &result)
+		maps.Copy(renewed, result.Receipts)
+		return result.Extended, err
+	})
+	return extended, renewed, err
+}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader([]byte("{}")))
+// releaseLeases hands the given receipts' leases back and returns the IDs of
+// the messages that were released
+func (w *Worker) releaseLeases(ctx context.Context, receipts []string) ([]int64, error) {
+	return w.postReceipts(ctx, "release", receipts, nil, func(raw []byte) ([]int64, error) {
+		var result struct {
+			Released []int64 `json:"released"`
+		}
+		err := json.Unmarshal(raw, &result)
+		return result.Released, err
+	})
+}
+
+// postReceipts sends receipts to /v1/messages:{op}-batch in chunks the
+// server accepts, along with the fields in extra, and collects the IDs that
+// decode pulls out of each response.
+func (w *Worker) postReceipts(ctx context.Context, op string, receipts []string, extra map[string]interface{}, decode func([]byte) ([]int64, error)) ([]int64, error) {
+	var done []int64
+	for start := 0; start < len(receipts); start += maxExtendBatch {
+		end := min(start+maxExtendBatch, len(receipts))
+		payload := map[string]interface{}{"receipts": receipts[start:end]}
+		for k, v := range extra {
+			payload[k] = v
+		}
+		body, err := json.Marshal(payload)
+		if err != nil {
+			return done, err
+		}
+
+		req, err := http.NewRequestWithContext(ctx, "POST", w.baseURL+"/v1/messages:"+op+"-batch", bytes.NewReader(body))
+		if err != nil {
+			return done, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := w.do(req)
+		if err != nil {
+			return done, err
+		}
+		bodyBytes, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return done, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			return done, fmt.Errorf("%s failed: %s - %s", op, resp.Status, string(bodyBytes))
+		}
+
+		ids, err := decode(bodyBytes)
+		if err != nil {
+			return done, err
+		}
+		done = append(done, ids...)
+	}
+	return done, nil
+}
+
+// ackMessage acknowledges a message using the receipt from its receive,
+// retrying under a single ack_id: if an earlier attempt deleted the message
+// but its response was lost, the server recognizes the retry and reports
+// success rather than 404.
+func (w *Worker) ackMessage(ctx context.Context, msg *Message) error {
+	url := fmt.Sprintf("%s/v1/messages/%d:ack", w.baseURL, msg.ID)
+
+	w.mu.Lock()
+	receipt := msg.Receipt // auto-extend may have renewed it
+	w.mu.Unlock()
+	body, err := json.Marshal(map[string]string{"receipt": receipt, "ack_id": newAckID()})
 	if err != nil {
 		return err
 	}
+
+	for attempt := 1; ; attempt++ {
+		retry, err := w.sendAck(ctx, url, body)
+		if err == nil || !retry || attempt == ackAttempts {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(time.Duration(attempt) * 100 * time.Millisecond):
+		}
+	}
+}
+
+// sendAck makes one ack attempt; retry reports whether a failure may be
+// worth sending again.
+func (w *Worker) sendAck(ctx context.Context, url string, body []byte) (retry bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
 	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := w.client.Do(req)
+	resp, err := w.do(req)
 	if err != nil {
-		return err
+		return ctx.Err() == nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		bodyBytes, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("ack failed: %s - %s", resp.Status, string(bodyBytes))
+		return resp.StatusCode >= 500, fmt.Errorf("ack failed: %s - %s", resp.Status, string(bodyBytes))
 	}
 
-	return nil
+	return false, nil
+}
+
+// newAckID names one ack so that its retries can be told apart from
+// another worker's ack of the same message.
+func newAckID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// notifyReceive hands OnReceive copies of a received batch. A panicking
+// callback is logged rather than taking the worker down with it.
+func (w *Worker) notifyReceive(queue string, msgs []*Message) {
+	if w.onReceive == nil {
+		return
+	}
+	batch := make([]*Message, len(msgs))
+	for i, msg := range msgs {
+		c := *msg
+		batch[i] = &c
+	}
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				log.Printf("OnReceive panicked for %s: %v", queue, r)
+			}
+		}()
+		w.onReceive(queue, batch)
+	}()
 }
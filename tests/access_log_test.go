@@ -0,0 +1,87 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aridsondez/AWS-SQS-LITE/internal/api"
+)
+
+func TestAccessLogRecord(t *testing.T) {
+	fmt.Println("\n=== Test: Access Log Record ===")
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	// a malformed body is rejected before the handler touches the store
+	h := api.NewServer(":0", nil, testConfig(), api.WithAccessLogger(logger)).Handler
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/queues/orders:receive",
+		strings.NewReader(`{"max": 1, "secret": "hunter2"`))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("Expected 400 for a malformed body, got %d", rec.Code)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("Expected one record per request, got %d: %q", len(lines), buf.String())
+	}
+	var record map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[0]), &record); err != nil {
+		t.Fatalf("Expected a JSON record, got %q: %v", lines[0], err)
+	}
+
+	want := map[string]interface{}{
+		"msg":    "http request",
+		"method": "POST",
+		"route":  "/v1/queues/{queue}:receive",
+		"queue":  "orders",
+		"status": float64(http.StatusBadRequest),
+		"bytes":  float64(rec.Body.Len()),
+	}
+	for k, v := range want {
+		if record[k] != v {
+			t.Fatalf("Expected %s=%v, got %v in %v", k, v, record[k], record)
+		}
+	}
+	if _, ok := record["duration"].(float64); !ok {
+		t.Fatalf("Expected a numeric duration, got %v", record["duration"])
+	}
+	if _, ok := record["request_id"].(string); !ok {
+		t.Fatalf("Expected a request_id, got %v", record["request_id"])
+	}
+	fmt.Println("✓ One record with method, route, queue, status, bytes and duration")
+
+	if strings.Contains(buf.String(), "hunter2") {
+		t.Fatalf("Request body leaked into the access log: %s", buf.String())
+	}
+	fmt.Println("✓ Request body not logged")
+}
+
+func TestAccessLogWorkerID(t *testing.T) {
+	fmt.Println("\n=== Test: Access Log Worker ID ===")
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	h := api.NewServer(":0", nil, testConfig(), api.WithAccessLogger(logger)).Handler
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/messages/7:ack", nil)
+	req.Header.Set("X-Worker-ID", "billing-7")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	var record map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("Expected a JSON record, got %q: %v", buf.String(), err)
+	}
+	if record["worker_id"] != "billing-7" {
+		t.Fatalf("Expected worker_id=billing-7, got %v", record)
+	}
+	fmt.Println("✓ X-Worker-ID logged as worker_id")
+}
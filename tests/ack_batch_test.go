@@ -0,0 +1,109 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"testing"
+)
+
+func TestAckBatchGroupOrder(t *testing.T) {
+	_, teardown := setupTestServer(t)
+	defer teardown()
+
+	fmt.Println("\n=== Test: Batch Ack In Group Order ===")
+
+	for i := 1; i <= 3; i++ {
+		enqueueMessage(t, "ack-group", map[string]interface{}{
+			"body":     map[string]int{"step": i},
+			"group_id": "order-42",
+		})
+	}
+	messages := receiveMessages(t, "ack-group", 3, 30000)
+	if len(messages) != 3 {
+		t.Fatalf("Expected 3 messages, got %d", len(messages))
+	}
+	for _, m := range messages {
+		if m["group_id"] != "order-42" {
+			t.Fatalf("Expected group_id order-42, got %v", m["group_id"])
+		}
+	}
+	sort.Slice(messages, func(i, j int) bool { return messages[i]["id"].(float64) < messages[j]["id"].(float64) })
+	fmt.Println("✓ Received the whole group")
+
+	// Message 1 failed; acking 2 and 3 would let them overtake it.
+	status, result := ackBatch(t, []string{
+		messages[2]["receipt"].(string),
+		messages[1]["receipt"].(string),
+	})
+	if status != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", status)
+	}
+	if len(result.Acked) != 0 || len(result.Rejected) != 2 {
+		t.Fatalf("Expected both acks rejected, got %+v", result)
+	}
+	fmt.Printf("✓ Out-of-order acks rejected: %+v\n", result.Rejected)
+
+	single, _ := json.Marshal(map[string]interface{}{"receipt": messages[1]["receipt"]})
+	resp, err := http.Post(fmt.Sprintf("http://localhost:9999/v1/messages/%d:ack", int64(messages[1]["id"].(float64))),
+		"application/json", bytes.NewReader(single))
+	if err != nil {
+		t.Fatalf("Ack failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusConflict {
+		t.Fatalf("Expected 409 acking ahead of the group, got %d", resp.StatusCode)
+	}
+	fmt.Println("✓ Single ack ahead of the group returns 409")
+
+	// With message 1 in the same batch, all three go through in order.
+	status, result = ackBatch(t, []string{
+		messages[2]["receipt"].(string),
+		messages[0]["receipt"].(string),
+		messages[1]["receipt"].(string),
+		"not-a-receipt",
+	})
+	if status != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", status)
+	}
+	if len(result.Acked) != 3 {
+		t.Fatalf("Expected 3 acked, got %+v", result)
+	}
+	for i, id := range result.Acked {
+		if id != int64(messages[i]["id"].(float64)) {
+			t.Fatalf("Expected acks in group order, got %v", result.Acked)
+		}
+	}
+	if len(result.Rejected) != 1 || result.Rejected[0].Receipt != "not-a-receipt" {
+		t.Fatalf("Expected only the malformed receipt rejected, got %+v", result.Rejected)
+	}
+	fmt.Printf("✓ Whole group acked in order: %v\n", result.Acked)
+
+	if status, _ := ackBatch(t, nil); status != http.StatusBadRequest {
+		t.Fatalf("Expected 400 for empty receipts, got %d", status)
+	}
+	fmt.Println("✓ Empty request rejected")
+}
+
+type ackBatchResult struct {
+	Acked    []int64 `json:"acked"`
+	Rejected []struct {
+		Receipt string `json:"receipt"`
+		Error   string `json:"error"`
+	} `json:"rejected"`
+}
+
+func ackBatch(t *testing.T, receipts []string) (int, ackBatchResult) {
+	body, _ := json.Marshal(map[string]interface{}{"receipts": receipts})
+	resp, err := http.Post("http://localhost:9999/v1/messages:ack-batch", "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("Ack batch failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var result ackBatchResult
+	json.NewDecoder(resp.Body).Decode(&result)
+	return resp.StatusCode, result
+}
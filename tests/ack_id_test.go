@@ -0,0 +1,37 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestAckIDReplay(t *testing.T) {
+	_, teardown := setupTestServer(t)
+	defer teardown()
+
+	fmt.Println("\n=== Test: Ack ID Replay ===")
+
+	enqueueMessage(t, "ack-id-test", map[string]interface{}{"body": map[string]int{"n": 1}})
+	messages := receiveMessages(t, "ack-id-test", 1, 30000)
+	if len(messages) != 1 {
+		t.Fatalf("Expected 1 message, got %v", messages)
+	}
+	path := fmt.Sprintf("/v1/messages/%d:ack", int64(messages[0]["id"].(float64)))
+	ack := map[string]interface{}{"receipt": messages[0]["receipt"], "ack_id": "ack-1"}
+
+	for i := 0; i < 2; i++ {
+		if status, out := doJSON(t, http.MethodPost, path, ack); status != http.StatusOK || out["ok"] != true {
+			t.Fatalf("Expected ack %d with the same ack_id to succeed, got %d %v", i+1, status, out)
+		}
+	}
+	fmt.Println("✓ Replayed ack with the same ack_id succeeds")
+
+	if status, _ := doJSON(t, http.MethodPost, path, map[string]interface{}{"receipt": messages[0]["receipt"], "ack_id": "ack-2"}); status != http.StatusNotFound {
+		t.Fatalf("Expected 404 for an ack with another ack_id, got %d", status)
+	}
+	if status, _ := doJSON(t, http.MethodPost, path, map[string]interface{}{"receipt": messages[0]["receipt"]}); status != http.StatusNotFound {
+		t.Fatalf("Expected 404 for an ack without an ack_id, got %d", status)
+	}
+	fmt.Println("✓ Other acks of the deleted message still 404")
+}
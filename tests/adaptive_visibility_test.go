@@ -0,0 +1,58 @@
+package tests
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aridsondez/AWS-SQS-LITE/pkg/worker"
+)
+
+func TestAdaptiveVisibility(t *testing.T) {
+	fmt.Println("\n=== Test: Adaptive Visibility ===")
+
+	est := worker.NewVisibilityEstimator(30*time.Second, time.Minute, 1.5)
+
+	for i := 0; i < 9; i++ {
+		est.Observe(2 * time.Second)
+	}
+	if got := est.Visibility(); got != 30*time.Second {
+		t.Fatalf("Expected the fallback before 10 samples, got %s", got)
+	}
+	fmt.Println("✓ Uses the fallback until there is history")
+
+	// 95 fast, 5 slow: the P95 is still the fast duration.
+	for i := 0; i < 86; i++ {
+		est.Observe(2 * time.Second)
+	}
+	for i := 0; i < 5; i++ {
+		est.Observe(8 * time.Second)
+	}
+	if got := est.Visibility(); got != 3*time.Second {
+		t.Fatalf("Expected P95 2s × 1.5 = 3s, got %s", got)
+	}
+	fmt.Println("✓ P95 × safety factor ignores the slowest 5%")
+
+	// Handlers slow down; the window rolls over to the new durations.
+	for i := 0; i < 100; i++ {
+		est.Observe(10 * time.Second)
+	}
+	if got := est.Visibility(); got != 15*time.Second {
+		t.Fatalf("Expected the estimate to follow slower handlers to 15s, got %s", got)
+	}
+	fmt.Println("✓ Tracks a slowdown")
+
+	for i := 0; i < 100; i++ {
+		est.Observe(time.Minute)
+	}
+	if got := est.Visibility(); got != time.Minute {
+		t.Fatalf("Expected the cap of 1m, got %s", got)
+	}
+	for i := 0; i < 100; i++ {
+		est.Observe(10 * time.Millisecond)
+	}
+	if got := est.Visibility(); got != time.Second {
+		t.Fatalf("Expected the 1s floor, got %s", got)
+	}
+	fmt.Println("✓ Clamped to [1s, max]")
+}
@@ -0,0 +1,94 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestArchiveReplay(t *testing.T) {
+	_, teardown := setupTestServer(t)
+	defer teardown()
+
+	fmt.Println("\n=== Test: Archive Replay ===")
+
+	q := "archive-test"
+	target := "archive-test-replay"
+	putQueueConfig(t, q, map[string]interface{}{"archive": true})
+
+	ids := make([]int64, 3)
+	for i := range ids {
+		ids[i] = enqueueMessage(t, q, map[string]interface{}{"body": map[string]int{"n": i}})
+	}
+	msgs := receiveMessages(t, q, 10, 30000)
+	if len(msgs) != 3 {
+		t.Fatalf("Expected 3 messages, got %d", len(msgs))
+	}
+	for _, m := range msgs {
+		ackMessage(t, m)
+	}
+	// acks on a queue without archive keep nothing
+	enqueueMessage(t, "archive-test-off", map[string]interface{}{"body": map[string]int{"n": 9}})
+	for _, m := range receiveMessages(t, "archive-test-off", 10, 30000) {
+		ackMessage(t, m)
+	}
+
+	status, result := doJSON(t, http.MethodPost, "/v1/archive/replay", map[string]interface{}{"queue": q, "dry_run": true})
+	if status != http.StatusOK || len(result["replayed"].([]interface{})) != 3 {
+		t.Fatalf("Expected a dry run to match all 3 acked messages, got %d %v", status, result)
+	}
+	status, result = doJSON(t, http.MethodPost, "/v1/archive/replay", map[string]interface{}{"queue": "archive-test-off"})
+	if status != http.StatusOK || len(result["replayed"].([]interface{})) != 0 {
+		t.Fatalf("Expected nothing archived without archive set, got %d %v", status, result)
+	}
+	fmt.Println("✓ Only acks on an archiving queue are archived")
+
+	subset := []int64{ids[0], ids[2]}
+	status, result = doJSON(t, http.MethodPost, "/v1/archive/replay",
+		map[string]interface{}{"ids": subset, "target": target, "dry_run": true})
+	if status != http.StatusOK || result["dry_run"] != true || len(result["replayed"].([]interface{})) != 2 {
+		t.Fatalf("Expected a dry run of 2 messages, got %d %v", status, result)
+	}
+	if got := receiveMessages(t, target, 10, 30000); len(got) != 0 {
+		t.Fatalf("Expected a dry run to enqueue nothing, got %d", len(got))
+	}
+	fmt.Println("✓ Dry run lists the subset without enqueuing it")
+
+	status, result = doJSON(t, http.MethodPost, "/v1/archive/replay",
+		map[string]interface{}{"ids": subset, "target": target})
+	if status != http.StatusOK {
+		t.Fatalf("Expected replay to succeed, got %d %v", status, result)
+	}
+	replayed := result["replayed"].([]interface{})
+	if len(replayed) != 2 {
+		t.Fatalf("Expected 2 replayed messages, got %v", result)
+	}
+	for i, raw := range replayed {
+		rm := raw.(map[string]interface{})
+		if int64(rm["archived_id"].(float64)) != subset[i] || rm["queue"] != target || rm["id"] == nil {
+			t.Fatalf("Expected archived message %d replayed to %s, got %v", subset[i], target, rm)
+		}
+	}
+
+	got := receiveMessages(t, target, 10, 30000)
+	if len(got) != 2 {
+		t.Fatalf("Expected 2 messages on %s, got %d", target, len(got))
+	}
+	for i, m := range got {
+		body := m["body"].(map[string]interface{})
+		if want := float64(2 * i); body["n"] != want || m["delivery_count"].(float64) != 1 {
+			t.Fatalf("Expected a fresh copy of message %v, got %v", want, m)
+		}
+		ackMessage(t, m)
+	}
+	fmt.Println("✓ Replay enqueues fresh copies of the subset into a new queue")
+
+	status, result = doJSON(t, http.MethodPost, "/v1/archive/replay", map[string]interface{}{"queue": q, "max": 1})
+	if status != http.StatusOK || len(result["replayed"].([]interface{})) != 1 || result["more"] != true {
+		t.Fatalf("Expected max to cap the replay at 1 with more left, got %d %v", status, result)
+	}
+	if status, _ := doJSON(t, http.MethodPost, "/v1/archive/replay", map[string]interface{}{"dry_run": true}); status != http.StatusBadRequest {
+		t.Fatalf("Expected 400 for a replay without queue or ids, got %d", status)
+	}
+	fmt.Println("✓ Replays are capped and need a filter")
+}
@@ -0,0 +1,138 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/aridsondez/AWS-SQS-LITE/internal/config"
+)
+
+func TestQueueAttributes(t *testing.T) {
+	cfg := testConfig()
+	cfg.MaxMessageBytes = 1024
+
+	_, teardown := setupTestServerWithConfig(t, cfg)
+	defer teardown()
+
+	fmt.Println("\n=== Test: Queue Attributes ===")
+
+	putQueueConfig(t, "attrs-test", map[string]interface{}{
+		"partitions":    1,
+		"visibility_ms": 45000,
+		"max_retries":   2,
+		"dlq":           "attrs-test-dlq",
+	})
+
+	for i := 0; i < 3; i++ {
+		enqueueMessage(t, "attrs-test", map[string]interface{}{
+			"body": map[string]int{"n": i},
+		})
+	}
+	enqueueMessage(t, "attrs-test", map[string]interface{}{
+		"body":     map[string]string{"task": "later"},
+		"delay_ms": 60000,
+	})
+	messages := receiveMessages(t, "attrs-test", 1, 0)
+	if len(messages) != 1 {
+		t.Fatalf("Expected 1 message, got %d", len(messages))
+	}
+	if got := messages[0]["max_retries"]; got != float64(2) {
+		t.Fatalf("Expected queue default max_retries 2, got %v", got)
+	}
+	if dlq, _ := messages[0]["dlq"].(string); dlq != "attrs-test-dlq" {
+		t.Fatalf("Expected queue default dlq, got %v", messages[0]["dlq"])
+	}
+	fmt.Println("✓ Enqueue applies queue defaults")
+
+	resp, err := http.Get("http://localhost:9999/v1/queues/attrs-test/attributes")
+	if err != nil {
+		t.Fatalf("Get attributes failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", resp.StatusCode)
+	}
+	var attrs map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&attrs)
+
+	want := map[string]interface{}{
+		"visibility_timeout_ms": float64(45000),
+		"max_retries":           float64(2),
+		"dlq":                   "attrs-test-dlq",
+		"max_message_bytes":     float64(1024),
+		"approximate_depth":     float64(2),
+		"in_flight":             float64(1),
+		"delayed":               float64(1),
+	}
+	for k, v := range want {
+		if attrs[k] != v {
+			t.Fatalf("Expected %s=%v, got %v", k, v, attrs[k])
+		}
+	}
+	fmt.Println("✓ Attributes report defaults and counts")
+
+	big := map[string]interface{}{"body": strings.Repeat("x", 2048)}
+	body, _ := json.Marshal(big)
+	resp, err = http.Post("http://localhost:9999/v1/queues/attrs-test/messages",
+		"application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Fatalf("Expected 413 for oversize body, got %d", resp.StatusCode)
+	}
+	fmt.Println("✓ Oversize body rejected with 413")
+}
+
+func TestDefaultMaxRetriesConfig(t *testing.T) {
+	cfg := testConfig()
+	cfg.DefaultMaxRetries = 7
+
+	_, teardown := setupTestServerWithConfig(t, cfg)
+	defer teardown()
+
+	fmt.Println("\n=== Test: DEFAULT_MAX_RETRIES ===")
+
+	enqueueMessage(t, "default-retries", map[string]interface{}{"body": map[string]int{"n": 0}})
+	enqueueMessage(t, "default-retries", map[string]interface{}{"body": map[string]int{"n": 1}, "max_retries": 2})
+	messages := receiveMessages(t, "default-retries", 2, 30000)
+	if len(messages) != 2 {
+		t.Fatalf("Expected 2 messages, got %d", len(messages))
+	}
+	if got := messages[0]["max_retries"]; got != float64(7) {
+		t.Fatalf("Expected the configured default max_retries 7, got %v", got)
+	}
+	if got := messages[1]["max_retries"]; got != float64(2) {
+		t.Fatalf("Expected an explicit max_retries to win, got %v", got)
+	}
+	for _, m := range messages {
+		ackMessage(t, m)
+	}
+	fmt.Println("✓ Enqueue applies the configured default")
+
+	status, attrs := doJSON(t, http.MethodGet, "/v1/queues/default-retries/attributes", nil)
+	if status != http.StatusOK || attrs["max_retries"] != float64(7) {
+		t.Fatalf("Expected attributes to report max_retries 7, got %d %v", status, attrs)
+	}
+	fmt.Println("✓ Attributes report the configured default")
+}
+
+func TestDefaultMaxRetriesMinimum(t *testing.T) {
+	fmt.Println("\n=== Test: DEFAULT_MAX_RETRIES Minimum ===")
+
+	t.Setenv("DATABASE_URL", "postgres://localhost/unused")
+	t.Setenv("DEFAULT_MAX_RETRIES", "0")
+	if _, err := config.LoadConfig(); err == nil {
+		t.Fatal("Expected DEFAULT_MAX_RETRIES=0 rejected")
+	}
+	t.Setenv("DEFAULT_MAX_RETRIES", "1")
+	if cfg, err := config.LoadConfig(); err != nil || cfg.DefaultMaxRetries != 1 {
+		t.Fatalf("Expected DEFAULT_MAX_RETRIES=1 accepted, got %v, %v", cfg, err)
+	}
+	fmt.Println("✓ A default of 0 retries is refused")
+}
@@ -0,0 +1,141 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aridsondez/AWS-SQS-LITE/internal/queue"
+	"github.com/aridsondez/AWS-SQS-LITE/internal/testutil"
+)
+
+func TestBackoffDelays(t *testing.T) {
+	fmt.Println("\n=== Test: Retry Backoff Strategies ===")
+
+	s := time.Second
+	cases := []struct {
+		strategy queue.RetryStrategy
+		want     [5]time.Duration // delivery counts 1-5
+	}{
+		{queue.RetryFixed, [5]time.Duration{2 * s, 2 * s, 2 * s, 2 * s, 2 * s}},
+		{queue.RetryLinear, [5]time.Duration{2 * s, 4 * s, 6 * s, 8 * s, 10 * s}},
+		{queue.RetryExponential, [5]time.Duration{2 * s, 4 * s, 8 * s, 16 * s, 20 * s}}, // capped at Max
+		{"", [5]time.Duration{2 * s, 4 * s, 8 * s, 16 * s, 20 * s}},                     // default
+	}
+	for _, tc := range cases {
+		b := queue.Backoff{Strategy: tc.strategy, Base: 2 * s, Max: 20 * s}
+		for n := 1; n <= 5; n++ {
+			if got := b.Delay(n); got != tc.want[n-1] {
+				t.Fatalf("%q at delivery %d: expected %v, got %v", tc.strategy, n, tc.want[n-1], got)
+			}
+		}
+		fmt.Printf("✓ %q: %v\n", tc.strategy, tc.want)
+	}
+
+	if d := (queue.Backoff{Strategy: queue.RetryExponential}).Delay(3); d != 0 {
+		t.Fatalf("Expected no delay without a base, got %v", d)
+	}
+	if d := (queue.Backoff{Base: s}).Delay(1000); d <= 0 {
+		t.Fatalf("Expected an uncapped exponential delay not to overflow, got %v", d)
+	}
+	fmt.Println("✓ Zero base disables backoff; uncapped delays don't overflow")
+
+	for _, name := range []string{"fixed", "linear", "exponential"} {
+		if !queue.RetryStrategy(name).Valid() {
+			t.Fatalf("Expected %q to be valid", name)
+		}
+	}
+	if queue.RetryStrategy("random").Valid() {
+		t.Fatal("Expected an unknown strategy to be invalid")
+	}
+	fmt.Println("✓ Strategy names validated")
+}
+
+func TestSweeperAppliesBackoff(t *testing.T) {
+	ctx := context.Background()
+	s, teardown := testutil.SetupStore(t)
+	defer teardown()
+
+	fmt.Println("\n=== Test: Sweeper Requeue Backoff ===")
+
+	if _, err := s.Enqueue(ctx, queue.Message{Queue: "backoff-test", Body: []byte(`{}`), MaxRetries: 5}, 0); err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+	if _, err := s.Pool.Exec(ctx, `UPDATE messages SET delivery_count = 2 WHERE queue = 'backoff-test'`); err != nil {
+		t.Fatalf("Set delivery_count: %v", err)
+	}
+	claimed, err := s.Claim(ctx, queue.ClaimOptions{Queue: "backoff-test", Limit: 1, Visibility: time.Millisecond})
+	if err != nil || len(claimed) != 1 {
+		t.Fatalf("Expected to claim 1, got %d (%v)", len(claimed), err)
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	opts := queue.SweepOptions{Backoff: queue.Backoff{Strategy: queue.RetryLinear, Base: 10 * time.Second}}
+	if _, err := s.Sweeper(ctx, opts); err != nil {
+		t.Fatalf("Sweep failed: %v", err)
+	}
+	var wait time.Duration
+	if err := s.Pool.QueryRow(ctx, `SELECT not_before - now() FROM messages WHERE queue = 'backoff-test'`).Scan(&wait); err != nil {
+		t.Fatalf("Query not_before: %v", err)
+	}
+	// third delivery, linear: 3 × 10s
+	if wait < 25*time.Second || wait > 30*time.Second {
+		t.Fatalf("Expected ~30s until redelivery, got %v", wait)
+	}
+	if out, _ := s.Claim(ctx, queue.ClaimOptions{Queue: "backoff-test", Limit: 1, Visibility: time.Minute}); len(out) != 0 {
+		t.Fatal("Expected the requeued message to wait out its backoff")
+	}
+	fmt.Printf("✓ Requeued with %v backoff\n", wait.Round(time.Second))
+}
+
+func TestRequeueInPlaceKeepsOrder(t *testing.T) {
+	ctx := context.Background()
+	s, teardown := testutil.SetupStore(t)
+	defer teardown()
+
+	fmt.Println("\n=== Test: Requeue In Place Keeps Order ===")
+
+	opts := queue.SweepOptions{Backoff: queue.Backoff{Base: 10 * time.Second}}
+	for _, inPlace := range []bool{true, false} {
+		name := fmt.Sprintf("in-place-%t", inPlace)
+		if err := s.PutQueueConfig(ctx, queue.QueueConfig{Queue: name, Partitions: 1, RequeueInPlace: inPlace}); err != nil {
+			t.Fatalf("Put config: %v", err)
+		}
+		var ids []int64
+		for i := 0; i < 3; i++ {
+			id, err := s.Enqueue(ctx, queue.Message{Queue: name, Body: []byte(`{}`), MaxRetries: 5}, 0)
+			if err != nil {
+				t.Fatalf("Enqueue failed: %v", err)
+			}
+			ids = append(ids, id)
+		}
+
+		// The first message's consumer dies: its lease runs out unacked.
+		claimed, err := s.Claim(ctx, queue.ClaimOptions{Queue: name, Limit: 1, Visibility: time.Millisecond})
+		if err != nil || len(claimed) != 1 || claimed[0].ID != ids[0] {
+			t.Fatalf("Expected to claim message %d, got %v (%v)", ids[0], claimed, err)
+		}
+		time.Sleep(50 * time.Millisecond)
+		if _, err := s.Sweeper(ctx, opts); err != nil {
+			t.Fatalf("Sweep failed: %v", err)
+		}
+
+		claimed, err = s.Claim(ctx, queue.ClaimOptions{Queue: name, Limit: 3, Visibility: time.Minute})
+		if err != nil {
+			t.Fatalf("Claim failed: %v", err)
+		}
+		var got []int64
+		for _, m := range claimed {
+			got = append(got, m.ID)
+		}
+		want := ids
+		if !inPlace {
+			want = ids[1:] // backing off behind the others
+		}
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Fatalf("requeue_in_place=%t: expected %v, got %v", inPlace, want, got)
+		}
+		fmt.Printf("✓ requeue_in_place=%t: next claim got %v\n", inPlace, got)
+	}
+}
@@ -0,0 +1,147 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/aridsondez/AWS-SQS-LITE/internal/queue"
+	"github.com/aridsondez/AWS-SQS-LITE/internal/testutil"
+)
+
+func enqueueBatch(t *testing.T, queue string, payload map[string]interface{}) (int, []map[string]interface{}) {
+	body, _ := json.Marshal(payload)
+	resp, err := http.Post(
+		fmt.Sprintf("http://localhost:9999/v1/queues/%s/messages:batch", queue),
+		"application/json",
+		bytes.NewReader(body),
+	)
+	if err != nil {
+		t.Fatalf("Batch enqueue failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Results []map[string]interface{} `json:"results"`
+	}
+	json.NewDecoder(resp.Body).Decode(&result)
+	return resp.StatusCode, result.Results
+}
+
+// batchWithInvalidEntry has a valid entry, one missing its body, and another valid one.
+func batchWithInvalidEntry(atomic bool) map[string]interface{} {
+	return map[string]interface{}{
+		"atomic": atomic,
+		"entries": []interface{}{
+			map[string]interface{}{"body": map[string]int{"n": 0}},
+			map[string]interface{}{"max_retries": 3},
+			map[string]interface{}{"body": map[string]int{"n": 2}},
+		},
+	}
+}
+
+func TestBatchEnqueueBestEffort(t *testing.T) {
+	_, teardown := setupTestServer(t)
+	defer teardown()
+
+	fmt.Println("\n=== Test: Batch Enqueue (best-effort) ===")
+
+	status, results := enqueueBatch(t, "batch-best-effort", batchWithInvalidEntry(false))
+	if status != http.StatusMultiStatus {
+		t.Fatalf("Expected 207, got %d", status)
+	}
+	if len(results) != 3 {
+		t.Fatalf("Expected 3 results, got %d", len(results))
+	}
+	for i, r := range results {
+		if int(r["index"].(float64)) != i {
+			t.Fatalf("Result %d has index %v", i, r["index"])
+		}
+	}
+	if results[0]["id"] == nil || results[2]["id"] == nil {
+		t.Fatalf("Expected valid entries to get ids, got %v", results)
+	}
+	if results[1]["error"] == nil || results[1]["id"] != nil {
+		t.Fatalf("Expected entry 1 to fail, got %v", results[1])
+	}
+	fmt.Println("✓ Per-entry results report the invalid entry")
+
+	messages := receiveMessages(t, "batch-best-effort", 10, 30000)
+	if len(messages) != 2 {
+		t.Fatalf("Expected the 2 valid entries enqueued, got %d", len(messages))
+	}
+	fmt.Println("✓ Valid entries were enqueued")
+}
+
+func TestBatchEnqueueAtomic(t *testing.T) {
+	_, teardown := setupTestServer(t)
+	defer teardown()
+
+	fmt.Println("\n=== Test: Batch Enqueue (atomic) ===")
+
+	status, results := enqueueBatch(t, "batch-atomic", batchWithInvalidEntry(true))
+	if status != http.StatusBadRequest {
+		t.Fatalf("Expected 400, got %d", status)
+	}
+	for _, r := range results {
+		if r["id"] != nil || r["error"] == nil {
+			t.Fatalf("Expected every entry to be rejected, got %v", r)
+		}
+	}
+	fmt.Println("✓ One invalid entry rejects the batch")
+
+	if messages := receiveMessages(t, "batch-atomic", 10, 30000); len(messages) != 0 {
+		t.Fatalf("Expected nothing enqueued, got %d", len(messages))
+	}
+	fmt.Println("✓ Nothing was enqueued")
+
+	payload := batchWithInvalidEntry(true)
+	payload["entries"].([]interface{})[1] = map[string]interface{}{"body": "fixed"}
+	status, results = enqueueBatch(t, "batch-atomic", payload)
+	if status != http.StatusCreated {
+		t.Fatalf("Expected 201 for a valid atomic batch, got %d", status)
+	}
+	if messages := receiveMessages(t, "batch-atomic", 10, 30000); len(messages) != len(results) {
+		t.Fatalf("Expected %d messages, got %d", len(results), len(messages))
+	}
+	fmt.Println("✓ Valid atomic batch enqueues every entry")
+}
+
+func TestEnqueueBatchNamesRefusedEntry(t *testing.T) {
+	ctx := context.Background()
+	s, teardown := testutil.SetupStore(t)
+	defer teardown()
+
+	fmt.Println("\n=== Test: Batch Enqueue Names The Refused Entry ===")
+
+	q := "batch-refused"
+	group := "g"
+	if _, err := s.SealGroup(ctx, q, group); err != nil {
+		t.Fatalf("Seal failed: %v", err)
+	}
+	for _, tc := range []struct {
+		bad  queue.Message
+		want error
+	}{
+		{queue.Message{Queue: q, Body: []byte(`{}`), GroupID: &group}, queue.ErrGroupSealed},
+		{queue.Message{Queue: q, Body: []byte(`{"s":"\ud800"}`)}, queue.ErrInvalidBody},
+	} {
+		entries := []queue.EnqueueEntry{
+			{Message: queue.Message{Queue: q, Body: []byte(`{}`)}},
+			{Message: tc.bad},
+		}
+		_, err := s.EnqueueBatch(ctx, entries)
+		var refused *queue.EntryError
+		if !errors.As(err, &refused) || refused.Index != 1 || !errors.Is(err, tc.want) {
+			t.Fatalf("Expected entry 1 refused with %v, got %v", tc.want, err)
+		}
+	}
+	if n, err := s.Count(ctx, q, queue.CountFilter{}); err != nil || n != 0 {
+		t.Fatalf("Expected the refused batches rolled back, got %d (%v)", n, err)
+	}
+	fmt.Println("✓ A refused entry is named and its batch rolled back")
+}
@@ -0,0 +1,193 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aridsondez/AWS-SQS-LITE/internal/queue"
+	"github.com/aridsondez/AWS-SQS-LITE/internal/testutil"
+)
+
+// Claim-path benchmarks. They run against $TEST_DATABASE_URL and skip
+// without it. pgxpool defaults to max(4, NumCPU) connections, so for the
+// high-concurrency cases add pool_max_conns to the URL or the pool, not
+// SKIP LOCKED, is what gets measured:
+//
+//	TEST_DATABASE_URL='postgres://...?pool_max_conns=64' \
+//	  go test ./tests -run '^$' -bench 'Enqueue|ClaimAck|SkipLocked' -benchmem
+
+// claimers are the concurrent goroutine counts the parallel benchmarks try.
+var claimers = []int{1, 8, 64}
+
+func BenchmarkEnqueue(b *testing.B) {
+	for _, n := range claimers {
+		b.Run(fmt.Sprintf("producers=%d", n), func(b *testing.B) {
+			ctx := context.Background()
+			s, teardown := testutil.SetupStore(b)
+			defer teardown()
+
+			msg := queue.Message{Queue: "bench-enqueue", Body: []byte(`{"n":1}`), MaxRetries: 5}
+			b.ReportAllocs()
+			b.ResetTimer()
+			start := time.Now()
+			runConcurrent(b, n, func() error {
+				_, err := s.Enqueue(ctx, msg, 0)
+				return err
+			})
+			b.ReportMetric(float64(b.N)/time.Since(start).Seconds(), "msgs/sec")
+		})
+	}
+}
+
+// BenchmarkClaimAck measures a full receive cycle: claim a batch, ack each
+// message. One op is one claim call.
+func BenchmarkClaimAck(b *testing.B) {
+	for _, batch := range []int{1, 10, 32} {
+		for _, n := range claimers {
+			b.Run(fmt.Sprintf("batch=%d/claimers=%d", batch, n), func(b *testing.B) {
+				ctx := context.Background()
+				s, teardown := testutil.SetupStore(b)
+				defer teardown()
+
+				qname := "bench-claim-ack"
+				seedMessages(b, s, qname, b.N*batch+1000)
+
+				var claimed atomic.Int64
+				b.ReportAllocs()
+				b.ResetTimer()
+				start := time.Now()
+				runConcurrent(b, n, func() error {
+					msgs, err := s.Claim(ctx, queue.ClaimOptions{Queue: qname, Limit: batch, Visibility: time.Minute})
+					if err != nil {
+						return err
+					}
+					for _, m := range msgs {
+						if _, err := s.Ack(ctx, m.Receipt()); err != nil {
+							return err
+						}
+					}
+					claimed.Add(int64(len(msgs)))
+					return nil
+				})
+				b.ReportMetric(float64(claimed.Load())/time.Since(start).Seconds(), "msgs/sec")
+			})
+		}
+	}
+}
+
+// BenchmarkSkipLocked piles many claimers onto the head of one queue, where
+// every claim races for the same few rows, under both claim orders. Besides
+// throughput it reports how often a claim came back short of its limit
+// although the queue still had plenty: rows skipped because another claimer
+// held them.
+func BenchmarkSkipLocked(b *testing.B) {
+	const batch = 10
+	for _, order := range []queue.ClaimOrder{queue.ClaimOrderID, queue.ClaimOrderVisibleAt} {
+		for _, n := range []int{16, 64, 256} {
+			b.Run(fmt.Sprintf("order=%s/claimers=%d", order, n), func(b *testing.B) {
+				ctx := context.Background()
+				s, teardown := testutil.SetupStore(b)
+				defer teardown()
+
+				qname := "bench-skip-locked"
+				if err := s.PutQueueConfig(ctx, queue.QueueConfig{Queue: qname, Partitions: 1, ClaimOrder: order}); err != nil {
+					b.Fatalf("put config: %v", err)
+				}
+				seedMessages(b, s, qname, b.N*batch+1000)
+
+				var claimed, short atomic.Int64
+				b.ReportAllocs()
+				b.ResetTimer()
+				start := time.Now()
+				runConcurrent(b, n, func() error {
+					msgs, err := s.Claim(ctx, queue.ClaimOptions{Queue: qname, Limit: batch, Visibility: time.Minute})
+					if err != nil {
+						return err
+					}
+					if len(msgs) < batch {
+						short.Add(1)
+					}
+					claimed.Add(int64(len(msgs)))
+					return nil
+				})
+				b.ReportMetric(float64(claimed.Load())/time.Since(start).Seconds(), "msgs/sec")
+				b.ReportMetric(float64(short.Load())/float64(b.N), "short/op")
+			})
+		}
+	}
+}
+
+// BenchmarkSweep measures sweeping a backlog of expired leases, half to be
+// requeued and half dead-lettered, at several batch sizes. One op is one
+// sweep of 10000 leases; seeding them is not timed.
+func BenchmarkSweep(b *testing.B) {
+	const backlog = 10000
+	for _, batch := range []int{100, 1000, backlog} {
+		b.Run(fmt.Sprintf("batch=%d", batch), func(b *testing.B) {
+			ctx := context.Background()
+			s, teardown := testutil.SetupStore(b)
+			defer teardown()
+
+			opts := queue.SweepOptions{BatchSize: batch}
+			b.ReportAllocs()
+			var elapsed time.Duration
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				_, err := s.Pool.Exec(ctx, `
+INSERT INTO messages (queue, body, lease_until, delivery_count, max_retries, dlq)
+SELECT 'bench-sweep', '{}'::jsonb, now() - interval '1 second', n % 2 * 4 + 1, 5, 'bench-sweep-dlq'
+FROM generate_series(1, $1) n`, backlog)
+				if err != nil {
+					b.Fatalf("seed: %v", err)
+				}
+				b.StartTimer()
+				start := time.Now()
+				n, err := s.Sweeper(ctx, opts)
+				elapsed += time.Since(start)
+				if err != nil {
+					b.Fatal(err)
+				}
+				if n != backlog {
+					b.Fatalf("swept %d of %d", n, backlog)
+				}
+			}
+			b.ReportMetric(float64(b.N*backlog)/elapsed.Seconds(), "msgs/sec")
+		})
+	}
+}
+
+// seedMessages inserts n immediately available messages in one statement.
+func seedMessages(b *testing.B, s *testutil.Store, qname string, n int) {
+	b.Helper()
+	_, err := s.Pool.Exec(context.Background(), `
+INSERT INTO messages (queue, body)
+SELECT $1, '{}'::jsonb FROM generate_series(1, $2)`, qname, n)
+	if err != nil {
+		b.Fatalf("seed: %v", err)
+	}
+}
+
+// runConcurrent runs op b.N times in total, spread over exactly n goroutines
+// (RunParallel only scales with GOMAXPROCS). A goroutine that hits an error
+// reports it and stops.
+func runConcurrent(b *testing.B, n int, op func() error) {
+	var next atomic.Int64
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for next.Add(1) <= int64(b.N) {
+				if err := op(); err != nil {
+					b.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,71 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+// enqueueRaw posts body verbatim as the message's "body" field.
+func enqueueRaw(t *testing.T, queue, body string) (int, string) {
+	payload := []byte(`{"body":` + body + `}`)
+	if body == "" {
+		payload = []byte(`{}`)
+	}
+	resp, err := http.Post(
+		fmt.Sprintf("http://localhost:9999/v1/queues/%s/messages", queue),
+		"application/json",
+		bytes.NewReader(payload),
+	)
+	if err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Error string `json:"error"`
+	}
+	json.NewDecoder(resp.Body).Decode(&result)
+	return resp.StatusCode, result.Error
+}
+
+func TestEnqueueBodyContract(t *testing.T) {
+	_, teardown := setupTestServer(t)
+	defer teardown()
+
+	fmt.Println("\n=== Test: Enqueue Body Contract ===")
+
+	putQueueConfig(t, "body-objects", map[string]interface{}{
+		"partitions":          1,
+		"require_object_body": true,
+	})
+
+	cases := []struct {
+		name       string
+		body       string // "" = field omitted
+		anyJSON    int    // status on a default queue
+		objectOnly int    // status on a require_object_body queue
+	}{
+		{"missing", "", http.StatusBadRequest, http.StatusBadRequest},
+		{"null", `null`, http.StatusBadRequest, http.StatusBadRequest},
+		{"empty object", `{}`, http.StatusCreated, http.StatusCreated},
+		{"empty array", `[]`, http.StatusCreated, http.StatusBadRequest},
+		{"string", `"hello"`, http.StatusCreated, http.StatusBadRequest},
+		{"number", `42`, http.StatusCreated, http.StatusBadRequest},
+	}
+	for _, tc := range cases {
+		if code, msg := enqueueRaw(t, "body-any", tc.body); code != tc.anyJSON {
+			t.Fatalf("%s on default queue: expected %d, got %d (%s)", tc.name, tc.anyJSON, code, msg)
+		}
+		code, msg := enqueueRaw(t, "body-objects", tc.body)
+		if code != tc.objectOnly {
+			t.Fatalf("%s on object-only queue: expected %d, got %d (%s)", tc.name, tc.objectOnly, code, msg)
+		}
+		if code == http.StatusBadRequest && msg == "" {
+			t.Fatalf("%s: expected an error message with the 400", tc.name)
+		}
+		fmt.Printf("✓ %s: %d / %d\n", tc.name, tc.anyJSON, tc.objectOnly)
+	}
+}
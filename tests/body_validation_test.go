@@ -0,0 +1,91 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/aridsondez/AWS-SQS-LITE/internal/queue"
+	"github.com/aridsondez/AWS-SQS-LITE/internal/testutil"
+)
+
+func TestEnqueueInvalidBodyIs400(t *testing.T) {
+	_, teardown := setupTestServer(t)
+	defer teardown()
+
+	fmt.Println("\n=== Test: Enqueue Invalid Body Is 400 ===")
+
+	for name, payload := range map[string]string{
+		"malformed JSON": `{"body": {"a": }`,
+		"invalid UTF-8":  "{\"body\": \"caf\xe9\"}",
+		"NUL escape":     `{"body": {"name": "a\u0000b"}}`,
+		"lone surrogate": `{"body": {"name": "a\ud800b"}}`,
+	} {
+		resp, err := http.Post("http://localhost:9999/v1/queues/body-validation:enqueue", "application/json", bytes.NewBufferString(payload))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400, got %d", name, resp.StatusCode)
+		}
+	}
+	fmt.Println("✓ Malformed, non-UTF-8, NUL-escaped and lone-surrogate bodies rejected with 400")
+
+	// An escaped backslash before u0000 is just text.
+	id := enqueueMessage(t, "body-validation", map[string]interface{}{"body": map[string]string{"path": `C:\u0000`}})
+	messages := receiveMessages(t, "body-validation", 1, 30000)
+	if len(messages) != 1 || int64(messages[0]["id"].(float64)) != id {
+		t.Fatalf("Expected message %d, got %v", id, messages)
+	}
+	ackMessage(t, messages[0])
+	fmt.Println("✓ Literal \\u0000 text still accepted")
+}
+
+func TestStoreRejectsInvalidBody(t *testing.T) {
+	db, teardown := testutil.SetupStore(t)
+	defer teardown()
+
+	fmt.Println("\n=== Test: Store Rejects Invalid Body ===")
+
+	_, err := db.Enqueue(context.Background(), queue.Message{Queue: "body-validation-store", Body: []byte("not json")}, 0)
+	if !errors.Is(err, queue.ErrInvalidBody) {
+		t.Fatalf("Expected ErrInvalidBody, got %v", err)
+	}
+	fmt.Println("✓ Store refuses the body before it reaches the jsonb column")
+}
+
+func TestValidateBody(t *testing.T) {
+	fmt.Println("\n=== Test: Validate Body ===")
+
+	for _, tc := range []struct {
+		body string
+		ok   bool
+	}{
+		{`{"a":1}`, true},
+		{`[1,"two",null]`, true},
+		{`"caf\u00e9"`, true},
+		{`"a\\u0000"`, true},
+		{`"a\\\u0000"`, false},
+		{`{"a":"\u0000"}`, false},
+		{`"\ud83d\ude00"`, true},
+		{`"\uD83D\uDE00"`, true},
+		{`"\\ud800"`, true},
+		{`"\ud800"`, false},
+		{`"\ud800x"`, false},
+		{`"\ud800\u0041"`, false},
+		{`"\udc00"`, false},
+		{"\"\xff\"", false},
+		{`{"a":`, false},
+		{``, false},
+	} {
+		err := queue.ValidateBody([]byte(tc.body))
+		if (err == nil) != tc.ok || (err != nil && !errors.Is(err, queue.ErrInvalidBody)) {
+			t.Fatalf("ValidateBody(%q): expected ok=%v, got %v", tc.body, tc.ok, err)
+		}
+	}
+	fmt.Println("✓ JSON in UTF-8 without \\u0000 or unpaired surrogates accepted, everything else refused")
+}
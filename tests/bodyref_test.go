@@ -0,0 +1,184 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aridsondez/AWS-SQS-LITE/internal/api"
+	"github.com/aridsondez/AWS-SQS-LITE/internal/config"
+)
+
+// fakeResolver serves claim-check bodies from memory; unknown refs fail.
+type fakeResolver struct {
+	mu     sync.Mutex
+	bodies map[string]string
+}
+
+func (f *fakeResolver) Resolve(_ context.Context, _ string, ref string) ([]byte, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	body, ok := f.bodies[ref]
+	if !ok {
+		return nil, errors.New("object not found")
+	}
+	return []byte(body), nil
+}
+
+func (f *fakeResolver) put(ref, body string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.bodies[ref] = body
+}
+
+func TestClaimCheckBodyResolvedAtReceive(t *testing.T) {
+	resolver := &fakeResolver{bodies: map[string]string{"blob://reports/1": `{"rows":1200}`}}
+	_, teardown := setupTestServerWithConfig(t, testConfig(), api.WithBodyResolver(resolver))
+	defer teardown()
+
+	fmt.Println("\n=== Test: Claim-Check Body Resolved At Receive ===")
+
+	status, out := doJSON(t, http.MethodPost, "/v1/queues/claim-check:enqueue", map[string]interface{}{
+		"body":     map[string]string{"inline": "too"},
+		"body_ref": "blob://reports/1",
+	})
+	if status != http.StatusBadRequest {
+		t.Fatalf("Expected 400 for both body and body_ref, got %d %v", status, out)
+	}
+
+	id := enqueueMessage(t, "claim-check", map[string]interface{}{"body_ref": "blob://reports/1"})
+	messages := receiveMessages(t, "claim-check", 1, 30000)
+	if len(messages) != 1 || int64(messages[0]["id"].(float64)) != id {
+		t.Fatalf("Expected message %d, got %v", id, messages)
+	}
+	body, _ := messages[0]["body"].(map[string]interface{})
+	if body["rows"] != float64(1200) || messages[0]["body_ref"] != "blob://reports/1" {
+		t.Fatalf("Expected the body inlined from its ref, got %v", messages[0])
+	}
+	ackMessage(t, messages[0])
+	fmt.Println("✓ Receive inlines the referenced body and keeps body_ref")
+}
+
+func TestClaimCheckFetchFailureRetries(t *testing.T) {
+	resolver := &fakeResolver{bodies: map[string]string{}}
+	_, teardown := setupTestServerWithConfig(t, testConfig(), api.WithBodyResolver(resolver))
+	defer teardown()
+
+	fmt.Println("\n=== Test: Claim-Check Fetch Failure Retries ===")
+
+	id := enqueueMessage(t, "claim-check-retry", map[string]interface{}{
+		"body_ref":    "blob://reports/2",
+		"max_retries": 3,
+	})
+
+	// Not uploaded yet: the claim is nacked instead of delivered.
+	if messages := receiveMessages(t, "claim-check-retry", 1, 30000); len(messages) != 0 {
+		t.Fatalf("Expected nothing delivered while the fetch fails, got %v", messages)
+	}
+	fmt.Println("✓ Unresolvable message withheld from the receive")
+
+	resolver.put("blob://reports/2", `{"rows":7}`)
+	messages := receiveEventually(t, "claim-check-retry", 30000)
+	m := messages[0]
+	if int64(m["id"].(float64)) != id || m["delivery_count"] != float64(2) {
+		t.Fatalf("Expected message %d on its second delivery, got %v", id, m)
+	}
+	if body, _ := m["body"].(map[string]interface{}); body["rows"] != float64(7) {
+		t.Fatalf("Expected the body inlined once fetchable, got %v", m["body"])
+	}
+	ackMessage(t, m)
+	fmt.Println("✓ Failed fetch nacked and retried, then delivered")
+}
+
+func TestHTTPBodyResolver(t *testing.T) {
+	fmt.Println("\n=== Test: HTTP Body Resolver ===")
+
+	objects := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/small":
+			w.Write([]byte(`{"ok":true}`))
+		case "/large":
+			w.Write([]byte(`{"pad":"` + strings.Repeat("x", 64) + `"}`))
+		case "/bounce":
+			http.Redirect(w, r, "/private/small", http.StatusFound)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer objects.Close()
+
+	resolver := api.NewHTTPBodyResolver(time.Second, 32, []string{objects.URL + "/"})
+	ctx := context.Background()
+
+	body, err := resolver.Resolve(ctx, "q", objects.URL+"/small")
+	if err != nil || string(body) != `{"ok":true}` {
+		t.Fatalf("Expected the object's body, got %q, %v", body, err)
+	}
+	if _, err := resolver.Resolve(ctx, "q", objects.URL+"/missing"); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Fatalf("Expected a 404 error, got %v", err)
+	}
+	if _, err := resolver.Resolve(ctx, "q", objects.URL+"/large"); err == nil || !strings.Contains(err.Error(), "exceeds") {
+		t.Fatalf("Expected a size error, got %v", err)
+	}
+	fmt.Println("✓ Fetches bodies, rejects missing and oversized ones")
+
+	narrow := api.NewHTTPBodyResolver(time.Second, 32, []string{objects.URL + "/small/", objects.URL + "/bounce"})
+	for _, ref := range []string{
+		"http://169.254.169.254/latest/meta-data/",
+		objects.URL + "/small/../large",
+		objects.URL + "/bounce",
+	} {
+		if _, err := narrow.Resolve(ctx, "q", ref); err == nil || !strings.Contains(err.Error(), "allowed prefix") {
+			t.Fatalf("Expected %s refused, got %v", ref, err)
+		}
+	}
+	fmt.Println("✓ Refuses refs and redirects outside the allowed prefixes")
+}
+
+func TestBodyRefAllowedPrefixes(t *testing.T) {
+	cfg := testConfig()
+	cfg.BodyRefPrefixes = []string{"https://objects.example.com/claims/"}
+	_, teardown := setupTestServerWithConfig(t, cfg)
+	defer teardown()
+
+	fmt.Println("\n=== Test: Body Ref Allowed Prefixes ===")
+
+	for _, ref := range []string{
+		"http://localhost:5432/",
+		"https://objects.example.com.attacker.net/claims/1",
+		"https://objects.example.com/claims/../admin",
+	} {
+		status, _ := doJSON(t, http.MethodPost, "/v1/queues/claim-prefix/messages", map[string]interface{}{"body_ref": ref})
+		if status != http.StatusBadRequest {
+			t.Fatalf("Expected 400 for body_ref %s, got %d", ref, status)
+		}
+	}
+	enqueueMessage(t, "claim-prefix", map[string]interface{}{"body_ref": "https://objects.example.com/claims/1"})
+	fmt.Println("✓ Enqueue refuses body_refs outside the allowed prefixes")
+}
+
+func TestBodyRefTimeoutRequiresPrefixes(t *testing.T) {
+	fmt.Println("\n=== Test: BODY_REF_TIMEOUT Requires Prefixes ===")
+
+	t.Setenv("DATABASE_URL", "postgres://localhost/unused")
+	t.Setenv("BODY_REF_TIMEOUT", "5")
+	if _, err := config.LoadConfig(); err == nil || !strings.Contains(err.Error(), "BODY_REF_ALLOWED_PREFIXES") {
+		t.Fatalf("Expected a missing prefixes error, got %v", err)
+	}
+	t.Setenv("BODY_REF_ALLOWED_PREFIXES", "https://objects.example.com")
+	if _, err := config.LoadConfig(); err == nil {
+		t.Fatal("Expected a prefix without a trailing slash rejected")
+	}
+	t.Setenv("BODY_REF_ALLOWED_PREFIXES", "https://objects.example.com/claims/, https://backup.example.com/")
+	cfg, err := config.LoadConfig()
+	if err != nil || len(cfg.BodyRefPrefixes) != 2 {
+		t.Fatalf("Expected two prefixes, got %v, %v", cfg, err)
+	}
+	fmt.Println("✓ Fetching bodies needs an allow-list of prefixes")
+}
@@ -0,0 +1,79 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestConsumerCheckpoints(t *testing.T) {
+	_, teardown := setupTestServer(t)
+	defer teardown()
+
+	fmt.Println("\n=== Test: Consumer Group Checkpoints ===")
+
+	var ids []int64
+	for i := 0; i < 3; i++ {
+		ids = append(ids, enqueueMessage(t, "log-test", map[string]interface{}{"body": map[string]int{"n": i}}))
+	}
+
+	read := func(group string) []int64 {
+		t.Helper()
+		status, out := doJSON(t, http.MethodPost, "/v1/queues/log-test/checkpoints/"+group+":read", map[string]int{"max": 10})
+		if status != http.StatusOK {
+			t.Fatalf("Read failed: %d %v", status, out)
+		}
+		var got []int64
+		for _, m := range out["messages"].([]interface{}) {
+			msg := m.(map[string]interface{})
+			if msg["receipt"] != nil {
+				t.Fatalf("Expected no receipt on a read, got %v", msg)
+			}
+			got = append(got, int64(msg["id"].(float64)))
+		}
+		return got
+	}
+	commit := func(group string, lastID int64) {
+		t.Helper()
+		status, out := doJSON(t, http.MethodPut, "/v1/queues/log-test/checkpoints/"+group, map[string]int64{"last_id": lastID})
+		if status != http.StatusOK || int64(out["last_id"].(float64)) != lastID {
+			t.Fatalf("Commit failed: %d %v", status, out)
+		}
+	}
+
+	if got := read("billing"); len(got) != 3 || got[0] != ids[0] {
+		t.Fatalf("Expected all 3 messages from the start, got %v", got)
+	}
+	commit("billing", ids[1])
+	if got := read("billing"); len(got) != 1 || got[0] != ids[2] {
+		t.Fatalf("Expected only the message after the checkpoint, got %v", got)
+	}
+	if got := read("billing"); len(got) != 1 || got[0] != ids[2] {
+		t.Fatalf("Expected the uncommitted message read again, got %v", got)
+	}
+	fmt.Println("✓ Reads resume after the committed checkpoint")
+
+	if got := read("audit"); len(got) != 3 {
+		t.Fatalf("Expected another group to read from the start, got %v", got)
+	}
+	fmt.Println("✓ Groups keep separate checkpoints")
+
+	commit("billing", 0)
+	if got := read("billing"); len(got) != 3 {
+		t.Fatalf("Expected a replay of all 3 after the reset, got %v", got)
+	}
+	fmt.Println("✓ Resetting the checkpoint replays")
+
+	if status, _ := doJSON(t, http.MethodPut, "/v1/queues/log-test/checkpoints/billing", map[string]int{"last_id": -1}); status != http.StatusBadRequest {
+		t.Fatalf("Expected 400 for a negative last_id, got %d", status)
+	}
+
+	messages := receiveMessages(t, "log-test", 10, 30000)
+	if len(messages) != 3 {
+		t.Fatalf("Expected reads to leave the messages receivable, got %v", messages)
+	}
+	for _, m := range messages {
+		ackMessage(t, m)
+	}
+	fmt.Println("✓ Reads lease nothing")
+}
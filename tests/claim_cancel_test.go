@@ -0,0 +1,76 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aridsondez/AWS-SQS-LITE/internal/queue"
+	"github.com/aridsondez/AWS-SQS-LITE/internal/testutil"
+)
+
+func TestClaimCancelledMidFlight(t *testing.T) {
+	db, closeDB := testutil.SetupStore(t)
+	defer closeDB()
+	ctx := context.Background()
+
+	fmt.Println("\n=== Test: Claim Cancelled Mid-Flight ===")
+
+	for i := 0; i < 3; i++ {
+		if _, err := db.Enqueue(ctx, queue.Message{Queue: "cancel-claim", Body: []byte(`{}`), MaxRetries: 3}, 0); err != nil {
+			t.Fatalf("Enqueue failed: %v", err)
+		}
+	}
+
+	// Hold a lock that makes the claim's FOR UPDATE wait, then give up on it.
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		t.Fatalf("Begin failed: %v", err)
+	}
+	if _, err := tx.Exec(ctx, `LOCK TABLE messages IN EXCLUSIVE MODE`); err != nil {
+		t.Fatalf("Lock failed: %v", err)
+	}
+	claimCtx, cancel := context.WithTimeout(ctx, 300*time.Millisecond)
+	defer cancel()
+	out, err := db.Claim(claimCtx, queue.ClaimOptions{Queue: "cancel-claim", Limit: 3, Visibility: time.Minute})
+	if err == nil || !errors.Is(claimCtx.Err(), context.DeadlineExceeded) {
+		t.Fatalf("Expected the blocked claim to fail on cancellation, got %d messages, %v", len(out), err)
+	}
+	if err := tx.Rollback(ctx); err != nil {
+		t.Fatalf("Rollback failed: %v", err)
+	}
+	fmt.Printf("✓ Claim aborted on cancellation: %v\n", err)
+
+	out, err = db.Claim(ctx, queue.ClaimOptions{Queue: "cancel-claim", Limit: 3, Visibility: time.Minute})
+	if err != nil || len(out) != 3 {
+		t.Fatalf("Expected all 3 messages claimable by the next caller, got %d, %v", len(out), err)
+	}
+	for _, m := range out {
+		if m.DeliveryCount != 1 {
+			t.Fatalf("Expected the cancelled claim not to count, message %d has delivery_count %d", m.ID, m.DeliveryCount)
+		}
+	}
+	fmt.Println("✓ Messages still claimable, no lease or delivery leaked")
+
+	// Leases claimed for a caller that then went away are handed back uncounted.
+	rcs := []queue.Receipt{out[0].Receipt(), out[1].Receipt()}
+	released, err := db.Release(ctx, rcs)
+	if err != nil || len(released) != 2 {
+		t.Fatalf("Expected 2 released, got %v, %v", released, err)
+	}
+	again, err := db.Claim(ctx, queue.ClaimOptions{Queue: "cancel-claim", Limit: 3, Visibility: time.Minute})
+	if err != nil || len(again) != 2 {
+		t.Fatalf("Expected the 2 released messages back, got %d, %v", len(again), err)
+	}
+	for _, m := range again {
+		if m.DeliveryCount != 1 {
+			t.Fatalf("Expected a released delivery not to count, message %d has delivery_count %d", m.ID, m.DeliveryCount)
+		}
+	}
+	if _, err := db.Ack(ctx, rcs[0]); !errors.Is(err, queue.ErrStaleReceipt) {
+		t.Fatalf("Expected the released receipt to be stale, got %v", err)
+	}
+	fmt.Println("✓ Released leases are available again, uncounted")
+}
@@ -0,0 +1,111 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestClaimOrderVisibleAt(t *testing.T) {
+	_, teardown := setupTestServer(t)
+	defer teardown()
+
+	fmt.Println("\n=== Test: Claim Order by Visibility ===")
+
+	putQueueConfig(t, "order-visible", map[string]interface{}{
+		"partitions":  1,
+		"claim_order": "visible_at",
+	})
+
+	// On each queue: a delayed message first (lower id), then an immediate
+	// one that has been waiting longer by the time both are claimable.
+	for _, q := range []string{"order-id", "order-visible"} {
+		enqueueMessage(t, q, map[string]interface{}{
+			"body":     map[string]string{"task": "delayed"},
+			"delay_ms": 1000,
+		})
+		enqueueMessage(t, q, map[string]interface{}{
+			"body": map[string]string{"task": "immediate"},
+		})
+	}
+	time.Sleep(1500 * time.Millisecond)
+
+	first := func(q string) string {
+		messages := receiveMessages(t, q, 1, 30000)
+		if len(messages) != 1 {
+			t.Fatalf("Expected 1 message from %s, got %d", q, len(messages))
+		}
+		return messages[0]["body"].(map[string]interface{})["task"].(string)
+	}
+
+	if got := first("order-id"); got != "delayed" {
+		t.Fatalf("Expected default id order to serve the delayed message first, got %s", got)
+	}
+	fmt.Println("✓ Default order: delayed message jumps ahead by id")
+
+	if got := first("order-visible"); got != "immediate" {
+		t.Fatalf("Expected visible_at order to serve the immediate message first, got %s", got)
+	}
+	fmt.Println("✓ visible_at order: message that was ready first is served first")
+}
+
+func TestClaimOrderFIFO(t *testing.T) {
+	_, teardown := setupTestServer(t)
+	defer teardown()
+
+	fmt.Println("\n=== Test: FIFO Claim Order ===")
+
+	status, _ := doJSON(t, http.MethodPut, "/v1/queues/order-fifo/config", map[string]interface{}{
+		"partitions":  4,
+		"claim_order": "fifo",
+	})
+	if status != http.StatusBadRequest {
+		t.Fatalf("Expected 400 for a partitioned FIFO queue, got %d", status)
+	}
+	putQueueConfig(t, "order-fifo", map[string]interface{}{
+		"partitions":  1,
+		"claim_order": "fifo",
+	})
+	fmt.Println("✓ FIFO needs a single partition")
+
+	enqueue := func(task string, delayMS int) {
+		enqueueMessage(t, "order-fifo", map[string]interface{}{
+			"body":     map[string]string{"task": task},
+			"delay_ms": delayMS,
+		})
+	}
+	tasks := func(max int) []string {
+		var out []string
+		for _, m := range receiveMessages(t, "order-fifo", max, 30000) {
+			out = append(out, m["body"].(map[string]interface{})["task"].(string))
+			ackMessage(t, m)
+		}
+		return out
+	}
+
+	enqueue("a", 0)
+	enqueue("b", 1000)
+	enqueue("c", 0)
+	enqueue("d", 0)
+
+	// Only the visible messages, in enqueue order; b is still delayed.
+	if got := tasks(1); len(got) != 1 || got[0] != "a" {
+		t.Fatalf("Expected [a], got %v", got)
+	}
+	if got := tasks(10); fmt.Sprint(got) != "[c d]" {
+		t.Fatalf("Expected [c d], got %v", got)
+	}
+	fmt.Println("✓ Visible messages served in enqueue order around a delayed one")
+
+	time.Sleep(1200 * time.Millisecond)
+	enqueue("e", 0)
+	enqueue("f", 2000)
+	enqueue("g", 0)
+
+	// b was enqueued before e and g, so once visible it goes first.
+	if got := tasks(10); fmt.Sprint(got) != "[b e g]" {
+		t.Fatalf("Expected [b e g], got %v", got)
+	}
+	fmt.Println("✓ A delayed message, once visible, goes ahead of later enqueues")
+}
@@ -0,0 +1,92 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestClaimByID(t *testing.T) {
+	_, teardown := setupTestServer(t)
+	defer teardown()
+
+	fmt.Println("\n=== Test: Claim A Message By ID ===")
+
+	var ids []int64
+	for i := 0; i < 3; i++ {
+		ids = append(ids, enqueueMessage(t, "claim-test", map[string]interface{}{"body": map[string]int{"n": i}}))
+	}
+	delayed := enqueueMessage(t, "claim-test", map[string]interface{}{"body": map[string]int{"n": 3}, "delay_ms": 600000})
+
+	status, m := doJSON(t, http.MethodPost, fmt.Sprintf("/v1/messages/%d:claim", ids[1]), map[string]interface{}{"visibility_ms": 30000})
+	if status != http.StatusOK || int64(m["id"].(float64)) != ids[1] || m["receipt"] == "" || m["delivery_count"] != float64(1) {
+		t.Fatalf("Expected message %d leased with a receipt, got %d %v", ids[1], status, m)
+	}
+	fmt.Println("✓ Middle message claimed ahead of the head of the queue")
+
+	if status, out := doJSON(t, http.MethodPost, fmt.Sprintf("/v1/messages/%d:claim", ids[1]), nil); status != http.StatusConflict {
+		t.Fatalf("Expected 409 claiming a leased message, got %d %v", status, out)
+	}
+	if status, out := doJSON(t, http.MethodPost, fmt.Sprintf("/v1/messages/%d:claim", delayed), nil); status != http.StatusConflict {
+		t.Fatalf("Expected 409 claiming a delayed message, got %d %v", status, out)
+	}
+	fmt.Println("✓ Leased and delayed messages refused with 409")
+
+	ackMessage(t, m)
+	if status, _ := doJSON(t, http.MethodPost, fmt.Sprintf("/v1/messages/%d:claim", ids[1]), nil); status != http.StatusNotFound {
+		t.Fatalf("Expected 404 claiming an acked message, got %d", status)
+	}
+	fmt.Println("✓ Claimed message acked with its receipt")
+
+	messages := receiveMessages(t, "claim-test", 10, 30000)
+	if len(messages) != 2 || int64(messages[0]["id"].(float64)) != ids[0] || int64(messages[1]["id"].(float64)) != ids[2] {
+		t.Fatalf("Expected the other two messages, got %v", messages)
+	}
+	for _, msg := range messages {
+		ackMessage(t, msg)
+	}
+	fmt.Println("✓ The rest of the queue delivered as usual")
+}
+
+func TestClaimByIDRespectsQueue(t *testing.T) {
+	_, teardown := setupTestServer(t)
+	defer teardown()
+
+	fmt.Println("\n=== Test: Claim By ID Respects Pause, Budget And Groups ===")
+
+	q := "claim-queue-test"
+	first := enqueueMessage(t, q, map[string]interface{}{"body": map[string]int{"n": 0}, "group_id": "g"})
+	second := enqueueMessage(t, q, map[string]interface{}{"body": map[string]int{"n": 1}, "group_id": "g"})
+	other := enqueueMessage(t, q, map[string]interface{}{"body": map[string]int{"n": 2}})
+	claim := func(id int64) (int, map[string]interface{}) {
+		return doJSON(t, http.MethodPost, fmt.Sprintf("/v1/messages/%d:claim", id), map[string]interface{}{"visibility_ms": 30000})
+	}
+
+	if status, out := claim(second); status != http.StatusConflict {
+		t.Fatalf("Expected 409 claiming a group message ahead of its group, got %d %v", status, out)
+	}
+	fmt.Println("✓ A group message can't be claimed ahead of an earlier one")
+
+	doJSON(t, http.MethodPost, "/v1/queues/"+q+":pause", nil)
+	if status, out := claim(other); status != http.StatusConflict {
+		t.Fatalf("Expected 409 claiming on a paused queue, got %d %v", status, out)
+	}
+	doJSON(t, http.MethodPost, "/v1/queues/"+q+":resume", nil)
+	fmt.Println("✓ A paused queue refuses claims by ID")
+
+	putQueueConfig(t, q, map[string]interface{}{"max_in_flight": 1})
+	status, m := claim(first)
+	if status != http.StatusOK {
+		t.Fatalf("Expected the group's first message claimed, got %d %v", status, m)
+	}
+	if status, out := claim(other); status != http.StatusConflict {
+		t.Fatalf("Expected 409 claiming past max_in_flight, got %d %v", status, out)
+	}
+	fmt.Println("✓ max_in_flight caps claims by ID")
+
+	ackMessage(t, m)
+	if status, out := claim(second); status != http.StatusOK {
+		t.Fatalf("Expected the next group message claimable once the first is acked, got %d %v", status, out)
+	}
+	fmt.Println("✓ The group's next message is claimable once the first is acked")
+}
@@ -0,0 +1,95 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aridsondez/AWS-SQS-LITE/pkg/client"
+)
+
+func TestClientErrorClasses(t *testing.T) {
+	fmt.Println("\n=== Test: Client Error Classes ===")
+
+	var status int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		fmt.Fprintf(w, `{"error": "status %d"}`, status)
+	}))
+	defer srv.Close()
+	c := client.NewClient(srv.URL)
+	ctx := context.Background()
+
+	calls := map[string]func() error{
+		"enqueue": func() error {
+			_, err := c.Enqueue(ctx, "orders", map[string]string{"k": "v"}, nil)
+			return err
+		},
+		"receive": func() error {
+			_, err := c.Receive(ctx, "orders", nil)
+			return err
+		},
+		"ack": func() error {
+			return c.Ack(ctx, 1, "1.1")
+		},
+	}
+	for _, tc := range []struct {
+		status int
+		want   error
+	}{
+		{http.StatusBadRequest, client.ErrBadRequest},
+		{http.StatusConflict, client.ErrBadRequest},
+		{http.StatusNotFound, client.ErrNotFound},
+		{http.StatusTooManyRequests, client.ErrRateLimited},
+		{http.StatusInternalServerError, client.ErrServer},
+		{http.StatusServiceUnavailable, client.ErrServer},
+	} {
+		status = tc.status
+		for op, call := range calls {
+			err := call()
+			if !errors.Is(err, tc.want) {
+				t.Fatalf("%s with %d: expected %v, got %v", op, tc.status, tc.want, err)
+			}
+			var se *client.StatusError
+			if !errors.As(err, &se) || se.StatusCode != tc.status || se.Op != op {
+				t.Fatalf("%s with %d: expected a StatusError, got %#v", op, tc.status, err)
+			}
+			if se.Message != fmt.Sprintf("status %d", tc.status) {
+				t.Fatalf("%s with %d: expected the server's message, got %q", op, tc.status, se.Message)
+			}
+		}
+		fmt.Printf("✓ %d → %v\n", tc.status, tc.want)
+	}
+}
+
+func TestClientReceiveAndAck(t *testing.T) {
+	_, teardown := setupTestServer(t)
+	defer teardown()
+
+	fmt.Println("\n=== Test: Client Receive And Ack ===")
+
+	c := client.NewClient("http://localhost:9999")
+	ctx := context.Background()
+	id, err := c.Enqueue(ctx, "client-queue", map[string]string{"task": "ship"}, nil)
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	msgs, err := c.Receive(ctx, "client-queue", &client.ReceiveOptions{Max: 5})
+	if err != nil {
+		t.Fatalf("Receive: %v", err)
+	}
+	if len(msgs) != 1 || msgs[0].ID != id || msgs[0].DeliveryCount != 1 {
+		t.Fatalf("Expected message %d on its first delivery, got %+v", id, msgs)
+	}
+	if err := c.Ack(ctx, id, msgs[0].Receipt); err != nil {
+		t.Fatalf("Ack: %v", err)
+	}
+	if err := c.Ack(ctx, id, msgs[0].Receipt); !errors.Is(err, client.ErrNotFound) {
+		t.Fatalf("Expected ErrNotFound acking twice, got %v", err)
+	}
+	fmt.Println("✓ Enqueue, receive and ack through the client; a second ack is ErrNotFound")
+}
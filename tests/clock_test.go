@@ -0,0 +1,27 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aridsondez/AWS-SQS-LITE/internal/testutil"
+)
+
+func TestClockSkew(t *testing.T) {
+	db, closeDB := testutil.SetupStore(t)
+	defer closeDB()
+
+	fmt.Println("\n=== Test: Clock Skew Check ===")
+
+	skew, err := db.ClockSkew(context.Background())
+	if err != nil {
+		t.Fatalf("ClockSkew: %v", err)
+	}
+	// The test database runs next to the tests, so its clock should agree.
+	if skew > time.Second || skew < -time.Second {
+		t.Fatalf("Expected negligible skew against the test database, got %v", skew)
+	}
+	fmt.Printf("✓ Database clock skew: %v\n", skew)
+}
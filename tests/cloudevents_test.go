@@ -0,0 +1,90 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCloudEventsQueue(t *testing.T) {
+	_, teardown := setupTestServer(t)
+	defer teardown()
+
+	fmt.Println("\n=== Test: CloudEvents Queue ===")
+
+	putQueueConfig(t, "ce-orders", map[string]interface{}{"cloudevents": true})
+
+	event := map[string]interface{}{
+		"specversion":     "1.0",
+		"id":              "A234-1234-1234",
+		"source":          "https://example.com/orders",
+		"type":            "com.example.order.created",
+		"time":            "2026-01-07T10:15:00Z",
+		"datacontenttype": "application/json",
+		"orderregion":     "eu-west", // extension attribute
+		"data":            map[string]interface{}{"order": float64(42)},
+	}
+	id := enqueueMessage(t, "ce-orders", map[string]interface{}{"body": event})
+	messages := receiveMessages(t, "ce-orders", 1, 30000)
+	if len(messages) != 1 || int64(messages[0]["id"].(float64)) != id {
+		t.Fatalf("Expected message %d, got %v", id, messages)
+	}
+	if !reflect.DeepEqual(messages[0]["body"], event) {
+		t.Fatalf("Expected the event back intact, got %v", messages[0]["body"])
+	}
+	ackMessage(t, messages[0])
+	fmt.Println("✓ CloudEvent received back intact, extensions included")
+
+	enqueueMessage(t, "ce-orders", map[string]interface{}{"body": map[string]int{"order": 43}})
+	messages = receiveMessages(t, "ce-orders", 1, 30000)
+	if len(messages) != 1 {
+		t.Fatalf("Expected the raw message, got %v", messages)
+	}
+	wrapped := messages[0]["body"].(map[string]interface{})
+	if wrapped["specversion"] != "1.0" || wrapped["source"] != "/v1/queues/ce-orders" || wrapped["type"] != "sqslite.message" ||
+		wrapped["id"] == "" || !reflect.DeepEqual(wrapped["data"], map[string]interface{}{"order": float64(43)}) {
+		t.Fatalf("Expected the raw body wrapped in an event, got %v", wrapped)
+	}
+	if _, err := time.Parse(time.RFC3339Nano, wrapped["time"].(string)); err != nil {
+		t.Fatalf("Expected an RFC 3339 time, got %v", wrapped["time"])
+	}
+	ackMessage(t, messages[0])
+	fmt.Println("✓ Raw body wrapped in an envelope")
+
+	for name, body := range map[string]map[string]interface{}{
+		"missing type":    {"specversion": "1.0", "id": "1", "source": "/s"},
+		"old specversion": {"specversion": "0.3", "id": "1", "source": "/s", "type": "t"},
+		"bad time":        {"specversion": "1.0", "id": "1", "source": "/s", "type": "t", "time": "yesterday"},
+	} {
+		if status, out := doJSON(t, http.MethodPost, "/v1/queues/ce-orders/messages", map[string]interface{}{"body": body}); status != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400, got %d %v", name, status, out)
+		}
+	}
+	fmt.Println("✓ Invalid events rejected with 400")
+
+	// Queues without the flag leave bodies alone, events or not.
+	raw := map[string]interface{}{"specversion": "0.3", "note": "not checked here"}
+	enqueueMessage(t, "ce-raw", map[string]interface{}{"body": raw})
+	messages = receiveMessages(t, "ce-raw", 1, 30000)
+	if len(messages) != 1 || !reflect.DeepEqual(messages[0]["body"], raw) {
+		t.Fatalf("Expected the body untouched on a raw queue, got %v", messages)
+	}
+	ackMessage(t, messages[0])
+	fmt.Println("✓ Raw queues coexist unchanged")
+
+	delayed := enqueueMessage(t, "ce-orders", map[string]interface{}{"body": map[string]int{"order": 44}, "delay_ms": 600000})
+	_, before := doJSON(t, http.MethodGet, "/v1/queues/ce-orders/delayed", nil)
+	status, out := doJSON(t, http.MethodPatch, fmt.Sprintf("/v1/messages/%d", delayed), map[string]interface{}{"body": map[string]int{"order": 45}})
+	if status != http.StatusOK {
+		t.Fatalf("Expected the edit to succeed, got %d %v", status, out)
+	}
+	was := before["messages"].([]interface{})[0].(map[string]interface{})["body"].(map[string]interface{})
+	edited := out["body"].(map[string]interface{})
+	if edited["id"] != was["id"] || edited["time"] != was["time"] ||
+		!reflect.DeepEqual(edited["data"], map[string]interface{}{"order": float64(45)}) {
+		t.Fatalf("Expected only the event's data replaced, was %v, got %v", was, edited)
+	}
+	fmt.Println("✓ Editing keeps the event's id and time")
+}
@@ -0,0 +1,63 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestCoalesceKey(t *testing.T) {
+	_, teardown := setupTestServer(t)
+	defer teardown()
+
+	fmt.Println("\n=== Test: Coalesce Key ===")
+
+	enqueue := func(n int, key string) (int, int64) {
+		t.Helper()
+		status, out := doJSON(t, http.MethodPost, "/v1/queues/coalesce-test/messages", map[string]interface{}{
+			"body": map[string]int{"n": n}, "coalesce_key": key,
+		})
+		if status != http.StatusCreated && status != http.StatusOK {
+			t.Fatalf("Enqueue failed: %d %v", status, out)
+		}
+		return status, int64(out["id"].(float64))
+	}
+
+	status, first := enqueue(1, "reindex")
+	if status != http.StatusCreated {
+		t.Fatalf("Expected 201 for the first enqueue, got %d", status)
+	}
+	if status, id := enqueue(2, "reindex"); status != http.StatusOK || id != first {
+		t.Fatalf("Expected 200 with id %d while it is pending, got %d %d", first, status, id)
+	}
+	if status, id := enqueue(3, "rebuild-cache"); status != http.StatusCreated || id == first {
+		t.Fatalf("Expected a new message for another key, got %d %d", status, id)
+	}
+	fmt.Println("✓ Pending key coalesces; other keys don't")
+
+	messages := receiveMessages(t, "coalesce-test", 10, 30000)
+	if len(messages) != 2 {
+		t.Fatalf("Expected one message per key, got %v", messages)
+	}
+	if status, id := enqueue(4, "reindex"); status != http.StatusOK || id != first {
+		t.Fatalf("Expected an in-flight message to still coalesce, got %d %d", status, id)
+	}
+	fmt.Println("✓ In-flight message still holds the key")
+
+	for _, m := range messages {
+		if m["coalesce_key"] == nil {
+			t.Fatalf("Expected coalesce_key on the received message, got %v", m)
+		}
+		ackMessage(t, m)
+	}
+	if status, id := enqueue(5, "reindex"); status != http.StatusCreated || id == first {
+		t.Fatalf("Expected a new message once the first was acked, got %d %d", status, id)
+	}
+	fmt.Println("✓ Key is free again after the ack")
+
+	if status, _ := doJSON(t, http.MethodPost, "/v1/queues/coalesce-test/messages", map[string]interface{}{
+		"body": map[string]int{"n": 6}, "coalesce_key": "reindex", "dedup_id": "d-1",
+	}); status != http.StatusBadRequest {
+		t.Fatalf("Expected 400 combining coalesce_key with dedup_id, got %d", status)
+	}
+}
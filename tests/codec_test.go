@@ -0,0 +1,111 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/aridsondez/AWS-SQS-LITE/pkg/client"
+	"github.com/aridsondez/AWS-SQS-LITE/pkg/codec"
+	"github.com/aridsondez/AWS-SQS-LITE/pkg/worker"
+)
+
+type codecOrder struct {
+	ID    int
+	Items []string
+	Total float64
+}
+
+// reverseCodec stands in for a codec registered by the application, such as
+// MessagePack: JSON with the bytes reversed.
+type reverseCodec struct{}
+
+func (reverseCodec) ContentType() string { return "application/x-reversed" }
+
+func (reverseCodec) Marshal(v any) ([]byte, error) {
+	b, err := json.Marshal(v)
+	return reversed(b), err
+}
+
+func (reverseCodec) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(reversed(data), v)
+}
+
+func reversed(b []byte) []byte {
+	out := make([]byte, len(b))
+	for i, c := range b {
+		out[len(b)-1-i] = c
+	}
+	return out
+}
+
+func TestBodyCodecs(t *testing.T) {
+	fmt.Println("\n=== Test: Body Codecs ===")
+
+	codec.Register(reverseCodec{})
+
+	var sent json.RawMessage
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Body json.RawMessage `json:"body"`
+		}
+		b, _ := io.ReadAll(r.Body)
+		json.Unmarshal(b, &req)
+		sent = req.Body
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id":1}`))
+	}))
+	defer srv.Close()
+	c := client.NewClient(srv.URL)
+
+	want := codecOrder{ID: 7, Items: []string{"a", "b"}, Total: 9.5}
+	for _, tc := range []struct {
+		name  string
+		codec codec.Codec
+		ctype string
+	}{
+		{"default", nil, "application/json"},
+		{"json", codec.JSON, "application/json"},
+		{"gob", codec.Gob, "application/x-gob"},
+		{"registered", reverseCodec{}, "application/x-reversed"},
+	} {
+		if _, err := c.Enqueue(context.Background(), "codec-test", want, &client.EnqueueOptions{Codec: tc.codec}); err != nil {
+			t.Fatalf("%s: enqueue failed: %v", tc.name, err)
+		}
+		if !json.Valid(sent) {
+			t.Fatalf("%s: expected a JSON body, sent %s", tc.name, sent)
+		}
+
+		msg := &worker.Message{Body: sent}
+		if got := msg.ContentType(); got != tc.ctype {
+			t.Fatalf("%s: expected content type %s, got %s", tc.name, tc.ctype, got)
+		}
+		var got codecOrder
+		if err := msg.Decode(&got); err != nil {
+			t.Fatalf("%s: decode failed: %v", tc.name, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("%s: expected %+v back, got %+v", tc.name, want, got)
+		}
+		var viaClient codecOrder
+		if err := (&client.Message{Body: sent}).Decode(&viaClient); err != nil || !reflect.DeepEqual(viaClient, want) {
+			t.Fatalf("%s: client decode gave %+v, %v", tc.name, viaClient, err)
+		}
+		fmt.Printf("✓ %s round trip as %s\n", tc.name, tc.ctype)
+	}
+
+	var plain map[string]int
+	if err := codec.Decode(json.RawMessage(`{"datacontenttype": 1, "n": 2}`), &plain); err != nil || plain["n"] != 2 {
+		t.Fatalf("Expected a plain JSON object to decode as JSON, got %v, %v", plain, err)
+	}
+	unknown := json.RawMessage(`{"datacontenttype": "application/x-unknown", "data_base64": "AAEC"}`)
+	if err := codec.Decode(unknown, &plain); err == nil {
+		t.Fatal("Expected an unregistered content type to fail to decode")
+	}
+	fmt.Println("✓ Plain JSON bodies pass through; unknown content types fail")
+}
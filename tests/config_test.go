@@ -0,0 +1,83 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReceiveDefaultsFromConfig(t *testing.T) {
+	cfg := testConfig()
+	cfg.VisibilityTimeout = 2 * time.Second
+	cfg.ReceiveMax = 3
+
+	_, teardown := setupTestServerWithConfig(t, cfg)
+	defer teardown()
+
+	fmt.Println("\n=== Test: Receive Defaults From Config ===")
+
+	for i := 0; i < 5; i++ {
+		enqueueMessage(t, "config-defaults", map[string]interface{}{
+			"body": map[string]int{"n": i},
+		})
+	}
+
+	// visibility_ms omitted → server default from config (2s, not 30s)
+	messages := receiveMessages(t, "config-defaults", 3, 0)
+	if len(messages) != 3 {
+		t.Fatalf("Expected 3 messages at the configured max, got %d", len(messages))
+	}
+	leaseUntil, err := time.Parse(time.RFC3339Nano, messages[0]["lease_until"].(string))
+	if err != nil {
+		t.Fatalf("Bad lease_until: %v", err)
+	}
+	if remaining := time.Until(leaseUntil); remaining > 5*time.Second {
+		t.Fatalf("Expected ~2s default visibility, lease has %s left", remaining)
+	}
+	fmt.Println("✓ Default visibility comes from config")
+
+	// asking for more than RECEIVE_MAX gets RECEIVE_MAX
+	messages = receiveMessages(t, "config-defaults", 100, 30000)
+	if len(messages) != 2 {
+		t.Fatalf("Expected max above RECEIVE_MAX to take the 2 left, got %d", len(messages))
+	}
+	fmt.Println("✓ RECEIVE_MAX bounds the receive batch")
+}
+
+func TestReceiveMaxAboveCeilingIsCapped(t *testing.T) {
+	cfg := testConfig()
+	cfg.ReceiveMax = 4
+
+	_, teardown := setupTestServerWithConfig(t, cfg)
+	defer teardown()
+
+	fmt.Println("\n=== Test: Receive Max Above Ceiling Is Capped ===")
+
+	for i := 0; i < 6; i++ {
+		enqueueMessage(t, "receive-ceiling", map[string]interface{}{"body": map[string]int{"n": i}})
+	}
+
+	resp, err := http.Post("http://localhost:9999/v1/queues/receive-ceiling:receive", "application/json",
+		strings.NewReader(`{"max": 100, "visibility_ms": 30000}`))
+	if err != nil {
+		t.Fatalf("Receive failed: %v", err)
+	}
+	defer resp.Body.Close()
+	var messages []map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&messages)
+	if len(messages) != 4 {
+		t.Fatalf("Expected max 100 capped at RECEIVE_MAX (4), got %d", len(messages))
+	}
+	if h := resp.Header.Get("X-Receive-Max"); h != "4" {
+		t.Fatalf("Expected X-Receive-Max: 4, got %q", h)
+	}
+	fmt.Println("✓ Asking for 100 returns RECEIVE_MAX messages, not 1")
+
+	if messages := receiveMessages(t, "receive-ceiling", 0, 30000); len(messages) != 1 {
+		t.Fatalf("Expected max 0 to mean 1, got %d", len(messages))
+	}
+	fmt.Println("✓ No max still means 1")
+}
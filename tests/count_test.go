@@ -0,0 +1,84 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestCountFilters(t *testing.T) {
+	db, teardown := setupTestServer(t)
+	defer teardown()
+
+	fmt.Println("\n=== Test: Filtered Count ===")
+
+	var ids []int64
+	for i := 0; i < 4; i++ {
+		ids = append(ids, enqueueMessage(t, "count-test", map[string]interface{}{
+			"body":     map[string]int{"n": i},
+			"group_id": fmt.Sprintf("g%d", i%2),
+		}))
+	}
+	enqueueMessage(t, "count-test", map[string]interface{}{"body": map[string]int{"n": 4}, "delay_ms": 60000})
+
+	// Two messages have been retried a lot; one of them is leased right now.
+	_, err := db.Pool.Exec(context.Background(),
+		`UPDATE messages SET delivery_count = 4 WHERE id = ANY($1)`, ids[:2])
+	if err != nil {
+		t.Fatalf("Set delivery_count: %v", err)
+	}
+	_, err = db.Pool.Exec(context.Background(),
+		`UPDATE messages SET lease_until = now() + interval '1 minute' WHERE id = $1`, ids[0])
+	if err != nil {
+		t.Fatalf("Set lease: %v", err)
+	}
+
+	cases := []struct {
+		query string
+		want  int64
+	}{
+		{"", 5},
+		{"?min_delivery_count=3", 2},
+		{"?min_delivery_count=3&state=available", 1},
+		{"?state=in_flight", 1},
+		{"?state=delayed", 1},
+		{"?group_id=g1&state=available", 2},
+		{"?group_id=g0&min_delivery_count=3", 1},
+	}
+	for _, c := range cases {
+		status, result := countMessages(t, "count-test", c.query)
+		if status != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d", c.query, status)
+		}
+		if result.Count != c.want {
+			t.Fatalf("%s: expected %d, got %d", c.query, c.want, result.Count)
+		}
+		fmt.Printf("✓ count%s = %d\n", c.query, result.Count)
+	}
+
+	for _, q := range []string{"?state=gone", "?min_delivery_count=-1", "?min_delivery_count=x"} {
+		if status, _ := countMessages(t, "count-test", q); status != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400, got %d", q, status)
+		}
+	}
+	fmt.Println("✓ Invalid filters rejected")
+}
+
+type countResult struct {
+	Count  int64 `json:"count"`
+	Capped bool  `json:"capped"`
+}
+
+func countMessages(t *testing.T, queue, query string) (int, countResult) {
+	resp, err := http.Get("http://localhost:9999/v1/queues/" + queue + "/count" + query)
+	if err != nil {
+		t.Fatalf("Count failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var result countResult
+	json.NewDecoder(resp.Body).Decode(&result)
+	return resp.StatusCode, result
+}
@@ -0,0 +1,78 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestDedupWindow(t *testing.T) {
+	db, teardown := setupTestServer(t)
+	defer teardown()
+
+	fmt.Println("\n=== Test: Dedup Window ===")
+
+	putQueueConfig(t, "dedup-test", map[string]interface{}{
+		"partitions":      1,
+		"dedup_window_ms": 1000,
+	})
+
+	payload := map[string]interface{}{
+		"body":     map[string]string{"task": "send-invoice"},
+		"dedup_id": "invoice-7",
+	}
+
+	firstID, status := enqueueDedup(t, "dedup-test", payload)
+	if status != http.StatusCreated {
+		t.Fatalf("Expected 201 on first enqueue, got %d", status)
+	}
+	dupID, status := enqueueDedup(t, "dedup-test", payload)
+	if status != http.StatusOK || dupID != firstID {
+		t.Fatalf("Expected duplicate to return %d with 200, got %d (status %d)", firstID, dupID, status)
+	}
+	fmt.Println("✓ Duplicate within the window is dropped")
+
+	time.Sleep(1500 * time.Millisecond)
+
+	againID, status := enqueueDedup(t, "dedup-test", payload)
+	if status != http.StatusCreated || againID == firstID {
+		t.Fatalf("Expected a new message after the window, got %d (status %d)", againID, status)
+	}
+	fmt.Println("✓ Same dedup_id accepted after the window")
+
+	// Once this record expires, the next sweep (every 2s) prunes it.
+	time.Sleep(3500 * time.Millisecond)
+	var remaining int
+	err := db.Pool.QueryRow(context.Background(),
+		"SELECT count(*) FROM enqueue_keys WHERE queue = 'dedup-test' AND expires_at <= now()").Scan(&remaining)
+	if err != nil {
+		t.Fatalf("Count failed: %v", err)
+	}
+	if remaining != 0 {
+		t.Fatalf("Expected expired dedup records pruned, %d remain", remaining)
+	}
+	fmt.Println("✓ Expired dedup records are pruned")
+}
+
+func enqueueDedup(t *testing.T, queue string, payload map[string]interface{}) (int64, int) {
+	body, _ := json.Marshal(payload)
+	resp, err := http.Post(
+		fmt.Sprintf("http://localhost:9999/v1/queues/%s/messages", queue),
+		"application/json",
+		bytes.NewReader(body),
+	)
+	if err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		ID int64 `json:"id"`
+	}
+	json.NewDecoder(resp.Body).Decode(&result)
+	return result.ID, resp.StatusCode
+}
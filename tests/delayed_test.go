@@ -0,0 +1,215 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aridsondez/AWS-SQS-LITE/internal/api"
+)
+
+func TestCancelDelayedMessage(t *testing.T) {
+	_, teardown := setupTestServer(t)
+	defer teardown()
+
+	fmt.Println("\n=== Test: Inspect And Cancel Delayed Messages ===")
+
+	later := enqueueMessage(t, "delayed-test", map[string]interface{}{
+		"body":     map[string]string{"notify": "reminder"},
+		"delay_ms": 600000,
+	})
+	soon := enqueueMessage(t, "delayed-test", map[string]interface{}{
+		"body":     map[string]string{"notify": "digest"},
+		"delay_ms": 300000,
+	})
+	now := enqueueMessage(t, "delayed-test", map[string]interface{}{"body": map[string]string{"notify": "now"}})
+
+	status, out := doJSON(t, http.MethodGet, "/v1/queues/delayed-test/delayed", nil)
+	list, _ := out["messages"].([]interface{})
+	if status != http.StatusOK || len(list) != 2 {
+		t.Fatalf("Expected the 2 delayed messages, got %d %v", status, out)
+	}
+	first := list[0].(map[string]interface{})
+	if int64(first["id"].(float64)) != soon || first["receipt"] != nil {
+		t.Fatalf("Expected message %d first and without a receipt, got %v", soon, first)
+	}
+	fmt.Println("✓ Delayed messages listed soonest first, not leased")
+
+	status, out = doJSON(t, http.MethodDelete, fmt.Sprintf("/v1/messages/%d", later), nil)
+	if status != http.StatusOK || out["cancelled"] != true || out["queue"] != "delayed-test" {
+		t.Fatalf("Expected message %d cancelled, got %d %v", later, status, out)
+	}
+	if status, _ := doJSON(t, http.MethodDelete, fmt.Sprintf("/v1/messages/%d", later), nil); status != http.StatusNotFound {
+		t.Fatalf("Expected 404 cancelling twice, got %d", status)
+	}
+	_, out = doJSON(t, http.MethodGet, "/v1/queues/delayed-test/delayed", nil)
+	if list, _ := out["messages"].([]interface{}); len(list) != 1 {
+		t.Fatalf("Expected 1 delayed message left, got %v", out)
+	}
+	fmt.Println("✓ Cancelled before it became visible")
+
+	// Once delivered, a message can't be cancelled without force.
+	messages := receiveMessages(t, "delayed-test", 1, 30000)
+	if len(messages) != 1 || int64(messages[0]["id"].(float64)) != now {
+		t.Fatalf("Expected message %d, got %v", now, messages)
+	}
+	status, _ = doJSON(t, http.MethodDelete, fmt.Sprintf("/v1/messages/%d", now), nil)
+	if status != http.StatusConflict {
+		t.Fatalf("Expected 409 cancelling a delivered message, got %d", status)
+	}
+	ackMessage(t, messages[0])
+	fmt.Println("✓ Delivered message refused")
+}
+
+func TestEditDelayedMessage(t *testing.T) {
+	_, teardown := setupTestServer(t)
+	defer teardown()
+
+	fmt.Println("\n=== Test: Edit Delayed Message ===")
+
+	id := enqueueMessage(t, "edit-test", map[string]interface{}{
+		"body":     map[string]string{"notify": "typo"},
+		"delay_ms": 600000,
+	})
+	status, out := doJSON(t, http.MethodPatch, fmt.Sprintf("/v1/messages/%d", id), map[string]interface{}{
+		"body": map[string]string{"notify": "fixed"},
+	})
+	if status != http.StatusOK || out["receipt"] != nil {
+		t.Fatalf("Expected message %d edited and not leased, got %d %v", id, status, out)
+	}
+	_, out = doJSON(t, http.MethodGet, "/v1/queues/edit-test/delayed", nil)
+	list, _ := out["messages"].([]interface{})
+	if len(list) != 1 {
+		t.Fatalf("Expected the delayed message, got %v", out)
+	}
+	body, _ := list[0].(map[string]interface{})["body"].(map[string]interface{})
+	if body["notify"] != "fixed" {
+		t.Fatalf("Expected the edited body, got %v", list[0])
+	}
+	fmt.Println("✓ Delayed message edited before it became visible")
+
+	if status, _ := doJSON(t, http.MethodPatch, fmt.Sprintf("/v1/messages/%d", id), map[string]interface{}{}); status != http.StatusBadRequest {
+		t.Fatalf("Expected 400 for a missing body, got %d", status)
+	}
+	if status, _ := doJSON(t, http.MethodPatch, "/v1/messages/999999999", map[string]interface{}{"body": 1}); status != http.StatusNotFound {
+		t.Fatalf("Expected 404 editing an unknown message, got %d", status)
+	}
+	fmt.Println("✓ Bad edits refused")
+
+	now := enqueueMessage(t, "edit-test", map[string]interface{}{"body": map[string]string{"notify": "now"}})
+	messages := receiveMessages(t, "edit-test", 1, 30000)
+	if len(messages) != 1 || int64(messages[0]["id"].(float64)) != now {
+		t.Fatalf("Expected message %d, got %v", now, messages)
+	}
+	status, _ = doJSON(t, http.MethodPatch, fmt.Sprintf("/v1/messages/%d", now), map[string]interface{}{"body": 1})
+	if status != http.StatusConflict {
+		t.Fatalf("Expected 409 editing an in-flight message, got %d", status)
+	}
+	ackMessage(t, messages[0])
+	fmt.Println("✓ In-flight message refused")
+}
+
+func TestForceCancelNeedsAdmin(t *testing.T) {
+	fmt.Println("\n=== Test: Force Cancel Needs Admin ===")
+
+	cfg := testConfig()
+	cfg.AdminToken = "secret"
+	h := api.NewServer(":0", nil, cfg).Handler
+
+	for _, tc := range []struct {
+		path, token string
+		want        int
+	}{
+		{"/v1/messages/abc", "", http.StatusBadRequest},
+		{"/v1/messages/7?force=true", "", http.StatusUnauthorized},
+		{"/v1/messages/7?force=true", "wrong", http.StatusUnauthorized},
+	} {
+		req := httptest.NewRequest(http.MethodDelete, tc.path, nil)
+		if tc.token != "" {
+			req.Header.Set("Authorization", "Bearer "+tc.token)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != tc.want {
+			t.Fatalf("DELETE %s (token %q): expected %d, got %d", tc.path, tc.token, tc.want, rec.Code)
+		}
+	}
+	fmt.Println("✓ Bad ids rejected; force refused without the admin token")
+}
+
+func TestDelayKeys(t *testing.T) {
+	_, teardown := setupTestServer(t)
+	defer teardown()
+
+	fmt.Println("\n=== Test: delay_ms And The Deprecated delay Key ===")
+
+	current := enqueueMessage(t, "delay-keys", map[string]interface{}{"body": map[string]int{"n": 0}, "delay_ms": 600000})
+	legacy := enqueueMessage(t, "delay-keys", map[string]interface{}{"body": map[string]int{"n": 1}, "delay": 300000})
+
+	status, out := doJSON(t, http.MethodGet, "/v1/queues/delay-keys/delayed", nil)
+	list, _ := out["messages"].([]interface{})
+	if status != http.StatusOK || len(list) != 2 {
+		t.Fatalf("Expected both messages delayed, got %d %v", status, out)
+	}
+	if int64(list[0].(map[string]interface{})["id"].(float64)) != legacy || int64(list[1].(map[string]interface{})["id"].(float64)) != current {
+		t.Fatalf("Expected the shorter legacy delay first, got %v", list)
+	}
+	fmt.Println("✓ Both keys delay the message")
+
+	for name, payload := range map[string]map[string]interface{}{
+		"both keys": {"body": map[string]int{}, "delay_ms": 1000, "delay": 1000},
+		"negative":  {"body": map[string]int{}, "delay_ms": -1},
+	} {
+		if status, out := doJSON(t, http.MethodPost, "/v1/queues/delay-keys/messages", payload); status != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400, got %d %v", name, status, out)
+		}
+	}
+	fmt.Println("✓ Conflicting or negative delays rejected with 400")
+}
+
+func TestDelayQueue(t *testing.T) {
+	_, teardown := setupTestServer(t)
+	defer teardown()
+
+	fmt.Println("\n=== Test: Delay Queue ===")
+
+	putQueueConfig(t, "delay-queue", map[string]interface{}{"partitions": 1, "default_delay_ms": 600000})
+
+	held := enqueueMessage(t, "delay-queue", map[string]interface{}{"body": map[string]int{"n": 1}})
+	if messages := receiveMessages(t, "delay-queue", 10, 30000); len(messages) != 0 {
+		t.Fatalf("Expected nothing claimable on a delay queue, got %v", messages)
+	}
+	_, out := doJSON(t, http.MethodGet, "/v1/queues/delay-queue/delayed", nil)
+	list, _ := out["messages"].([]interface{})
+	if len(list) != 1 || int64(list[0].(map[string]interface{})["id"].(float64)) != held {
+		t.Fatalf("Expected message %d held back by the default delay, got %v", held, out)
+	}
+	fmt.Println("✓ Enqueue without a delay takes the queue's default")
+
+	now := enqueueMessage(t, "delay-queue", map[string]interface{}{"body": map[string]int{"n": 2}, "delay_ms": 0})
+	messages := receiveMessages(t, "delay-queue", 10, 30000)
+	if len(messages) != 1 || int64(messages[0]["id"].(float64)) != now {
+		t.Fatalf("Expected only message %d, enqueued with delay_ms 0, got %v", now, messages)
+	}
+	ackMessage(t, messages[0])
+	fmt.Println("✓ An explicit delay_ms overrides it")
+
+	scheduled := enqueueMessage(t, "delay-queue", map[string]interface{}{"body": map[string]int{"n": 3}, "not_before": time.Now().Add(-10 * time.Second).Format(time.RFC3339)})
+	messages = receiveMessages(t, "delay-queue", 10, 30000)
+	if len(messages) != 1 || int64(messages[0]["id"].(float64)) != scheduled {
+		t.Fatalf("Expected message %d, scheduled by not_before, got %v", scheduled, messages)
+	}
+	ackMessage(t, messages[0])
+	fmt.Println("✓ not_before overrides it too")
+
+	if status, _ := doJSON(t, http.MethodPut, "/v1/queues/delay-queue/config", map[string]interface{}{"default_delay_ms": -1}); status != http.StatusBadRequest {
+		t.Fatalf("Expected 400 for a negative default_delay_ms, got %d", status)
+	}
+	_, out = doJSON(t, http.MethodGet, "/v1/queues/delay-queue/attributes", nil)
+	if out["default_delay_ms"] != float64(600000) {
+		t.Fatalf("Expected default_delay_ms in the attributes, got %v", out)
+	}
+	fmt.Println("✓ Attributes report the default; negative defaults rejected")
+}
@@ -0,0 +1,58 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestReceiveDepthHeader(t *testing.T) {
+	_, teardown := setupTestServer(t)
+	defer teardown()
+
+	fmt.Println("\n=== Test: Receive Depth Header ===")
+
+	q := "depth-header-test"
+	for i := 0; i < 20; i++ {
+		enqueueMessage(t, q, map[string]interface{}{"body": map[string]int{"n": i}})
+	}
+
+	receive := func(max int) int {
+		body, _ := json.Marshal(map[string]interface{}{"max": max, "visibility_ms": 30000})
+		resp, err := http.Post(fmt.Sprintf("http://localhost:9999/v1/queues/%s:receive", q),
+			"application/json", bytes.NewReader(body))
+		if err != nil {
+			t.Fatalf("Receive failed: %v", err)
+		}
+		defer resp.Body.Close()
+		depth, err := strconv.Atoi(resp.Header.Get("X-Approx-Queue-Depth"))
+		if err != nil {
+			t.Fatalf("Expected a numeric X-Approx-Queue-Depth, got %q", resp.Header.Get("X-Approx-Queue-Depth"))
+		}
+		return depth
+	}
+
+	if depth := receive(5); depth != 15 {
+		t.Fatalf("Expected depth 15 after claiming 5 of 20, got %d", depth)
+	}
+	fmt.Println("✓ First receive reports the 15 left")
+
+	// within the cache window the claim is subtracted, not recounted
+	if depth := receive(5); depth != 10 {
+		t.Fatalf("Expected depth 10 after claiming 5 more, got %d", depth)
+	}
+	fmt.Println("✓ Cached depth follows receives")
+
+	for i := 0; i < 10; i++ {
+		enqueueMessage(t, q, map[string]interface{}{"body": map[string]int{"n": i}})
+	}
+	time.Sleep(1100 * time.Millisecond)
+	if depth := receive(1); depth != 19 {
+		t.Fatalf("Expected depth 19 once recounted after 10 more enqueues, got %d", depth)
+	}
+	fmt.Println("✓ Depth recounted after the cache window picks up new enqueues")
+}
@@ -0,0 +1,107 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aridsondez/AWS-SQS-LITE/internal/queue"
+	"github.com/aridsondez/AWS-SQS-LITE/internal/testutil"
+)
+
+func TestDiagnoseCounts(t *testing.T) {
+	ctx := context.Background()
+	s, teardown := testutil.SetupStore(t)
+	defer teardown()
+
+	fmt.Println("\n=== Test: Diagnose Counts ===")
+
+	enqueue := func(delay time.Duration) int64 {
+		t.Helper()
+		id, err := s.Enqueue(ctx, queue.Message{Queue: "diag-counts", Body: []byte(`{}`)}, delay)
+		if err != nil {
+			t.Fatalf("Enqueue failed: %v", err)
+		}
+		return id
+	}
+	claim := func(vis time.Duration) {
+		t.Helper()
+		if got, err := s.Claim(ctx, queue.ClaimOptions{Queue: "diag-counts", Limit: 1, Visibility: vis}); err != nil || len(got) != 1 {
+			t.Fatalf("Expected to claim 1, got %d (%v)", len(got), err)
+		}
+	}
+
+	// no background sweeper here, so an expired lease stays expired
+	enqueue(0)
+	claim(time.Millisecond)
+	enqueue(0)
+	claim(time.Hour)
+	backedOff := enqueue(0)
+	if _, err := s.Pool.Exec(ctx, `UPDATE messages SET delivery_count = 1, not_before = now() + interval '1 hour' WHERE id = $1`, backedOff); err != nil {
+		t.Fatalf("Backoff failed: %v", err)
+	}
+	enqueue(time.Hour)
+	enqueue(0)
+	time.Sleep(20 * time.Millisecond)
+
+	d, err := s.Diagnose(ctx, "diag-counts")
+	if err != nil {
+		t.Fatalf("Diagnose failed: %v", err)
+	}
+	if d.Available != 1 || d.Leased != 1 || d.LeaseExpired != 1 || d.Delayed != 1 || d.BackedOff != 1 {
+		t.Fatalf("Expected one message in each state, got %+v", d)
+	}
+	if d.NextVisible == nil || time.Until(*d.NextVisible) < 50*time.Minute {
+		t.Fatalf("Expected the next visible time about an hour out, got %v", d.NextVisible)
+	}
+	fmt.Println("✓ Each state counted on its own")
+}
+
+func TestDiagnoseEndpoint(t *testing.T) {
+	_, teardown := setupTestServer(t)
+	defer teardown()
+
+	fmt.Println("\n=== Test: Diagnose Endpoint ===")
+
+	diagnose := func(wantClaimable int, wantReasons ...string) {
+		t.Helper()
+		status, out := doJSON(t, http.MethodGet, "/v1/queues/diag-test/diagnose", nil)
+		if status != http.StatusOK {
+			t.Fatalf("Diagnose failed: %d %v", status, out)
+		}
+		var reasons []string
+		for _, r := range out["reasons"].([]interface{}) {
+			reasons = append(reasons, r.(string))
+		}
+		if out["claimable"] != float64(wantClaimable) || !reflect.DeepEqual(reasons, wantReasons) {
+			t.Fatalf("Expected claimable %d with reasons %v, got %v", wantClaimable, wantReasons, out)
+		}
+	}
+
+	diagnose(0, "empty")
+	fmt.Println("✓ Empty queue says so")
+
+	enqueueMessage(t, "diag-test", map[string]interface{}{"body": map[string]int{"n": 1}})
+	enqueueMessage(t, "diag-test", map[string]interface{}{"body": map[string]int{"n": 2}, "delay_ms": 3600000})
+	leased := receiveMessages(t, "diag-test", 1, 300000)
+	diagnose(0, "leased", "delayed")
+	fmt.Println("✓ Leased and delayed messages explained")
+
+	putQueueConfig(t, "diag-test", map[string]interface{}{"partitions": 1, "max_in_flight": 1})
+	enqueueMessage(t, "diag-test", map[string]interface{}{"body": map[string]int{"n": 3}})
+	diagnose(0, "max_in_flight", "leased", "delayed")
+	fmt.Println("✓ Full in-flight budget explained")
+
+	putQueueConfig(t, "diag-test", map[string]interface{}{"partitions": 1})
+	diagnose(1, "leased", "delayed")
+	if status, _ := doJSON(t, http.MethodPost, "/v1/queues/diag-test:pause", nil); status != http.StatusOK {
+		t.Fatalf("Pause failed: %d", status)
+	}
+	diagnose(0, "paused", "leased", "delayed")
+	fmt.Println("✓ Paused queue explained")
+
+	ackMessage(t, leased[0])
+}
@@ -0,0 +1,111 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/aridsondez/AWS-SQS-LITE/internal/queue"
+)
+
+// categoryRouter dead-letters by the body's "category", dropping spam.
+type categoryRouter struct{}
+
+func (categoryRouter) RouteDLQ(_ context.Context, msg queue.Message) (queue.DLQRoute, error) {
+	var body struct {
+		Category string `json:"category"`
+	}
+	if err := json.Unmarshal(msg.Body, &body); err != nil {
+		return queue.DLQRoute{}, err
+	}
+	switch body.Category {
+	case "payments":
+		return queue.DLQRoute{DLQ: "router-payments-dlq"}, nil
+	case "spam":
+		return queue.DLQRoute{Drop: true}, nil
+	}
+	return queue.DLQRoute{DLQ: "router-general-dlq"}, nil
+}
+
+func TestDLQRouterRoutesByAttribute(t *testing.T) {
+	db, teardown := setupTestServerWithSweep(t, testConfig(), queue.SweepOptions{Router: categoryRouter{}})
+	defer teardown()
+
+	fmt.Println("\n=== Test: DLQ Router Routes By Attribute ===")
+
+	ids := make(map[string]int64)
+	for _, category := range []string{"payments", "email", "spam"} {
+		// the static dlq is ignored once a router is set
+		ids[category] = enqueueMessage(t, "router-src", map[string]interface{}{
+			"body":        map[string]string{"category": category},
+			"max_retries": 1,
+			"dlq":         "router-static-dlq",
+		})
+	}
+	if msgs := receiveMessages(t, "router-src", 3, 200); len(msgs) != 3 {
+		t.Fatalf("Expected 3 messages, got %d", len(msgs))
+	}
+
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		var left int
+		if err := db.Pool.QueryRow(context.Background(),
+			`SELECT count(*) FROM messages WHERE queue = 'router-src'`).Scan(&left); err != nil {
+			t.Fatalf("Count source queue: %v", err)
+		}
+		if left == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the sweeper to route every message, %d left", left)
+		}
+		time.Sleep(250 * time.Millisecond)
+	}
+
+	for dlq, category := range map[string]string{"router-payments-dlq": "payments", "router-general-dlq": "email"} {
+		_, out := doJSON(t, http.MethodGet, "/v1/queues/"+dlq+"/failures", nil)
+		failures, _ := out["failures"].([]interface{})
+		if len(failures) != 1 {
+			t.Fatalf("Expected 1 dead letter in %s, got %v", dlq, out)
+		}
+		f := failures[0].(map[string]interface{})
+		if f["body"].(map[string]interface{})["category"] != category || f["source_queue"] != "router-src" {
+			t.Fatalf("Expected the %s message in %s, got %v", category, dlq, f)
+		}
+	}
+	_, out := doJSON(t, http.MethodGet, "/v1/queues/router-static-dlq/failures", nil)
+	if failures, _ := out["failures"].([]interface{}); len(failures) != 0 {
+		t.Fatalf("Expected nothing in the static DLQ, got %v", out)
+	}
+	var spam int
+	db.Pool.QueryRow(context.Background(), `SELECT count(*) FROM messages WHERE id = $1`, ids["spam"]).Scan(&spam)
+	if spam != 0 {
+		t.Fatal("Expected the spam message dropped")
+	}
+	fmt.Println("✓ Payments and other failures dead-lettered apart; spam dropped")
+}
+
+func TestDefaultDLQRouter(t *testing.T) {
+	fmt.Println("\n=== Test: Default DLQ Router ===")
+
+	dlq := "orders-dlq"
+	rules := []queue.DLQRule{{MaxDeliveries: 1, DLQ: "orders-fast-fail"}, {MinDeliveries: 3, MaxDeliveries: 4, DLQ: "orders-slow"}}
+	for _, tc := range []struct {
+		msg  queue.Message
+		want queue.DLQRoute
+	}{
+		{queue.Message{DeliveryCount: 1, DLQ: &dlq, DLQRules: rules}, queue.DLQRoute{DLQ: "orders-fast-fail"}},
+		{queue.Message{DeliveryCount: 4, DLQ: &dlq, DLQRules: rules}, queue.DLQRoute{DLQ: "orders-slow"}},
+		{queue.Message{DeliveryCount: 2, DLQ: &dlq, DLQRules: rules}, queue.DLQRoute{DLQ: dlq}},
+		{queue.Message{DeliveryCount: 5, DLQRules: rules}, queue.DLQRoute{}},
+	} {
+		got, err := queue.DefaultDLQRouter{}.RouteDLQ(context.Background(), tc.msg)
+		if err != nil || got != tc.want {
+			t.Fatalf("Deliveries %d: expected %+v, got %+v, %v", tc.msg.DeliveryCount, tc.want, got, err)
+		}
+	}
+	fmt.Println("✓ Rules by delivery count first, then dlq, else no DLQ")
+}
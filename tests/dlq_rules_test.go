@@ -0,0 +1,91 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestDLQRoutingRules(t *testing.T) {
+	_, teardown := setupTestServer(t)
+	defer teardown()
+
+	fmt.Println("\n=== Test: DLQ Routing Rules ===")
+
+	rules := []map[string]interface{}{
+		{"max_deliveries": 1, "dlq": "dlq-a"}, // failed on the first try
+		{"min_deliveries": 2, "dlq": "dlq-b"}, // failed after retries
+	}
+	enqueueMessage(t, "dlq-rules", map[string]interface{}{
+		"body":        map[string]string{"task": "fast-fail"},
+		"max_retries": 1,
+		"dlq_rules":   rules,
+	})
+	enqueueMessage(t, "dlq-rules", map[string]interface{}{
+		"body":        map[string]string{"task": "slow-fail"},
+		"max_retries": 2,
+		"dlq_rules":   rules,
+	})
+
+	// Let both leases expire twice; the first message dead-letters after one
+	// delivery, the second after two.
+	for i := 1; i <= 2; i++ {
+		messages := receiveMessages(t, "dlq-rules", 2, 1000)
+		if len(messages) == 0 {
+			t.Fatalf("Attempt %d: expected messages to receive", i)
+		}
+		time.Sleep(3 * time.Second)
+	}
+	time.Sleep(2 * time.Second)
+
+	if messages := receiveMessages(t, "dlq-rules", 2, 30000); len(messages) != 0 {
+		t.Fatalf("Expected source queue to be empty, got %d messages", len(messages))
+	}
+
+	for dlq, task := range map[string]string{"dlq-a": "fast-fail", "dlq-b": "slow-fail"} {
+		messages := receiveMessages(t, dlq, 2, 30000)
+		if len(messages) != 1 {
+			t.Fatalf("Expected 1 message in %s, got %d", dlq, len(messages))
+		}
+		body := messages[0]["body"].(map[string]interface{})
+		if body["task"] != task {
+			t.Fatalf("Expected %s in %s, got %v", task, dlq, body["task"])
+		}
+		fmt.Printf("✓ %s routed to %s\n", task, dlq)
+	}
+}
+
+func TestSelfReferentialDLQRejected(t *testing.T) {
+	_, teardown := setupTestServer(t)
+	defer teardown()
+
+	fmt.Println("\n=== Test: Self-Referential DLQ ===")
+
+	cases := map[string]map[string]interface{}{
+		"dlq": {"body": map[string]int{"n": 1}, "dlq": "loop"},
+		"dlq_rules": {"body": map[string]int{"n": 2}, "dlq": "loop-dlq",
+			"dlq_rules": []map[string]interface{}{{"min_deliveries": 2, "dlq": "loop"}}},
+	}
+	for name, payload := range cases {
+		status, out := doJSON(t, http.MethodPost, "/v1/queues/loop/messages", payload)
+		if status != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400, got %d", name, status)
+		}
+		fmt.Printf("✓ %s pointing at the source rejected: %v\n", name, out["error"])
+	}
+
+	if status, _ := doJSON(t, http.MethodPut, "/v1/queues/loop/config", map[string]interface{}{
+		"partitions": 1,
+		"dlq":        "loop",
+	}); status != http.StatusBadRequest {
+		t.Fatalf("Expected 400 for a queue config DLQ'ing into itself, got %d", status)
+	}
+	fmt.Println("✓ Queue config DLQ pointing at itself rejected")
+
+	if status, _ := doJSON(t, http.MethodPost, "/v1/queues/loop/messages",
+		map[string]interface{}{"body": map[string]int{"n": 3}, "dlq": "loop-dlq"}); status != http.StatusCreated {
+		t.Fatalf("Expected 201 for a distinct DLQ, got %d", status)
+	}
+	fmt.Println("✓ A distinct DLQ is still accepted")
+}
@@ -0,0 +1,121 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aridsondez/AWS-SQS-LITE/pkg/worker"
+)
+
+func TestReceiveEnvelope(t *testing.T) {
+	_, teardown := setupTestServer(t)
+	defer teardown()
+
+	fmt.Println("\n=== Test: Receive Response Formats ===")
+
+	for i := 0; i < 2; i++ {
+		enqueueMessage(t, "envelope", map[string]interface{}{"body": map[string]int{"n": i}})
+	}
+
+	receive := func(accept string) map[string]interface{} {
+		body, _ := json.Marshal(map[string]interface{}{"max": 1, "visibility_ms": 30000})
+		req, _ := http.NewRequest(http.MethodPost, "http://localhost:9999/v1/queues/envelope:receive", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Receive failed: %v", err)
+		}
+		defer resp.Body.Close()
+		var raw json.RawMessage
+		json.NewDecoder(resp.Body).Decode(&raw)
+		var out map[string]interface{}
+		if err := json.Unmarshal(raw, &out); err != nil {
+			return map[string]interface{}{"array": string(raw)}
+		}
+		return out
+	}
+
+	if got := receive(""); got["array"] == nil || !strings.HasPrefix(got["array"].(string), "[") {
+		t.Fatalf("Expected a bare array by default, got %v", got)
+	}
+	fmt.Println("✓ Default response is a bare array")
+
+	got := receive("application/json, application/vnd.sqslite.envelope+json")
+	if got["count"] != float64(1) || len(got["messages"].([]interface{})) != 1 {
+		t.Fatalf("Expected an envelope with one message, got %v", got)
+	}
+	fmt.Println("✓ Accept variant returns {messages, count}")
+
+	got = receive("application/vnd.sqslite.envelope+json")
+	if got["count"] != float64(0) || got["messages"] == nil {
+		t.Fatalf("Expected an empty envelope with messages: [], got %v", got)
+	}
+	fmt.Println("✓ Empty receive is {messages: [], count: 0}")
+}
+
+func TestReceiveEnvelopeByConfig(t *testing.T) {
+	cfg := testConfig()
+	cfg.ReceiveEnvelope = true
+	_, teardown := setupTestServerWithConfig(t, cfg)
+	defer teardown()
+
+	fmt.Println("\n=== Test: Receive Envelope By Config ===")
+
+	enqueueMessage(t, "envelope-cfg", map[string]interface{}{"body": map[string]int{"n": 1}})
+	status, got := doJSON(t, http.MethodPost, "/v1/queues/envelope-cfg:receive", map[string]interface{}{"max": 5})
+	if status != http.StatusOK || got["count"] != float64(1) {
+		t.Fatalf("Expected an envelope without asking, got %d %v", status, got)
+	}
+	fmt.Println("✓ RECEIVE_ENVELOPE wraps every receive")
+}
+
+func TestWorkerReadsEnvelope(t *testing.T) {
+	fmt.Println("\n=== Test: Worker Reads Both Response Formats ===")
+
+	for _, shape := range []string{"array", "envelope"} {
+		var served, acked atomic.Int64
+		fake := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch {
+			case strings.HasSuffix(r.URL.Path, ":receive"):
+				var out []map[string]interface{}
+				if served.Add(1) == 1 {
+					out = append(out, map[string]interface{}{"id": 1, "body": "{}", "receipt": "1.1"})
+				}
+				if shape == "envelope" {
+					json.NewEncoder(w).Encode(map[string]interface{}{"messages": out, "count": len(out)})
+					return
+				}
+				json.NewEncoder(w).Encode(out)
+			case strings.HasSuffix(r.URL.Path, ":ack"):
+				acked.Add(1)
+				w.Write([]byte(`{"ok":true}`))
+			}
+		}))
+
+		w := worker.New(worker.Config{BaseURL: fake.URL, PollDelay: 20 * time.Millisecond})
+		w.Handle("shapes", func(ctx context.Context, msg *worker.Message) error { return nil })
+		ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+		go w.Run(ctx)
+		for acked.Load() == 0 && ctx.Err() == nil {
+			time.Sleep(10 * time.Millisecond)
+		}
+		cancel()
+		fake.Close()
+
+		if acked.Load() != 1 {
+			t.Fatalf("Expected the worker to process the %s response, acked %d", shape, acked.Load())
+		}
+		fmt.Printf("✓ Worker processed a message from the %s shape\n", shape)
+	}
+}
@@ -0,0 +1,86 @@
+package tests
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEventStream(t *testing.T) {
+	_, teardown := setupTestServer(t)
+	defer teardown()
+
+	fmt.Println("\n=== Test: SSE Event Stream ===")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	req, _ := http.NewRequestWithContext(ctx, "GET", "http://localhost:9999/v1/queues/events-test/events", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Expected text/event-stream, got %q", ct)
+	}
+
+	got := make(chan map[string]interface{}, 8)
+	go func() {
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			line := scanner.Text()
+			if !strings.HasPrefix(line, "data: ") {
+				continue
+			}
+			var ev map[string]interface{}
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &ev); err == nil {
+				got <- ev
+			}
+		}
+		close(got)
+	}()
+
+	msgID := enqueueMessage(t, "events-test", map[string]interface{}{
+		"body": map[string]string{"task": "observe-me"},
+	})
+	messages := receiveMessages(t, "events-test", 1, 30000)
+	if len(messages) != 1 {
+		t.Fatalf("Expected 1 message, got %d", len(messages))
+	}
+	ackMessage(t, messages[0])
+
+	for _, want := range []string{"enqueued", "received", "acked"} {
+		select {
+		case ev, ok := <-got:
+			if !ok {
+				t.Fatalf("Stream closed before %s event", want)
+			}
+			if ev["type"] != want {
+				t.Fatalf("Expected %s event, got %v", want, ev["type"])
+			}
+			if int64(ev["id"].(float64)) != msgID {
+				t.Fatalf("Expected event for message %d, got %v", msgID, ev["id"])
+			}
+			assertUTCTimestamp(t, "at", ev["at"])
+			fmt.Printf("✓ Observed %s event\n", want)
+		case <-ctx.Done():
+			t.Fatalf("Timed out waiting for %s event", want)
+		}
+	}
+
+	// Observing must not lease anything: a fresh message stays claimable.
+	enqueueMessage(t, "events-test", map[string]interface{}{
+		"body": map[string]string{"task": "still-available"},
+	})
+	if messages := receiveMessages(t, "events-test", 1, 30000); len(messages) != 1 {
+		t.Fatalf("Expected message to remain claimable while observed, got %d", len(messages))
+	}
+	fmt.Println("✓ Stream did not lease messages")
+}
@@ -0,0 +1,228 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aridsondez/AWS-SQS-LITE/pkg/worker"
+)
+
+func TestExtendBatch(t *testing.T) {
+	db, teardown := setupTestServer(t)
+	defer teardown()
+
+	fmt.Println("\n=== Test: Batched Lease Extension ===")
+
+	for i := 0; i < 3; i++ {
+		enqueueMessage(t, "extend-test", map[string]interface{}{"body": map[string]int{"n": i}})
+	}
+
+	// Two long leases and one that expires before we extend.
+	live := receiveMessages(t, "extend-test", 2, 30000)
+	if len(live) != 2 {
+		t.Fatalf("Expected 2 messages, got %d", len(live))
+	}
+	expired := receiveMessages(t, "extend-test", 1, 200)
+	if len(expired) != 1 {
+		t.Fatalf("Expected 1 message, got %d", len(expired))
+	}
+	time.Sleep(300 * time.Millisecond)
+
+	receipts := []string{
+		live[0]["receipt"].(string),
+		live[1]["receipt"].(string),
+		expired[0]["receipt"].(string),
+		"not-a-receipt",
+	}
+	status, result := extendBatch(t, receipts, 120000)
+	if status != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", status)
+	}
+
+	want := map[int64]bool{
+		int64(live[0]["id"].(float64)): true,
+		int64(live[1]["id"].(float64)): true,
+	}
+	if len(result.Extended) != 2 || !want[result.Extended[0]] || !want[result.Extended[1]] {
+		t.Fatalf("Expected %v extended, got %v", want, result.Extended)
+	}
+	fmt.Printf("✓ Extended live leases: %v\n", result.Extended)
+
+	if len(result.Failed) != 2 {
+		t.Fatalf("Expected 2 failures, got %+v", result.Failed)
+	}
+	failed := map[string]bool{}
+	for _, f := range result.Failed {
+		failed[f.Receipt] = true
+	}
+	if !failed[expired[0]["receipt"].(string)] || !failed["not-a-receipt"] {
+		t.Fatalf("Expected expired and malformed receipts to fail, got %+v", result.Failed)
+	}
+	fmt.Println("✓ Expired and malformed receipts reported as failed")
+
+	var minLease time.Duration
+	err := db.Pool.QueryRow(context.Background(),
+		`SELECT min(lease_until - now()) FROM messages WHERE id = ANY($1)`,
+		result.Extended).Scan(&minLease)
+	if err != nil {
+		t.Fatalf("Query lease: %v", err)
+	}
+	if minLease < 60*time.Second {
+		t.Fatalf("Expected leases pushed to ~120s, shortest is %v", minLease)
+	}
+	fmt.Printf("✓ Leases moved forward (shortest remaining %v)\n", minLease.Round(time.Second))
+
+	if status, _ := extendBatch(t, nil, 1000); status != http.StatusBadRequest {
+		t.Fatalf("Expected 400 for empty receipts, got %d", status)
+	}
+	fmt.Println("✓ Empty request rejected")
+}
+
+func TestReleaseBatch(t *testing.T) {
+	_, teardown := setupTestServer(t)
+	defer teardown()
+
+	fmt.Println("\n=== Test: Batched Lease Release ===")
+
+	for i := 0; i < 2; i++ {
+		enqueueMessage(t, "release-test", map[string]interface{}{"body": map[string]int{"n": i}})
+	}
+	held := receiveMessages(t, "release-test", 2, 30000)
+	if len(held) != 2 {
+		t.Fatalf("Expected 2 messages, got %d", len(held))
+	}
+
+	status, out := doJSON(t, http.MethodPost, "/v1/messages:release-batch", map[string]interface{}{
+		"receipts": []string{held[0]["receipt"].(string), held[1]["receipt"].(string), "not-a-receipt"},
+	})
+	if status != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", status)
+	}
+	if released, _ := out["released"].([]interface{}); len(released) != 2 {
+		t.Fatalf("Expected 2 released, got %v", out)
+	}
+	if failed, _ := out["failed"].([]interface{}); len(failed) != 1 {
+		t.Fatalf("Expected the malformed receipt to fail, got %v", out)
+	}
+	fmt.Println("✓ Released both leases; malformed receipt reported")
+
+	again := receiveMessages(t, "release-test", 2, 30000)
+	if len(again) != 2 {
+		t.Fatalf("Expected both messages back at once, got %d", len(again))
+	}
+	for _, m := range again {
+		if m["delivery_count"] != float64(1) {
+			t.Fatalf("Expected the released delivery not to count, got %v", m["delivery_count"])
+		}
+	}
+	fmt.Println("✓ Released messages redelivered at once, uncounted")
+}
+
+func TestWorkerAutoExtend(t *testing.T) {
+	fmt.Println("\n=== Test: Worker Auto-Extend ===")
+
+	var (
+		mu      sync.Mutex
+		served  bool
+		extends [][]string
+		acked   []string
+	)
+	fake := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case strings.HasSuffix(r.URL.Path, ":receive"):
+			if served {
+				w.Write([]byte(`[]`))
+				return
+			}
+			served = true
+			w.Write([]byte(`[{"id":1,"body":{},"receipt":"1.1"},{"id":2,"body":{},"receipt":"2.1"}]`))
+		case strings.HasSuffix(r.URL.Path, ":extend-batch"):
+			var req struct {
+				Receipts []string `json:"receipts"`
+			}
+			json.NewDecoder(r.Body).Decode(&req)
+			extends = append(extends, req.Receipts)
+			w.Write([]byte(`{"extended":[1,2],"receipts":{"1":"1.1.4102444800000","2":"2.1.4102444800000"},"failed":[]}`))
+		case strings.HasSuffix(r.URL.Path, ":ack"):
+			var req struct {
+				Receipt string `json:"receipt"`
+			}
+			json.NewDecoder(r.Body).Decode(&req)
+			acked = append(acked, req.Receipt)
+			w.Write([]byte(`{"ok":true}`))
+		}
+	}))
+	defer fake.Close()
+
+	w := worker.New(worker.Config{
+		BaseURL:     fake.URL,
+		PollDelay:   10 * time.Millisecond,
+		Concurrency: 2,
+		Visibility:  100 * time.Millisecond,
+		AutoExtend:  true,
+	})
+	w.Handle("auto-extend-test", func(ctx context.Context, msg *worker.Message) error {
+		time.Sleep(300 * time.Millisecond) // outlives the visibility timeout
+		return ctx.Err()
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	w.Run(ctx)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(extends) == 0 {
+		t.Fatal("Expected the worker to extend leases")
+	}
+	for _, receipts := range extends {
+		if len(receipts) > 2 {
+			t.Fatalf("Expected at most the 2 running messages per call, got %v", receipts)
+		}
+	}
+	if len(extends[0]) != 2 {
+		t.Fatalf("Expected both running leases in one call, got %v", extends[0])
+	}
+	fmt.Printf("✓ %d extend-batch call(s), first renewed %v\n", len(extends), extends[0])
+
+	for _, receipt := range acked {
+		if !strings.HasSuffix(receipt, ".4102444800000") {
+			t.Fatalf("Expected acks to use the renewed receipts, got %v", acked)
+		}
+	}
+	fmt.Printf("✓ Acked with renewed receipts %v\n", acked)
+}
+
+type extendBatchResult struct {
+	Extended []int64 `json:"extended"`
+	Failed   []struct {
+		Receipt string `json:"receipt"`
+		Error   string `json:"error"`
+	} `json:"failed"`
+}
+
+func extendBatch(t *testing.T, receipts []string, visibilityMS int) (int, extendBatchResult) {
+	body, _ := json.Marshal(map[string]interface{}{
+		"receipts":      receipts,
+		"visibility_ms": visibilityMS,
+	})
+	resp, err := http.Post("http://localhost:9999/v1/messages:extend-batch", "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("Extend batch failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var result extendBatchResult
+	json.NewDecoder(resp.Body).Decode(&result)
+	return resp.StatusCode, result
+}
@@ -0,0 +1,190 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestDLQFailureContext(t *testing.T) {
+	_, teardown := setupTestServer(t)
+	defer teardown()
+
+	fmt.Println("\n=== Test: DLQ Failure Context ===")
+
+	id := enqueueMessage(t, "fail-src", map[string]interface{}{
+		"body":        map[string]string{"task": "charge-card"},
+		"max_retries": 1,
+		"dlq":         "fail-dlq",
+	})
+	messages := receiveMessages(t, "fail-src", 1, 30000)
+	if len(messages) != 1 {
+		t.Fatalf("Expected 1 message, got %d", len(messages))
+	}
+
+	status, _ := doJSON(t, http.MethodPost, fmt.Sprintf("/v1/messages/%d:nack", id), map[string]interface{}{
+		"receipt": messages[0]["receipt"],
+		"error":   "payment gateway returned 503",
+	})
+	if status != http.StatusOK {
+		t.Fatalf("Expected 200 from nack, got %d", status)
+	}
+	fmt.Println("✓ Nacked with a reason")
+
+	// The nack ended the lease; at max_retries the next sweep dead-letters it.
+	time.Sleep(3 * time.Second)
+
+	status, out := doJSON(t, http.MethodGet, "/v1/queues/fail-dlq/failures?limit=5", nil)
+	if status != http.StatusOK {
+		t.Fatalf("Expected 200 from failures, got %d", status)
+	}
+	failures, _ := out["failures"].([]interface{})
+	if len(failures) != 1 {
+		t.Fatalf("Expected 1 failure record, got %v", out)
+	}
+	f := failures[0].(map[string]interface{})
+	if f["last_error"] != "payment gateway returned 503" {
+		t.Fatalf("Expected the nack reason kept, got %v", f["last_error"])
+	}
+	if f["source_queue"] != "fail-src" || f["deliveries"] != float64(1) {
+		t.Fatalf("Expected source fail-src after 1 delivery, got %v / %v", f["source_queue"], f["deliveries"])
+	}
+	if f["failed_at"] == nil || f["dead_lettered_at"] == nil {
+		t.Fatalf("Expected failed_at and dead_lettered_at, got %v", f)
+	}
+	if body := f["body"].(map[string]interface{}); body["task"] != "charge-card" {
+		t.Fatalf("Expected the original body, got %v", body)
+	}
+	fmt.Printf("✓ Failure context intact: %v from %v\n", f["last_error"], f["source_queue"])
+
+	// Peeking holds no lease: the dead letter is still there to receive.
+	if dead := receiveMessages(t, "fail-dlq", 1, 30000); len(dead) != 1 {
+		t.Fatalf("Expected the dead letter still receivable, got %d", len(dead))
+	}
+	fmt.Println("✓ Peek left the dead letter in place")
+
+	if status, _ := doJSON(t, http.MethodGet, "/v1/queues/fail-dlq/failures?limit=0", nil); status != http.StatusBadRequest {
+		t.Fatalf("Expected 400 for limit=0, got %d", status)
+	}
+	if status, _ := doJSON(t, http.MethodPost, fmt.Sprintf("/v1/messages/%d:nack", id), map[string]interface{}{
+		"receipt": messages[0]["receipt"],
+	}); status != http.StatusNotFound {
+		t.Fatalf("Expected 404 nacking a message that has moved on, got %d", status)
+	}
+	fmt.Println("✓ Bad limit and dead receipts rejected")
+}
+
+func TestTerminalNackDeadLettersAtOnce(t *testing.T) {
+	_, teardown := setupTestServer(t)
+	defer teardown()
+
+	fmt.Println("\n=== Test: Terminal Nack Dead-Letters At Once ===")
+
+	id := enqueueMessage(t, "terminal-src", map[string]interface{}{
+		"body":        map[string]string{"task": "parse-invoice"},
+		"max_retries": 2,
+		"dlq":         "terminal-dlq",
+	})
+
+	// With a retry left, a terminal nack is an ordinary one.
+	messages := receiveMessages(t, "terminal-src", 1, 30000)
+	if len(messages) != 1 {
+		t.Fatalf("Expected 1 message, got %d", len(messages))
+	}
+	status, out := doJSON(t, http.MethodPost, fmt.Sprintf("/v1/messages/%d:nack", id), map[string]interface{}{
+		"receipt":  messages[0]["receipt"],
+		"error":    "malformed invoice",
+		"terminal": true,
+	})
+	if status != http.StatusOK || out["dlq"] != nil {
+		t.Fatalf("Expected a plain nack before the retry limit, got %d %v", status, out)
+	}
+	fmt.Println("✓ Terminal nack with retries left only ends the lease")
+
+	messages = receiveEventually(t, "terminal-src", 30000)
+	if messages[0]["delivery_count"].(float64) != 2 {
+		t.Fatalf("Expected the second delivery, got %v", messages[0]["delivery_count"])
+	}
+
+	// At the limit it goes straight to the DLQ, before any sweep.
+	status, out = doJSON(t, http.MethodPost, fmt.Sprintf("/v1/messages/%d:nack", id), map[string]interface{}{
+		"receipt":  messages[0]["receipt"],
+		"error":    "malformed invoice",
+		"terminal": true,
+	})
+	if status != http.StatusOK || out["dlq"] != "terminal-dlq" {
+		t.Fatalf("Expected the message dead-lettered to terminal-dlq, got %d %v", status, out)
+	}
+
+	_, out = doJSON(t, http.MethodGet, "/v1/queues/terminal-dlq/failures", nil)
+	failures, _ := out["failures"].([]interface{})
+	if len(failures) != 1 {
+		t.Fatalf("Expected 1 failure record right after the nack, got %v", out)
+	}
+	f := failures[0].(map[string]interface{})
+	if f["last_error"] != "malformed invoice" || f["source_queue"] != "terminal-src" || f["deliveries"] != float64(2) {
+		t.Fatalf("Unexpected failure context %v", f)
+	}
+	if msgs := receiveMessages(t, "terminal-src", 1, 30000); len(msgs) != 0 {
+		t.Fatalf("Expected terminal-src empty, got %d", len(msgs))
+	}
+	fmt.Println("✓ Terminal nack at the retry limit dead-letters without a sweep")
+}
+
+func TestRedriveDefaultsToSourceQueue(t *testing.T) {
+	_, teardown := setupTestServer(t)
+	defer teardown()
+
+	fmt.Println("\n=== Test: Redrive Defaults To Source Queue ===")
+
+	deadLetter := func(queue string) int64 {
+		id := enqueueMessage(t, queue, map[string]interface{}{
+			"body":        map[string]string{"task": "sync"},
+			"max_retries": 1,
+			"dlq":         "redrive-dlq",
+		})
+		messages := receiveMessages(t, queue, 1, 30000)
+		if len(messages) != 1 {
+			t.Fatalf("Expected 1 message in %s, got %d", queue, len(messages))
+		}
+		status, out := doJSON(t, http.MethodPost, fmt.Sprintf("/v1/messages/%d:nack", id), map[string]interface{}{
+			"receipt":  messages[0]["receipt"],
+			"terminal": true,
+		})
+		if status != http.StatusOK || out["dlq"] != "redrive-dlq" {
+			t.Fatalf("Expected message %d dead-lettered, got %d %v", id, status, out)
+		}
+		return id
+	}
+
+	// Two sources share the DLQ; each dead letter goes home on its own.
+	first, second := deadLetter("redrive-src-a"), deadLetter("redrive-src-b")
+	status, out := doJSON(t, http.MethodPost, "/v1/queues/redrive-dlq:redrive", nil)
+	if status != http.StatusOK || out["redriven"] != float64(2) {
+		t.Fatalf("Expected 2 redriven, got %d %v", status, out)
+	}
+	for queue, id := range map[string]int64{"redrive-src-a": first, "redrive-src-b": second} {
+		messages := receiveMessages(t, queue, 1, 30000)
+		if len(messages) != 1 || int64(messages[0]["id"].(float64)) != id {
+			t.Fatalf("Expected message %d back in %s, got %v", id, queue, messages)
+		}
+		ackMessage(t, messages[0])
+	}
+	fmt.Println("✓ Redrive without a target returns each dead letter to its source")
+
+	third := deadLetter("redrive-src-a")
+	if status, _ := doJSON(t, http.MethodPost, "/v1/queues/redrive-dlq:redrive", map[string]string{"target": "redrive-dlq"}); status != http.StatusBadRequest {
+		t.Fatalf("Expected 400 redriving a DLQ into itself, got %d", status)
+	}
+	status, out = doJSON(t, http.MethodPost, "/v1/queues/redrive-dlq:redrive", map[string]string{"target": "redrive-fixed"})
+	if status != http.StatusOK || out["redriven"] != float64(1) {
+		t.Fatalf("Expected 1 redriven, got %d %v", status, out)
+	}
+	messages := receiveMessages(t, "redrive-fixed", 1, 30000)
+	if len(messages) != 1 || int64(messages[0]["id"].(float64)) != third {
+		t.Fatalf("Expected message %d in redrive-fixed, got %v", third, messages)
+	}
+	ackMessage(t, messages[0])
+	fmt.Println("✓ An explicit target overrides the source")
+}
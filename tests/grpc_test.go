@@ -0,0 +1,100 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+
+	"github.com/aridsondez/AWS-SQS-LITE/internal/api"
+	"github.com/aridsondez/AWS-SQS-LITE/internal/testutil"
+	"github.com/aridsondez/AWS-SQS-LITE/pkg/sqslitepb"
+)
+
+func TestGRPCFlow(t *testing.T) {
+	db, closeDB := testutil.SetupStore(t)
+	defer closeDB()
+
+	fmt.Println("\n=== Test: gRPC Enqueue → Receive → Ack ===")
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	srv := api.NewGRPCServer(db, testConfig())
+	go srv.Serve(lis)
+	defer srv.GracefulStop()
+
+	conn, err := grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+	client := sqslitepb.NewQueueServiceClient(conn)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var ids []int64
+	for i := 0; i < 2; i++ {
+		resp, err := client.Enqueue(ctx, &sqslitepb.EnqueueRequest{
+			Queue: "grpc-test",
+			Body:  []byte(fmt.Sprintf(`{"n":%d}`, i)),
+		})
+		if err != nil {
+			t.Fatalf("Enqueue: %v", err)
+		}
+		ids = append(ids, resp.Id)
+		fmt.Printf("✓ Enqueued message ID: %d\n", resp.Id)
+	}
+
+	recv, err := client.Receive(ctx, &sqslitepb.ReceiveRequest{Queue: "grpc-test", Max: 1, VisibilityMs: 30000})
+	if err != nil {
+		t.Fatalf("Receive: %v", err)
+	}
+	if len(recv.Messages) != 1 || recv.Messages[0].Id != ids[0] {
+		t.Fatalf("Expected the first message, got %v", recv.Messages)
+	}
+	first := recv.Messages[0]
+	if _, err := client.Ack(ctx, &sqslitepb.AckRequest{Receipt: first.Receipt}); err != nil {
+		t.Fatalf("Ack: %v", err)
+	}
+	_, err = client.Ack(ctx, &sqslitepb.AckRequest{Receipt: first.Receipt})
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("Expected NotFound acking twice, got %v", err)
+	}
+	fmt.Println("✓ Received and acked over gRPC")
+
+	streamCtx, stopStream := context.WithCancel(ctx)
+	stream, err := client.StreamReceive(streamCtx, &sqslitepb.StreamReceiveRequest{Queue: "grpc-test", Batch: 1, VisibilityMs: 30000})
+	if err != nil {
+		t.Fatalf("StreamReceive: %v", err)
+	}
+	second, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("stream Recv: %v", err)
+	}
+	stopStream()
+	if second.Id != ids[1] || second.LeaseUntil == nil {
+		t.Fatalf("Expected the second message with a lease, got %v", second)
+	}
+	fmt.Printf("✓ Streamed message ID: %d\n", second.Id)
+
+	if _, err := client.Extend(ctx, &sqslitepb.ExtendRequest{Receipt: second.Receipt, VisibilityMs: 60000}); err != nil {
+		t.Fatalf("Extend: %v", err)
+	}
+	if _, err := client.Nack(ctx, &sqslitepb.NackRequest{Receipt: second.Receipt}); err != nil {
+		t.Fatalf("Nack: %v", err)
+	}
+	_, err = client.Extend(ctx, &sqslitepb.ExtendRequest{Receipt: second.Receipt, VisibilityMs: 60000})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Fatalf("Expected FailedPrecondition extending a nacked lease, got %v", err)
+	}
+	fmt.Println("✓ Extend and Nack over gRPC")
+}
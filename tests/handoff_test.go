@@ -0,0 +1,93 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestAckAndEnqueue(t *testing.T) {
+	_, teardown := setupTestServer(t)
+	defer teardown()
+
+	fmt.Println("\n=== Test: Ack And Enqueue ===")
+
+	enqueueMessage(t, "handoff-step1", map[string]interface{}{"body": map[string]string{"order": "o-1"}})
+	messages := receiveMessages(t, "handoff-step1", 1, 30000)
+	if len(messages) != 1 {
+		t.Fatalf("Expected 1 message, got %d", len(messages))
+	}
+	first := messages[0]
+
+	status, out := doJSON(t, http.MethodPost, fmt.Sprintf("/v1/messages/%d:ack-and-enqueue", int64(first["id"].(float64))), map[string]interface{}{
+		"receipt": first["receipt"],
+		"queue":   "handoff-step2",
+		"message": map[string]interface{}{"body": map[string]string{"order": "o-1", "step": "ship"}},
+	})
+	if status != http.StatusCreated || out["ok"] != true {
+		t.Fatalf("Expected ack-and-enqueue to succeed, got %d %v", status, out)
+	}
+	next := receiveMessages(t, "handoff-step2", 1, 30000)
+	if len(next) != 1 || next[0]["id"] != out["id"] {
+		t.Fatalf("Expected message %v in the next queue, got %v", out["id"], next)
+	}
+	ackMessage(t, next[0])
+	if left := receiveMessages(t, "handoff-step1", 1, 30000); len(left) != 0 {
+		t.Fatalf("Expected the first step acked, got %v", left)
+	}
+	fmt.Println("✓ First step acked and next step enqueued")
+
+	// A sealed group refuses the enqueue; the ack must not happen either.
+	enqueueMessage(t, "handoff-step1", map[string]interface{}{"body": map[string]string{"order": "o-2"}})
+	enqueueMessage(t, "handoff-sealed", map[string]interface{}{"body": map[string]int{"n": 0}, "group_id": "o-2"})
+	if status, out := doJSON(t, http.MethodPost, "/v1/queues/handoff-sealed/groups/o-2:seal", nil); status != http.StatusOK {
+		t.Fatalf("Expected the group sealed, got %d %v", status, out)
+	}
+	messages = receiveMessages(t, "handoff-step1", 1, 30000)
+	if len(messages) != 1 {
+		t.Fatalf("Expected 1 message, got %d", len(messages))
+	}
+	second := messages[0]
+	status, _ = doJSON(t, http.MethodPost, fmt.Sprintf("/v1/messages/%d:ack-and-enqueue", int64(second["id"].(float64))), map[string]interface{}{
+		"receipt": second["receipt"],
+		"queue":   "handoff-sealed",
+		"message": map[string]interface{}{"body": map[string]int{"n": 1}, "group_id": "o-2"},
+	})
+	if status != http.StatusConflict {
+		t.Fatalf("Expected 409 enqueueing into a sealed group, got %d", status)
+	}
+	// still leased under the same receipt, so a plain ack goes through
+	ackMessage(t, second)
+	fmt.Println("✓ Failed enqueue left the message unacked")
+
+	status, _ = doJSON(t, http.MethodPost, fmt.Sprintf("/v1/messages/%d:ack-and-enqueue", int64(second["id"].(float64))), map[string]interface{}{
+		"receipt": second["receipt"],
+		"queue":   "handoff-step2",
+		"message": map[string]interface{}{"body": map[string]int{"n": 2}},
+	})
+	if status != http.StatusNotFound {
+		t.Fatalf("Expected 404 for an acked message, got %d", status)
+	}
+	if left := receiveMessages(t, "handoff-step2", 1, 30000); len(left) != 0 {
+		t.Fatalf("Expected nothing enqueued for a failed ack, got %v", left)
+	}
+	fmt.Println("✓ Failed ack enqueued nothing")
+
+	// A successor whose coalesce key is pending is the pending one.
+	pending := enqueueMessage(t, "handoff-coalesce", map[string]interface{}{"body": map[string]int{"n": 0}, "coalesce_key": "reindex"})
+	enqueueMessage(t, "handoff-step1", map[string]interface{}{"body": map[string]string{"order": "o-3"}})
+	messages = receiveMessages(t, "handoff-step1", 1, 30000)
+	if len(messages) != 1 {
+		t.Fatalf("Expected 1 message, got %d", len(messages))
+	}
+	third := messages[0]
+	status, out = doJSON(t, http.MethodPost, fmt.Sprintf("/v1/messages/%d:ack-and-enqueue", int64(third["id"].(float64))), map[string]interface{}{
+		"receipt": third["receipt"],
+		"queue":   "handoff-coalesce",
+		"message": map[string]interface{}{"body": map[string]int{"n": 1}, "coalesce_key": "reindex"},
+	})
+	if status != http.StatusOK || out["id"] != float64(pending) {
+		t.Fatalf("Expected 200 with the pending message %d, got %d %v", pending, status, out)
+	}
+	fmt.Println("✓ Coalesced successor answered 200 with the pending message")
+}
@@ -0,0 +1,72 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestIdempotencyKeyReplay(t *testing.T) {
+	db, teardown := setupTestServer(t)
+	defer teardown()
+
+	fmt.Println("\n=== Test: Idempotency-Key Replay ===")
+
+	payload := map[string]interface{}{
+		"body": map[string]string{"task": "charge-card"},
+	}
+
+	firstID, firstStatus := enqueueWithKey(t, "idem-queue", "order-42-charge", payload)
+	if firstStatus != http.StatusCreated {
+		t.Fatalf("Expected 201 on first enqueue, got %d", firstStatus)
+	}
+	fmt.Printf("✓ First enqueue created message %d\n", firstID)
+
+	secondID, secondStatus := enqueueWithKey(t, "idem-queue", "order-42-charge", payload)
+	if secondStatus != http.StatusOK {
+		t.Fatalf("Expected 200 on replay, got %d", secondStatus)
+	}
+	if secondID != firstID {
+		t.Fatalf("Expected replay to return message %d, got %d", firstID, secondID)
+	}
+	fmt.Printf("✓ Replay returned original message %d with 200\n", secondID)
+
+	var count int
+	err := db.Pool.QueryRow(context.Background(),
+		"SELECT count(*) FROM messages WHERE queue = 'idem-queue'").Scan(&count)
+	if err != nil {
+		t.Fatalf("Count failed: %v", err)
+	}
+	if count != 1 {
+		t.Fatalf("Expected exactly 1 row after replay, got %d", count)
+	}
+	fmt.Println("✓ Only one message stored")
+
+	otherID, otherStatus := enqueueWithKey(t, "idem-queue", "order-43-charge", payload)
+	if otherStatus != http.StatusCreated || otherID == firstID {
+		t.Fatalf("Expected a new message for a different key, got %d (status %d)", otherID, otherStatus)
+	}
+	fmt.Println("✓ Different key enqueues a new message")
+}
+
+func enqueueWithKey(t *testing.T, queue, key string, payload map[string]interface{}) (int64, int) {
+	body, _ := json.Marshal(payload)
+	req, _ := http.NewRequest(http.MethodPost,
+		fmt.Sprintf("http://localhost:9999/v1/queues/%s/messages", queue), bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Idempotency-Key", key)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var result map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&result)
+	id, _ := result["id"].(float64)
+	return int64(id), resp.StatusCode
+}
@@ -0,0 +1,67 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestEnqueueDelayJitter(t *testing.T) {
+	db, teardown := setupTestServer(t)
+	defer teardown()
+
+	fmt.Println("\n=== Test: Delay Jitter ===")
+
+	entries := make([]interface{}, 10)
+	for i := range entries {
+		entries[i] = map[string]interface{}{"body": map[string]int{"n": i}}
+	}
+	status, results := enqueueBatch(t, "jitter", map[string]interface{}{
+		"entries":         entries,
+		"delay_jitter_ms": 60000,
+	})
+	if status != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d %v", status, results)
+	}
+
+	rows, err := db.Pool.Query(context.Background(),
+		`SELECT not_before - enqueued_at FROM messages WHERE queue = 'jitter'`)
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	defer rows.Close()
+	var lo, hi time.Duration
+	seen := make(map[time.Duration]bool)
+	for rows.Next() {
+		var d time.Duration
+		if err := rows.Scan(&d); err != nil {
+			t.Fatalf("Scan failed: %v", err)
+		}
+		if d < 0 || d > 61*time.Second {
+			t.Fatalf("Expected each delay within the 60s window, got %s", d)
+		}
+		if len(seen) == 0 || d < lo {
+			lo = d
+		}
+		hi = max(hi, d)
+		seen[d] = true
+	}
+	if len(seen) < 5 || hi-lo < 5*time.Second {
+		t.Fatalf("Expected spread-out not_before values, got %d distinct over %s", len(seen), hi-lo)
+	}
+	fmt.Printf("✓ 10 messages spread over %s (%d distinct not_before)\n", (hi - lo).Round(time.Millisecond), len(seen))
+
+	if got := receiveMessages(t, "jitter", 10, 30000); len(got) > 2 {
+		t.Fatalf("Expected most messages still delayed, %d were visible at once", len(got))
+	}
+	fmt.Println("✓ Not all visible at once")
+
+	status, _ = doJSON(t, http.MethodPost, "/v1/queues/jitter/messages",
+		map[string]interface{}{"body": map[string]int{"n": 0}, "delay_jitter_ms": -1})
+	if status != http.StatusBadRequest {
+		t.Fatalf("Expected 400 for negative jitter, got %d", status)
+	}
+	fmt.Println("✓ Negative jitter rejected")
+}
@@ -0,0 +1,154 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestReceiveMinMessages(t *testing.T) {
+	_, teardown := setupTestServer(t)
+	defer teardown()
+
+	fmt.Println("\n=== Test: Long Poll With min_messages ===")
+
+	enqueueMessage(t, "min-batch", map[string]interface{}{"body": map[string]int{"n": 0}})
+
+	// Trickle two more in while the receive is waiting for three.
+	go func() {
+		for i := 1; i <= 2; i++ {
+			time.Sleep(300 * time.Millisecond)
+			enqueueMessage(t, "min-batch", map[string]interface{}{"body": map[string]int{"n": i}})
+		}
+	}()
+
+	start := time.Now()
+	status, messages := receiveWith(t, "min-batch", map[string]interface{}{
+		"max": 5, "min_messages": 3, "wait_ms": 5000, "visibility_ms": 30000,
+	})
+	elapsed := time.Since(start)
+	if status != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", status)
+	}
+	if len(messages) != 3 {
+		t.Fatalf("Expected all 3 messages, got %d", len(messages))
+	}
+	if elapsed < 500*time.Millisecond || elapsed > 3*time.Second {
+		t.Fatalf("Expected to return once the third message arrived, took %v", elapsed)
+	}
+	fmt.Printf("✓ Returned %d messages after %v\n", len(messages), elapsed.Round(10*time.Millisecond))
+
+	// Not enough ever arrives: return what's there when the wait expires.
+	enqueueMessage(t, "min-batch", map[string]interface{}{"body": map[string]int{"n": 3}})
+	start = time.Now()
+	_, messages = receiveWith(t, "min-batch", map[string]interface{}{
+		"max": 5, "min_messages": 2, "wait_ms": 1000, "visibility_ms": 30000,
+	})
+	elapsed = time.Since(start)
+	if len(messages) != 1 || elapsed < time.Second {
+		t.Fatalf("Expected 1 message after the full wait, got %d after %v", len(messages), elapsed)
+	}
+	fmt.Printf("✓ Wait expired, returned the %d available\n", len(messages))
+
+	status, _ = receiveWith(t, "min-batch", map[string]interface{}{"max": 2, "min_messages": 3, "wait_ms": 1000})
+	if status != http.StatusBadRequest {
+		t.Fatalf("Expected 400 for min_messages > max, got %d", status)
+	}
+	fmt.Println("✓ min_messages > max rejected")
+}
+
+func receiveWith(t *testing.T, queue string, payload map[string]interface{}) (int, []map[string]interface{}) {
+	body, _ := json.Marshal(payload)
+	resp, err := http.Post(
+		fmt.Sprintf("http://localhost:9999/v1/queues/%s:receive", queue),
+		"application/json",
+		bytes.NewReader(body),
+	)
+	if err != nil {
+		t.Fatalf("Receive failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var messages []map[string]interface{}
+	if resp.StatusCode == http.StatusOK {
+		json.NewDecoder(resp.Body).Decode(&messages)
+	}
+	return resp.StatusCode, messages
+}
+
+func TestReceivePreferWait(t *testing.T) {
+	_, teardown := setupTestServer(t)
+	defer teardown()
+
+	fmt.Println("\n=== Test: Long Poll With Prefer: wait ===")
+
+	preferReceive := func(prefer string) (*http.Response, []map[string]interface{}) {
+		// no body at all: the header is the whole request
+		req, _ := http.NewRequest(http.MethodPost, "http://localhost:9999/v1/queues/prefer-wait:receive", nil)
+		req.Header.Set("Prefer", prefer)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Receive failed: %v", err)
+		}
+		defer resp.Body.Close()
+		var messages []map[string]interface{}
+		if resp.StatusCode == http.StatusOK {
+			json.NewDecoder(resp.Body).Decode(&messages)
+		}
+		return resp, messages
+	}
+
+	start := time.Now()
+	resp, messages := preferReceive("wait=1")
+	elapsed := time.Since(start)
+	if resp.StatusCode != http.StatusOK || len(messages) != 0 || elapsed < time.Second {
+		t.Fatalf("Expected an empty receive after waiting 1s, got %d %v after %v", resp.StatusCode, messages, elapsed)
+	}
+	if got := resp.Header.Get("Preference-Applied"); got != "wait=1" {
+		t.Fatalf("Expected Preference-Applied: wait=1, got %q", got)
+	}
+	fmt.Printf("✓ Empty queue held for %v\n", elapsed.Round(10*time.Millisecond))
+
+	go func() {
+		time.Sleep(300 * time.Millisecond)
+		enqueueMessage(t, "prefer-wait", map[string]interface{}{"body": map[string]int{"n": 1}})
+	}()
+	start = time.Now()
+	_, messages = preferReceive("respond-async, wait=5")
+	elapsed = time.Since(start)
+	if len(messages) != 1 || elapsed > 3*time.Second {
+		t.Fatalf("Expected the message once it arrived, got %d after %v", len(messages), elapsed)
+	}
+	ackMessage(t, messages[0])
+	fmt.Printf("✓ Returned as soon as a message arrived, after %v\n", elapsed.Round(10*time.Millisecond))
+
+	// a message is waiting, so this returns at once
+	enqueueMessage(t, "prefer-wait", map[string]interface{}{"body": map[string]int{"n": 2}})
+	resp, messages = preferReceive("wait=600")
+	if resp.Header.Get("Preference-Applied") != "wait=20" || len(messages) != 1 {
+		t.Fatalf("Expected the wait clamped to 20s, got %q", resp.Header.Get("Preference-Applied"))
+	}
+	ackMessage(t, messages[0])
+	fmt.Println("✓ Long waits clamped")
+
+	if resp, _ := preferReceive("wait=soon"); resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("Expected 400 for a malformed wait, got %d", resp.StatusCode)
+	}
+	fmt.Println("✓ Malformed wait rejected")
+
+	req, _ := http.NewRequest(http.MethodPost, "http://localhost:9999/v1/queues/prefer-wait:receive", bytes.NewReader([]byte(`{"wait_ms": 0}`)))
+	req.Header.Set("Prefer", "wait=5")
+	start = time.Now()
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Receive failed: %v", err)
+	}
+	resp.Body.Close()
+	if elapsed := time.Since(start); resp.StatusCode != http.StatusOK || elapsed > 2*time.Second || resp.Header.Get("Preference-Applied") != "" {
+		t.Fatalf("Expected wait_ms 0 to return at once without applying the header, got %d after %v", resp.StatusCode, elapsed)
+	}
+	fmt.Println("✓ An explicit wait_ms of 0 overrides the header")
+}
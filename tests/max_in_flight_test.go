@@ -0,0 +1,130 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestMaxInFlightTrimsReceive(t *testing.T) {
+	_, teardown := setupTestServer(t)
+	defer teardown()
+
+	fmt.Println("\n=== Test: Max In Flight Trims Receive ===")
+
+	q := "max-in-flight-test"
+	putQueueConfig(t, q, map[string]interface{}{"partitions": 1, "max_in_flight": 5})
+	for i := 0; i < 10; i++ {
+		enqueueMessage(t, q, map[string]interface{}{"body": map[string]int{"n": i}})
+	}
+
+	held := receiveMessages(t, q, 4, 30000)
+	if len(held) != 4 {
+		t.Fatalf("Expected 4 messages within the budget, got %d", len(held))
+	}
+	fmt.Println("✓ 4 of 5 in-flight slots used")
+
+	receive := func() ([]map[string]interface{}, string) {
+		body, _ := json.Marshal(map[string]interface{}{"max": 10, "visibility_ms": 30000})
+		resp, err := http.Post(fmt.Sprintf("http://localhost:9999/v1/queues/%s:receive", q),
+			"application/json", bytes.NewReader(body))
+		if err != nil {
+			t.Fatalf("Receive failed: %v", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("Expected a trimmed receive, not an error, got %d", resp.StatusCode)
+		}
+		var msgs []map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&msgs)
+		return msgs, resp.Header.Get("X-Receive-Max")
+	}
+
+	msgs, limit := receive()
+	if len(msgs) != 1 || limit != "1" {
+		t.Fatalf("Expected max 10 trimmed to 1, got %d messages with X-Receive-Max %q", len(msgs), limit)
+	}
+	fmt.Println("✓ Receive of 10 trimmed to the 1 remaining slot")
+
+	msgs, limit = receive()
+	if len(msgs) != 0 || limit != "0" {
+		t.Fatalf("Expected nothing from a full budget, got %d messages with X-Receive-Max %q", len(msgs), limit)
+	}
+	fmt.Println("✓ Full budget hands out nothing")
+
+	ackMessage(t, held[0])
+	ackMessage(t, held[1])
+	msgs, limit = receive()
+	if len(msgs) != 2 || limit != "2" {
+		t.Fatalf("Expected 2 slots freed by acks, got %d messages with X-Receive-Max %q", len(msgs), limit)
+	}
+	fmt.Println("✓ Acks free budget for the next receive")
+}
+
+func TestMaxTotalInFlight(t *testing.T) {
+	cfg := testConfig()
+	cfg.MaxTotalInFlight = 3
+	_, teardown := setupTestServerWithConfig(t, cfg)
+	defer teardown()
+
+	fmt.Println("\n=== Test: MAX_TOTAL_IN_FLIGHT ===")
+
+	var lastID int64
+	for _, q := range []string{"total-a", "total-b", "total-c"} {
+		for i := 0; i < 3; i++ {
+			lastID = enqueueMessage(t, q, map[string]interface{}{"body": map[string]int{"n": i}})
+		}
+	}
+
+	receive := func(path string, payload map[string]interface{}) (int, []map[string]interface{}, http.Header) {
+		body, _ := json.Marshal(payload)
+		resp, err := http.Post("http://localhost:9999"+path, "application/json", bytes.NewReader(body))
+		if err != nil {
+			t.Fatalf("Receive failed: %v", err)
+		}
+		defer resp.Body.Close()
+		var msgs []map[string]interface{}
+		if resp.StatusCode == http.StatusOK {
+			json.NewDecoder(resp.Body).Decode(&msgs)
+		}
+		return resp.StatusCode, msgs, resp.Header
+	}
+
+	held := receiveMessages(t, "total-a", 2, 30000)
+	if len(held) != 2 {
+		t.Fatalf("Expected 2 messages within the server-wide cap, got %d", len(held))
+	}
+	status, msgs, header := receive("/v1/queues/total-b:receive", map[string]interface{}{"max": 3, "visibility_ms": 30000})
+	if status != http.StatusOK || len(msgs) != 1 || header.Get("X-Receive-Max") != "1" {
+		t.Fatalf("Expected another queue's receive trimmed to 1, got %d with %d messages, X-Receive-Max %q",
+			status, len(msgs), header.Get("X-Receive-Max"))
+	}
+	fmt.Println("✓ The cap is shared across queues: 2 + 1 of 3")
+
+	status, _, header = receive("/v1/queues/total-c:receive", map[string]interface{}{"max": 1, "visibility_ms": 30000})
+	if status != http.StatusTooManyRequests || header.Get("Retry-After") == "" {
+		t.Fatalf("Expected 429 with Retry-After from a third queue, got %d", status)
+	}
+	status, _, _ = receive("/v1/queues:receive", map[string]interface{}{
+		"max":    2,
+		"queues": []map[string]interface{}{{"name": "total-b"}, {"name": "total-c"}},
+	})
+	if status != http.StatusTooManyRequests {
+		t.Fatalf("Expected 429 from a multi-queue receive, got %d", status)
+	}
+	if status, _ := doJSON(t, http.MethodPost, fmt.Sprintf("/v1/messages/%d:claim", lastID), nil); status != http.StatusTooManyRequests {
+		t.Fatalf("Expected 429 claiming by ID, got %d", status)
+	}
+	fmt.Println("✓ Further receives throttled with 429 regardless of queue")
+
+	ackMessage(t, held[0])
+	time.Sleep(1100 * time.Millisecond) // past the cached count
+	status, msgs, _ = receive("/v1/queues/total-c:receive", map[string]interface{}{"max": 3, "visibility_ms": 30000})
+	if status != http.StatusOK || len(msgs) != 1 {
+		t.Fatalf("Expected the acked slot free again, got %d with %d messages", status, len(msgs))
+	}
+	fmt.Println("✓ Acks free the cap once it is recounted")
+}
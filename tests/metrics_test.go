@@ -0,0 +1,127 @@
+package tests
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLatencyHistograms(t *testing.T) {
+	_, teardown := setupTestServer(t)
+	defer teardown()
+
+	fmt.Println("\n=== Test: Enqueue/Receive Latency Histograms ===")
+
+	for i := 0; i < 2; i++ {
+		enqueueMessage(t, "latency-test", map[string]interface{}{"body": map[string]int{"n": i}})
+	}
+	receiveMessages(t, "latency-test", 2, 30000)
+
+	samples := scrapeMetrics(t)
+	for name, want := range map[string]float64{
+		`sqs_enqueue_duration_seconds_count{queue="latency-test"}`: 2,
+		`sqs_receive_duration_seconds_count{queue="latency-test"}`: 1,
+	} {
+		if got := samples[name]; got != want {
+			t.Fatalf("Expected %s = %v, got %v", name, want, got)
+		}
+		fmt.Printf("✓ %s = %v\n", name, want)
+	}
+	if samples[`sqs_enqueue_duration_seconds_sum{queue="latency-test"}`] <= 0 {
+		t.Fatal("Expected enqueue durations to sum to more than zero")
+	}
+}
+
+func TestMessageAgeHistograms(t *testing.T) {
+	_, teardown := setupTestServer(t)
+	defer teardown()
+
+	fmt.Println("\n=== Test: Message Age At Receive And Ack ===")
+
+	enqueueMessage(t, "age-test", map[string]interface{}{"body": map[string]string{"task": "quick"}})
+	time.Sleep(200 * time.Millisecond)
+	messages := receiveMessages(t, "age-test", 1, 30000)
+	if len(messages) != 1 {
+		t.Fatalf("Expected 1 message, got %d", len(messages))
+	}
+	time.Sleep(100 * time.Millisecond)
+	ackMessage(t, messages[0])
+
+	samples := scrapeMetrics(t)
+	atReceive := samples[`sqs_message_age_at_receive_seconds_sum{queue="age-test"}`]
+	atAck := samples[`sqs_message_age_at_ack_seconds_sum{queue="age-test"}`]
+	for name, want := range map[string]float64{
+		`sqs_message_age_at_receive_seconds_count{queue="age-test"}`: 1,
+		`sqs_message_age_at_ack_seconds_count{queue="age-test"}`:     1,
+	} {
+		if got := samples[name]; got != want {
+			t.Fatalf("Expected %s = %v, got %v", name, want, got)
+		}
+	}
+	if atReceive < 0.2 || atReceive > 5 {
+		t.Fatalf("Expected an age at receive of about 0.2s, got %v", atReceive)
+	}
+	if atAck < atReceive+0.1 || atAck > 5 {
+		t.Fatalf("Expected an age at ack of about 0.3s, after the receive's %v, got %v", atReceive, atAck)
+	}
+	fmt.Printf("✓ Age at receive %.3fs, at ack %.3fs\n", atReceive, atAck)
+}
+
+// scrapeMetrics reads /metrics into a map from "name{labels}" to value.
+func scrapeMetrics(t *testing.T) map[string]float64 {
+	resp, err := http.Get("http://localhost:9999/metrics")
+	if err != nil {
+		t.Fatalf("Scrape failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	samples := make(map[string]float64)
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		i := strings.LastIndexByte(line, ' ')
+		if v, err := strconv.ParseFloat(line[i+1:], 64); err == nil {
+			samples[line[:i]] = v
+		}
+	}
+	return samples
+}
+
+func TestMessagesReceivedByWorker(t *testing.T) {
+	cfg := testConfig()
+	cfg.WorkerMetrics = true
+	_, teardown := setupTestServerWithConfig(t, cfg)
+	defer teardown()
+
+	fmt.Println("\n=== Test: Messages Received By Worker ===")
+
+	for i := 0; i < 3; i++ {
+		enqueueMessage(t, "by-worker", map[string]interface{}{"body": map[string]int{"n": i}})
+	}
+	for _, worker := range []string{"replica-a", "replica-b"} {
+		req, _ := http.NewRequest(http.MethodPost, "http://localhost:9999/v1/queues/by-worker:receive",
+			strings.NewReader(`{"max": 1, "visibility_ms": 30000}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Worker-ID", worker)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Receive failed: %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	samples := scrapeMetrics(t)
+	a := samples[`sqs_messages_received_by_worker_total{queue="by-worker",worker="replica-a"}`]
+	b := samples[`sqs_messages_received_by_worker_total{queue="by-worker",worker="replica-b"}`]
+	if a != 1 || b != 1 {
+		t.Fatalf("Expected one receive counted per worker, got a=%v b=%v", a, b)
+	}
+	fmt.Println("✓ Receives counted per X-Worker-ID")
+}
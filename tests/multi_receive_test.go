@@ -0,0 +1,97 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/aridsondez/AWS-SQS-LITE/internal/queue"
+)
+
+func TestAllocateClaims(t *testing.T) {
+	cases := []struct {
+		weights []int
+		limit   int
+		want    []int
+	}{
+		{[]int{1, 1}, 10, []int{5, 5}},
+		{[]int{9, 1}, 10, []int{9, 1}},  // light queue still gets its slot
+		{[]int{100, 1}, 3, []int{2, 1}}, // guaranteed slot beats weight
+		{[]int{1, 1, 1}, 3, []int{1, 1, 1}},
+		{[]int{2, 1}, 6, []int{4, 2}},
+		{[]int{0, 1}, 4, []int{2, 2}},       // non-positive weight counts as 1
+		{[]int{1, 1, 1}, 5, []int{2, 2, 1}}, // rounding ties go to earlier queues
+	}
+	for _, tc := range cases {
+		got := queue.AllocateClaims(tc.weights, tc.limit)
+		if !slices.Equal(got, tc.want) {
+			t.Fatalf("AllocateClaims(%v, %d) = %v, want %v", tc.weights, tc.limit, got, tc.want)
+		}
+	}
+}
+
+func receiveMulti(t *testing.T, payload map[string]interface{}) []map[string]interface{} {
+	body, _ := json.Marshal(payload)
+	resp, err := http.Post("http://localhost:9999/v1/queues:receive", "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("Multi-receive failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Multi-receive returned %d", resp.StatusCode)
+	}
+	var messages []map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&messages)
+	return messages
+}
+
+func TestMultiReceiveWeightedFairness(t *testing.T) {
+	_, teardown := setupTestServer(t)
+	defer teardown()
+
+	fmt.Println("\n=== Test: Weighted Multi-Receive ===")
+
+	for i := 0; i < 40; i++ {
+		enqueueMessage(t, "fair-a", map[string]interface{}{"body": map[string]int{"n": i}})
+	}
+	for i := 0; i < 3; i++ {
+		enqueueMessage(t, "fair-b", map[string]interface{}{"body": map[string]int{"n": i}})
+	}
+
+	payload := map[string]interface{}{
+		"queues": []map[string]interface{}{
+			{"name": "fair-a", "weight": 9},
+			{"name": "fair-b", "weight": 1},
+		},
+		"max":           5,
+		"visibility_ms": 30000,
+	}
+
+	// A alone could fill every batch; B must still get a slot in each.
+	for round := 1; round <= 3; round++ {
+		counts := map[string]int{}
+		for _, m := range receiveMulti(t, payload) {
+			counts[m["queue"].(string)]++
+		}
+		if counts["fair-b"] != 1 {
+			t.Fatalf("Round %d: expected 1 message from fair-b, got %v", round, counts)
+		}
+		if counts["fair-a"] != 4 {
+			t.Fatalf("Round %d: expected fair-a to fill the rest, got %v", round, counts)
+		}
+	}
+	fmt.Println("✓ Trickle queue claimed every round despite the busy queue")
+
+	// B is drained now; its slot goes back to A.
+	counts := map[string]int{}
+	for _, m := range receiveMulti(t, payload) {
+		counts[m["queue"].(string)]++
+	}
+	if counts["fair-a"] != 5 {
+		t.Fatalf("Expected unused budget to go to fair-a, got %v", counts)
+	}
+	fmt.Println("✓ Unused budget is redistributed")
+}
@@ -0,0 +1,98 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestEnqueueAbsoluteNotBefore(t *testing.T) {
+	db, teardown := setupTestServer(t)
+	defer teardown()
+
+	fmt.Println("\n=== Test: Enqueue With Absolute not_before ===")
+
+	at := time.Now().Add(2 * time.Second).UTC().Truncate(time.Millisecond)
+	id := enqueueMessage(t, "scheduled", map[string]interface{}{
+		"body":       map[string]string{"task": "send-reminder"},
+		"not_before": at.Format(time.RFC3339Nano),
+	})
+
+	var stored time.Time
+	if err := db.Pool.QueryRow(context.Background(),
+		`SELECT not_before FROM messages WHERE id = $1`, id).Scan(&stored); err != nil {
+		t.Fatalf("Query not_before: %v", err)
+	}
+	if !stored.Equal(at) {
+		t.Fatalf("Expected not_before %v, got %v", at, stored)
+	}
+	fmt.Printf("✓ Stored not_before %s as given\n", stored.UTC().Format(time.RFC3339Nano))
+
+	if messages := receiveMessages(t, "scheduled", 1, 30000); len(messages) != 0 {
+		t.Fatalf("Expected nothing before not_before, got %d", len(messages))
+	}
+	time.Sleep(time.Until(at) + 100*time.Millisecond)
+	messages := receiveMessages(t, "scheduled", 1, 30000)
+	if len(messages) != 1 || int64(messages[0]["id"].(float64)) != id {
+		t.Fatalf("Expected the scheduled message once due, got %v", messages)
+	}
+	fmt.Println("✓ Hidden until not_before, then delivered")
+
+	cases := []struct {
+		name    string
+		payload map[string]interface{}
+		want    int
+	}{
+		{"both delay and not_before", map[string]interface{}{
+			"body": map[string]int{}, "delay_ms": 1000, "not_before": at.Format(time.RFC3339),
+		}, http.StatusBadRequest},
+		{"far in the past", map[string]interface{}{
+			"body": map[string]int{}, "not_before": time.Now().Add(-time.Hour).Format(time.RFC3339),
+		}, http.StatusBadRequest},
+		{"not a timestamp", map[string]interface{}{
+			"body": map[string]int{}, "not_before": "tomorrow",
+		}, http.StatusBadRequest},
+		{"slightly in the past", map[string]interface{}{
+			"body": map[string]int{}, "not_before": time.Now().Add(-10 * time.Second).Format(time.RFC3339),
+		}, http.StatusCreated},
+	}
+	for _, tc := range cases {
+		if status, out := doJSON(t, http.MethodPost, "/v1/queues/scheduled-checks/messages", tc.payload); status != tc.want {
+			t.Fatalf("%s: expected %d, got %d (%v)", tc.name, tc.want, status, out)
+		}
+		fmt.Printf("✓ %s: %d\n", tc.name, tc.want)
+	}
+}
+
+func TestEnqueueBeyondMaxLifetime(t *testing.T) {
+	cfg := testConfig()
+	cfg.MaxMessageLifetime = time.Hour
+	_, teardown := setupTestServerWithConfig(t, cfg)
+	defer teardown()
+
+	fmt.Println("\n=== Test: Enqueue Beyond MAX_MESSAGE_LIFETIME ===")
+
+	cases := []struct {
+		name    string
+		payload map[string]interface{}
+		want    int
+	}{
+		{"delay past the lifetime", map[string]interface{}{
+			"body": map[string]int{}, "delay_ms": 2 * time.Hour.Milliseconds(),
+		}, http.StatusBadRequest},
+		{"not_before past the lifetime", map[string]interface{}{
+			"body": map[string]int{}, "not_before": time.Now().Add(2 * time.Hour).Format(time.RFC3339),
+		}, http.StatusBadRequest},
+		{"delay within the lifetime", map[string]interface{}{
+			"body": map[string]int{}, "delay_ms": 30 * time.Minute.Milliseconds(),
+		}, http.StatusCreated},
+	}
+	for _, tc := range cases {
+		if status, out := doJSON(t, http.MethodPost, "/v1/queues/lifetime-checks/messages", tc.payload); status != tc.want {
+			t.Fatalf("%s: expected %d, got %d (%v)", tc.name, tc.want, status, out)
+		}
+		fmt.Printf("✓ %s: %d\n", tc.name, tc.want)
+	}
+}
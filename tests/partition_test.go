@@ -0,0 +1,109 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/aridsondez/AWS-SQS-LITE/internal/queue"
+	"github.com/aridsondez/AWS-SQS-LITE/internal/testutil"
+)
+
+func TestPartitionedQueue(t *testing.T) {
+	db, teardown := setupTestServer(t)
+	defer teardown()
+
+	fmt.Println("\n=== Test: Partitioned Queue ===")
+
+	putQueueConfig(t, "partitioned", map[string]interface{}{"partitions": 4})
+	fmt.Println("✓ Configured queue with 4 partitions")
+
+	for i := 0; i < 8; i++ {
+		enqueueMessage(t, "partitioned", map[string]interface{}{
+			"body": map[string]int{"n": i},
+		})
+	}
+
+	var parts []int
+	rows, err := db.Pool.Query(context.Background(),
+		"SELECT DISTINCT partition FROM messages WHERE queue = 'partitioned' ORDER BY partition")
+	if err != nil {
+		t.Fatalf("Query partitions failed: %v", err)
+	}
+	for rows.Next() {
+		var p int
+		_ = rows.Scan(&p)
+		parts = append(parts, p)
+	}
+	rows.Close()
+	if len(parts) != 4 {
+		t.Fatalf("Expected messages spread over 4 partitions, got %v", parts)
+	}
+	fmt.Printf("✓ Messages spread over partitions %v\n", parts)
+
+	messages := receiveMessages(t, "partitioned", 8, 30000)
+	if len(messages) != 8 {
+		t.Fatalf("Expected to claim all 8 messages across partitions, got %d", len(messages))
+	}
+	fmt.Println("✓ Single receive drained every partition")
+}
+
+// BenchmarkClaimContention compares many concurrent claimers on one hot
+// queue with and without partitions. Run with:
+//
+//	go test ./tests -run '^$' -bench ClaimContention -cpu 16
+func BenchmarkClaimContention(b *testing.B) {
+	for _, partitions := range []int{1, 8} {
+		b.Run(fmt.Sprintf("partitions=%d", partitions), func(b *testing.B) {
+			ctx := context.Background()
+			s, teardown := testutil.SetupStore(b)
+			defer teardown()
+
+			qname := fmt.Sprintf("bench-contention-%d", partitions)
+			if err := s.PutQueueConfig(ctx, queue.QueueConfig{Queue: qname, Partitions: partitions}); err != nil {
+				b.Fatalf("put config: %v", err)
+			}
+			_, err := s.Pool.Exec(ctx, `
+INSERT INTO messages (queue, body, partition)
+SELECT $1, '{}'::jsonb, g % $2 FROM generate_series(1, $3) g`, qname, partitions, b.N+1000)
+			if err != nil {
+				b.Fatalf("seed: %v", err)
+			}
+
+			b.ResetTimer()
+			start := time.Now()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					msgs, err := s.Claim(ctx, queue.ClaimOptions{Queue: qname, Limit: 1, Visibility: time.Minute})
+					if err != nil {
+						b.Errorf("claim: %v", err)
+						return
+					}
+					for _, m := range msgs {
+						_, _ = s.Ack(ctx, m.Receipt())
+					}
+				}
+			})
+			b.ReportMetric(float64(b.N)/time.Since(start).Seconds(), "msgs/sec")
+		})
+	}
+}
+
+func putQueueConfig(t *testing.T, queue string, payload map[string]interface{}) {
+	body, _ := json.Marshal(payload)
+	req, _ := http.NewRequest(http.MethodPut,
+		fmt.Sprintf("http://localhost:9999/v1/queues/%s/config", queue), bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Put config failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Put config returned %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,64 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestPauseAndResumeQueue(t *testing.T) {
+	_, teardown := setupTestServer(t)
+	defer teardown()
+
+	fmt.Println("\n=== Test: Pause And Resume Queue ===")
+
+	q := "pause-test"
+	enqueueMessage(t, q, map[string]interface{}{"body": map[string]int{"n": 1}})
+
+	status, result := doJSON(t, http.MethodPost, "/v1/queues/"+q+":pause", nil)
+	if status != http.StatusOK || result["paused"] != true {
+		t.Fatalf("Expected pause to succeed, got %d %v", status, result)
+	}
+	fmt.Println("✓ Queue paused")
+
+	// Messages keep accumulating while nothing is delivered.
+	enqueueMessage(t, q, map[string]interface{}{"body": map[string]int{"n": 2}})
+	enqueueMessage(t, q, map[string]interface{}{"body": map[string]int{"n": 3}})
+	if msgs := receiveMessages(t, q, 10, 30000); len(msgs) != 0 {
+		t.Fatalf("Expected no messages from a paused queue, got %d", len(msgs))
+	}
+
+	start := time.Now()
+	status, msgs := receiveWith(t, q, map[string]interface{}{"max": 10, "wait_ms": 1000})
+	if status != http.StatusOK || len(msgs) != 0 {
+		t.Fatalf("Expected an empty long poll, got %d with %d messages", status, len(msgs))
+	}
+	if time.Since(start) < 900*time.Millisecond {
+		t.Fatalf("Long poll on a paused queue returned early after %s", time.Since(start))
+	}
+	fmt.Println("✓ Receives return empty while paused, long polls wait out")
+
+	_, attrs := doJSON(t, http.MethodGet, "/v1/queues/"+q+"/attributes", nil)
+	if attrs["paused"] != true || attrs["approximate_depth"].(float64) != 3 {
+		t.Fatalf("Expected paused with depth 3, got %v", attrs)
+	}
+	fmt.Println("✓ Attributes show paused with depth 3")
+
+	status, result = doJSON(t, http.MethodPost, "/v1/queues/"+q+":resume", nil)
+	if status != http.StatusOK || result["paused"] != false {
+		t.Fatalf("Expected resume to succeed, got %d %v", status, result)
+	}
+	msgs = receiveMessages(t, q, 10, 30000)
+	if len(msgs) != 3 {
+		t.Fatalf("Expected 3 messages after resume, got %d", len(msgs))
+	}
+	for _, m := range msgs {
+		ackMessage(t, m)
+	}
+	_, attrs = doJSON(t, http.MethodGet, "/v1/queues/"+q+"/attributes", nil)
+	if attrs["paused"] != false {
+		t.Fatalf("Expected unpaused, got %v", attrs["paused"])
+	}
+	fmt.Println("✓ Resumed queue delivers the backlog")
+}
@@ -0,0 +1,48 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aridsondez/AWS-SQS-LITE/internal/api"
+)
+
+func TestPprofEndpoint(t *testing.T) {
+	fmt.Println("\n=== Test: pprof Endpoint ===")
+
+	get := func(h http.Handler, token string) int {
+		req := httptest.NewRequest(http.MethodGet, "/debug/pprof/", nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// handlers are only routed, never reach the store
+	disabled := api.NewServer(":0", nil, testConfig()).Handler
+	if code := get(disabled, ""); code != http.StatusNotFound {
+		t.Fatalf("Expected 404 with pprof disabled, got %d", code)
+	}
+	fmt.Println("✓ Not mounted by default")
+
+	cfg := testConfig()
+	cfg.EnablePprof = true
+	cfg.AdminToken = "s3cret"
+	enabled := api.NewServer(":0", nil, cfg).Handler
+	if code := get(enabled, ""); code != http.StatusUnauthorized {
+		t.Fatalf("Expected 401 without a token, got %d", code)
+	}
+	if code := get(enabled, "wrong"); code != http.StatusUnauthorized {
+		t.Fatalf("Expected 401 with a bad token, got %d", code)
+	}
+	fmt.Println("✓ Requires the admin token")
+
+	if code := get(enabled, "s3cret"); code != http.StatusOK {
+		t.Fatalf("Expected 200 with the admin token, got %d", code)
+	}
+	fmt.Println("✓ Serves the pprof index to admins")
+}
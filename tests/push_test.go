@@ -0,0 +1,107 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aridsondez/AWS-SQS-LITE/internal/api"
+)
+
+func TestPushSubscription(t *testing.T) {
+	cfg := testConfig()
+	cfg.PushInterval = 100 * time.Millisecond
+	cfg.PushTimeout = 5 * time.Second
+	cfg.AdminToken = "s3cret"
+	db, teardown := setupTestServerWithConfig(t, cfg)
+	defer teardown()
+
+	fmt.Println("\n=== Test: Push Subscription ===")
+
+	var (
+		mu       sync.Mutex
+		received = make(map[int64]int) // message ID -> POSTs seen
+		failOnce int64                 // the first POST of this message gets a 500
+	)
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var m struct {
+			ID   int64          `json:"id"`
+			Body map[string]int `json:"body"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&m); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		received[m.ID]++
+		if m.ID == failOnce && received[m.ID] == 1 {
+			http.Error(w, "try again", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer hook.Close()
+
+	if status, _ := doJSON(t, http.MethodPost, "/v1/queues/push-test/subscriptions", map[string]string{"url": hook.URL}); status != http.StatusUnauthorized {
+		t.Fatalf("Expected 401 subscribing without the admin token, got %d", status)
+	}
+	if status, out := doAdminJSON(t, http.MethodPost, "/v1/queues/push-test/subscriptions", "s3cret", map[string]string{"url": "ftp://example.com"}); status != http.StatusBadRequest {
+		t.Fatalf("Expected 400 for a non-http URL, got %d %v", status, out)
+	}
+	status, out := doAdminJSON(t, http.MethodPost, "/v1/queues/push-test/subscriptions", "s3cret", map[string]string{"url": hook.URL})
+	if status != http.StatusOK || out["url"] != hook.URL {
+		t.Fatalf("Expected the subscription back, got %d %v", status, out)
+	}
+	fmt.Println("✓ Webhook registered")
+
+	mu.Lock()
+	var ids []int64
+	for i := 0; i < 3; i++ {
+		ids = append(ids, enqueueMessage(t, "push-test", map[string]interface{}{"body": map[string]int{"n": i}, "max_retries": 3}))
+	}
+	failOnce = ids[1]
+	mu.Unlock()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go api.NewPushDeliverer(db, cfg).Start(ctx)
+
+	deadline := time.Now().Add(15 * time.Second)
+	for {
+		st, err := db.Stats(context.Background(), "push-test")
+		if err != nil {
+			t.Fatalf("Stats failed: %v", err)
+		}
+		if st.Available == 0 && st.InFlight == 0 && st.Delayed == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected every message pushed and acked, still have %+v", st)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if received[ids[0]] != 1 || received[ids[2]] != 1 {
+		t.Fatalf("Expected one POST each for the healthy messages, got %v", received)
+	}
+	if received[ids[1]] != 2 {
+		t.Fatalf("Expected message %d POSTed again after its 500, got %v", ids[1], received)
+	}
+	fmt.Println("✓ Messages delivered and acked on 2xx; the failed one redelivered")
+
+	if status, out := doAdminJSON(t, http.MethodDelete, "/v1/queues/push-test/subscriptions", "s3cret", nil); status != http.StatusOK {
+		t.Fatalf("Expected the subscription deleted, got %d %v", status, out)
+	}
+	if status, _ := doJSON(t, http.MethodGet, "/v1/queues/push-test/subscriptions", nil); status != http.StatusNotFound {
+		t.Fatalf("Expected 404 after delete, got %d", status)
+	}
+	fmt.Println("✓ Subscription deleted")
+}
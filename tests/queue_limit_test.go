@@ -0,0 +1,114 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestMaxQueues(t *testing.T) {
+	cfg := testConfig()
+	cfg.MaxQueues = 2
+	cfg.AdminToken = "s3cret"
+	_, teardown := setupTestServerWithConfig(t, cfg)
+	defer teardown()
+
+	fmt.Println("\n=== Test: MAX_QUEUES ===")
+
+	enqueueMessage(t, "limit-a", map[string]interface{}{"body": map[string]int{"n": 0}})
+	enqueueMessage(t, "limit-b", map[string]interface{}{"body": map[string]int{"n": 1}})
+
+	status, out := doJSON(t, http.MethodPost, "/v1/queues/limit-c/messages", map[string]interface{}{"body": map[string]int{"n": 2}})
+	if status != http.StatusBadRequest {
+		t.Fatalf("Expected 400 enqueuing to a third queue, got %d %v", status, out)
+	}
+	if status, out := doJSON(t, http.MethodPost, "/v1/queues/limit-c/messages:batch", map[string]interface{}{
+		"entries": []map[string]interface{}{{"body": map[string]int{"n": 2}}},
+	}); status != http.StatusBadRequest {
+		t.Fatalf("Expected 400 batch enqueuing to a third queue, got %d %v", status, out)
+	}
+	fmt.Println("✓ New queue past the limit rejected with 400")
+
+	enqueueMessage(t, "limit-a", map[string]interface{}{"body": map[string]int{"n": 3}})
+	fmt.Println("✓ Existing queues still accept messages")
+
+	if code := adminQueueRequest(t, http.MethodDelete, "limit-b", ""); code != http.StatusUnauthorized {
+		t.Fatalf("Expected 401 deleting a queue without the admin token, got %d", code)
+	}
+	if code := adminQueueRequest(t, http.MethodDelete, "limit-b", "s3cret"); code != http.StatusOK {
+		t.Fatalf("Expected limit-b deleted, got %d", code)
+	}
+	id := enqueueMessage(t, "limit-c", map[string]interface{}{"body": map[string]int{"n": 4}, "max_retries": 1, "dlq": "limit-dlq"})
+	fmt.Println("✓ Deleting a queue frees its place")
+
+	messages := receiveMessages(t, "limit-c", 1, 30000)
+	if len(messages) != 1 {
+		t.Fatalf("Expected 1 message, got %d", len(messages))
+	}
+	if status, out := doJSON(t, http.MethodPost, fmt.Sprintf("/v1/messages/%d:nack", id), map[string]interface{}{
+		"receipt": messages[0]["receipt"], "terminal": true,
+	}); status != http.StatusOK {
+		t.Fatalf("Expected the message dead-lettered, got %d %v", status, out)
+	}
+	if status, out := doJSON(t, http.MethodPost, "/v1/queues/limit-dlq:redrive", map[string]string{"target": "limit-d"}); status != http.StatusBadRequest {
+		t.Fatalf("Expected 400 redriving to a new queue past the limit, got %d %v", status, out)
+	}
+	if status, out := doJSON(t, http.MethodPost, "/v1/queues/limit-dlq:redrive", map[string]string{"target": "limit-a"}); status != http.StatusOK || out["redriven"] != float64(1) {
+		t.Fatalf("Expected the dead letter redriven to an existing queue, got %d %v", status, out)
+	}
+	fmt.Println("✓ Redrive target admitted like an enqueue")
+}
+
+func TestStrictQueues(t *testing.T) {
+	cfg := testConfig()
+	cfg.StrictQueues = true
+	cfg.AdminToken = "s3cret"
+	_, teardown := setupTestServerWithConfig(t, cfg)
+	defer teardown()
+
+	fmt.Println("\n=== Test: STRICT_QUEUES ===")
+
+	status, out := doJSON(t, http.MethodPost, "/v1/queues/strict-q/messages", map[string]interface{}{"body": map[string]int{"n": 0}})
+	if status != http.StatusNotFound {
+		t.Fatalf("Expected 404 enqueuing to a queue that wasn't created, got %d %v", status, out)
+	}
+	fmt.Println("✓ Uncreated queue refused")
+
+	if code := adminQueueRequest(t, http.MethodPut, "strict-q", "s3cret"); code != http.StatusCreated {
+		t.Fatalf("Expected 201 creating the queue, got %d", code)
+	}
+	if code := adminQueueRequest(t, http.MethodPut, "strict-q", "s3cret"); code != http.StatusOK {
+		t.Fatalf("Expected 200 creating it again, got %d", code)
+	}
+	id := enqueueMessage(t, "strict-q", map[string]interface{}{"body": map[string]int{"n": 1}})
+	messages := receiveMessages(t, "strict-q", 1, 30000)
+	if len(messages) != 1 || int64(messages[0]["id"].(float64)) != id {
+		t.Fatalf("Expected message %d, got %v", id, messages)
+	}
+	ackMessage(t, messages[0])
+	fmt.Println("✓ Created queue accepts messages")
+
+	if code := adminQueueRequest(t, http.MethodDelete, "strict-q", "s3cret"); code != http.StatusOK {
+		t.Fatalf("Expected the queue deleted, got %d", code)
+	}
+	if code := adminQueueRequest(t, http.MethodDelete, "strict-q", "s3cret"); code != http.StatusNotFound {
+		t.Fatalf("Expected 404 deleting it twice, got %d", code)
+	}
+	if status, _ := doJSON(t, http.MethodPost, "/v1/queues/strict-q/messages", map[string]interface{}{"body": map[string]int{"n": 2}}); status != http.StatusNotFound {
+		t.Fatalf("Expected 404 enqueuing to the deleted queue, got %d", status)
+	}
+	fmt.Println("✓ Deleted queue refused again")
+}
+
+func adminQueueRequest(t *testing.T, method, qname, token string) int {
+	req, _ := http.NewRequest(method, "http://localhost:9999/v1/queues/"+qname, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s queue failed: %v", method, err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
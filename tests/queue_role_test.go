@@ -0,0 +1,67 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestDLQRoleHeader(t *testing.T) {
+	_, teardown := setupTestServer(t)
+	defer teardown()
+
+	fmt.Println("\n=== Test: DLQ Role Tagging ===")
+
+	putQueueConfig(t, "role-dlq", map[string]interface{}{"role": "dlq"})
+
+	enqueueMessage(t, "role-main", map[string]interface{}{
+		"body":        map[string]string{"task": "will-fail"},
+		"max_retries": 1,
+		"dlq":         "role-dlq",
+	})
+
+	// First delivery, on an ordinary queue: neither marker.
+	role, messages := receiveWithRole(t, "role-main", 1000)
+	if len(messages) != 1 {
+		t.Fatalf("Expected 1 message, got %d", len(messages))
+	}
+	if role != "" || messages[0]["dead_lettered_at"] != nil {
+		t.Fatalf("Expected no DLQ markers on role-main, got role=%q message=%v", role, messages[0])
+	}
+	fmt.Println("✓ Ordinary queue: no X-Queue-Role, no dead_lettered_at")
+
+	// Let the lease lapse; max_retries=1 sends it to the DLQ on the next sweep.
+	time.Sleep(4 * time.Second)
+
+	role, messages = receiveWithRole(t, "role-dlq", 30000)
+	if len(messages) != 1 {
+		t.Fatalf("Expected 1 dead letter, got %d", len(messages))
+	}
+	if role != "dlq" {
+		t.Fatalf("Expected X-Queue-Role: dlq, got %q", role)
+	}
+	if messages[0]["dead_lettered_at"] == nil {
+		t.Fatalf("Expected dead_lettered_at on a dead letter, got %v", messages[0])
+	}
+	fmt.Printf("✓ DLQ receive tagged: role=%s dead_lettered_at=%v\n", role, messages[0]["dead_lettered_at"])
+}
+
+func receiveWithRole(t *testing.T, queue string, visibilityMS int) (string, []map[string]interface{}) {
+	body, _ := json.Marshal(map[string]interface{}{"max": 1, "visibility_ms": visibilityMS})
+	resp, err := http.Post(
+		fmt.Sprintf("http://localhost:9999/v1/queues/%s:receive", queue),
+		"application/json",
+		bytes.NewReader(body),
+	)
+	if err != nil {
+		t.Fatalf("Receive failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var messages []map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&messages)
+	return resp.Header.Get("X-Queue-Role"), messages
+}
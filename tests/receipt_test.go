@@ -0,0 +1,165 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAckReceiptValidation(t *testing.T) {
+	_, teardown := setupTestServer(t)
+	defer teardown()
+
+	fmt.Println("\n=== Test: Ack Receipt Validation ===")
+
+	enqueueMessage(t, "receipt-test", map[string]interface{}{"body": map[string]string{"n": "1"}})
+	enqueueMessage(t, "receipt-test", map[string]interface{}{"body": map[string]string{"n": "2"}})
+	messages := receiveMessages(t, "receipt-test", 2, 30000)
+	if len(messages) != 2 {
+		t.Fatalf("Expected 2 messages, got %d", len(messages))
+	}
+	first, second := messages[0], messages[1]
+	firstID := int64(first["id"].(float64))
+
+	cases := []struct {
+		name    string
+		body    string
+		status  int
+		wantErr string
+	}{
+		{"missing receipt", `{}`, http.StatusBadRequest, "receipt required"},
+		{"empty body", ``, http.StatusBadRequest, "receipt required"},
+		{"malformed receipt", `{"receipt":"not-a-receipt"}`, http.StatusBadRequest, "invalid receipt format"},
+		{"mismatched receipt", fmt.Sprintf(`{"receipt":%q}`, second["receipt"]), http.StatusForbidden, "does not match"},
+	}
+	for _, tc := range cases {
+		status, msg := postAck(t, firstID, tc.body)
+		if status != tc.status || !strings.Contains(msg, tc.wantErr) {
+			t.Fatalf("%s: expected %d %q, got %d %q", tc.name, tc.status, tc.wantErr, status, msg)
+		}
+		fmt.Printf("✓ %s → %d %s\n", tc.name, status, msg)
+	}
+
+	// the message is untouched by the rejected acks
+	ackMessage(t, first)
+	fmt.Println("✓ Valid receipt still acks")
+}
+
+func postAck(t *testing.T, id int64, body string) (int, string) {
+	resp, err := http.Post(
+		fmt.Sprintf("http://localhost:9999/v1/messages/%d:ack", id),
+		"application/json",
+		bytes.NewReader([]byte(body)),
+	)
+	if err != nil {
+		t.Fatalf("Ack failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var result map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&result)
+	msg, _ := result["error"].(string)
+	return resp.StatusCode, msg
+}
+
+func TestStaleAckIsFenced(t *testing.T) {
+	db, teardown := setupTestServer(t)
+	defer teardown()
+
+	fmt.Println("\n=== Test: Lease Fencing Rejects Stale Ack ===")
+
+	msgID := enqueueMessage(t, "fencing-test", map[string]interface{}{
+		"body": map[string]string{"task": "contended"},
+	})
+
+	// worker A leases briefly and stalls past its visibility timeout
+	workerA := receiveMessages(t, "fencing-test", 1, 1000)
+	if len(workerA) != 1 {
+		t.Fatalf("Expected worker A to get 1 message, got %d", len(workerA))
+	}
+	fmt.Printf("✓ Worker A leased message %d (receipt %s)\n", msgID, workerA[0]["receipt"])
+
+	fmt.Println("Waiting 3 seconds for sweeper to requeue...")
+	time.Sleep(3 * time.Second)
+
+	// worker B picks it up after the sweeper requeues it
+	workerB := receiveMessages(t, "fencing-test", 1, 30000)
+	if len(workerB) != 1 {
+		t.Fatalf("Expected worker B to get the requeued message, got %d", len(workerB))
+	}
+	fmt.Printf("✓ Worker B leased message %d (receipt %s)\n", msgID, workerB[0]["receipt"])
+
+	// worker A wakes up and tries to ack with its old receipt
+	status, msg := postAck(t, msgID, fmt.Sprintf(`{"receipt":%q}`, workerA[0]["receipt"]))
+	if status != http.StatusForbidden {
+		t.Fatalf("Expected stale ack to be refused with 403, got %d %q", status, msg)
+	}
+	fmt.Printf("✓ Stale ack refused: %s\n", msg)
+
+	var remaining int
+	_ = db.Pool.QueryRow(context.Background(), "SELECT count(*) FROM messages WHERE id = $1", msgID).Scan(&remaining)
+	if remaining != 1 {
+		t.Fatalf("Expected message to survive the stale ack")
+	}
+
+	ackMessage(t, workerB[0])
+	fmt.Println("✓ Current holder's ack succeeds")
+}
+
+func TestExpiredReceiptRejected(t *testing.T) {
+	_, teardown := setupTestServer(t)
+	defer teardown()
+
+	fmt.Println("\n=== Test: Expired Receipt Rejected ===")
+
+	q := "receipt-expiry-test"
+	enqueueMessage(t, q, map[string]interface{}{"body": map[string]string{"n": "1"}})
+	enqueueMessage(t, q, map[string]interface{}{"body": map[string]string{"n": "2"}})
+	msgs := receiveMessages(t, q, 2, 1000)
+	if len(msgs) != 2 {
+		t.Fatalf("Expected 2 messages, got %d", len(msgs))
+	}
+	lapsed, extended := msgs[0], msgs[1]
+	if parts := strings.Split(lapsed["receipt"].(string), "."); len(parts) != 3 {
+		t.Fatalf("Expected the receipt to carry its lease expiry, got %q", lapsed["receipt"])
+	}
+
+	status, result := doJSON(t, http.MethodPost, "/v1/messages:extend-batch", map[string]interface{}{
+		"receipts": []interface{}{extended["receipt"]}, "visibility_ms": 30000,
+	})
+	renewed, _ := result["receipts"].(map[string]interface{})[fmt.Sprint(extended["id"])].(string)
+	if status != http.StatusOK || renewed == "" || renewed == extended["receipt"] {
+		t.Fatalf("Expected extend to return a renewed receipt, got %d %v", status, result)
+	}
+	fmt.Println("✓ Extend returned a renewed receipt")
+
+	time.Sleep(1200 * time.Millisecond) // past the original 1s lease
+
+	id := int64(lapsed["id"].(float64))
+	status, msg := postAck(t, id, fmt.Sprintf(`{"receipt":%q}`, lapsed["receipt"]))
+	if status != http.StatusForbidden || !strings.Contains(msg, "expired") {
+		t.Fatalf("Expected an ack past the lease to be refused, got %d %q", status, msg)
+	}
+	status, result = doJSON(t, http.MethodPost, fmt.Sprintf("/v1/messages/%d:nack", id),
+		map[string]interface{}{"receipt": lapsed["receipt"]})
+	if status != http.StatusForbidden {
+		t.Fatalf("Expected a nack past the lease to be refused, got %d %v", status, result)
+	}
+	fmt.Printf("✓ Ack and nack after the lease window refused: %s\n", msg)
+
+	extendedID := int64(extended["id"].(float64))
+	status, msg = postAck(t, extendedID, fmt.Sprintf(`{"receipt":%q}`, extended["receipt"]))
+	if status != http.StatusForbidden {
+		t.Fatalf("Expected the pre-extend receipt to have expired, got %d %q", status, msg)
+	}
+	status, msg = postAck(t, extendedID, fmt.Sprintf(`{"receipt":%q}`, renewed))
+	if status != http.StatusOK {
+		t.Fatalf("Expected the renewed receipt to ack, got %d %q", status, msg)
+	}
+	fmt.Println("✓ Renewed receipt acks after the original lease would have ended")
+}
@@ -0,0 +1,85 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestApproximateReceiveCountSurvivesRedrive(t *testing.T) {
+	_, teardown := setupTestServer(t)
+	defer teardown()
+
+	fmt.Println("\n=== Test: Approximate Receive Count Across Requeue And Redrive ===")
+
+	dlq := "rc-dlq"
+	id := enqueueMessage(t, "rc-src", map[string]interface{}{
+		"body":        map[string]string{"task": "flaky"},
+		"max_retries": 2,
+		"dlq":         dlq,
+	})
+
+	// Two unacked deliveries: one requeue, then the move to the DLQ.
+	for attempt := 1; attempt <= 2; attempt++ {
+		msgs := receiveEventually(t, "rc-src", 500)
+		if msgs[0]["delivery_count"].(float64) != float64(attempt) ||
+			msgs[0]["approximate_receive_count"].(float64) != float64(attempt) {
+			t.Fatalf("Attempt %d: got delivery_count %v, approximate_receive_count %v",
+				attempt, msgs[0]["delivery_count"], msgs[0]["approximate_receive_count"])
+		}
+	}
+	fmt.Println("✓ Both counters climb across a requeue")
+
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		_, failures := doJSON(t, http.MethodGet, "/v1/queues/"+dlq+"/failures", nil)
+		if list, _ := failures["failures"].([]interface{}); len(list) == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Message never reached the DLQ")
+		}
+		time.Sleep(250 * time.Millisecond)
+	}
+	fmt.Println("✓ Message dead-lettered")
+
+	status, result := doJSON(t, http.MethodPost, "/v1/queues/"+dlq+":redrive", map[string]int{"max": 10})
+	if status != http.StatusOK || result["redriven"].(float64) != 1 {
+		t.Fatalf("Expected 1 redriven, got %d %v", status, result)
+	}
+
+	msgs := receiveMessages(t, "rc-src", 1, 30000)
+	if len(msgs) != 1 {
+		t.Fatalf("Expected the redriven message back in rc-src, got %d", len(msgs))
+	}
+	m := msgs[0]
+	if m["body"].(map[string]interface{})["task"] != "flaky" {
+		t.Fatalf("Unexpected body %v", m["body"])
+	}
+	if m["delivery_count"].(float64) != 1 {
+		t.Fatalf("Expected a fresh delivery_count of 1, got %v", m["delivery_count"])
+	}
+	if m["approximate_receive_count"].(float64) != 3 {
+		t.Fatalf("Expected approximate_receive_count 3, got %v", m["approximate_receive_count"])
+	}
+	if m["dlq"] != dlq {
+		t.Fatalf("Expected the redriven message to dead-letter to %s again, got %v", dlq, m["dlq"])
+	}
+	ackMessage(t, m)
+	fmt.Printf("✓ Message %d redriven with delivery_count 1, approximate_receive_count 3\n", id)
+}
+
+// receiveEventually polls until one message of queue can be claimed.
+func receiveEventually(t *testing.T, queue string, visibilityMS int) []map[string]interface{} {
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		if msgs := receiveMessages(t, queue, 1, visibilityMS); len(msgs) == 1 {
+			return msgs
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("No message became available in %s", queue)
+		}
+		time.Sleep(250 * time.Millisecond)
+	}
+}
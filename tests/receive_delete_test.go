@@ -0,0 +1,60 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestReceiveAndDelete(t *testing.T) {
+	db, teardown := setupTestServer(t)
+	defer teardown()
+
+	fmt.Println("\n=== Test: Receive And Delete ===")
+
+	for i := 0; i < 3; i++ {
+		enqueueMessage(t, "rad", map[string]interface{}{"body": map[string]int{"n": i}})
+	}
+
+	body, _ := json.Marshal(map[string]interface{}{"max": 2})
+	resp, err := http.Post(
+		"http://localhost:9999/v1/queues/rad:receive-and-delete",
+		"application/json",
+		bytes.NewReader(body),
+	)
+	if err != nil {
+		t.Fatalf("Receive-and-delete failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", resp.StatusCode)
+	}
+	var messages []map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&messages)
+	if len(messages) != 2 {
+		t.Fatalf("Expected 2 messages, got %d", len(messages))
+	}
+	for _, m := range messages {
+		if m["receipt"] != nil || m["lease_until"] != nil {
+			t.Fatalf("Expected no receipt or lease, got %v", m)
+		}
+	}
+	fmt.Printf("✓ Received %d messages without receipts\n", len(messages))
+
+	// Gone at once: not in flight waiting on a sweep, not available.
+	st, err := db.Stats(context.Background(), "rad")
+	if err != nil {
+		t.Fatalf("Stats failed: %v", err)
+	}
+	if st.Available != 1 || st.InFlight != 0 {
+		t.Fatalf("Expected 1 available and 0 in flight, got %+v", st)
+	}
+	rest := receiveMessages(t, "rad", 10, 30000)
+	if len(rest) != 1 || rest[0]["id"] == messages[0]["id"] || rest[0]["id"] == messages[1]["id"] {
+		t.Fatalf("Expected only the third message left, got %v", rest)
+	}
+	fmt.Println("✓ Deleted messages are gone immediately")
+}
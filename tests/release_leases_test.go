@@ -0,0 +1,72 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestReleaseQueueLeases(t *testing.T) {
+	cfg := testConfig()
+	cfg.AdminToken = "s3cret"
+	_, teardown := setupTestServerWithConfig(t, cfg)
+	defer teardown()
+
+	fmt.Println("\n=== Test: Release Queue Leases ===")
+
+	for i := 0; i < 3; i++ {
+		enqueueMessage(t, "release-all", map[string]interface{}{"body": map[string]int{"n": i}})
+	}
+	leased := receiveMessages(t, "release-all", 10, 300000)
+	if len(leased) != 3 {
+		t.Fatalf("Expected 3 leased, got %d", len(leased))
+	}
+	if got := receiveMessages(t, "release-all", 10, 300000); len(got) != 0 {
+		t.Fatalf("Expected nothing claimable while leased, got %d", len(got))
+	}
+
+	release := func(token string) (int, map[string]interface{}) {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodPost, "http://localhost:9999/v1/queues/release-all:release-leases", nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Release leases failed: %v", err)
+		}
+		defer resp.Body.Close()
+		var out map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&out)
+		return resp.StatusCode, out
+	}
+	if status, _ := release(""); status != http.StatusUnauthorized {
+		t.Fatalf("Expected 401 without the admin token, got %d", status)
+	}
+	status, out := release("s3cret")
+	if status != http.StatusOK || out["released"] != float64(3) {
+		t.Fatalf("Expected 3 leases released, got %d %v", status, out)
+	}
+	fmt.Println("✓ Every lease in the queue released")
+
+	again := receiveMessages(t, "release-all", 10, 30000)
+	if len(again) != 3 {
+		t.Fatalf("Expected all 3 claimable at once, got %d", len(again))
+	}
+	for _, m := range again {
+		if m["delivery_count"] != float64(2) {
+			t.Fatalf("Expected the released delivery to count, got %v", m)
+		}
+	}
+	fmt.Println("✓ Released messages claimable at once, deliveries counted")
+
+	if status, _ := doJSON(t, http.MethodPost, fmt.Sprintf("/v1/messages/%d:ack", int64(leased[0]["id"].(float64))),
+		map[string]interface{}{"receipt": leased[0]["receipt"]}); status == http.StatusOK {
+		t.Fatal("Expected a receipt from before the release to be stale")
+	}
+	for _, m := range again {
+		ackMessage(t, m)
+	}
+	fmt.Println("✓ Old receipts can't ack")
+}
@@ -0,0 +1,96 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/aridsondez/AWS-SQS-LITE/internal/testutil"
+)
+
+func TestRenameQueue(t *testing.T) {
+	_, teardown := setupTestServer(t)
+	defer teardown()
+
+	fmt.Println("\n=== Test: Rename Queue ===")
+
+	oldName, newName := "ordres", "orders-renamed"
+	putQueueConfig(t, oldName, map[string]interface{}{"partitions": 2, "max_retries": 7})
+	for i := 0; i < 3; i++ {
+		enqueueMessage(t, oldName, map[string]interface{}{"body": map[string]int{"n": i}})
+	}
+
+	status, result := doJSON(t, http.MethodPost, "/v1/queues/"+oldName+":rename", map[string]interface{}{"new_name": newName})
+	if status != http.StatusOK || result["moved"] != float64(3) {
+		t.Fatalf("Expected 3 messages moved, got %d %v", status, result)
+	}
+	fmt.Println("✓ Renamed with 3 messages")
+
+	if msgs := receiveMessages(t, oldName, 10, 30000); len(msgs) != 0 {
+		t.Fatalf("Expected the old name to be empty, got %d", len(msgs))
+	}
+	msgs := receiveMessages(t, newName, 10, 30000)
+	if len(msgs) != 3 {
+		t.Fatalf("Expected 3 messages under the new name, got %d", len(msgs))
+	}
+	for _, m := range msgs {
+		ackMessage(t, m)
+	}
+	_, cfg := doJSON(t, http.MethodGet, "/v1/queues/"+newName+"/config", nil)
+	if cfg["partitions"] != float64(2) || cfg["max_retries"] != float64(7) {
+		t.Fatalf("Expected the config to move with the queue, got %v", cfg)
+	}
+	fmt.Println("✓ Messages receivable and config kept under the new name")
+
+	other := "orders-other"
+	enqueueMessage(t, other, map[string]interface{}{"body": map[string]int{"n": 1}})
+	enqueueMessage(t, newName, map[string]interface{}{"body": map[string]int{"n": 2}})
+	status, _ = doJSON(t, http.MethodPost, "/v1/queues/"+other+":rename", map[string]interface{}{"new_name": newName})
+	if status != http.StatusConflict {
+		t.Fatalf("Expected 409 renaming onto a queue with messages, got %d", status)
+	}
+	status, result = doJSON(t, http.MethodPost, "/v1/queues/"+other+":rename", map[string]interface{}{"new_name": newName, "merge": true})
+	if status != http.StatusOK || result["moved"] != float64(1) {
+		t.Fatalf("Expected the merge to move 1 message, got %d %v", status, result)
+	}
+	if msgs := receiveMessages(t, newName, 10, 30000); len(msgs) != 2 {
+		t.Fatalf("Expected both messages after the merge, got %d", len(msgs))
+	}
+	fmt.Println("✓ Rename onto a non-empty queue needs merge")
+}
+
+func TestRenameQueueMovesSubscriptionsAndCheckpoints(t *testing.T) {
+	ctx := context.Background()
+	s, teardown := testutil.SetupStore(t)
+	defer teardown()
+
+	fmt.Println("\n=== Test: Rename Queue Moves Subscriptions And Checkpoints ===")
+
+	if _, err := s.PutPushSubscription(ctx, "hooked-old", "http://example.com/hook"); err != nil {
+		t.Fatalf("PutPushSubscription failed: %v", err)
+	}
+	if _, err := s.SetCheckpoint(ctx, "hooked-old", "billing", 42); err != nil {
+		t.Fatalf("SetCheckpoint failed: %v", err)
+	}
+	if _, err := s.RenameQueue(ctx, "hooked-old", "hooked-new", false); err != nil {
+		t.Fatalf("RenameQueue failed: %v", err)
+	}
+	subs, err := s.PushSubscriptions(ctx)
+	if err != nil {
+		t.Fatalf("PushSubscriptions failed: %v", err)
+	}
+	if len(subs) != 1 || subs[0].Queue != "hooked-new" || subs[0].URL != "http://example.com/hook" {
+		t.Fatalf("Expected the webhook under the new name, got %v", subs)
+	}
+	fmt.Println("✓ Push subscription moved with the queue")
+
+	cp, err := s.GetCheckpoint(ctx, "hooked-new", "billing")
+	if err != nil || cp.LastID != 42 {
+		t.Fatalf("Expected the checkpoint under the new name, got %v, %v", cp, err)
+	}
+	if cp, _ := s.GetCheckpoint(ctx, "hooked-old", "billing"); !cp.UpdatedAt.IsZero() {
+		t.Fatalf("Expected no checkpoint left under the old name, got %v", cp)
+	}
+	fmt.Println("✓ Consumer group checkpoint moved with the queue")
+}
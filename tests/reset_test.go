@@ -0,0 +1,77 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestResetDeliveryCount(t *testing.T) {
+	cfg := testConfig()
+	cfg.AdminToken = "s3cret"
+	_, teardown := setupTestServerWithConfig(t, cfg)
+	defer teardown()
+
+	fmt.Println("\n=== Test: Reset Delivery Count ===")
+
+	id := enqueueMessage(t, "reset", map[string]interface{}{
+		"body":        map[string]string{"task": "flaky"},
+		"max_retries": 2,
+		"dlq":         "reset-dlq",
+	})
+
+	// Use up both deliveries; the second lease is the last before the DLQ.
+	receiveMessages(t, "reset", 1, 1000)
+	time.Sleep(3 * time.Second)
+	messages := receiveMessages(t, "reset", 1, 30000)
+	if len(messages) != 1 || messages[0]["delivery_count"] != float64(2) {
+		t.Fatalf("Expected the second delivery, got %v", messages)
+	}
+	stale := messages[0]
+	fmt.Println("✓ Retries exhausted (delivery_count=2, max_retries=2)")
+
+	if code := resetMessage(t, id, ""); code != http.StatusUnauthorized {
+		t.Fatalf("Expected 401 without the admin token, got %d", code)
+	}
+	if code := resetMessage(t, id, "s3cret"); code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", code)
+	}
+	if code := resetMessage(t, id+1000, "s3cret"); code != http.StatusNotFound {
+		t.Fatalf("Expected 404 for an unknown message, got %d", code)
+	}
+	fmt.Println("✓ Reset requires the admin token")
+
+	// The reset ended the lease, so it is deliverable again right away.
+	messages = receiveMessages(t, "reset", 1, 30000)
+	if len(messages) != 1 || messages[0]["delivery_count"] != float64(1) {
+		t.Fatalf("Expected a fresh delivery with delivery_count 1, got %v", messages)
+	}
+	fmt.Println("✓ Redelivered with delivery_count 1")
+
+	body, _ := json.Marshal(map[string]interface{}{"receipt": stale["receipt"]})
+	resp, err := http.Post(fmt.Sprintf("http://localhost:9999/v1/messages/%d:ack", id), "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("Ack failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("Expected the pre-reset receipt to be stale (403), got %d", resp.StatusCode)
+	}
+	fmt.Println("✓ Receipt from before the reset can't ack")
+}
+
+func resetMessage(t *testing.T, id int64, token string) int {
+	req, _ := http.NewRequest(http.MethodPost, fmt.Sprintf("http://localhost:9999/v1/messages/%d:reset", id), nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Reset failed: %v", err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
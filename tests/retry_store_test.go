@@ -0,0 +1,65 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/aridsondez/AWS-SQS-LITE/internal/queue"
+	"github.com/aridsondez/AWS-SQS-LITE/internal/queue/store"
+)
+
+func TestRetryStore(t *testing.T) {
+	fmt.Println("\n=== Test: Retry On Serialization Failure ===")
+
+	var calls int
+	var fail error
+	fake := &fakeStore{enqueue: func(ctx context.Context, m queue.Message, delay time.Duration) (int64, error) {
+		calls++
+		if calls == 1 {
+			return 0, fail
+		}
+		return 42, nil
+	}}
+	s := store.NewRetryStore(fake, 3)
+	ctx := context.Background()
+
+	fail = fmt.Errorf("enqueue: %w", &pgconn.PgError{Code: "40001"})
+	id, err := s.Enqueue(ctx, queue.Message{Queue: "retry"}, 0)
+	if err != nil || id != 42 {
+		t.Fatalf("Expected the retry to succeed with id 42, got %d, %v", id, err)
+	}
+	if calls != 2 {
+		t.Fatalf("Expected 2 calls, got %d", calls)
+	}
+	fmt.Println("✓ Serialization failure retried once, then succeeded")
+
+	calls = 0
+	fail = &pgconn.PgError{Code: "23505"} // unique_violation
+	if _, err := s.Enqueue(ctx, queue.Message{Queue: "retry"}, 0); !errors.Is(err, fail) {
+		t.Fatalf("Expected the non-retryable error back, got %v", err)
+	}
+	if calls != 1 {
+		t.Fatalf("Expected no retry, got %d calls", calls)
+	}
+	fmt.Println("✓ Non-retryable error returned untouched")
+
+	// Deadlocks forever: give up after maxRetries and surface the error.
+	calls = 0
+	deadlock := &pgconn.PgError{Code: "40P01"}
+	fake.enqueue = func(ctx context.Context, m queue.Message, delay time.Duration) (int64, error) {
+		calls++
+		return 0, deadlock
+	}
+	if _, err := s.Enqueue(ctx, queue.Message{Queue: "retry"}, 0); !errors.Is(err, deadlock) {
+		t.Fatalf("Expected the deadlock error after retries, got %v", err)
+	}
+	if calls != 4 {
+		t.Fatalf("Expected 1 call plus 3 retries, got %d", calls)
+	}
+	fmt.Println("✓ Gives up after the retry limit")
+}
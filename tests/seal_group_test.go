@@ -0,0 +1,132 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/aridsondez/AWS-SQS-LITE/internal/queue"
+	"github.com/aridsondez/AWS-SQS-LITE/internal/testutil"
+)
+
+func TestSealGroup(t *testing.T) {
+	_, teardown := setupTestServer(t)
+	defer teardown()
+
+	fmt.Println("\n=== Test: Seal Group ===")
+
+	q := "seal-group-test"
+	var ids []int64
+	for i := 0; i < 3; i++ {
+		ids = append(ids, enqueueMessage(t, q, map[string]interface{}{
+			"body": map[string]int{"step": i}, "group_id": "order-1",
+		}))
+	}
+	enqueueMessage(t, q, map[string]interface{}{"body": map[string]int{"step": 0}, "group_id": "order-2"})
+
+	status, result := doJSON(t, http.MethodPost, "/v1/queues/"+q+"/groups/order-1:seal", nil)
+	if status != http.StatusOK || result["sealed"] != true || result["last_id"] != float64(ids[2]) {
+		t.Fatalf("Expected order-1 sealed at its last message %d, got %d %v", ids[2], status, result)
+	}
+	fmt.Println("✓ Group sealed at its last message")
+
+	status, _ = doJSON(t, http.MethodPost, "/v1/queues/"+q+"/messages", map[string]interface{}{
+		"body": map[string]int{"step": 3}, "group_id": "order-1",
+	})
+	if status != http.StatusConflict {
+		t.Fatalf("Expected 409 enqueueing into a sealed group, got %d", status)
+	}
+	enqueueMessage(t, q, map[string]interface{}{"body": map[string]int{"step": 1}, "group_id": "order-2"})
+	fmt.Println("✓ Sealed group refuses enqueues; other groups don't")
+
+	msgs := receiveMessages(t, q, 10, 30000)
+	if len(msgs) != 5 {
+		t.Fatalf("Expected 5 messages, got %d", len(msgs))
+	}
+	for _, m := range msgs {
+		last := int64(m["id"].(float64)) == ids[2]
+		if sealed, _ := m["group_sealed"].(bool); sealed != last {
+			t.Fatalf("Expected group_sealed only on message %d, got %v on %v", ids[2], sealed, m["id"])
+		}
+	}
+	fmt.Println("✓ Only the sealed group's final message carries group_sealed")
+
+	status, result = doJSON(t, http.MethodPost, "/v1/queues/"+q+"/groups/order-1:seal", nil)
+	if status != http.StatusOK || result["last_id"] != float64(ids[2]) {
+		t.Fatalf("Expected sealing again to keep last_id %d, got %d %v", ids[2], status, result)
+	}
+	status, result = doJSON(t, http.MethodPost, "/v1/queues/"+q+"/groups/empty:seal", nil)
+	if status != http.StatusOK || result["last_id"] != nil {
+		t.Fatalf("Expected a null last_id for an empty group, got %d %v", status, result)
+	}
+	fmt.Println("✓ Resealing is a no-op; an empty group seals with no last message")
+
+	entries := []map[string]interface{}{
+		{"body": map[string]int{"step": 2}, "group_id": "order-2"},
+		{"body": map[string]int{"step": 4}, "group_id": "order-1"},
+		{"body": map[string]int{"step": 3}, "group_id": "order-2"},
+	}
+	status, result = doJSON(t, http.MethodPost, "/v1/queues/"+q+"/messages:batch",
+		map[string]interface{}{"entries": entries, "atomic": true})
+	if status != http.StatusConflict {
+		t.Fatalf("Expected 409 for an atomic batch into a sealed group, got %d %v", status, result)
+	}
+	status, result = doJSON(t, http.MethodPost, "/v1/queues/"+q+"/messages:batch",
+		map[string]interface{}{"entries": entries})
+	if status != http.StatusMultiStatus {
+		t.Fatalf("Expected 207 for a best-effort batch into a sealed group, got %d %v", status, result)
+	}
+	results := result["results"].([]interface{})
+	for i, raw := range results {
+		res := raw.(map[string]interface{})
+		if refused := i == 1; (res["error"] != nil) != refused || (res["id"] != nil) == refused {
+			t.Fatalf("Expected only entry 1 refused, got %v", results)
+		}
+	}
+	if n := len(receiveMessages(t, q, 10, 30000)); n != 2 {
+		t.Fatalf("Expected the batch's 2 order-2 messages enqueued, got %d", n)
+	}
+	fmt.Println("✓ A batch reports the sealed group's entry alone and enqueues the rest")
+}
+
+func TestSweeperPrunesDrainedSeals(t *testing.T) {
+	ctx := context.Background()
+	s, teardown := testutil.SetupStore(t)
+	defer teardown()
+
+	fmt.Println("\n=== Test: Sweeper Prunes Drained Seals ===")
+
+	q := "seal-prune-test"
+	group := "order-1"
+	if _, err := s.Enqueue(ctx, queue.Message{Queue: q, Body: []byte(`{}`), GroupID: &group}, 0); err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+	for _, g := range []string{"order-1", "drained", "fresh"} {
+		if _, err := s.SealGroup(ctx, q, g); err != nil {
+			t.Fatalf("Seal %s failed: %v", g, err)
+		}
+	}
+	if _, err := s.Pool.Exec(ctx, "UPDATE sealed_groups SET sealed_at = now() - interval '2 hours' WHERE group_id <> 'fresh'"); err != nil {
+		t.Fatalf("Backdating seals failed: %v", err)
+	}
+
+	if _, err := s.Sweeper(ctx, queue.SweepOptions{}); err != nil {
+		t.Fatalf("Sweep failed: %v", err)
+	}
+	var left []string
+	rows, err := s.Pool.Query(ctx, "SELECT group_id FROM sealed_groups WHERE queue = $1 ORDER BY group_id", q)
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	for rows.Next() {
+		var g string
+		_ = rows.Scan(&g)
+		left = append(left, g)
+	}
+	rows.Close()
+	if len(left) != 2 || left[0] != "fresh" || left[1] != "order-1" {
+		t.Fatalf("Expected only the drained old seal pruned, have %v", left)
+	}
+	fmt.Println("✓ Old seals of drained groups pruned; live and recent ones kept")
+}
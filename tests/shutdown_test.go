@@ -0,0 +1,241 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aridsondez/AWS-SQS-LITE/internal/api"
+	"github.com/aridsondez/AWS-SQS-LITE/internal/queue"
+	"github.com/aridsondez/AWS-SQS-LITE/internal/queue/sweeper"
+	"github.com/aridsondez/AWS-SQS-LITE/internal/testutil"
+)
+
+func TestShutdownReleasesLongPoll(t *testing.T) {
+	db, closeDB := testutil.SetupStore(t)
+	defer closeDB()
+	ctx := context.Background()
+
+	fmt.Println("\n=== Test: Shutdown Mid-Long-Poll ===")
+
+	srv := api.NewServer(":9998", db, testConfig())
+	go func() {
+		_ = srv.ListenAndServe()
+	}()
+	time.Sleep(100 * time.Millisecond)
+
+	if _, err := db.Enqueue(ctx, queue.Message{Queue: "shutdown-poll", Body: []byte(`{}`), MaxRetries: 3}, 0); err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+
+	// Waits for a second message that never comes, until the server stops.
+	type result struct {
+		status   int
+		messages []map[string]interface{}
+		err      error
+	}
+	done := make(chan result, 1)
+	go func() {
+		body, _ := json.Marshal(map[string]interface{}{"max": 2, "min_messages": 2, "wait_ms": 10000})
+		resp, err := http.Post("http://localhost:9998/v1/queues/shutdown-poll:receive", "application/json", bytes.NewReader(body))
+		if err != nil {
+			done <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		var messages []map[string]interface{}
+		err = json.NewDecoder(resp.Body).Decode(&messages)
+		done <- result{status: resp.StatusCode, messages: messages, err: err}
+	}()
+	time.Sleep(300 * time.Millisecond)
+
+	start := time.Now()
+	shutCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutCtx); err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+	res := <-done
+	if res.err != nil || res.status != http.StatusOK || len(res.messages) != 0 {
+		t.Fatalf("Expected an empty 200 from the cut-short long poll, got %d %v %v", res.status, res.messages, res.err)
+	}
+	fmt.Printf("✓ Long poll ended by shutdown after %v\n", time.Since(start).Round(time.Millisecond))
+
+	out, err := db.Claim(ctx, queue.ClaimOptions{Queue: "shutdown-poll", Limit: 2, Visibility: time.Minute})
+	if err != nil || len(out) != 1 {
+		t.Fatalf("Expected the message claimable right away, got %d, %v", len(out), err)
+	}
+	if out[0].DeliveryCount != 1 {
+		t.Fatalf("Expected the released delivery not to count, got delivery_count %d", out[0].DeliveryCount)
+	}
+	fmt.Println("✓ Message immediately claimable after shutdown, uncounted")
+}
+
+// gatedClaimStore holds ClaimMulti until gate is closed.
+type gatedClaimStore struct {
+	*testutil.Store
+	entered chan struct{}
+	gate    chan struct{}
+}
+
+func (g *gatedClaimStore) ClaimMulti(ctx context.Context, opts queue.ClaimMultiOptions) ([]queue.Message, error) {
+	close(g.entered)
+	<-g.gate
+	return g.Store.ClaimMulti(ctx, opts)
+}
+
+func TestShutdownReleasesMultiReceive(t *testing.T) {
+	db, closeDB := testutil.SetupStore(t)
+	defer closeDB()
+	ctx := context.Background()
+
+	fmt.Println("\n=== Test: Shutdown Mid-Multi-Receive ===")
+
+	gated := &gatedClaimStore{Store: db, entered: make(chan struct{}), gate: make(chan struct{})}
+	srv := api.NewServer(":9998", gated, testConfig())
+	go func() {
+		_ = srv.ListenAndServe()
+	}()
+	time.Sleep(100 * time.Millisecond)
+
+	for _, q := range []string{"shutdown-multi-a", "shutdown-multi-b"} {
+		if _, err := db.Enqueue(ctx, queue.Message{Queue: q, Body: []byte(`{}`), MaxRetries: 3}, 0); err != nil {
+			t.Fatalf("Enqueue failed: %v", err)
+		}
+	}
+
+	type result struct {
+		status   int
+		messages []map[string]interface{}
+		err      error
+	}
+	done := make(chan result, 1)
+	go func() {
+		body, _ := json.Marshal(map[string]interface{}{
+			"queues": []map[string]interface{}{{"name": "shutdown-multi-a"}, {"name": "shutdown-multi-b"}},
+			"max":    2,
+		})
+		resp, err := http.Post("http://localhost:9998/v1/queues:receive", "application/json", bytes.NewReader(body))
+		if err != nil {
+			done <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		var messages []map[string]interface{}
+		err = json.NewDecoder(resp.Body).Decode(&messages)
+		done <- result{status: resp.StatusCode, messages: messages, err: err}
+	}()
+	<-gated.entered
+
+	// the claim lands once shutdown has begun
+	shutDone := make(chan error, 1)
+	go func() { shutDone <- srv.Shutdown(ctx) }()
+	time.Sleep(100 * time.Millisecond)
+	close(gated.gate)
+
+	res := <-done
+	if res.err != nil || res.status != http.StatusOK || len(res.messages) != 0 {
+		t.Fatalf("Expected an empty 200 from the multi-receive, got %d %v %v", res.status, res.messages, res.err)
+	}
+	if err := <-shutDone; err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+	fmt.Println("✓ Multi-receive claiming during shutdown answered empty")
+
+	for _, q := range []string{"shutdown-multi-a", "shutdown-multi-b"} {
+		out, err := db.Claim(ctx, queue.ClaimOptions{Queue: q, Limit: 1, Visibility: time.Minute})
+		if err != nil || len(out) != 1 || out[0].DeliveryCount != 1 {
+			t.Fatalf("Expected %s's message claimable right away and uncounted, got %v, %v", q, out, err)
+		}
+	}
+	fmt.Println("✓ Both queues' messages immediately claimable after shutdown, uncounted")
+}
+
+// TestShutdownOrder runs cmd/api's shutdown sequence against a fake store:
+// the HTTP server drains while the sweeper keeps sweeping, then the sweeper
+// stops, letting the sweep it is in finish.
+func TestShutdownOrder(t *testing.T) {
+	fmt.Println("\n=== Test: Shutdown Order ===")
+
+	goroutines := runtime.NumGoroutine()
+
+	var (
+		running, sweeps, cancelled atomic.Int32
+		serverDown                 atomic.Bool
+		sweptAfterDrain            atomic.Bool
+	)
+	fake := &fakeStore{sweep: func(ctx context.Context, opts queue.SweepOptions) (int, error) {
+		running.Add(1)
+		defer running.Add(-1)
+		if serverDown.Load() {
+			sweptAfterDrain.Store(true)
+		}
+		time.Sleep(50 * time.Millisecond)
+		if ctx.Err() != nil {
+			cancelled.Add(1)
+		}
+		sweeps.Add(1)
+		return 0, nil
+	}}
+	swp := sweeper.New(fake, 10*time.Millisecond, queue.SweepOptions{})
+	go swp.Start(context.Background())
+
+	srv := api.NewServer(":9997", fake, testConfig())
+	served := make(chan error, 1)
+	go func() { served <- srv.ListenAndServe() }()
+	time.Sleep(100 * time.Millisecond)
+
+	resp, err := http.Get("http://localhost:9997/healthz")
+	if err != nil {
+		t.Fatalf("healthz failed: %v", err)
+	}
+	resp.Body.Close()
+	http.DefaultClient.CloseIdleConnections()
+
+	if err := srv.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+	if err := <-served; err != http.ErrServerClosed {
+		t.Fatalf("Expected ErrServerClosed, got %v", err)
+	}
+	serverDown.Store(true)
+	if sweeps.Load() == 0 {
+		t.Fatal("Expected sweeps while the server was up")
+	}
+	// wait until a sweep starts after the drain, so Stop lands mid-sweep
+	for deadline := time.Now().Add(time.Second); !sweptAfterDrain.Load() || running.Load() == 0; {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the sweeper still sweeping after the server drained")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	fmt.Println("✓ Sweeper keeps sweeping while and after the server drains")
+
+	swp.Stop()
+	if running.Load() != 0 {
+		t.Fatal("Expected no sweep running once Stop returns")
+	}
+	if cancelled.Load() != 0 {
+		t.Fatalf("Expected the last sweep to finish uncancelled, %d were cancelled", cancelled.Load())
+	}
+	n := sweeps.Load()
+	time.Sleep(50 * time.Millisecond)
+	if sweeps.Load() != n {
+		t.Fatal("Expected no sweeps after Stop")
+	}
+	fmt.Println("✓ Stop waits for the sweep in progress, then none run")
+
+	for deadline := time.Now().Add(time.Second); runtime.NumGoroutine() > goroutines; {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected at most %d goroutines after shutdown, have %d", goroutines, runtime.NumGoroutine())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	fmt.Println("✓ No goroutines left behind")
+}
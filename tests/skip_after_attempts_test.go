@@ -0,0 +1,73 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestPoisonedGroupLeadIsSkipped(t *testing.T) {
+	_, teardown := setupTestServer(t)
+	defer teardown()
+
+	fmt.Println("\n=== Test: Poisoned Group Lead Is Skipped ===")
+
+	putQueueConfig(t, "skip-group", map[string]interface{}{
+		"partitions":          1,
+		"max_retries":         10,
+		"skip_after_attempts": 2,
+	})
+	for i := 1; i <= 3; i++ {
+		enqueueMessage(t, "skip-group", map[string]interface{}{
+			"body":     map[string]int{"step": i},
+			"group_id": "order-7",
+		})
+	}
+
+	// The lead is taken on a short lease and never acked.
+	lead := receiveMessages(t, "skip-group", 1, 500)
+	if len(lead) != 1 {
+		t.Fatalf("Expected the lead message, got %d", len(lead))
+	}
+	rest := receiveMessages(t, "skip-group", 2, 30000)
+	if len(rest) != 2 {
+		t.Fatalf("Expected 2 group-mates, got %d", len(rest))
+	}
+	receipts := []string{rest[0]["receipt"].(string), rest[1]["receipt"].(string)}
+
+	status, result := ackBatch(t, receipts)
+	if status != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", status)
+	}
+	if len(result.Acked) != 0 {
+		t.Fatalf("Expected group-mates blocked behind a first-attempt lead, got %+v", result)
+	}
+	fmt.Println("✓ Group-mates wait on the lead's first attempt")
+
+	// Wait for the sweeper to hand the lead out again; its second delivery
+	// reaches skip_after_attempts.
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		lead = receiveMessages(t, "skip-group", 1, 500)
+		if len(lead) == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Lead was never redelivered")
+		}
+		time.Sleep(250 * time.Millisecond)
+	}
+	if lead[0]["delivery_count"].(float64) != 2 {
+		t.Fatalf("Expected delivery_count 2, got %v", lead[0]["delivery_count"])
+	}
+
+	status, result = ackBatch(t, receipts)
+	if status != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", status)
+	}
+	if len(result.Acked) != 2 || len(result.Rejected) != 0 {
+		t.Fatalf("Expected both group-mates acked past the poisoned lead, got %+v", result)
+	}
+	fmt.Println("✓ Group-mates flow once the lead hits skip_after_attempts")
+}
@@ -0,0 +1,66 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/aridsondez/AWS-SQS-LITE/internal/metrics"
+	"github.com/aridsondez/AWS-SQS-LITE/internal/queue"
+	"github.com/aridsondez/AWS-SQS-LITE/internal/queue/store"
+)
+
+// fakeStore is a store.Store whose methods panic unless overridden; tests
+// set only the funcs they exercise.
+type fakeStore struct {
+	store.Store
+	enqueue func(ctx context.Context, m queue.Message, delay time.Duration) (int64, error)
+	stats   func(ctx context.Context, name string) (queue.Stats, error)
+	sweep   func(ctx context.Context, opts queue.SweepOptions) (int, error)
+}
+
+func (f *fakeStore) Enqueue(ctx context.Context, m queue.Message, delay time.Duration) (int64, error) {
+	return f.enqueue(ctx, m, delay)
+}
+
+func (f *fakeStore) Stats(ctx context.Context, name string) (queue.Stats, error) {
+	return f.stats(ctx, name)
+}
+
+func (f *fakeStore) Sweeper(ctx context.Context, opts queue.SweepOptions) (int, error) {
+	return f.sweep(ctx, opts)
+}
+
+func TestSlowQueryStore(t *testing.T) {
+	fmt.Println("\n=== Test: Slow Query Logging ===")
+
+	delay := 0 * time.Millisecond
+	fake := &fakeStore{stats: func(ctx context.Context, name string) (queue.Stats, error) {
+		time.Sleep(delay)
+		return queue.Stats{Available: 7}, nil
+	}}
+	s := store.NewSlowQueryStore(fake, 50*time.Millisecond)
+	slow := metrics.SlowQueries.WithLabelValues("Stats")
+	before := testutil.ToFloat64(slow)
+
+	st, err := s.Stats(context.Background(), "slow-q")
+	if err != nil || st.Available != 7 {
+		t.Fatalf("Expected the wrapped result, got %+v, %v", st, err)
+	}
+	if got := testutil.ToFloat64(slow) - before; got != 0 {
+		t.Fatalf("Expected a fast call not to count, got %v", got)
+	}
+	fmt.Println("✓ Fast call passes through uncounted")
+
+	delay = 80 * time.Millisecond
+	if _, err := s.Stats(context.Background(), "slow-q"); err != nil {
+		t.Fatalf("Stats failed: %v", err)
+	}
+	if got := testutil.ToFloat64(slow) - before; got != 1 {
+		t.Fatalf("Expected sqs_slow_queries_total{op=\"Stats\"} to rise by 1, got %v", got)
+	}
+	fmt.Println("✓ Slow call counted")
+}
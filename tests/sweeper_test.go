@@ -0,0 +1,463 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	promtest "github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/aridsondez/AWS-SQS-LITE/internal/metrics"
+	"github.com/aridsondez/AWS-SQS-LITE/internal/queue"
+	"github.com/aridsondez/AWS-SQS-LITE/internal/queue/sweeper"
+	"github.com/aridsondez/AWS-SQS-LITE/internal/testutil"
+)
+
+func TestSweeperLagGauge(t *testing.T) {
+	ctx := context.Background()
+	s, teardown := testutil.SetupStore(t)
+	defer teardown()
+
+	fmt.Println("\n=== Test: Sweeper Lag Gauge ===")
+
+	// no background sweeper here: we drive sweeps by hand
+	for i := 0; i < 3; i++ {
+		if _, err := s.Enqueue(ctx, queue.Message{Queue: "lag-test", Body: []byte(`{}`)}, 0); err != nil {
+			t.Fatalf("Enqueue failed: %v", err)
+		}
+	}
+	claimed, err := s.Claim(ctx, queue.ClaimOptions{Queue: "lag-test", Limit: 3, Visibility: time.Millisecond})
+	if err != nil || len(claimed) != 3 {
+		t.Fatalf("Expected to claim 3, got %d (%v)", len(claimed), err)
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	if _, err := s.Sweeper(ctx, queue.SweepOptions{}); err != nil {
+		t.Fatalf("Sweep failed: %v", err)
+	}
+	if lag := promtest.ToFloat64(metrics.SweeperLag); lag != 3 {
+		t.Fatalf("Expected lag gauge 3, got %v", lag)
+	}
+	fmt.Println("✓ Lag gauge reflects the expired backlog")
+
+	if _, err := s.Sweeper(ctx, queue.SweepOptions{}); err != nil {
+		t.Fatalf("Sweep failed: %v", err)
+	}
+	if lag := promtest.ToFloat64(metrics.SweeperLag); lag != 0 {
+		t.Fatalf("Expected lag gauge 0 after the backlog was swept, got %v", lag)
+	}
+	fmt.Println("✓ Lag gauge drops once swept")
+}
+
+func TestOldestInFlightAgeGauge(t *testing.T) {
+	ctx := context.Background()
+	s, teardown := testutil.SetupStore(t)
+	defer teardown()
+
+	fmt.Println("\n=== Test: Oldest In-Flight Age Gauge ===")
+
+	if _, err := s.Enqueue(ctx, queue.Message{Queue: "inflight-age", Body: []byte(`{}`)}, 0); err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+	claimed, err := s.Claim(ctx, queue.ClaimOptions{Queue: "inflight-age", Limit: 1, Visibility: time.Second})
+	if err != nil || len(claimed) != 1 {
+		t.Fatalf("Expected to claim 1, got %d (%v)", len(claimed), err)
+	}
+	gauge := func() float64 {
+		if _, err := s.Sweeper(ctx, queue.SweepOptions{}); err != nil {
+			t.Fatalf("Sweep failed: %v", err)
+		}
+		return promtest.ToFloat64(metrics.OldestInFlightAge.WithLabelValues("inflight-age"))
+	}
+
+	first := gauge()
+	time.Sleep(300 * time.Millisecond)
+	second := gauge()
+	if second <= first || second < 0.3 {
+		t.Fatalf("Expected the age to climb while leased, got %v then %v", first, second)
+	}
+	fmt.Println("✓ Age climbs while the lease is live")
+
+	time.Sleep(time.Second)
+	if age := gauge(); age != 0 {
+		t.Fatalf("Expected no in-flight age once the lease expired, got %v", age)
+	}
+	fmt.Println("✓ Gauge clears once the lease expires")
+}
+
+func TestRapidRequeueMetric(t *testing.T) {
+	ctx := context.Background()
+	s, teardown := testutil.SetupStore(t)
+	defer teardown()
+
+	fmt.Println("\n=== Test: Rapid Requeue Metric ===")
+
+	for i := 0; i < 3; i++ {
+		if _, err := s.Enqueue(ctx, queue.Message{Queue: "rapid-requeue", Body: []byte(`{}`), MaxRetries: 5}, 0); err != nil {
+			t.Fatalf("Enqueue failed: %v", err)
+		}
+	}
+	rapid := metrics.RapidRequeues.WithLabelValues("rapid-requeue")
+	before := promtest.ToFloat64(rapid)
+
+	claimed, err := s.Claim(ctx, queue.ClaimOptions{Queue: "rapid-requeue", Limit: 2, Visibility: 100 * time.Millisecond})
+	if err != nil || len(claimed) != 2 {
+		t.Fatalf("Expected to claim 2, got %d (%v)", len(claimed), err)
+	}
+	time.Sleep(200 * time.Millisecond)
+	if _, err := s.Sweeper(ctx, queue.SweepOptions{}); err != nil {
+		t.Fatalf("Sweep failed: %v", err)
+	}
+	if got := promtest.ToFloat64(rapid) - before; got != 2 {
+		t.Fatalf("Expected 2 rapid requeues for a 100ms visibility, got %v", got)
+	}
+	fmt.Println("✓ Sub-second leases counted as rapid requeues")
+
+	// A nack ends the lease just as quickly, but on purpose.
+	nacked, err := s.Claim(ctx, queue.ClaimOptions{Queue: "rapid-requeue", Limit: 1, Visibility: time.Hour})
+	if err != nil || len(nacked) != 1 {
+		t.Fatalf("Expected to claim 1, got %d (%v)", len(nacked), err)
+	}
+	if ok, err := s.Nack(ctx, nacked[0].Receipt(), "boom"); err != nil || !ok {
+		t.Fatalf("Nack failed: %v %v", ok, err)
+	}
+	before = promtest.ToFloat64(rapid)
+	if n, err := s.Sweeper(ctx, queue.SweepOptions{}); err != nil || n != 1 {
+		t.Fatalf("Expected the nacked message requeued, got %d (%v)", n, err)
+	}
+	if got := promtest.ToFloat64(rapid) - before; got != 0 {
+		t.Fatalf("Expected a nack not to count, got %v", got)
+	}
+	fmt.Println("✓ Nacked messages not counted")
+
+	// Nor does a lease its consumer cut short with an extend.
+	extended, err := s.Claim(ctx, queue.ClaimOptions{Queue: "rapid-requeue", Limit: 1, Visibility: time.Hour})
+	if err != nil || len(extended) != 1 {
+		t.Fatalf("Expected to claim 1, got %d (%v)", len(extended), err)
+	}
+	if ids, err := s.ExtendBatch(ctx, []queue.Receipt{extended[0].Receipt()}, time.Millisecond); err != nil || len(ids) != 1 {
+		t.Fatalf("Extend failed: %v %v", ids, err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	before = promtest.ToFloat64(rapid)
+	if n, err := s.Sweeper(ctx, queue.SweepOptions{}); err != nil || n != 1 {
+		t.Fatalf("Expected the extended message requeued, got %d (%v)", n, err)
+	}
+	if got := promtest.ToFloat64(rapid) - before; got != 0 {
+		t.Fatalf("Expected an extended lease not to count, got %v", got)
+	}
+	fmt.Println("✓ Extended leases not counted")
+}
+
+func TestSweeperPurgesOldDLQMessages(t *testing.T) {
+	ctx := context.Background()
+	s, teardown := testutil.SetupStore(t)
+	defer teardown()
+
+	fmt.Println("\n=== Test: DLQ Retention Purge ===")
+
+	// one dead letter past retention, one inside it, one ordinary message
+	_, err := s.Pool.Exec(ctx, `
+INSERT INTO messages (queue, body, dlqd_at) VALUES
+  ('purge-dlq', '{"age":"old"}', now() - interval '2 hours'),
+  ('purge-dlq', '{"age":"new"}', now() - interval '10 minutes'),
+  ('purge-dlq', '{"age":"live"}', NULL)`)
+	if err != nil {
+		t.Fatalf("Seed failed: %v", err)
+	}
+
+	// zero retention keeps everything
+	if _, err := s.Sweeper(ctx, queue.SweepOptions{}); err != nil {
+		t.Fatalf("Sweep failed: %v", err)
+	}
+	if st, _ := s.Stats(ctx, "purge-dlq"); st.Available != 3 {
+		t.Fatalf("Expected nothing purged without retention, have %d", st.Available)
+	}
+	fmt.Println("✓ Retention is opt-in")
+
+	before := promtest.ToFloat64(metrics.DLQPurged)
+	if _, err := s.Sweeper(ctx, queue.SweepOptions{DLQRetention: time.Hour}); err != nil {
+		t.Fatalf("Sweep failed: %v", err)
+	}
+	var left []string
+	rows, err := s.Pool.Query(ctx, "SELECT body->>'age' FROM messages WHERE queue = 'purge-dlq' ORDER BY id")
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	for rows.Next() {
+		var age string
+		_ = rows.Scan(&age)
+		left = append(left, age)
+	}
+	rows.Close()
+	if len(left) != 2 || left[0] != "new" || left[1] != "live" {
+		t.Fatalf("Expected only the old dead letter purged, left %v", left)
+	}
+	if purged := promtest.ToFloat64(metrics.DLQPurged) - before; purged != 1 {
+		t.Fatalf("Expected purge metric +1, got %v", purged)
+	}
+	fmt.Println("✓ Dead letter past retention purged")
+}
+
+func TestSweeperMaxLifetime(t *testing.T) {
+	ctx := context.Background()
+	s, teardown := testutil.SetupStore(t)
+	defer teardown()
+
+	fmt.Println("\n=== Test: Max Message Lifetime ===")
+
+	never, err := s.Enqueue(ctx, queue.Message{Queue: "lifetime-test", Body: []byte(`{"n":1}`)}, 0)
+	if err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+	if _, err := s.Enqueue(ctx, queue.Message{Queue: "lifetime-test", Body: []byte(`{"n":2}`)}, time.Hour); err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+	leased, err := s.Claim(ctx, queue.ClaimOptions{Queue: "lifetime-test", Limit: 1, Visibility: time.Hour})
+	if err != nil || len(leased) != 1 || leased[0].ID != never {
+		t.Fatalf("Expected to lease message %d, got %v (%v)", never, leased, err)
+	}
+	if _, err := s.Enqueue(ctx, queue.Message{Queue: "lifetime-test", Body: []byte(`{"n":3}`)}, 0); err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+	dead, err := s.Enqueue(ctx, queue.Message{Queue: "lifetime-test", Body: []byte(`{"n":5}`)}, 0)
+	if err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+	if _, err := s.Pool.Exec(ctx, "UPDATE messages SET dlqd_at = now() WHERE id = $1", dead); err != nil {
+		t.Fatalf("Dead-lettering failed: %v", err)
+	}
+	time.Sleep(300 * time.Millisecond)
+	fresh, err := s.Enqueue(ctx, queue.Message{Queue: "lifetime-test", Body: []byte(`{"n":4}`)}, 0)
+	if err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+
+	if _, err := s.Sweeper(ctx, queue.SweepOptions{}); err != nil {
+		t.Fatalf("Sweep failed: %v", err)
+	}
+	var n int
+	if err := s.Pool.QueryRow(ctx, "SELECT count(*) FROM messages WHERE queue = 'lifetime-test'").Scan(&n); err != nil || n != 5 {
+		t.Fatalf("Expected nothing deleted without a lifetime, have %d (%v)", n, err)
+	}
+	fmt.Println("✓ No lifetime keeps everything")
+
+	before := promtest.ToFloat64(metrics.MessagesExpired)
+	if _, err := s.Sweeper(ctx, queue.SweepOptions{MaxLifetime: 200 * time.Millisecond}); err != nil {
+		t.Fatalf("Sweep failed: %v", err)
+	}
+	var left []int64
+	rows, err := s.Pool.Query(ctx, "SELECT id FROM messages WHERE queue = 'lifetime-test'")
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	for rows.Next() {
+		var id int64
+		_ = rows.Scan(&id)
+		left = append(left, id)
+	}
+	rows.Close()
+	if len(left) != 2 || !slices.Contains(left, fresh) || !slices.Contains(left, dead) {
+		t.Fatalf("Expected messages %d and %d left, have %v", fresh, dead, left)
+	}
+	if expired := promtest.ToFloat64(metrics.MessagesExpired) - before; expired != 3 {
+		t.Fatalf("Expected expired metric +3, got %v", expired)
+	}
+	fmt.Println("✓ Old messages deleted whether waiting, delayed or leased; dead letters kept")
+}
+
+func TestSweeperDeliveryCeiling(t *testing.T) {
+	ctx := context.Background()
+	s, teardown := testutil.SetupStore(t)
+	defer teardown()
+
+	fmt.Println("\n=== Test: Delivery Attempts Ceiling ===")
+
+	const ceiling = 3
+	dlq := "ceiling-dlq"
+	withDLQ, err := s.Enqueue(ctx, queue.Message{Queue: "ceiling", Body: []byte(`{"dlq":true}`), MaxRetries: 1000000, DLQ: &dlq}, 0)
+	if err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+	if _, err := s.Enqueue(ctx, queue.Message{Queue: "ceiling", Body: []byte(`{"dlq":false}`), MaxRetries: 1000000}, 0); err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+
+	opts := queue.SweepOptions{MaxDeliveries: ceiling}
+	droppedBefore := promtest.ToFloat64(metrics.MessagesDropped)
+	for delivery := 1; delivery <= ceiling; delivery++ {
+		claimed, err := s.Claim(ctx, queue.ClaimOptions{Queue: "ceiling", Limit: 2, Visibility: time.Millisecond})
+		if err != nil || len(claimed) != 2 {
+			t.Fatalf("Delivery %d: expected to claim both, got %d (%v)", delivery, len(claimed), err)
+		}
+		time.Sleep(20 * time.Millisecond)
+		if _, err := s.Sweeper(ctx, opts); err != nil {
+			t.Fatalf("Sweep failed: %v", err)
+		}
+	}
+
+	if st, _ := s.Stats(ctx, "ceiling"); st.Available+st.InFlight != 0 {
+		t.Fatalf("Expected the ceiling to take both messages off the queue, have %+v", st)
+	}
+	claimed, err := s.Claim(ctx, queue.ClaimOptions{Queue: dlq, Limit: 2, Visibility: time.Minute})
+	if err != nil || len(claimed) != 1 || string(claimed[0].Body) != `{"dlq": true}` {
+		t.Fatalf("Expected the message with a DLQ dead-lettered, got %d (%v)", len(claimed), err)
+	}
+	fmt.Printf("✓ max_retries=1000000 dead-lettered after %d deliveries (was message %d)\n", ceiling, withDLQ)
+
+	if dropped := promtest.ToFloat64(metrics.MessagesDropped) - droppedBefore; dropped != 1 {
+		t.Fatalf("Expected the message without a DLQ dropped, metric +%v", dropped)
+	}
+	fmt.Println("✓ Message without a DLQ deleted at the ceiling")
+}
+
+func TestSweeperBatchesDeliveryCeiling(t *testing.T) {
+	ctx := context.Background()
+	s, teardown := testutil.SetupStore(t)
+	defer teardown()
+
+	fmt.Println("\n=== Test: Batched Delivery Ceiling Drop ===")
+
+	// 7 expired leases at the ceiling with no DLQ, across two queues
+	_, err := s.Pool.Exec(ctx, `
+INSERT INTO messages (queue, body, lease_until, delivery_count, max_retries)
+SELECT CASE WHEN n <= 5 THEN 'ceiling-batch-a' ELSE 'ceiling-batch-b' END, jsonb_build_object('n', n),
+       now() - interval '1 second', 3, 1000000
+FROM generate_series(1, 7) n`)
+	if err != nil {
+		t.Fatalf("Seed failed: %v", err)
+	}
+
+	droppedBefore := promtest.ToFloat64(metrics.MessagesDropped)
+	// a batch of 3 takes three statements to get through them all
+	if _, err := s.Sweeper(ctx, queue.SweepOptions{MaxDeliveries: 3, BatchSize: 3}); err != nil {
+		t.Fatalf("Sweep failed: %v", err)
+	}
+	if dropped := promtest.ToFloat64(metrics.MessagesDropped) - droppedBefore; dropped != 7 {
+		t.Fatalf("Expected all 7 dropped in one sweep, metric +%v", dropped)
+	}
+	for _, q := range []string{"ceiling-batch-a", "ceiling-batch-b"} {
+		if n, err := s.Count(ctx, q, queue.CountFilter{}); err != nil || n != 0 {
+			t.Fatalf("Expected %s emptied, have %d (%v)", q, n, err)
+		}
+	}
+	fmt.Println("✓ One sweep drops every capped message, a batch at a time")
+}
+
+func TestSweeperBatchesRequeueAndDLQ(t *testing.T) {
+	ctx := context.Background()
+	s, teardown := testutil.SetupStore(t)
+	defer teardown()
+
+	fmt.Println("\n=== Test: Batched Requeue And DLQ Sweep ===")
+
+	// 10 expired leases with retries left, 7 exhausted with a DLQ, and 3
+	// exhausted without one, which are requeued as before
+	_, err := s.Pool.Exec(ctx, `
+INSERT INTO messages (queue, body, lease_until, delivery_count, max_retries, dlq)
+SELECT 'sweep-batch', jsonb_build_object('n', n), now() - interval '1 second',
+       CASE WHEN n <= 10 THEN 1 ELSE 3 END, 3,
+       CASE WHEN n BETWEEN 11 AND 17 THEN 'sweep-batch-dlq' END
+FROM generate_series(1, 20) n`)
+	if err != nil {
+		t.Fatalf("Seed failed: %v", err)
+	}
+
+	requeuedBefore := promtest.ToFloat64(metrics.MessagesRequeued)
+	dlqdBefore := promtest.ToFloat64(metrics.MessagesDLQd)
+	// a batch of 4 takes several transactions to get through both sets
+	processed, err := s.Sweeper(ctx, queue.SweepOptions{BatchSize: 4})
+	if err != nil {
+		t.Fatalf("Sweep failed: %v", err)
+	}
+	if processed != 20 {
+		t.Fatalf("Expected all 20 swept in one sweep, got %d", processed)
+	}
+	if st, _ := s.Stats(ctx, "sweep-batch"); st.Available != 13 || st.InFlight != 0 {
+		t.Fatalf("Expected 13 requeued and none left in flight, have %+v", st)
+	}
+	var dead int
+	if err := s.Pool.QueryRow(ctx, `
+SELECT count(*) FROM messages
+WHERE queue = 'sweep-batch-dlq' AND dlqd_at IS NOT NULL AND dlq_source = 'sweep-batch' AND dlq_deliveries = 3`).Scan(&dead); err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if dead != 7 {
+		t.Fatalf("Expected 7 dead letters with their source and deliveries, got %d", dead)
+	}
+	if got := promtest.ToFloat64(metrics.MessagesRequeued) - requeuedBefore; got != 13 {
+		t.Fatalf("Expected requeue metric +13, got %v", got)
+	}
+	if got := promtest.ToFloat64(metrics.MessagesDLQd) - dlqdBefore; got != 7 {
+		t.Fatalf("Expected DLQ metric +7, got %v", got)
+	}
+	fmt.Println("✓ Requeue and DLQ candidates swept together across batches")
+}
+
+func TestSweeperBacksOffOnErrors(t *testing.T) {
+	fmt.Println("\n=== Test: Sweeper Backs Off On Errors ===")
+
+	const (
+		interval = 20 * time.Millisecond
+		failing  = 3
+	)
+	var (
+		mu       sync.Mutex
+		calls    []time.Time
+		degraded []bool
+		done     = make(chan struct{})
+	)
+	fake := &fakeStore{sweep: func(ctx context.Context, opts queue.SweepOptions) (int, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls = append(calls, time.Now())
+		degraded = append(degraded, promtest.ToFloat64(metrics.SweeperConsecutiveErrors) > 0)
+		switch n := len(calls); {
+		case n <= failing:
+			return 0, errors.New("connection refused")
+		case n == failing+3:
+			close(done)
+		}
+		return 0, nil
+	}}
+	swp := sweeper.New(fake, interval, queue.SweepOptions{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go swp.Start(ctx)
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the sweeper to recover and keep sweeping")
+	}
+	cancel()
+
+	mu.Lock()
+	defer mu.Unlock()
+	// after the k-th failure in a row the sweeper waits 2^k intervals
+	for k := 1; k <= failing; k++ {
+		gap, want := calls[k].Sub(calls[k-1]), interval<<k
+		if gap < want {
+			t.Fatalf("Expected at least %s after failure %d, waited %s", want, k, gap)
+		}
+		if !degraded[k] {
+			t.Fatalf("Expected consecutive errors counted after failure %d", k)
+		}
+	}
+	fmt.Println("✓ Wait doubled after each consecutive failure")
+
+	if degraded[failing+1] {
+		t.Fatal("Expected the sweeper healthy again after a successful sweep")
+	}
+	if gap := calls[failing+2].Sub(calls[failing+1]); gap >= interval<<failing {
+		t.Fatalf("Expected the normal interval after recovery, waited %s", gap)
+	}
+	if v := promtest.ToFloat64(metrics.SweeperConsecutiveErrors); v != 0 {
+		t.Fatalf("Expected the consecutive errors gauge reset, got %v", v)
+	}
+	fmt.Println("✓ Recovered to the normal interval and cleared degraded")
+}
@@ -0,0 +1,42 @@
+package tests
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestTimestampsAreUTC(t *testing.T) {
+	// pgx hands timestamptz back in time.Local; make that something other
+	// than UTC so a leaked zone would show up as an offset.
+	local := time.Local
+	time.Local = time.FixedZone("UTC+5", 5*60*60)
+	defer func() { time.Local = local }()
+
+	_, teardown := setupTestServer(t)
+	defer teardown()
+
+	fmt.Println("\n=== Test: UTC Timestamps ===")
+
+	enqueueMessage(t, "timestamps", map[string]interface{}{"body": map[string]string{"task": "when"}})
+	messages := receiveMessages(t, "timestamps", 1, 30000)
+	if len(messages) != 1 {
+		t.Fatalf("Expected 1 message, got %d", len(messages))
+	}
+	for _, field := range []string{"enqueued_at", "not_before", "lease_until"} {
+		assertUTCTimestamp(t, field, messages[0][field])
+		fmt.Printf("✓ %s = %v\n", field, messages[0][field])
+	}
+}
+
+var utcTimestamp = regexp.MustCompile(`^\d{4}-\d\d-\d\dT\d\d:\d\d:\d\d\.\d{9}Z$`)
+
+// assertUTCTimestamp checks v is RFC 3339 in UTC with nanosecond precision.
+func assertUTCTimestamp(t *testing.T, field string, v interface{}) {
+	t.Helper()
+	s, ok := v.(string)
+	if !ok || !utcTimestamp.MatchString(s) {
+		t.Fatalf("Expected %s as UTC RFC 3339 with 9 fractional digits, got %v", field, v)
+	}
+}
@@ -0,0 +1,77 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestTopicFanOut(t *testing.T) {
+	_, teardown := setupTestServer(t)
+	defer teardown()
+
+	fmt.Println("\n=== Test: Topic Fan-Out ===")
+
+	status, subs := doJSON(t, http.MethodPut, "/v1/topics/orders-placed/subscriptions",
+		map[string]interface{}{"queues": []string{"billing", "shipping"}})
+	if status != http.StatusOK || len(subs["queues"].([]interface{})) != 2 {
+		t.Fatalf("Expected 2 subscriptions, got %d %v", status, subs)
+	}
+	fmt.Println("✓ Subscribed billing and shipping")
+
+	status, published := doJSON(t, http.MethodPost, "/v1/topics/orders-placed/messages",
+		map[string]interface{}{"body": map[string]string{"order": "A-1"}})
+	if status != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d %v", status, published)
+	}
+	copies := published["messages"].([]interface{})
+	if len(copies) != 2 {
+		t.Fatalf("Expected a message ID per subscriber, got %v", copies)
+	}
+
+	for _, c := range copies {
+		c := c.(map[string]interface{})
+		qname := c["queue"].(string)
+		messages := receiveMessages(t, qname, 10, 30000)
+		if len(messages) != 1 || messages[0]["id"] != c["id"] {
+			t.Fatalf("Expected message %v in %s, got %v", c["id"], qname, messages)
+		}
+		if messages[0]["body"].(map[string]interface{})["order"] != "A-1" {
+			t.Fatalf("Expected the published body in %s, got %v", qname, messages[0]["body"])
+		}
+		fmt.Printf("✓ %s got its own copy (id %v)\n", qname, c["id"])
+	}
+
+	status, _ = doJSON(t, http.MethodPost, "/v1/topics/nobody-listens/messages",
+		map[string]interface{}{"body": map[string]string{"order": "A-2"}})
+	if status != http.StatusNotFound {
+		t.Fatalf("Expected 404 for a topic without subscriptions, got %d", status)
+	}
+	fmt.Println("✓ Publishing to a topic without subscribers is 404")
+}
+
+// doJSON sends payload to path on the test server and decodes the JSON object reply.
+func doJSON(t *testing.T, method, path string, payload interface{}) (int, map[string]interface{}) {
+	return doAdminJSON(t, method, path, "", payload)
+}
+
+// doAdminJSON is doJSON with token, if set, as the admin bearer token.
+func doAdminJSON(t *testing.T, method, path, token string, payload interface{}) (int, map[string]interface{}) {
+	body, _ := json.Marshal(payload)
+	req, _ := http.NewRequest(method, "http://localhost:9999"+path, bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s failed: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	var out map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&out)
+	return resp.StatusCode, out
+}
@@ -0,0 +1,64 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/aridsondez/AWS-SQS-LITE/pkg/client"
+)
+
+func TestTraceParentRoundTrip(t *testing.T) {
+	_, teardown := setupTestServer(t)
+	defer teardown()
+
+	fmt.Println("\n=== Test: traceparent Round Trip ===")
+
+	const tp = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	c := client.NewClient("http://localhost:9999")
+	ctx := context.Background()
+	id, err := c.Enqueue(ctx, "trace-test", map[string]int{"n": 1}, &client.EnqueueOptions{TraceParent: tp})
+	if err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+
+	body, _ := json.Marshal(map[string]int{"max": 1, "visibility_ms": 30000})
+	resp, err := http.Post("http://localhost:9999/v1/queues/trace-test:receive", "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("Receive failed: %v", err)
+	}
+	defer resp.Body.Close()
+	var msgs []map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&msgs)
+	if len(msgs) != 1 || int64(msgs[0]["id"].(float64)) != id {
+		t.Fatalf("Expected message %d, got %v", id, msgs)
+	}
+	if got := resp.Header.Get("traceparent"); got != tp {
+		t.Fatalf("Expected traceparent header %q, got %q", tp, got)
+	}
+	if msgs[0]["traceparent"] != tp || msgs[0]["trace_id"] != tp {
+		t.Fatalf("Expected traceparent and trace_id %q, got %v", tp, msgs[0])
+	}
+	ackMessage(t, msgs[0])
+	fmt.Println("✓ traceparent stored on enqueue and returned on receive")
+
+	req, _ := http.NewRequest(http.MethodPost, "http://localhost:9999/v1/queues/trace-test/messages",
+		bytes.NewReader([]byte(`{"body":{"n":2},"trace_id":"order-42"}`)))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("traceparent", "not-a-traceparent")
+	if resp, err := http.DefaultClient.Do(req); err != nil || resp.StatusCode != http.StatusCreated {
+		t.Fatalf("Expected a malformed traceparent ignored, got %v %v", resp, err)
+	}
+	got, err := c.Receive(ctx, "trace-test", nil)
+	if err != nil || len(got) != 1 {
+		t.Fatalf("Receive failed: %v %v", got, err)
+	}
+	if got[0].TraceParent != "" {
+		t.Fatalf("Expected no traceparent for a freeform trace_id, got %q", got[0].TraceParent)
+	}
+	c.Ack(ctx, got[0].ID, got[0].Receipt)
+	fmt.Println("✓ Freeform trace_id kept but not propagated")
+}
@@ -0,0 +1,64 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/aridsondez/AWS-SQS-LITE/internal/api"
+)
+
+// redactor drops "ssn" before a body is stored and marks bodies on the way out.
+type redactor struct{}
+
+func (redactor) BeforeEnqueue(_ context.Context, _ string, body []byte) ([]byte, error) {
+	var fields map[string]interface{}
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return body, nil // not an object: nothing to redact
+	}
+	delete(fields, "ssn")
+	return json.Marshal(fields)
+}
+
+func (redactor) AfterReceive(_ context.Context, _ string, body []byte) ([]byte, error) {
+	var fields map[string]interface{}
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return body, nil
+	}
+	fields["redacted"] = true
+	return json.Marshal(fields)
+}
+
+func TestTransformerRedactsField(t *testing.T) {
+	db, teardown := setupTestServerWithConfig(t, testConfig(), api.WithTransformer(redactor{}))
+	defer teardown()
+
+	fmt.Println("\n=== Test: Body Transformer ===")
+
+	id := enqueueMessage(t, "transform", map[string]interface{}{
+		"body": map[string]string{"name": "ada", "ssn": "123-45-6789"},
+	})
+
+	// The stored row never held the field.
+	var stored []byte
+	if err := db.Pool.QueryRow(context.Background(), `SELECT body FROM messages WHERE id = $1`, id).Scan(&stored); err != nil {
+		t.Fatalf("Read stored body: %v", err)
+	}
+	var fields map[string]interface{}
+	json.Unmarshal(stored, &fields)
+	if _, ok := fields["ssn"]; ok || fields["name"] != "ada" {
+		t.Fatalf("Expected ssn redacted before storing, got %s", stored)
+	}
+	fmt.Printf("✓ Stored body redacted: %s\n", stored)
+
+	messages := receiveMessages(t, "transform", 1, 30000)
+	if len(messages) != 1 {
+		t.Fatalf("Expected 1 message, got %d", len(messages))
+	}
+	body := messages[0]["body"].(map[string]interface{})
+	if _, ok := body["ssn"]; ok || body["redacted"] != true {
+		t.Fatalf("Expected redacted body marked on receive, got %v", body)
+	}
+	fmt.Printf("✓ Received body transformed: %v\n", body)
+}
@@ -0,0 +1,144 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/aridsondez/AWS-SQS-LITE/internal/config"
+)
+
+var ulidPattern = regexp.MustCompile(`^[0-9A-HJKMNP-TV-Z]{26}$`)
+
+func setupULIDServer(t *testing.T) func() {
+	cfg := testConfig()
+	cfg.MessageIDFormat = config.MessageIDULID
+	_, teardown := setupTestServerWithConfig(t, cfg)
+	return teardown
+}
+
+func TestULIDMessageLifecycle(t *testing.T) {
+	teardown := setupULIDServer(t)
+	defer teardown()
+
+	fmt.Println("\n=== Test: ULID Message Lifecycle ===")
+
+	status, out := doJSON(t, http.MethodPost, "/v1/queues/ulid-test/messages", map[string]interface{}{"body": map[string]int{"n": 1}})
+	id, _ := out["id"].(string)
+	if status != http.StatusCreated || !ulidPattern.MatchString(id) {
+		t.Fatalf("Expected a ULID from enqueue, got %d %v", status, out)
+	}
+	fmt.Printf("✓ Enqueue returned ULID %s\n", id)
+
+	messages := receiveMessages(t, "ulid-test", 1, 30000)
+	if len(messages) != 1 || messages[0]["id"] != id {
+		t.Fatalf("Expected message %s, got %v", id, messages)
+	}
+	receipt, _ := messages[0]["receipt"].(string)
+	if !strings.HasPrefix(receipt, id+".") {
+		t.Fatalf("Expected the receipt to name the message by ULID, got %q", receipt)
+	}
+	fmt.Println("✓ Receive shows the same ULID, and receipts lead with it")
+
+	if status, _ := doJSON(t, http.MethodPost, "/v1/messages/1:ack", map[string]interface{}{"receipt": receipt}); status != http.StatusBadRequest {
+		t.Fatalf("Expected 400 for a serial id, got %d", status)
+	}
+	if status, _ := doJSON(t, http.MethodPost, "/v1/messages/01ARZ3NDEKTSV4RRFFQ69G5FAV:ack", map[string]interface{}{"receipt": receipt}); status != http.StatusNotFound {
+		t.Fatalf("Expected 404 for an unknown ULID, got %d", status)
+	}
+	ack := map[string]interface{}{"receipt": receipt, "ack_id": "ulid-ack-1"}
+	if status, out := doJSON(t, http.MethodPost, "/v1/messages/"+id+":ack", ack); status != http.StatusOK {
+		t.Fatalf("Expected the ack to succeed, got %d %v", status, out)
+	}
+	if status, out := doJSON(t, http.MethodPost, "/v1/messages/"+id+":ack", ack); status != http.StatusOK {
+		t.Fatalf("Expected the retried ack to replay, got %d %v", status, out)
+	}
+	if status, _ := doJSON(t, http.MethodPost, "/v1/messages/"+id+":ack", map[string]interface{}{"receipt": receipt}); status != http.StatusNotFound {
+		t.Fatalf("Expected 404 acking a gone message, got %d", status)
+	}
+	fmt.Println("✓ Ack by ULID, ack_id replay, and 404 once gone")
+
+	if status, _ := doJSON(t, http.MethodPost, "/v1/queues/ulid-test/checkpoints/readers:read", nil); status != http.StatusNotImplemented {
+		t.Fatalf("Expected checkpoints refused, got %d", status)
+	}
+	fmt.Println("✓ Checkpoints, which need serial ids, are refused")
+}
+
+func TestULIDBatches(t *testing.T) {
+	teardown := setupULIDServer(t)
+	defer teardown()
+
+	fmt.Println("\n=== Test: ULID Batches ===")
+
+	status, out := doJSON(t, http.MethodPost, "/v1/queues/ulid-batch/messages:batch", map[string]interface{}{
+		"entries": []map[string]interface{}{{"body": map[string]int{"n": 1}}, {"body": map[string]int{"n": 2}}},
+	})
+	if status != http.StatusCreated {
+		t.Fatalf("Expected the batch enqueued, got %d %v", status, out)
+	}
+	enqueued := map[string]bool{}
+	for _, r := range out["results"].([]interface{}) {
+		id, _ := r.(map[string]interface{})["id"].(string)
+		if !ulidPattern.MatchString(id) {
+			t.Fatalf("Expected ULIDs from batch enqueue, got %v", out)
+		}
+		enqueued[id] = true
+	}
+
+	messages := receiveMessages(t, "ulid-batch", 2, 30000)
+	receipts := make([]interface{}, 0, len(messages))
+	for _, m := range messages {
+		if !enqueued[m["id"].(string)] {
+			t.Fatalf("Received %v, which wasn't enqueued", m["id"])
+		}
+		receipts = append(receipts, m["receipt"])
+	}
+
+	status, out = doJSON(t, http.MethodPost, "/v1/messages:extend-batch", map[string]interface{}{"receipts": receipts, "visibility_ms": 60000})
+	renewed, _ := out["receipts"].(map[string]interface{})
+	if status != http.StatusOK || len(renewed) != 2 {
+		t.Fatalf("Expected both leases extended, got %d %v", status, out)
+	}
+	receipts = receipts[:0]
+	for id, rc := range renewed {
+		if !enqueued[id] || !strings.HasPrefix(rc.(string), id+".") {
+			t.Fatalf("Expected renewed receipts by ULID, got %v", renewed)
+		}
+		receipts = append(receipts, rc)
+	}
+	fmt.Println("✓ Batch enqueue and extend-batch name messages by ULID")
+
+	status, out = doJSON(t, http.MethodPost, "/v1/messages:ack-batch", map[string]interface{}{"receipts": receipts})
+	acked, _ := out["acked"].([]interface{})
+	if status != http.StatusOK || len(acked) != 2 {
+		t.Fatalf("Expected both acked, got %d %v", status, out)
+	}
+	for _, id := range acked {
+		if !enqueued[id.(string)] {
+			t.Fatalf("Expected acked ULIDs, got %v", acked)
+		}
+	}
+	fmt.Println("✓ Ack-batch takes and reports ULIDs")
+}
+
+func TestMessageIDFormatConfig(t *testing.T) {
+	fmt.Println("\n=== Test: MESSAGE_ID_FORMAT Config ===")
+
+	t.Setenv("DATABASE_URL", "postgres://localhost/unused")
+	cfg, err := config.LoadConfig()
+	if err != nil || cfg.MessageIDFormat != config.MessageIDSerial {
+		t.Fatalf("Expected serial ids by default, got %v, %v", cfg, err)
+	}
+	t.Setenv("MESSAGE_ID_FORMAT", "uuid")
+	if _, err := config.LoadConfig(); err == nil {
+		t.Fatal("Expected an unknown format rejected")
+	}
+	t.Setenv("MESSAGE_ID_FORMAT", config.MessageIDULID)
+	t.Setenv("GRPC_PORT", "9090")
+	if _, err := config.LoadConfig(); err == nil || !strings.Contains(err.Error(), "GRPC_PORT") {
+		t.Fatalf("Expected ulid refused alongside gRPC, got %v", err)
+	}
+	fmt.Println("✓ Serial by default; ulid can't be combined with gRPC")
+}
@@ -0,0 +1,840 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aridsondez/AWS-SQS-LITE/pkg/worker"
+)
+
+func TestWorkerPrefetch(t *testing.T) {
+	fmt.Println("\n=== Test: Worker Prefetch ===")
+
+	const prefetch = 4
+	var (
+		mu       sync.Mutex
+		nextID   int64
+		leased   int // received but not yet acked
+		peak     int
+		maxAsked int
+	)
+
+	// Fake server: hands out as many messages as asked for and tracks how
+	// many the worker holds at once.
+	fake := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case strings.HasSuffix(r.URL.Path, ":receive"):
+			var req struct {
+				Max int `json:"max"`
+			}
+			json.NewDecoder(r.Body).Decode(&req)
+			maxAsked = max(maxAsked, req.Max)
+			out := make([]map[string]interface{}, 0, req.Max)
+			for i := 0; i < req.Max; i++ {
+				nextID++
+				out = append(out, map[string]interface{}{
+					"id": nextID, "body": "{}", "receipt": fmt.Sprintf("%d.1", nextID),
+				})
+			}
+			leased += len(out)
+			peak = max(peak, leased)
+			json.NewEncoder(w).Encode(out)
+		case strings.HasSuffix(r.URL.Path, ":ack"):
+			leased--
+			w.Write([]byte(`{"ok":true}`))
+		}
+	}))
+	defer fake.Close()
+
+	var processed atomic.Int64
+	w := worker.New(worker.Config{
+		BaseURL:     fake.URL,
+		PollDelay:   10 * time.Millisecond,
+		BatchSize:   10,
+		Prefetch:    prefetch,
+		Concurrency: 2,
+		Visibility:  30 * time.Second,
+	})
+	w.Handle("prefetch-test", func(ctx context.Context, msg *worker.Message) error {
+		time.Sleep(20 * time.Millisecond) // slow handler
+		processed.Add(1)
+		return nil
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	w.Run(ctx)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if processed.Load() == 0 {
+		t.Fatal("Expected the worker to process messages")
+	}
+	if maxAsked > prefetch {
+		t.Fatalf("Expected receives capped at prefetch %d, asked for %d", prefetch, maxAsked)
+	}
+	// buffered (prefetch) plus the ones being handled (concurrency)
+	if peak > prefetch+2 {
+		t.Fatalf("Expected at most %d leased at once, saw %d", prefetch+2, peak)
+	}
+	fmt.Printf("✓ Held at most %d leases (processed %d)\n", peak, processed.Load())
+}
+
+func TestWorkerDefaultHandler(t *testing.T) {
+	fmt.Println("\n=== Test: Worker Default Handler ===")
+
+	var mu sync.Mutex
+	queues := []string{"alpha", "beta"}
+	pending := map[string]int{"alpha": 2, "beta": 2, "gamma": 2}
+	var nextID int64
+
+	fake := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case r.URL.Path == "/v1/queues":
+			json.NewEncoder(w).Encode(map[string]interface{}{"queues": queues})
+		case strings.HasSuffix(r.URL.Path, ":receive"):
+			q := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/v1/queues/"), ":receive")
+			out := []map[string]interface{}{}
+			if pending[q] > 0 {
+				pending[q]--
+				nextID++
+				out = append(out, map[string]interface{}{
+					"id": nextID, "body": "{}", "receipt": fmt.Sprintf("%d.1", nextID),
+				})
+			}
+			json.NewEncoder(w).Encode(out)
+		case strings.HasSuffix(r.URL.Path, ":ack"):
+			w.Write([]byte(`{"ok":true}`))
+		}
+	}))
+	defer fake.Close()
+
+	var hmu sync.Mutex
+	handled := map[string]int{}
+	w := worker.New(worker.Config{
+		BaseURL:           fake.URL,
+		PollDelay:         10 * time.Millisecond,
+		DiscoveryInterval: 50 * time.Millisecond,
+	})
+	w.HandleDefault(func(ctx context.Context, msg *worker.Message) error {
+		hmu.Lock()
+		handled[msg.Queue]++
+		hmu.Unlock()
+		return nil
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 600*time.Millisecond)
+	defer cancel()
+	go func() {
+		// a queue created while the worker is running
+		time.Sleep(150 * time.Millisecond)
+		mu.Lock()
+		queues = append(queues, "gamma")
+		mu.Unlock()
+	}()
+	if err := w.Run(ctx); err != nil {
+		t.Fatalf("Run with only a default handler failed: %v", err)
+	}
+
+	hmu.Lock()
+	defer hmu.Unlock()
+	for _, q := range []string{"alpha", "beta", "gamma"} {
+		if handled[q] != 2 {
+			t.Fatalf("Expected default handler to process 2 messages from %s, got %v", q, handled)
+		}
+	}
+	fmt.Println("✓ Default handler processed every discovered queue, including a new one")
+}
+
+func TestWorkerReleasesBufferedOnShutdown(t *testing.T) {
+	fmt.Println("\n=== Test: Worker Releases Buffered Messages On Shutdown ===")
+
+	var (
+		mu       sync.Mutex
+		served   bool
+		released []string
+	)
+	fake := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case strings.HasSuffix(r.URL.Path, ":receive"):
+			if served {
+				w.Write([]byte(`[]`))
+				return
+			}
+			served = true
+			w.Write([]byte(`[{"id":1,"body":{},"receipt":"1.1"},{"id":2,"body":{},"receipt":"2.1"},{"id":3,"body":{},"receipt":"3.1"}]`))
+		case strings.HasSuffix(r.URL.Path, ":release-batch"):
+			var req struct {
+				Receipts []string `json:"receipts"`
+			}
+			json.NewDecoder(r.Body).Decode(&req)
+			released = append(released, req.Receipts...)
+			w.Write([]byte(`{"released":[2,3],"failed":[]}`))
+		}
+	}))
+	defer fake.Close()
+
+	started := make(chan int64, 3)
+	w := worker.New(worker.Config{
+		BaseURL:     fake.URL,
+		PollDelay:   10 * time.Millisecond,
+		BatchSize:   3,
+		Concurrency: 1,
+		Visibility:  30 * time.Second,
+	})
+	w.Handle("release-test", func(ctx context.Context, msg *worker.Message) error {
+		started <- msg.ID
+		<-ctx.Done() // still busy when the worker stops
+		return ctx.Err()
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		w.Run(ctx)
+		close(done)
+	}()
+
+	var running int64
+	select {
+	case running = <-started:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected a handler to start")
+	}
+	cancel()
+	<-done
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(released) != 2 {
+		t.Fatalf("Expected the 2 buffered messages released, got %v", released)
+	}
+	for _, rc := range released {
+		if rc == fmt.Sprintf("%d.1", running) {
+			t.Fatalf("Expected the running message %d not to be released, got %v", running, released)
+		}
+	}
+	fmt.Printf("✓ Released %v; message %d was mid-handler and kept\n", released, running)
+}
+
+func TestWorkerPanicHandler(t *testing.T) {
+	fmt.Println("\n=== Test: Worker Panic Handler ===")
+
+	var (
+		served atomic.Bool
+		acks   atomic.Int64
+	)
+	fake := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, ":receive"):
+			if served.Swap(true) {
+				w.Write([]byte(`[]`))
+				return
+			}
+			w.Write([]byte(`[{"id": 7, "body": {}, "receipt": "7.1", "delivery_count": 1}]`))
+		case strings.HasSuffix(r.URL.Path, ":ack"):
+			acks.Add(1)
+			w.Write([]byte(`{"ok":true}`))
+		}
+	}))
+	defer fake.Close()
+
+	type panicked struct {
+		id        int64
+		recovered any
+	}
+	got := make(chan panicked, 1)
+	w := worker.New(worker.Config{
+		BaseURL:   fake.URL,
+		PollDelay: 10 * time.Millisecond,
+		PanicHandler: func(msg *worker.Message, recovered any) {
+			got <- panicked{msg.ID, recovered}
+		},
+	})
+	w.Handle("panic-test", func(ctx context.Context, msg *worker.Message) error {
+		panic("boom")
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		w.Run(ctx)
+		close(done)
+	}()
+
+	select {
+	case p := <-got:
+		if p.id != 7 || p.recovered != "boom" {
+			t.Fatalf("Expected message 7 with \"boom\", got %d with %v", p.id, p.recovered)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("PanicHandler never fired")
+	}
+	cancel()
+	<-done
+
+	if n := acks.Load(); n != 0 {
+		t.Fatalf("Expected the panicked message not to be acked, got %d acks", n)
+	}
+	fmt.Println("✓ PanicHandler got the message and recovered value; no ack")
+}
+
+func TestWorkerRunN(t *testing.T) {
+	fmt.Println("\n=== Test: Worker RunN ===")
+
+	var (
+		mu       sync.Mutex
+		nextID   int64
+		leased   int
+		acked    int
+		released int
+	)
+	fake := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case strings.HasSuffix(r.URL.Path, ":receive"):
+			var req struct {
+				Max int `json:"max"`
+			}
+			json.NewDecoder(r.Body).Decode(&req)
+			out := make([]map[string]interface{}, 0, req.Max)
+			for i := 0; i < req.Max; i++ {
+				nextID++
+				out = append(out, map[string]interface{}{
+					"id": nextID, "body": "{}", "receipt": fmt.Sprintf("%d.1", nextID),
+				})
+			}
+			leased += len(out)
+			json.NewEncoder(w).Encode(out)
+		case strings.HasSuffix(r.URL.Path, ":ack"):
+			acked++
+			w.Write([]byte(`{"ok":true}`))
+		case strings.HasSuffix(r.URL.Path, ":release-batch"):
+			var req struct {
+				Receipts []string `json:"receipts"`
+			}
+			json.NewDecoder(r.Body).Decode(&req)
+			released += len(req.Receipts)
+			w.Write([]byte(`{"released":[],"failed":[]}`))
+		}
+	}))
+	defer fake.Close()
+
+	var handled, running atomic.Int64
+	w := worker.New(worker.Config{
+		BaseURL:     fake.URL,
+		PollDelay:   10 * time.Millisecond,
+		BatchSize:   4,
+		Concurrency: 3,
+		Visibility:  30 * time.Second,
+	})
+	w.Handle("run-n", func(ctx context.Context, msg *worker.Message) error {
+		running.Add(1)
+		defer running.Add(-1)
+		time.Sleep(50 * time.Millisecond)
+		handled.Add(1)
+		return nil
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	start := time.Now()
+	if err := w.RunN(ctx, 5); err != nil {
+		t.Fatalf("RunN: %v", err)
+	}
+	if ctx.Err() != nil {
+		t.Fatal("Expected RunN to return on its own, not at the deadline")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if handled.Load() != 5 || acked != 5 {
+		t.Fatalf("Expected exactly 5 handled and acked, got %d handled, %d acked", handled.Load(), acked)
+	}
+	if running.Load() != 0 {
+		t.Fatalf("Expected no handler still running, got %d", running.Load())
+	}
+	if leased != acked+released {
+		t.Fatalf("Expected every extra lease released: %d leased, %d acked, %d released", leased, acked, released)
+	}
+	fmt.Printf("✓ Stopped after exactly 5 messages in %s (%d extra released)\n", time.Since(start).Round(time.Millisecond), released)
+}
+
+func TestWorkerRunFor(t *testing.T) {
+	fmt.Println("\n=== Test: Worker RunFor ===")
+
+	var acks atomic.Int64
+	fake := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, ":receive"):
+			w.Write([]byte(`[{"id": 1, "body": {}, "receipt": "1.1"}]`))
+		case strings.HasSuffix(r.URL.Path, ":ack"):
+			acks.Add(1)
+			w.Write([]byte(`{"ok":true}`))
+		}
+	}))
+	defer fake.Close()
+
+	finished := make(chan struct{}, 1)
+	w := worker.New(worker.Config{BaseURL: fake.URL, PollDelay: 10 * time.Millisecond, BatchSize: 1})
+	w.Handle("run-for", func(ctx context.Context, msg *worker.Message) error {
+		select {
+		case <-time.After(300 * time.Millisecond):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		finished <- struct{}{}
+		return nil
+	})
+
+	start := time.Now()
+	if err := w.RunFor(context.Background(), 100*time.Millisecond); err != nil {
+		t.Fatalf("RunFor: %v", err)
+	}
+	elapsed := time.Since(start)
+	select {
+	case <-finished:
+	default:
+		t.Fatal("Expected RunFor to wait for the running handler")
+	}
+	if acks.Load() != 1 || elapsed > 2*time.Second {
+		t.Fatalf("Expected the one started message acked before returning, got %d acks after %s", acks.Load(), elapsed)
+	}
+	fmt.Printf("✓ RunFor stopped polling at its deadline and drained the handler (%s)\n", elapsed.Round(time.Millisecond))
+}
+
+func TestWorkerDeferredAck(t *testing.T) {
+	fmt.Println("\n=== Test: Worker Deferred Ack ===")
+
+	var (
+		mu      sync.Mutex
+		served  bool
+		extends []string
+		acks    = map[string]time.Time{}
+	)
+	fake := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case strings.HasSuffix(r.URL.Path, ":receive"):
+			if served {
+				w.Write([]byte(`[]`))
+				return
+			}
+			served = true
+			w.Write([]byte(`[{"id":1,"body":{},"receipt":"1.1"},{"id":2,"body":{},"receipt":"2.1"}]`))
+		case strings.HasSuffix(r.URL.Path, ":extend-batch"):
+			var req struct {
+				Receipts []string `json:"receipts"`
+			}
+			json.NewDecoder(r.Body).Decode(&req)
+			extends = append(extends, req.Receipts...)
+			ids := make([]string, 0, len(req.Receipts))
+			for _, rc := range req.Receipts {
+				ids = append(ids, strings.SplitN(rc, ".", 2)[0])
+			}
+			fmt.Fprintf(w, `{"extended":[%s],"failed":[]}`, strings.Join(ids, ","))
+		case strings.HasSuffix(r.URL.Path, ":ack"):
+			acks[strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/v1/messages/"), ":ack")] = time.Now()
+			w.Write([]byte(`{"ok":true}`))
+		}
+	}))
+	defer fake.Close()
+
+	var secondStarted time.Time
+	w := worker.New(worker.Config{
+		BaseURL:     fake.URL,
+		PollDelay:   10 * time.Millisecond,
+		Concurrency: 1,
+		Visibility:  100 * time.Millisecond,
+	})
+	w.Handle("deferred-ack-test", func(ctx context.Context, msg *worker.Message) error {
+		done := make(chan error, 1)
+		if msg.ID == 1 {
+			// the downstream job confirms well after the lease would have run out
+			time.AfterFunc(400*time.Millisecond, func() { done <- nil })
+		} else {
+			mu.Lock()
+			secondStarted = time.Now()
+			mu.Unlock()
+			time.AfterFunc(100*time.Millisecond, func() { done <- fmt.Errorf("downstream rejected it") })
+		}
+		return worker.Defer(done)
+	})
+
+	start := time.Now()
+	ctx, cancel := context.WithTimeout(context.Background(), 800*time.Millisecond)
+	defer cancel()
+	w.Run(ctx)
+
+	mu.Lock()
+	defer mu.Unlock()
+	acked, ok := acks["1"]
+	if !ok || acked.Sub(start) < 400*time.Millisecond {
+		t.Fatalf("Expected message 1 acked after its confirmation, got %v", acks)
+	}
+	if _, ok := acks["2"]; ok {
+		t.Fatal("Expected the failed confirmation to leave message 2 unacked")
+	}
+	fmt.Printf("✓ Acked once confirmed, %s after start; failed confirmation not acked\n", acked.Sub(start).Round(time.Millisecond))
+
+	var renewals int
+	for _, rc := range extends {
+		if strings.HasPrefix(rc, "1.") {
+			renewals++
+		}
+	}
+	if renewals < 3 {
+		t.Fatalf("Expected the lease renewed while waiting, got %d extends of message 1", renewals)
+	}
+	fmt.Printf("✓ Lease renewed %d times while waiting, without AutoExtend\n", renewals)
+
+	if secondStarted.IsZero() || !secondStarted.Before(acked) {
+		t.Fatal("Expected the handler slot freed while message 1 waited")
+	}
+	fmt.Println("✓ The only handler slot was free for the next message meanwhile")
+}
+
+func TestWorkerMaxConcurrentRequests(t *testing.T) {
+	fmt.Println("\n=== Test: Worker Max Concurrent Requests ===")
+
+	var (
+		inFlight, peak atomic.Int32
+		nextID         atomic.Int64
+		acks           atomic.Int32
+	)
+	fake := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for p := peak.Load(); n > p && !peak.CompareAndSwap(p, n); p = peak.Load() {
+		}
+		time.Sleep(20 * time.Millisecond)
+
+		switch {
+		case strings.HasSuffix(r.URL.Path, ":receive"):
+			fmt.Fprintf(w, `[{"id":%d,"body":{},"receipt":"r"},{"id":%d,"body":{},"receipt":"r"}]`, nextID.Add(1), nextID.Add(1))
+		case strings.HasSuffix(r.URL.Path, ":ack"):
+			acks.Add(1)
+			w.Write([]byte(`{"ok":true}`))
+		default:
+			w.Write([]byte(`{"released":[]}`))
+		}
+	}))
+	defer fake.Close()
+
+	w := worker.New(worker.Config{
+		BaseURL:               fake.URL,
+		PollDelay:             time.Millisecond,
+		BatchSize:             2,
+		Concurrency:           4,
+		MaxConcurrentRequests: 2,
+	})
+	for _, q := range []string{"limit-a", "limit-b", "limit-c", "limit-d"} {
+		w.Handle(q, func(ctx context.Context, msg *worker.Message) error { return nil })
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	w.Run(ctx)
+
+	if acks.Load() == 0 {
+		t.Fatal("Expected messages to be processed")
+	}
+	if p := peak.Load(); p > 2 {
+		t.Fatalf("Expected at most 2 requests in flight, saw %d", p)
+	}
+	fmt.Printf("✓ %d acks across 4 queues with at most %d requests in flight\n", acks.Load(), peak.Load())
+}
+
+func TestWorkerSendsWorkerID(t *testing.T) {
+	fmt.Println("\n=== Test: Worker Sends Worker ID ===")
+
+	var (
+		mu   sync.Mutex
+		seen = make(map[string]string) // path suffix -> X-Worker-ID
+	)
+	fake := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		op := r.URL.Path[strings.LastIndexByte(r.URL.Path, ':')+1:]
+		mu.Lock()
+		seen[op] = r.Header.Get("X-Worker-ID")
+		mu.Unlock()
+		switch op {
+		case "receive":
+			w.Write([]byte(`[{"id":1,"body":{},"receipt":"r"}]`))
+		case "ack":
+			w.Write([]byte(`{"ok":true}`))
+		default:
+			w.Write([]byte(`{"released":[]}`))
+		}
+	}))
+	defer fake.Close()
+
+	w := worker.New(worker.Config{BaseURL: fake.URL, PollDelay: time.Millisecond, WorkerID: "billing-7"})
+	w.Handle("worker-id", func(ctx context.Context, msg *worker.Message) error { return nil })
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	w.Run(ctx)
+
+	mu.Lock()
+	defer mu.Unlock()
+	for _, op := range []string{"receive", "ack"} {
+		if seen[op] != "billing-7" {
+			t.Fatalf("Expected X-Worker-ID billing-7 on %s, got %q", op, seen[op])
+		}
+	}
+	fmt.Println("✓ Configured ID sent on receive and ack")
+}
+
+func TestWorkerHealthCheck(t *testing.T) {
+	fmt.Println("\n=== Test: Worker Health Check ===")
+
+	var receives atomic.Int64
+	fake := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, ":receive"):
+			receives.Add(1)
+			w.Write([]byte(`[]`))
+		default:
+			w.Write([]byte(`{"released":[]}`))
+		}
+	}))
+	defer fake.Close()
+
+	var down atomic.Bool
+	down.Store(true)
+	w := worker.New(worker.Config{
+		BaseURL:        fake.URL,
+		PollDelay:      5 * time.Millisecond,
+		HealthInterval: 20 * time.Millisecond,
+		HealthCheck: func(ctx context.Context) error {
+			if down.Load() {
+				return fmt.Errorf("database unreachable")
+			}
+			return nil
+		},
+	})
+	w.Handle("health", func(ctx context.Context, msg *worker.Message) error { return nil })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() { w.Run(ctx); close(done) }()
+	defer func() { cancel(); <-done }()
+
+	time.Sleep(150 * time.Millisecond)
+	if n := receives.Load(); n != 0 {
+		t.Fatalf("Expected no polls while the health check fails, got %d", n)
+	}
+	fmt.Println("✓ No polling while unhealthy")
+
+	down.Store(false)
+	time.Sleep(150 * time.Millisecond)
+	if receives.Load() == 0 {
+		t.Fatal("Expected polling to resume once the health check passes")
+	}
+	fmt.Println("✓ Polling resumes on recovery")
+
+	down.Store(true)
+	time.Sleep(60 * time.Millisecond) // let the next check see it
+	paused := receives.Load()
+	time.Sleep(150 * time.Millisecond)
+	if n := receives.Load(); n != paused {
+		t.Fatalf("Expected polling paused again, receives went from %d to %d", paused, n)
+	}
+	fmt.Println("✓ Polling pauses again when the check fails")
+}
+
+func TestWorkerPropagatesTraceParent(t *testing.T) {
+	fmt.Println("\n=== Test: Worker Propagates traceparent ===")
+
+	const (
+		callerTP  = "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"
+		messageTP = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	)
+	var (
+		mu   sync.Mutex
+		seen = make(map[string]string) // path suffix -> traceparent
+	)
+	fake := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		op := r.URL.Path[strings.LastIndexByte(r.URL.Path, ':')+1:]
+		mu.Lock()
+		seen[op] = r.Header.Get("traceparent")
+		mu.Unlock()
+		switch op {
+		case "receive":
+			fmt.Fprintf(w, `[{"id":1,"body":{},"receipt":"r","traceparent":%q}]`, messageTP)
+		case "ack":
+			w.Write([]byte(`{"ok":true}`))
+		default:
+			w.Write([]byte(`{"released":[]}`))
+		}
+	}))
+	defer fake.Close()
+
+	var inHandler atomic.Value
+	w := worker.New(worker.Config{BaseURL: fake.URL, PollDelay: time.Millisecond})
+	w.Handle("trace", func(ctx context.Context, msg *worker.Message) error {
+		inHandler.Store(worker.TraceParent(ctx))
+		return nil
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	w.Run(worker.ContextWithTraceParent(ctx, callerTP))
+
+	if got := inHandler.Load(); got != messageTP {
+		t.Fatalf("Expected the handler's context to carry %q, got %v", messageTP, got)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if seen["receive"] != callerTP {
+		t.Fatalf("Expected receive sent with the caller's traceparent, got %q", seen["receive"])
+	}
+	if seen["ack"] != messageTP {
+		t.Fatalf("Expected ack sent with the message's traceparent, got %q", seen["ack"])
+	}
+	fmt.Println("✓ Receive joins the caller's trace; handler and ack continue the message's")
+}
+
+func TestWorkerRetriesAckWithSameAckID(t *testing.T) {
+	fmt.Println("\n=== Test: Worker Retries Ack With Same Ack ID ===")
+
+	var (
+		mu     sync.Mutex
+		ackIDs []string
+	)
+	fake := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, ":receive"):
+			mu.Lock()
+			n := len(ackIDs)
+			mu.Unlock()
+			if n > 0 {
+				w.Write([]byte(`[]`))
+				return
+			}
+			w.Write([]byte(`[{"id":1,"body":{},"receipt":"r"}]`))
+		case strings.HasSuffix(r.URL.Path, ":ack"):
+			var req struct {
+				AckID string `json:"ack_id"`
+			}
+			json.NewDecoder(r.Body).Decode(&req)
+			mu.Lock()
+			ackIDs = append(ackIDs, req.AckID)
+			first := len(ackIDs) == 1
+			mu.Unlock()
+			if first {
+				http.Error(w, "unavailable", http.StatusServiceUnavailable)
+				return
+			}
+			w.Write([]byte(`{"ok":true}`))
+		default:
+			w.Write([]byte(`{"released":[]}`))
+		}
+	}))
+	defer fake.Close()
+
+	w := worker.New(worker.Config{BaseURL: fake.URL, PollDelay: 10 * time.Millisecond})
+	w.Handle("ack-retry", func(ctx context.Context, msg *worker.Message) error { return nil })
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	w.Run(ctx)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(ackIDs) != 2 {
+		t.Fatalf("Expected the failed ack retried once, got %d attempts", len(ackIDs))
+	}
+	if ackIDs[0] == "" || ackIDs[0] != ackIDs[1] {
+		t.Fatalf("Expected both attempts to carry the same ack_id, got %q", ackIDs)
+	}
+	fmt.Println("✓ Ack retried after a 503 with the same ack_id")
+}
+
+func TestWorkerOnReceive(t *testing.T) {
+	fmt.Println("\n=== Test: Worker OnReceive Hook ===")
+
+	var served atomic.Bool
+	fake := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, ":receive"):
+			if served.Swap(true) {
+				w.Write([]byte(`[]`))
+				return
+			}
+			w.Write([]byte(`[
+				{"id": 1, "body": {}, "receipt": "1.1", "delivery_count": 1, "enqueued_at": "2026-01-02T03:04:05.000000000Z"},
+				{"id": 2, "body": {}, "receipt": "2.1", "delivery_count": 1, "enqueued_at": "2026-01-02T03:04:06.000000000Z"}
+			]`))
+		case strings.HasSuffix(r.URL.Path, ":ack"):
+			w.Write([]byte(`{"ok":true}`))
+		}
+	}))
+	defer fake.Close()
+
+	type batch struct {
+		queue string
+		msgs  []*worker.Message
+	}
+	got := make(chan batch, 4)
+	handled := make(chan int64, 2)
+	w := worker.New(worker.Config{
+		BaseURL:   fake.URL,
+		PollDelay: 10 * time.Millisecond,
+		OnReceive: func(queue string, msgs []*worker.Message) {
+			got <- batch{queue, msgs}
+		},
+	})
+	w.Handle("hook-test", func(ctx context.Context, msg *worker.Message) error {
+		handled <- msg.ID
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		w.Run(ctx)
+		close(done)
+	}()
+
+	select {
+	case b := <-got:
+		if b.queue != "hook-test" || len(b.msgs) != 2 || b.msgs[0].ID != 1 || b.msgs[1].ID != 2 {
+			t.Fatalf("Expected messages 1 and 2 from hook-test, got %s %v", b.queue, b.msgs)
+		}
+		want := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+		if !b.msgs[0].EnqueuedAt.Equal(want) || b.msgs[1].Queue != "hook-test" {
+			t.Fatalf("Expected enqueued_at %v and the queue set, got %+v", want, b.msgs[0])
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("OnReceive never fired")
+	}
+	for i := 0; i < 2; i++ {
+		select {
+		case <-handled:
+		case <-time.After(2 * time.Second):
+			t.Fatal("Expected both messages handled")
+		}
+	}
+	time.Sleep(100 * time.Millisecond) // several more, empty, polls
+	cancel()
+	<-done
+
+	if n := len(got); n != 0 {
+		t.Fatalf("Expected empty polls not to call OnReceive, got %d more calls", n)
+	}
+	fmt.Println("✓ OnReceive got the batch, once, and handlers still ran")
+}